@@ -8,7 +8,9 @@ import (
 	"crowdfunding-backend/internal/api/payment"
 	"crowdfunding-backend/internal/api/project"
 	"crowdfunding-backend/internal/api/user"
+	"crowdfunding-backend/internal/gateway"
 	"crowdfunding-backend/internal/middleware"
+	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/repository/mysql"
 	"crowdfunding-backend/internal/service"
 	"crowdfunding-backend/internal/util"
@@ -91,23 +93,62 @@ func main() {
 		util.Logger.Fatal("初始化本地存储败", zap.Error(err))
 	}
 
+	// 个人数据导出文件不放在公开的上传目录中，只能通过带令牌的下载链接获取
+	exportStorage, err := storage.NewLocalStorage(config.AppConfig.ExportStoragePath)
+	if err != nil {
+		util.Logger.Fatal("初始化导出文件存储失败", zap.Error(err))
+	}
+
 	// 初化存储库、服务和处理器
 	userRepo := mysql.NewUserRepository(db)
-	userService := service.NewUserService(userRepo)
+	sessionRepo := mysql.NewSessionRepository(db)
+	mfaService := service.NewMFAService(mysql.NewMFARepository(db), userRepo)
+	userService := service.NewUserService(userRepo, sessionRepo, mysql.NewVerificationRepository(db), mfaService)
 	authHandler := user.NewAuthHandler(userService)
+	sessionHandler := user.NewSessionHandler(userService)
+	mfaHandler := user.NewMFAHandler(mfaService, userService)
+	loginProtection := service.NewLoginProtectionService(mysql.NewAuthThrottleRepository(db), userRepo, service.LoginProtectionPolicy{
+		MaxAccountFailures: config.AppConfig.LoginMaxFailures,
+		MaxIPFailures:      config.AppConfig.LoginIPMaxFailures,
+		MaxResetRequests:   config.AppConfig.PasswordResetLimit,
+		Lockout:            time.Duration(config.AppConfig.LoginLockout) * time.Minute,
+	})
+	unlockHandler := user.NewUnlockHandler(loginProtection)
+	oidcService := service.NewOIDCService(mysql.NewIdentityRepository(db), userRepo, config.AppConfig.OIDCProviders)
+	oidcHandler := user.NewOIDCHandler(oidcService, userService)
+	adminSessionHandler := admin.NewSessionHandler(userService)
+	adminAccountHandler := admin.NewAccountHandler(userService)
+	rbacService := service.NewRBACService(mysql.NewRoleRepository(db), userRepo)
+	roleHandler := admin.NewRoleHandler(rbacService)
+	auditHandler := admin.NewAuditHandler(service.NewAuditService(mysql.NewAuditRepository(db)))
 	profileHandler := user.NewProfileHandler(userService, localStorage)
 	projectRepo := mysql.NewProjectRepository(db)
-	projectService := service.NewProjectService(projectRepo)
+	exchangeRateRepo := mysql.NewExchangeRateRepository(db)
+	exchangeRateService := service.NewExchangeRateService(exchangeRateRepo)
+	exchangeRateHandler := admin.NewExchangeRateHandler(exchangeRateService)
+	projectService := service.NewProjectService(projectRepo, exchangeRateService)
 	projectHandler := project.NewProjectHandler(projectService, localStorage)
+	rewardRepo := mysql.NewRewardRepository(db)
+	rewardHandler := project.NewRewardHandler(service.NewRewardService(rewardRepo, projectRepo))
+	shippingService := service.NewShippingService(mysql.NewShippingRepository(db), rewardRepo, projectRepo)
+	shippingHandler := project.NewShippingHandler(shippingService)
 
 	// 添加 paymentRepo 初始化
 	paymentRepo := mysql.NewPaymentRepository(db)
 
+	// 初始化支付渠道
+	paymentGateway, err := gateway.New(config.AppConfig.PaymentProvider)
+	if err != nil {
+		util.Logger.Fatal("初始化支付渠道失败", zap.Error(err))
+	}
+
 	// 修改 AdminService 初始化
 	adminService := service.NewAdminService(
 		userRepo,
 		projectRepo,
 		paymentRepo,
+		projectService,
+		paymentGateway,
 		db,
 	)
 	adminHandler := admin.NewAdminHandler(adminService)
@@ -116,41 +157,79 @@ func main() {
 		paymentRepo,
 		userRepo,
 		projectRepo,
+		rewardRepo,
+		paymentGateway,
+		exchangeRateService,
+		shippingService,
 		db,
 	)
 	paymentHandler := payment.NewPaymentHandler(paymentService, projectService)
 
+	// 初始化幂等键服务，防止客户端重试重复创建支持和订单
+	idempotencyRepo := mysql.NewIdempotencyRepository(db)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, time.Duration(config.AppConfig.IdempotencyKeyTTL)*time.Hour)
+	webhookHandler := payment.NewWebhookHandler(paymentService)
+
+	// 初始化 PayoutService 和 SettlementService
+	payoutService := service.NewPayoutService(paymentRepo, projectRepo, config.AppConfig.PlatformFeePercent)
+	payoutHandler := payment.NewPayoutHandler(payoutService)
+	settlementService := service.NewSettlementService(projectRepo, paymentRepo, projectService, payoutService)
+
+	// 初始化 RefundService
+	refundService := service.NewRefundService(paymentRepo, paymentGateway, db)
+	refundHandler := payment.NewRefundHandler(refundService)
+
+	// 订阅项目状态流转
+	projectService.OnTransition(refundService.OnProjectTransition)
+	projectService.OnTransition(settlementService.OnProjectTransition)
+
 	// 初始化 CommunityService 和 CommunityHandler
 	communityRepo := mysql.NewCommunityRepository(db)
 	communityService := service.NewCommunityService(communityRepo)
 	communityHandler := community.NewCommunityHandler(communityService, localStorage)
 
-	// 初始化 EmailService
-	emailService := service.NewEmailService(userRepo)
-
-	// 测试发送邮件
-	err = emailService.SendVerificationEmail("your-test-email@example.com", "TestUser")
-	if err != nil {
-		util.Logger.Error("测试发送邮件失败", zap.Error(err))
-	} else {
-		util.Logger.Info("测试发送邮件成功")
-	}
+	// 初始化个人数据导出
+	exportService := service.NewDataExportService(mysql.NewDataExportRepository(db), userRepo, paymentRepo, communityRepo, exportStorage)
+	exportHandler := user.NewExportHandler(exportService)
 
-	// 启动定时任务检查过期项目
+	// 启动定时任务结算过期项目
 	go func() {
 		ticker := time.NewTicker(1 * time.Minute) // 每分钟检查一次
 		for range ticker.C {
 			util.Logger.Info("开始检查过期项目")
-			if err := adminService.CheckExpiredProjects(); err != nil {
+			if err := settlementService.ProcessExpiredProjects(); err != nil {
 				util.Logger.Error("检查过期项目失败", zap.Error(err))
 			}
+			if err := refundService.RetryPendingRefunds(); err != nil {
+				util.Logger.Error("重试退款失败", zap.Error(err))
+			}
+			if err := refundService.RetryFailedProjectRefunds(); err != nil {
+				util.Logger.Error("重试众筹失败项目退款失败", zap.Error(err))
+			}
+			if err := paymentService.ReleaseStaleExtraCharges(); err != nil {
+				util.Logger.Error("退回未应用的补差价扣款失败", zap.Error(err))
+			}
+			if _, err := idempotencyService.PurgeExpired(); err != nil {
+				util.Logger.Error("清理过期幂等键失败", zap.Error(err))
+			}
+			if _, err := userService.PurgeExpiredSessions(); err != nil {
+				util.Logger.Error("清理过期会话失败", zap.Error(err))
+			}
+			if _, err := userService.PurgeExpiredVerifications(); err != nil {
+				util.Logger.Error("清理过期验证令牌失败", zap.Error(err))
+			}
+			if _, err := userService.PurgeDeletedAccounts(); err != nil {
+				util.Logger.Error("匿名化已注销账户失败", zap.Error(err))
+			}
+			if _, err := exportService.PurgeExpiredExports(); err != nil {
+				util.Logger.Error("清理过期导出文件失败", zap.Error(err))
+			}
+			if _, err := oidcService.PurgeExpiredStates(); err != nil {
+				util.Logger.Error("清理过期第三方登录请求失败", zap.Error(err))
+			}
 		}
 	}()
 
-	// 初始化 RefundService
-	refundService := service.NewRefundService(paymentRepo, db)
-	refundHandler := payment.NewRefundHandler(refundService)
-
 	// 初始化错误监控
 	errorMonitor := middleware.NewErrorMonitor()
 
@@ -159,6 +238,7 @@ func main() {
 
 	// 添加中间件
 	r.Use(middleware.RecoveryMiddleware())
+	r.Use(middleware.RequestIDMiddleware())
 	r.Use(middleware.ErrorMonitorMiddleware(errorMonitor))
 
 	// 配置 CORS
@@ -171,11 +251,15 @@ func main() {
 		"Content-Length",
 		"Content-Type",
 		"Authorization",
+		middleware.IdempotencyKeyHeader,
+		middleware.RequestIDHeader,
 	}
 	corsConfig.ExposeHeaders = []string{
 		"Content-Length",
 		"Content-Type",
 		"Access-Control-Allow-Origin",
+		middleware.IdempotentReplayedHeader,
+		middleware.RequestIDHeader,
 	}
 
 	// 先应用 CORS 中间件
@@ -205,10 +289,19 @@ func main() {
 	{
 		// 用户相关路由
 		api.POST("/register", authHandler.Register)
-		api.POST("/login", authHandler.Login)
-		api.POST("/request-password-reset", authHandler.RequestPasswordReset)
+		api.POST("/login", middleware.LoginProtectionMiddleware(loginProtection, service.LoginActionLogin), authHandler.Login)
+		api.POST("/login/mfa", middleware.LoginProtectionMiddleware(loginProtection, service.LoginActionMFALogin), authHandler.MFALogin)
+		api.POST("/request-password-reset", middleware.LoginProtectionMiddleware(loginProtection, service.LoginActionPasswordReset), authHandler.RequestPasswordReset)
+		api.GET("/unlock-account", unlockHandler.UnlockAccount)
+		api.GET("/auth/oidc/providers", oidcHandler.ListProviders)
+		api.GET("/auth/oidc/:provider/login", oidcHandler.StartLogin)
+		api.POST("/auth/oidc/:provider/callback", middleware.LoginProtectionMiddleware(loginProtection, service.LoginActionOIDCLogin), oidcHandler.Callback)
+		api.GET("/account/export/download", exportHandler.DownloadExport)
+		api.POST("/account/restore", middleware.LoginProtectionMiddleware(loginProtection, service.LoginActionRestore), authHandler.RestoreAccount)
 		api.POST("/reset-password", authHandler.ResetPassword)
 		api.GET("/verify-email", authHandler.VerifyEmail)
+		// 访问令牌过期后仍需能够刷新，因此不经过认证中间件
+		api.POST("/refresh-token", authHandler.RefreshToken)
 
 		// 需要认证的路由
 		authorized := api.Group("/")
@@ -216,18 +309,34 @@ func main() {
 		{
 			authorized.GET("/profile", profileHandler.GetProfile)
 			authorized.PUT("/profile", profileHandler.UpdateProfile)
+			authorized.PUT("/password", profileHandler.ChangePassword)
 			authorized.POST("/logout", authHandler.Logout)
-			authorized.POST("/refresh-token", authHandler.RefreshToken)
+			authorized.GET("/sessions", sessionHandler.ListSessions)
+			authorized.DELETE("/sessions", sessionHandler.RevokeOtherSessions)
+			authorized.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+			authorized.GET("/mfa", mfaHandler.GetStatus)
+			authorized.POST("/mfa/enroll", mfaHandler.Enroll)
+			authorized.POST("/mfa/verify", mfaHandler.Verify)
+			authorized.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+			authorized.POST("/mfa/disable", mfaHandler.Disable)
 			authorized.POST("/profile/avatar", profileHandler.UploadAvatar)
 			authorized.DELETE("/account", profileHandler.DeleteAccount)
+			authorized.POST("/account/export", exportHandler.RequestExport)
+			authorized.GET("/identities", oidcHandler.ListIdentities)
+			authorized.POST("/identities/:provider/link", oidcHandler.StartLink)
+			authorized.POST("/identities/:provider/callback", oidcHandler.LinkCallback)
+			authorized.DELETE("/identities/:provider", oidcHandler.Unlink)
 		}
 
 		// 项目相关路由
 		api.POST("/projects", middleware.AuthMiddleware(userService), projectHandler.CreateProject)
 		api.GET("/projects/:id", projectHandler.GetProject)
+		api.GET("/projects/:id/preview", middleware.AuthMiddleware(userService), projectHandler.PreviewProject)
 		api.PUT("/projects/:id", middleware.AuthMiddleware(userService), projectHandler.UpdateProject)
+		api.POST("/projects/:id/submit", middleware.AuthMiddleware(userService), projectHandler.SubmitProject)
+		api.GET("/projects/:id/reviews", middleware.AuthMiddleware(userService), projectHandler.GetProjectReviews)
 		api.GET("/projects", projectHandler.ListProjects)
-		api.POST("/projects/:id/pledge", middleware.AuthMiddleware(userService), projectHandler.PledgeToProject)
+		api.POST("/projects/:id/pledge", middleware.AuthMiddleware(userService), middleware.IdempotencyMiddleware(idempotencyService), projectHandler.PledgeToProject)
 
 		// 新增的项目相关路由
 		api.POST("/projects/search", projectHandler.SearchProjects)
@@ -238,53 +347,127 @@ func main() {
 		api.GET("/projects/:id/updates", projectHandler.GetProjectUpdates)
 		api.POST("/projects/:id/comments", middleware.AuthMiddleware(userService), projectHandler.CreateProjectComment)
 		api.GET("/projects/:id/comments", projectHandler.GetProjectComments)
+		api.GET("/projects/:id/payouts", middleware.AuthMiddleware(userService), payoutHandler.GetProjectPayouts)
+		api.GET("/projects/:id/rewards", rewardHandler.ListRewardTiers)
+		api.POST("/projects/:id/rewards", middleware.AuthMiddleware(userService), rewardHandler.CreateRewardTier)
+		api.PUT("/projects/:id/rewards/:reward_id", middleware.AuthMiddleware(userService), rewardHandler.UpdateRewardTier)
+		api.DELETE("/projects/:id/rewards/:reward_id", middleware.AuthMiddleware(userService), rewardHandler.DeleteRewardTier)
+		api.GET("/projects/:id/add-ons", rewardHandler.ListRewardAddOns)
+		api.POST("/projects/:id/add-ons", middleware.AuthMiddleware(userService), rewardHandler.CreateRewardAddOn)
+		api.PUT("/projects/:id/add-ons/:add_on_id", middleware.AuthMiddleware(userService), rewardHandler.UpdateRewardAddOn)
+		api.DELETE("/projects/:id/add-ons/:add_on_id", middleware.AuthMiddleware(userService), rewardHandler.DeleteRewardAddOn)
+		api.GET("/projects/:id/shipping-zones", shippingHandler.ListShippingZones)
+		api.POST("/projects/:id/shipping-zones", middleware.AuthMiddleware(userService), shippingHandler.CreateShippingZone)
+		api.PUT("/projects/:id/shipping-zones/:zone_id", middleware.AuthMiddleware(userService), shippingHandler.UpdateShippingZone)
+		api.DELETE("/projects/:id/shipping-zones/:zone_id", middleware.AuthMiddleware(userService), shippingHandler.DeleteShippingZone)
 
 		// 支付相关路由
-		api.POST("/payments/projects/:project_id", middleware.AuthMiddleware(userService), paymentHandler.CreatePayment)
+		api.POST("/payments/projects/:project_id", middleware.AuthMiddleware(userService), middleware.IdempotencyMiddleware(idempotencyService), paymentHandler.CreatePayment)
 		api.GET("/orders/:id", middleware.AuthMiddleware(userService), paymentHandler.GetOrder)
+		api.GET("/orders/:id/history", middleware.AuthMiddleware(userService), paymentHandler.GetOrderHistory)
+		api.PATCH("/orders/:id", middleware.AuthMiddleware(userService), middleware.IdempotencyMiddleware(idempotencyService), paymentHandler.ModifyOrder)
+		api.DELETE("/orders/:id", middleware.AuthMiddleware(userService), paymentHandler.CancelOrder)
+		api.GET("/orders/:id/changes", middleware.AuthMiddleware(userService), paymentHandler.GetOrderChanges)
 		api.GET("/orders", middleware.AuthMiddleware(userService), paymentHandler.ListOrders)
 		api.POST("/orders/:id/refund/failed", middleware.AuthMiddleware(userService), paymentHandler.RequestRefundForFailedProject)
 		api.GET("/orders/:id/refund", middleware.AuthMiddleware(userService), refundHandler.GetRefundStatus)
 
-		// 管理员路由组
+		// 支付渠道回调，通过签名校验身份，无需登录
+		api.POST("/payments/webhook/:provider", webhookHandler.HandleWebhook)
+
+		// 管理员路由组，进入后台需要管理员或运营角色，每个操作再校验具体权限
 		adminRoutes := api.Group("/admin")
-		adminRoutes.Use(middleware.AuthMiddleware(userService), middleware.AdminMiddleware(userService))
+		adminRoutes.Use(middleware.AuthMiddleware(userService), middleware.AdminMiddleware(userService, rbacService))
 		{
+			requirePermission := func(permission string) gin.HandlerFunc {
+				return middleware.RequirePermission(rbacService, permission)
+			}
+
 			// 项目管理
 			projectAdmin := adminRoutes.Group("/projects")
 			{
-				projectAdmin.GET("", adminHandler.GetProjects)                      // 获取项目列表
-				projectAdmin.POST("/:id/review", adminHandler.ReviewProject)        // 审核项目
-				projectAdmin.PATCH("/:id/status", adminHandler.UpdateProjectStatus) // 更新项目状态
-				projectAdmin.DELETE("/:id", adminHandler.DeleteProject)             // 删除项目
-				projectAdmin.GET("/:id/pledgers", adminHandler.GetProjectPledgers)  // 获取支持者
-				projectAdmin.POST("/categories", projectHandler.CreateCategory)     // 创建分类
-				projectAdmin.POST("/tags", projectHandler.CreateTag)                // 创建标签
+				projectAdmin.GET("", requirePermission(model.PermissionProjectRead), adminHandler.GetProjects)                        // 获取项目列表
+				projectAdmin.POST("/:id/review", requirePermission(model.PermissionProjectReview), adminHandler.ReviewProject)        // 审核项目
+				projectAdmin.GET("/:id/reviews", requirePermission(model.PermissionProjectRead), adminHandler.GetProjectReviews)      // 审核记录
+				projectAdmin.PATCH("/:id/status", requirePermission(model.PermissionProjectManage), adminHandler.UpdateProjectStatus) // 更新项目状态
+				projectAdmin.DELETE("/:id", requirePermission(model.PermissionProjectManage), adminHandler.DeleteProject)             // 删除项目
+				projectAdmin.GET("/:id/pledgers", requirePermission(model.PermissionOrderRead), adminHandler.GetProjectPledgers)      // 获取支持者
+				projectAdmin.GET("/:id/refunds", requirePermission(model.PermissionOrderRead), refundHandler.GetProjectRefundReport)  // 退款执行报告
+				projectAdmin.POST("/categories", requirePermission(model.PermissionProjectManage), projectHandler.CreateCategory)     // 创建分类
+				projectAdmin.POST("/tags", requirePermission(model.PermissionProjectManage), projectHandler.CreateTag)                // 创建标签
 			}
 
 			// 用户管理
 			userAdmin := adminRoutes.Group("/users")
 			{
-				userAdmin.GET("", adminHandler.GetUsers)                // 获取用户列表
-				userAdmin.PUT("/:id/role", adminHandler.UpdateUserRole) // 更新用户角色
+				userAdmin.GET("", requirePermission(model.PermissionUserRead), adminHandler.GetUsers)                              // 获取用户列表
+				userAdmin.PUT("/:id/role", requirePermission(model.PermissionRoleManage), roleHandler.SetUserRole)                 // 替换用户角色
+				userAdmin.GET("/:id/roles", requirePermission(model.PermissionUserRead), roleHandler.GetUserRoles)                 // 查看用户角色
+				userAdmin.POST("/:id/roles", requirePermission(model.PermissionRoleManage), roleHandler.AssignRole)                // 分配角色
+				userAdmin.DELETE("/:id/roles/:role", requirePermission(model.PermissionRoleManage), roleHandler.RevokeRole)        // 收回角色
+				userAdmin.GET("/:id/sessions", requirePermission(model.PermissionUserRead), adminSessionHandler.ListUserSessions)  // 查看登录会话
+				userAdmin.DELETE("/:id/sessions", requirePermission(model.PermissionUserManage), adminSessionHandler.ForceLogout)  // 强制下线
+				userAdmin.POST("/:id/suspend", requirePermission(model.PermissionUserManage), adminAccountHandler.SuspendUser)     // 停用账户
+				userAdmin.POST("/:id/unsuspend", requirePermission(model.PermissionUserManage), adminAccountHandler.UnsuspendUser) // 恢复停用的账户
+			}
+
+			// 角色和权限管理
+			adminRoutes.GET("/me/permissions", roleHandler.MyPermissions)                                               // 当前管理员的权限
+			adminRoutes.GET("/permissions", requirePermission(model.PermissionRoleManage), roleHandler.ListPermissions) // 可授予的权限
+			roleAdmin := adminRoutes.Group("/roles")
+			roleAdmin.Use(requirePermission(model.PermissionRoleManage))
+			{
+				roleAdmin.GET("", roleHandler.ListRoles)         // 获取角色列表
+				roleAdmin.POST("", roleHandler.CreateRole)       // 创建自定义角色
+				roleAdmin.PUT("/:id", roleHandler.UpdateRole)    // 修改角色权限
+				roleAdmin.DELETE("/:id", roleHandler.DeleteRole) // 删除自定义角色
 			}
 
 			// 订单和退款管理
 			orderAdmin := adminRoutes.Group("/orders")
 			{
-				orderAdmin.GET("/refunds", adminHandler.GetRefundRequests)         // 获取退款列表
-				orderAdmin.GET("/refunds/:id/approve", adminHandler.ProcessRefund) // 处理退款
+				orderAdmin.GET("/refunds", requirePermission(model.PermissionOrderRead), adminHandler.GetRefundRequests)             // 获取退款列表
+				orderAdmin.GET("/refunds/:id/approve", requirePermission(model.PermissionRefundApprove), adminHandler.ProcessRefund) // 处理退款
+				orderAdmin.GET("/:id", requirePermission(model.PermissionOrderRead), paymentHandler.GetOrder)                        // 查看订单
 			}
 
 			// 发货管理
 			shipmentAdmin := adminRoutes.Group("/shipments")
+			shipmentAdmin.Use(requirePermission(model.PermissionShipmentManage))
 			{
 				shipmentAdmin.POST("", adminHandler.CreateShipment)          // 创建发货记录
 				shipmentAdmin.PUT("/:id", adminHandler.UpdateShipmentStatus) // 更新发货状态
 			}
 
+			// 创作者结算管理
+			payoutAdmin := adminRoutes.Group("/payouts")
+			{
+				payoutAdmin.GET("", requirePermission(model.PermissionPayoutRead), payoutHandler.ListPayouts)                   // 获取结算记录列表
+				payoutAdmin.POST("/:id/approve", requirePermission(model.PermissionPayoutApprove), payoutHandler.ApprovePayout) // 审批结算
+				payoutAdmin.POST("/:id/paid", requirePermission(model.PermissionPayoutApprove), payoutHandler.MarkPayoutPaid)   // 标记已打款
+			}
+
+			// 汇率管理
+			rateAdmin := adminRoutes.Group("/exchange-rates")
+			{
+				rateAdmin.GET("", requirePermission(model.PermissionExchangeRateRead), exchangeRateHandler.ListExchangeRates)     // 获取汇率列表
+				rateAdmin.POST("", requirePermission(model.PermissionExchangeRateManage), exchangeRateHandler.CreateExchangeRate) // 录入汇率
+			}
+
+			// 社区管理
+			adminRoutes.DELETE("/posts/:id", requirePermission(model.PermissionCommunityModerate), communityHandler.ModeratePost)       // 删除违规动态
+			adminRoutes.DELETE("/comments/:id", requirePermission(model.PermissionCommunityModerate), communityHandler.ModerateComment) // 删除违规评论
+
 			// 系统管理
-			adminRoutes.GET("/stats", adminHandler.GetSystemStats) // 系统统计
+			adminRoutes.GET("/stats", requirePermission(model.PermissionStatsRead), adminHandler.GetSystemStats) // 系统统计
+
+			// 审计日志
+			auditAdmin := adminRoutes.Group("/audit")
+			auditAdmin.Use(requirePermission(model.PermissionAuditRead))
+			{
+				auditAdmin.GET("", auditHandler.ListAuditLogs)          // 查询审计日志
+				auditAdmin.GET("/export", auditHandler.ExportAuditLogs) // 导出 CSV
+			}
 		}
 
 		// 社区相关路由
@@ -321,7 +504,7 @@ func main() {
 		api.PUT("/addresses/:id/default", middleware.AuthMiddleware(userService), userHandler.SetDefaultAddress)
 
 		// 在 API 路由组中添加
-		api.POST("/admin/login", authHandler.AdminLogin)
+		api.POST("/admin/login", middleware.LoginProtectionMiddleware(loginProtection, service.LoginActionAdminLogin), authHandler.AdminLogin)
 
 		// 在社区相关路由部分添加
 		api.POST("/comments/:id/reply", middleware.AuthMiddleware(userService), communityHandler.CreateCommentReply)
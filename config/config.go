@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -31,7 +32,30 @@ type Config struct {
 	GCSBucketName      string
 	GCSCredentialsFile string
 	LocalStoragePath   string
-	Debug              bool // 是否开启调试模式
+	PaymentProvider    string                        // 支付渠道，默认使用本地沙箱
+	WebhookSecrets     map[string]string             // 各支付渠道回调签名密钥，格式 provider:secret,provider:secret
+	PlatformFeePercent float64                       // 平台服务费比例（百分比），从创作者结算金额中扣除
+	IdempotencyKeyTTL  int                           // 幂等键有效期（小时）
+	AccessTokenTTL     int                           // 访问令牌有效期（分钟）
+	RefreshTokenTTL    int                           // 刷新令牌有效期（小时），每次刷新后重新计算
+	MFAIssuer          string                        // 两步验证器中显示的应用名称
+	LoginMaxFailures   int                           // 同一账户连续登录失败多少次后锁定
+	LoginIPMaxFailures int                           // 同一 IP 连续登录失败多少次后锁定
+	PasswordResetLimit int                           // 同一邮箱在锁定时长内最多请求几次密码重置
+	LoginLockout       int                           // 锁定时长（分钟），也是失败计数的统计窗口
+	AccountDeleteGrace int                           // 注销账户后可恢复的天数，到期后匿名化个人数据
+	ExportStoragePath  string                        // 个人数据导出文件目录，不能位于公开的上传目录中
+	ExportLinkTTL      int                           // 数据导出下载链接有效期（小时）
+	OIDCProviders      map[string]OIDCProviderConfig // 第三方登录的 OpenID Connect 提供方，按名称索引
+	Debug              bool                          // 是否开启调试模式
+}
+
+// OIDCProviderConfig 单个 OpenID Connect 提供方的配置
+type OIDCProviderConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // 提供方回调的前端页面，前端再将 code 和 state 提交给后端
 }
 
 // AppConfig 是全局配置变量
@@ -67,8 +91,23 @@ func Init() {
 		GCSBucketName:      getEnv("GCS_BUCKET_NAME", ""),
 		GCSCredentialsFile: getEnv("GCS_CREDENTIALS_FILE", ""),
 		LocalStoragePath:   getEnv("LOCAL_STORAGE_PATH", "./uploads"),
+		PaymentProvider:    getEnv("PAYMENT_PROVIDER", "sandbox"),
+		WebhookSecrets:     getEnvAsMap("PAYMENT_WEBHOOK_SECRETS"),
+		PlatformFeePercent: getEnvAsFloat("PLATFORM_FEE_PERCENT", 5),
+		IdempotencyKeyTTL:  getEnvAsInt("IDEMPOTENCY_KEY_TTL_HOURS", 24),
+		AccessTokenTTL:     getEnvAsInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+		RefreshTokenTTL:    getEnvAsInt("REFRESH_TOKEN_TTL_HOURS", 720),
+		MFAIssuer:          getEnv("MFA_ISSUER", "Crowdfunding"),
+		LoginMaxFailures:   getEnvAsInt("LOGIN_MAX_FAILURES", 5),
+		LoginIPMaxFailures: getEnvAsInt("LOGIN_IP_MAX_FAILURES", 20),
+		PasswordResetLimit: getEnvAsInt("PASSWORD_RESET_LIMIT", 5),
+		LoginLockout:       getEnvAsInt("LOGIN_LOCKOUT_MINUTES", 15),
+		AccountDeleteGrace: getEnvAsInt("ACCOUNT_DELETE_GRACE_DAYS", 30),
+		ExportStoragePath:  getEnv("EXPORT_STORAGE_PATH", "./exports"),
+		ExportLinkTTL:      getEnvAsInt("EXPORT_LINK_TTL_HOURS", 48),
 		Debug:              getEnvAsBool("DEBUG", true),
 	}
+	AppConfig.OIDCProviders = loadOIDCProviders(AppConfig.FrontendURL)
 
 	// 在 Init 函数中临时修改日志级别
 	AppConfig.LogLevel = "debug"
@@ -112,6 +151,47 @@ func getEnvAsBool(key string, defaultVal bool) bool {
 	return defaultVal
 }
 
+func getEnvAsFloat(key string, defaultVal float64) float64 {
+	valStr := getEnv(key, "")
+	if val, err := strconv.ParseFloat(valStr, 64); err == nil {
+		return val
+	}
+	return defaultVal
+}
+
+// getEnvAsMap 解析形如 key1:value1,key2:value2 的环境变量
+func getEnvAsMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || k == "" || v == "" {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// loadOIDCProviders 读取 OIDC_PROVIDERS 中列出的提供方，每个提供方的配置来自
+// OIDC_<NAME>_ISSUER、OIDC_<NAME>_CLIENT_ID、OIDC_<NAME>_CLIENT_SECRET 和 OIDC_<NAME>_REDIRECT_URL
+func loadOIDCProviders(frontendURL string) map[string]OIDCProviderConfig {
+	providers := make(map[string]OIDCProviderConfig)
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers[name] = OIDCProviderConfig{
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", frontendURL+"/auth/oidc/"+name+"/callback"),
+		}
+	}
+	return providers
+}
+
 func validateConfig() {
 	if AppConfig.DBHost == "" || AppConfig.DBPort == "" || AppConfig.DBUser == "" || AppConfig.DBPassword == "" || AppConfig.DBName == "" {
 		log.Fatal("错误：数据库配置不完整")
@@ -122,4 +202,9 @@ func validateConfig() {
 	if AppConfig.SMTPHost == "" || AppConfig.SMTPUsername == "" || AppConfig.SMTPPassword == "" {
 		log.Fatal("错误：SMTP配置不完整")
 	}
+	for name, provider := range AppConfig.OIDCProviders {
+		if provider.Issuer == "" || provider.ClientID == "" || provider.ClientSecret == "" {
+			log.Fatalf("错误：OIDC 提供方 %s 配置不完整", name)
+		}
+	}
 }
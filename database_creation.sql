@@ -386,4 +386,594 @@ ALTER TABLE orders ADD COLUMN is_reward BOOLEAN DEFAULT FALSE;
 -- 修改 comments 表,添加 parent_id 字段
 ALTER TABLE comments 
 ADD COLUMN parent_id INT NULL,
-ADD FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE;
\ No newline at end of file
+ADD FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE;
+-- 订单表添加支付渠道信息，订单只有在支付渠道确认扣款后才变为已支付
+ALTER TABLE orders
+ADD COLUMN payment_provider VARCHAR(32) NULL,
+ADD COLUMN payment_intent_id VARCHAR(128) NULL,
+ADD COLUMN paid_at TIMESTAMP NULL,
+MODIFY COLUMN status ENUM(
+    'pending',              -- 待支付
+    'paid',                 -- 已支付
+    'failed',               -- 支付失败
+    'shipped',              -- 已发货
+    'delivered',            -- 已送达
+    'refunded',             -- 已退款
+    'refund_pending',       -- 退款处理中
+    'refund_rejected',      -- 退款被拒绝
+    'crowdfunding_failed'   -- 众筹失败
+) NOT NULL DEFAULT 'pending';
+
+CREATE INDEX idx_orders_payment_intent ON orders(payment_provider, payment_intent_id);
+
+-- 支持记录添加支付失败状态
+ALTER TABLE pledges MODIFY COLUMN status ENUM('pending', 'completed', 'failed', 'refunded') DEFAULT 'pending';
+
+-- 支付渠道回调事件表，按渠道事件ID去重
+CREATE TABLE IF NOT EXISTS payment_webhook_events (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    event_id VARCHAR(128) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    order_id INT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_provider_event (provider, event_id),
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 订单状态流转历史表
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    from_status VARCHAR(32) NOT NULL,
+    to_status VARCHAR(32) NOT NULL,
+    actor VARCHAR(64) NOT NULL,           -- 操作者，如 system、user:1、admin:2、webhook:sandbox
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    INDEX idx_order_status_history_order (order_id, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 项目生命周期：添加暂停状态
+ALTER TABLE projects MODIFY COLUMN status ENUM('draft', 'pending_review', 'active', 'completed', 'failed', 'rejected', 'suspended') DEFAULT 'draft';
+
+-- 项目失败后的订单处理改由应用层的项目状态流转回调完成，以便记录订单状态历史
+DROP TRIGGER IF EXISTS check_project_status_change;
+DROP PROCEDURE IF EXISTS update_project_status_proc;
+
+-- 订单添加待发货状态：项目众筹成功结算后，已支付订单进入待发货
+ALTER TABLE orders MODIFY COLUMN status ENUM(
+    'pending',              -- 待支付
+    'paid',                 -- 已支付
+    'failed',               -- 支付失败
+    'ready_to_ship',        -- 待发货（项目已结算）
+    'shipped',              -- 已发货
+    'delivered',            -- 已送达
+    'refunded',             -- 已退款
+    'refund_pending',       -- 退款处理中
+    'refund_rejected',      -- 退款被拒绝
+    'crowdfunding_failed'   -- 众筹失败
+) NOT NULL DEFAULT 'pending';
+
+-- 创作者结算记录表，每个项目众筹成功后生成一条
+CREATE TABLE IF NOT EXISTS project_payouts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    project_id INT NOT NULL,
+    creator_id INT NOT NULL,
+    gross_amount DECIMAL(10, 2) NOT NULL,     -- 结算订单总额
+    net_amount DECIMAL(10, 2) NOT NULL,       -- 应付创作者金额
+    status ENUM('pending', 'approved', 'paid') NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_project_payout (project_id),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (creator_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 结算记录添加平台服务费、退款扣减及审批打款信息
+ALTER TABLE project_payouts
+ADD COLUMN refund_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER gross_amount,   -- 已退款订单金额
+ADD COLUMN fee_percent DECIMAL(5, 2) NOT NULL DEFAULT 0 AFTER refund_amount,     -- 平台服务费比例
+ADD COLUMN fee_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER fee_percent,       -- 平台服务费
+ADD COLUMN approved_by INT NULL,
+ADD COLUMN approved_at TIMESTAMP NULL,
+ADD COLUMN paid_by INT NULL,
+ADD COLUMN paid_at TIMESTAMP NULL,
+ADD COLUMN payment_reference VARCHAR(128) NULL,                                   -- 打款流水号
+ADD INDEX idx_project_payouts_status (status),
+ADD FOREIGN KEY (approved_by) REFERENCES users(id),
+ADD FOREIGN KEY (paid_by) REFERENCES users(id);
+
+-- 订单退款执行记录表，记录通过支付渠道发起的退款及重试情况
+CREATE TABLE IF NOT EXISTS order_refunds (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    project_id INT NOT NULL,
+    refund_request_id INT NULL,
+    provider VARCHAR(32) NOT NULL,
+    payment_intent_id VARCHAR(128) NOT NULL DEFAULT '',
+    amount DECIMAL(10, 2) NOT NULL,
+    status ENUM('pending', 'succeeded', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,                 -- 已尝试次数
+    last_error VARCHAR(512),
+    gateway_refund_id VARCHAR(128),                  -- 支付渠道侧的退款ID
+    next_attempt_at TIMESTAMP NULL,                  -- 下次重试时间
+    completed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (refund_request_id) REFERENCES refund_requests(id) ON DELETE SET NULL,
+    INDEX idx_order_refunds_due (status, next_attempt_at),
+    INDEX idx_order_refunds_project (project_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 多币种支持：项目声明币种，订单和支持记录保存支付币种的原始金额及折算汇率
+ALTER TABLE projects
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY' AFTER min_reward_amount;   -- 项目币种，已筹金额和目标金额均以此币种计
+
+ALTER TABLE pledges
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY' AFTER amount,             -- 项目币种（amount 为折算后金额）
+ADD COLUMN original_amount DECIMAL(10, 2) NULL AFTER currency,               -- 支付币种原始金额
+ADD COLUMN original_currency CHAR(3) NULL AFTER original_amount;             -- 支付币种
+
+ALTER TABLE orders
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY' AFTER amount,             -- 项目币种（amount 为折算后金额）
+ADD COLUMN original_amount DECIMAL(10, 2) NULL AFTER currency,               -- 实际扣款金额
+ADD COLUMN original_currency CHAR(3) NULL AFTER original_amount,             -- 实际扣款币种
+ADD COLUMN exchange_rate DECIMAL(18, 8) NOT NULL DEFAULT 1 AFTER original_currency; -- 下单时使用的汇率
+
+ALTER TABLE order_refunds
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY' AFTER amount,             -- 退款币种，与扣款币种一致
+ADD COLUMN project_amount DECIMAL(10, 2) NULL AFTER currency,                -- 折算为项目币种的退款金额
+ADD COLUMN project_currency CHAR(3) NULL AFTER project_amount;                -- 项目币种
+
+-- 已有数据均为默认币种
+UPDATE pledges SET original_amount = amount, original_currency = currency WHERE original_amount IS NULL;
+UPDATE orders SET original_amount = amount, original_currency = currency WHERE original_amount IS NULL;
+UPDATE order_refunds SET project_amount = amount, project_currency = currency WHERE project_amount IS NULL;
+
+-- 汇率表，由管理员维护。1 个 base_currency 兑换 rate 个 quote_currency，
+-- 折算时取生效时间不晚于当前时间的最新一条
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate DECIMAL(18, 8) NOT NULL,
+    effective_from DATETIME NOT NULL,     -- 生效时间
+    created_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_exchange_rates_pair (base_currency, quote_currency, effective_from)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 幂等键记录表，客户端通过 Idempotency-Key 请求头重试时回放首次请求的响应
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,                 -- 请求方法、路径和请求体的 SHA-256
+    status ENUM('processing', 'completed') NOT NULL DEFAULT 'processing',
+    response_status INT NULL,
+    response_content_type VARCHAR(128) NULL,
+    response_body MEDIUMBLOB NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,                  -- 过期后同一个键可以重新使用
+    UNIQUE KEY uk_idempotency_user_key (user_id, idempotency_key),
+    INDEX idx_idempotency_expires (expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 登录会话表，访问令牌的 jti 即会话ID。刷新令牌只保存 SHA-256 哈希，每次刷新轮换，
+-- 已轮换的旧刷新令牌再次出现时视为泄露并撤销整个会话
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL,
+    refresh_token_hash CHAR(64) NOT NULL,
+    device VARCHAR(255) NULL,                       -- 登录设备（User-Agent）
+    ip_address VARCHAR(45) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,                  -- 刷新令牌过期时间
+    revoked_at TIMESTAMP NULL,
+    revoke_reason VARCHAR(64) NULL,
+    INDEX idx_user_sessions_user (user_id, revoked_at),
+    INDEX idx_user_sessions_expires (expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 两步验证（TOTP）密钥，enabled_at 为空表示已生成密钥但尚未完成验证
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id INT PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL,                    -- Base32 编码的 TOTP 密钥
+    enabled_at TIMESTAMP NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,       -- 最近一次通过验证的时间步，防止验证码重放
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 两步验证恢复码，只保存 SHA-256 哈希，每个恢复码只能使用一次
+CREATE TABLE IF NOT EXISTS user_mfa_recovery_codes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_mfa_recovery_user (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 会话是否已通过两步验证，管理员路由只接受已通过两步验证的会话
+ALTER TABLE user_sessions ADD COLUMN mfa_verified_at TIMESTAMP NULL AFTER ip_address;
+
+-- 登录失败计数，按账户（邮箱）、IP 和密码重置分别统计，用于指数退避和临时锁定
+CREATE TABLE IF NOT EXISTS auth_throttles (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    scope VARCHAR(16) NOT NULL,                     -- account / ip / reset / reset_ip / mfa
+    throttle_key VARCHAR(255) NOT NULL,             -- 小写邮箱或客户端 IP
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at DATETIME NULL,
+    next_attempt_at DATETIME NULL,                  -- 退避期内拒绝请求
+    locked_until DATETIME NULL,
+    unlock_token_hash CHAR(64) NULL,                -- 解锁邮件中令牌的 SHA-256
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_auth_throttles_scope_key (scope, throttle_key),
+    INDEX idx_auth_throttles_unlock (unlock_token_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 登录和密码重置审计记录，只追加不修改
+CREATE TABLE IF NOT EXISTS login_audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NULL,
+    email VARCHAR(100) NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    user_agent VARCHAR(255) NULL,
+    action VARCHAR(32) NOT NULL,                    -- login / admin_login / mfa_login / password_reset / unlock
+    result VARCHAR(16) NOT NULL,                    -- success / failure / blocked / locked
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_login_audit_user (user_id, created_at),
+    INDEX idx_login_audit_email (email, created_at),
+    INDEX idx_login_audit_ip (ip_address, created_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 邮箱验证和密码重置令牌只保存 SHA-256 哈希，使用或撤销后立即失效
+DELETE FROM user_verifications;
+ALTER TABLE user_verifications
+    CHANGE COLUMN token token_hash CHAR(64) NOT NULL,
+    ADD COLUMN used_at TIMESTAMP NULL AFTER expires_at,
+    ADD COLUMN revoked_at TIMESTAMP NULL AFTER used_at,
+    ADD UNIQUE KEY uk_user_verifications_token (token_hash),
+    ADD INDEX idx_user_verifications_user (user_id, type);
+
+-- 账户生命周期：unverified -> active -> suspended / deleted，注销后在恢复期内可以恢复，到期后匿名化
+ALTER TABLE users
+    ADD COLUMN status ENUM('unverified', 'active', 'suspended', 'deleted') NOT NULL DEFAULT 'unverified' AFTER is_verified,
+    ADD COLUMN suspended_at TIMESTAMP NULL AFTER updated_at,
+    ADD COLUMN suspend_reason VARCHAR(255) NULL AFTER suspended_at,
+    ADD COLUMN purged_at TIMESTAMP NULL AFTER deleted_at,
+    ADD INDEX idx_users_status (status, deleted_at);
+
+UPDATE users SET status = IF(is_verified, 'active', 'unverified');
+UPDATE users SET status = 'deleted' WHERE deleted_at IS NOT NULL;
+
+-- 个人数据导出任务，压缩包保存在导出目录中，下载链接中的令牌只保存 SHA-256 哈希
+CREATE TABLE IF NOT EXISTS data_exports (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    status ENUM('pending', 'ready', 'failed', 'expired') NOT NULL DEFAULT 'pending',
+    file_path VARCHAR(255) NULL,
+    token_hash CHAR(64) NULL,
+    error_message VARCHAR(255) NULL,
+    expires_at DATETIME NULL,                       -- 下载链接过期时间，过期后删除文件
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME NULL,
+    UNIQUE KEY uk_data_exports_token (token_hash),
+    INDEX idx_data_exports_user (user_id, status),
+    INDEX idx_data_exports_expires (status, expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 第三方登录（OpenID Connect）身份，provider + subject 唯一对应一个用户，每个用户每个提供方只能绑定一个身份
+CREATE TABLE IF NOT EXISTS user_identities (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,                  -- 提供方 ID Token 中的 sub
+    email VARCHAR(100) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP NULL,
+    UNIQUE KEY uk_user_identities_subject (provider, subject),
+    UNIQUE KEY uk_user_identities_user (user_id, provider),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- OIDC 授权流程的 state，只保存 SHA-256 哈希，回调时删除保证一次性使用
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state_hash CHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,            -- PKCE code_verifier
+    user_id INT NULL,                               -- 非空表示已登录用户绑定身份
+    expires_at DATETIME NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_oidc_login_states_expires (expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 基于角色的权限控制：权限标识固定在代码中，角色和授权保存在数据库，admin 角色拥有全部权限（*）。
+-- users.role 由角色分配推导：拥有 admin 角色为 admin，拥有其他角色为 staff，否则为 user
+ALTER TABLE users MODIFY COLUMN role ENUM('user', 'staff', 'admin') DEFAULT 'user';
+
+CREATE TABLE IF NOT EXISTS roles (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    description VARCHAR(255) NULL,
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,       -- 内置角色不能修改或删除
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_roles_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INT NOT NULL,
+    permission VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role_id, permission),
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id INT NOT NULL,
+    role_id INT NOT NULL,
+    assigned_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id),
+    INDEX idx_user_roles_role (role_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    FOREIGN KEY (assigned_by) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO roles (name, description, is_system) VALUES
+    ('admin', '管理员，拥有全部权限', TRUE),
+    ('moderator', '社区管理，删除违规动态和评论', TRUE),
+    ('reviewer', '项目审核', TRUE),
+    ('finance', '财务，处理退款、结算和汇率', TRUE),
+    ('support', '客服，只读查看订单和用户', TRUE);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission FROM roles r
+JOIN (
+    SELECT 'admin' AS role_name, '*' AS permission
+    UNION ALL SELECT 'moderator', 'community.moderate'
+    UNION ALL SELECT 'reviewer', 'project.read'
+    UNION ALL SELECT 'reviewer', 'project.review'
+    UNION ALL SELECT 'finance', 'order.read'
+    UNION ALL SELECT 'finance', 'refund.approve'
+    UNION ALL SELECT 'finance', 'payout.read'
+    UNION ALL SELECT 'finance', 'payout.approve'
+    UNION ALL SELECT 'finance', 'exchange_rate.read'
+    UNION ALL SELECT 'finance', 'exchange_rate.manage'
+    UNION ALL SELECT 'support', 'order.read'
+    UNION ALL SELECT 'support', 'user.read'
+) p ON p.role_name = r.name;
+
+-- 现有管理员迁移为 admin 角色
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM users u JOIN roles r ON r.name = 'admin'
+WHERE u.role = 'admin';
+
+-- 管理操作审计日志：与操作在同一事务中写入，只追加，触发器禁止修改和删除
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor_id INT NOT NULL,                          -- 不设外键，用户注销后审计记录仍保留
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id INT NOT NULL,
+    before_data JSON NULL,
+    after_data JSON NULL,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_audit_log_actor (actor_id, created_at),
+    INDEX idx_audit_log_target (target_type, target_id, created_at),
+    INDEX idx_audit_log_action (action, created_at),
+    INDEX idx_audit_log_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+DELIMITER //
+
+DROP TRIGGER IF EXISTS audit_log_no_update//
+DROP TRIGGER IF EXISTS audit_log_no_delete//
+
+CREATE TRIGGER audit_log_no_update
+BEFORE UPDATE ON audit_log
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+END//
+
+CREATE TRIGGER audit_log_no_delete
+BEFORE DELETE ON audit_log
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+END//
+
+DELIMITER ;
+
+-- 项目回报档位：quantity_limit 为空表示不限量，quantity_claimed 为待支付和已支付订单占用的数量
+CREATE TABLE IF NOT EXISTS reward_tiers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    project_id INT NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    description TEXT,
+    price DECIMAL(10, 2) NOT NULL,                  -- 档位最低支持金额，项目币种
+    image_url VARCHAR(255) NOT NULL DEFAULT '',
+    quantity_limit INT NULL,
+    quantity_claimed INT NOT NULL DEFAULT 0,
+    estimated_delivery CHAR(7) NOT NULL,            -- 预计发货月份，格式 YYYY-MM
+    shipping_required BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    INDEX idx_reward_tiers_project (project_id, price),
+    CHECK (quantity_limit IS NULL OR quantity_claimed <= quantity_limit)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE pledges
+ADD COLUMN reward_tier_id INT NULL AFTER status,
+ADD FOREIGN KEY (reward_tier_id) REFERENCES reward_tiers(id);
+
+ALTER TABLE orders
+ADD COLUMN reward_tier_id INT NULL AFTER is_reward,
+ADD FOREIGN KEY (reward_tier_id) REFERENCES reward_tiers(id);
+
+-- 项目加购项：需搭配回报档位购买，quantity_claimed 为待支付和已支付订单占用的数量
+CREATE TABLE IF NOT EXISTS reward_add_ons (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    project_id INT NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    description TEXT,
+    price DECIMAL(10, 2) NOT NULL,                  -- 单价，项目币种
+    image_url VARCHAR(255) NOT NULL DEFAULT '',
+    quantity_limit INT NULL,
+    quantity_claimed INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    INDEX idx_reward_add_ons_project (project_id),
+    CHECK (quantity_limit IS NULL OR quantity_claimed <= quantity_limit)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 订单明细：下单时按目录价格记录档位、加购项和额外支持金额，小计之和等于订单金额
+CREATE TABLE IF NOT EXISTS order_items (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    item_type ENUM('reward', 'add_on', 'bonus') NOT NULL,
+    reward_tier_id INT NULL,
+    add_on_id INT NULL,
+    title VARCHAR(100) NOT NULL,
+    unit_price DECIMAL(10, 2) NOT NULL,             -- 项目币种
+    quantity INT NOT NULL DEFAULT 1,
+    subtotal DECIMAL(10, 2) NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (reward_tier_id) REFERENCES reward_tiers(id),
+    FOREIGN KEY (add_on_id) REFERENCES reward_add_ons(id),
+    INDEX idx_order_items_order (order_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 收货地址增加国家，用于匹配配送区域
+ALTER TABLE user_addresses
+ADD COLUMN country CHAR(2) NOT NULL DEFAULT 'CN' AFTER phone;                 -- ISO 3166-1 两位国家代码
+
+-- 项目配送区域：创作者按国家或省份划分区域，并为需要发货的回报档位设置各区域运费
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    project_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    INDEX idx_shipping_zones_project (project_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 配送区域包含的地区，province 为空字符串表示整个国家；同一地区在一个项目中只能属于一个区域
+CREATE TABLE IF NOT EXISTS shipping_zone_regions (
+    zone_id INT NOT NULL,
+    project_id INT NOT NULL,
+    country CHAR(2) NOT NULL,
+    province VARCHAR(50) NOT NULL DEFAULT '',
+    PRIMARY KEY (zone_id, country, province),
+    UNIQUE KEY uk_shipping_region_project (project_id, country, province),
+    FOREIGN KEY (zone_id) REFERENCES shipping_zones(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 回报档位在各配送区域的运费，项目币种；档位未设置运费的区域不可配送
+CREATE TABLE IF NOT EXISTS shipping_zone_rates (
+    zone_id INT NOT NULL,
+    reward_tier_id INT NOT NULL,
+    fee DECIMAL(10, 2) NOT NULL,
+    PRIMARY KEY (zone_id, reward_tier_id),
+    FOREIGN KEY (zone_id) REFERENCES shipping_zones(id) ON DELETE CASCADE,
+    FOREIGN KEY (reward_tier_id) REFERENCES reward_tiers(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 订单保存金额明细：amount 为订单总额 = subtotal + shipping_fee
+ALTER TABLE orders
+ADD COLUMN subtotal DECIMAL(10, 2) NULL AFTER pledge_id,                      -- 为空的历史订单小计等于 amount
+ADD COLUMN shipping_fee DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER subtotal;
+
+-- 支持修改与取消：众筹进行中支持者可以修改所选回报或取消支持
+ALTER TABLE orders MODIFY COLUMN status ENUM(
+    'pending',              -- 待支付
+    'paid',                 -- 已支付
+    'failed',               -- 支付失败
+    'ready_to_ship',        -- 待发货（项目已结算）
+    'shipped',              -- 已发货
+    'delivered',            -- 已送达
+    'refunded',             -- 已退款
+    'refund_pending',       -- 退款处理中
+    'refund_rejected',      -- 退款被拒绝
+    'crowdfunding_failed',  -- 众筹失败
+    'cancelled'             -- 支持者在众筹期间取消
+) NOT NULL DEFAULT 'pending';
+
+ALTER TABLE pledges MODIFY COLUMN status ENUM('pending', 'completed', 'failed', 'refunded', 'cancelled') DEFAULT 'pending';
+
+-- 订单变更记录：每次修改或取消记录变更前后的金额和档位，补扣款使用单独的支付意图
+CREATE TABLE IF NOT EXISTS order_changes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    change_type ENUM('modify', 'cancel') NOT NULL,
+    actor VARCHAR(64) NOT NULL,                        -- 操作者，如 user:1
+    old_reward_tier_id INT NULL,
+    new_reward_tier_id INT NULL,
+    old_amount DECIMAL(10, 2) NOT NULL,                -- 变更前订单总额，项目币种
+    new_amount DECIMAL(10, 2) NOT NULL,                -- 变更后订单总额，取消时为0
+    amount_delta DECIMAL(10, 2) NOT NULL,              -- 项目币种差额，正数为补扣款，负数为退款
+    original_delta DECIMAL(10, 2) NOT NULL,            -- 扣款币种差额，按下单汇率折算
+    payment_intent_id VARCHAR(128) NOT NULL DEFAULT '', -- 补扣款的支付意图
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    INDEX idx_order_changes_order (order_id, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 订单变更产生的部分退款关联到变更记录，退款成功后不改变订单状态
+ALTER TABLE order_refunds
+ADD COLUMN order_change_id INT NULL AFTER refund_request_id,
+ADD FOREIGN KEY (order_change_id) REFERENCES order_changes(id) ON DELETE SET NULL;
+
+-- 项目审核记录：每次审核保存结论、总体意见和逐字段意见，创建者修改后重新提交
+CREATE TABLE IF NOT EXISTS project_reviews (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    project_id INT NOT NULL,
+    reviewer_id INT NOT NULL,                          -- 不设外键，审核员注销后记录仍保留
+    decision ENUM('approved', 'changes_requested', 'rejected') NOT NULL,
+    comment TEXT NOT NULL,
+    field_comments JSON NULL,                          -- [{"field": "title", "comment": "..."}]
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    INDEX idx_project_reviews_project (project_id, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 第三方登录 state 绑定发起授权的浏览器：回调时校验 HttpOnly Cookie，防止授权链接被转发给他人完成登录或绑定
+ALTER TABLE oidc_login_states
+ADD COLUMN binding_hash CHAR(64) NOT NULL DEFAULT '' AFTER code_verifier;
+
+-- 有补扣款的订单修改先单独记录为待确认并保存支付意图，扣款完成后再锁定订单应用修改；
+-- 中断后仍为待确认的补扣款由定时任务退回
+ALTER TABLE order_changes
+ADD COLUMN status ENUM('pending', 'applied', 'failed') NOT NULL DEFAULT 'applied' AFTER change_type,
+ADD INDEX idx_order_changes_status (status, created_at);
+
+-- 保留上一次轮换前的刷新令牌哈希：只有已轮换的令牌再次出现才撤销会话，其他不匹配的令牌仅拒绝
+ALTER TABLE user_sessions
+ADD COLUMN previous_refresh_token_hash CHAR(64) NULL AFTER refresh_token_hash;
+
+-- 同步扣款已判定失败后才收到的扣款成功回调：渠道侧已扣款但订单未入账，标记待人工对账退款
+ALTER TABLE payment_webhook_events
+ADD COLUMN needs_reconciliation BOOLEAN NOT NULL DEFAULT FALSE AFTER payload,
+ADD INDEX idx_webhook_events_reconciliation (needs_reconciliation);
@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"crowdfunding-backend/internal/middleware"
+	"crowdfunding-backend/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler 处理管理员停用和恢复用户账户的请求
+type AccountHandler struct {
+	userService *service.UserService
+}
+
+func NewAccountHandler(userService *service.UserService) *AccountHandler {
+	return &AccountHandler{userService}
+}
+
+// SuspendUser 停用账户，用户在所有设备上立即下线且不能再登录
+func (h *AccountHandler) SuspendUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason" binding:"required,max=255"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请填写停用原因",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	adminID, _ := c.Get("user_id")
+	if userID == adminID.(int) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "不能停用自己的账户",
+		})
+		return
+	}
+	if err := h.userService.SuspendUser(userID, middleware.AuditActor(c), input.Reason); err != nil {
+		h.handleAccountError(c, err, "停用账户失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "账户已停用",
+	})
+}
+
+// UnsuspendUser 恢复被停用的账户
+func (h *AccountHandler) UnsuspendUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.userService.UnsuspendUser(userID, middleware.AuditActor(c)); err != nil {
+		h.handleAccountError(c, err, "恢复账户失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "账户已恢复",
+	})
+}
+
+func (h *AccountHandler) handleAccountError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidAccountState):
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+}
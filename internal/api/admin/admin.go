@@ -1,8 +1,10 @@
 package admin
 
 import (
+	"crowdfunding-backend/internal/middleware"
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/service"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -47,6 +49,7 @@ func (h *AdminHandler) GetProjects(c *gin.Context) {
 			"created_at":        p.CreatedAt.Format(time.RFC3339),
 			"total_amount":      p.TotalAmount,
 			"total_goal_amount": p.TotalGoalAmount,
+			"currency":          p.Currency,
 			"creator": gin.H{
 				"username": p.Creator.Username,
 				"email":    p.Creator.Email,
@@ -80,8 +83,10 @@ func (h *AdminHandler) ReviewProject(c *gin.Context) {
 	}
 
 	var input struct {
-		Approved bool   `json:"approved" binding:"required"`
-		Comment  string `json:"comment"`
+		Decision      string                      `json:"decision"` // approved、changes_requested 或 rejected
+		Approved      *bool                       `json:"approved"` // 兼容只区分通过和驳回的旧请求，未填写 decision 时使用
+		Comment       string                      `json:"comment"`
+		FieldComments []model.ProjectFieldComment `json:"field_comments"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -92,8 +97,42 @@ func (h *AdminHandler) ReviewProject(c *gin.Context) {
 		return
 	}
 
-	err = h.adminService.ReviewProject(projectID, input.Approved, input.Comment)
+	review := &model.ProjectReview{
+		Decision:      input.Decision,
+		Comment:       input.Comment,
+		FieldComments: input.FieldComments,
+	}
+	if review.Decision == "" && input.Approved != nil {
+		review.Decision = model.ProjectReviewRejected
+		if *input.Approved {
+			review.Decision = model.ProjectReviewApproved
+		}
+	}
+
+	err = h.adminService.ReviewProject(projectID, review, middleware.AuditActor(c))
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidProjectReview) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+		if service.IsInvalidTransition(err) {
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "项目当前状态不允许审核",
+				"error":   err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "项目不存在",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "审核项目失败",
@@ -104,10 +143,45 @@ func (h *AdminHandler) ReviewProject(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
+		"data":    review,
 		"message": "项目审核完成",
 	})
 }
 
+// GetProjectReviews 获取项目的审核记录
+func (h *AdminHandler) GetProjectReviews(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的项目ID",
+		})
+		return
+	}
+
+	reviews, err := h.adminService.GetProjectReviews(projectID)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "项目不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取审核记录失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": reviews,
+	})
+}
+
 func (h *AdminHandler) UpdateProjectStatus(c *gin.Context) {
 	projectID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -131,14 +205,7 @@ func (h *AdminHandler) UpdateProjectStatus(c *gin.Context) {
 	}
 
 	// 验证状态值
-	validStatuses := map[string]bool{
-		"pending_review": true,
-		"active":         true,
-		"completed":      true,
-		"failed":         true,
-		"rejected":       true,
-	}
-	if !validStatuses[input.Status] {
+	if !service.IsValidProjectStatus(input.Status) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
 			"message": "Invalid status value",
@@ -146,8 +213,23 @@ func (h *AdminHandler) UpdateProjectStatus(c *gin.Context) {
 		return
 	}
 
-	_, err = h.adminService.UpdateProjectStatus(projectID, input.Status)
+	_, err = h.adminService.UpdateProjectStatus(projectID, input.Status, middleware.AuditActor(c))
 	if err != nil {
+		if service.IsInvalidTransition(err) {
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "Project status transition not allowed",
+				"error":   err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "Project not found",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "Failed to update project status",
@@ -173,8 +255,15 @@ func (h *AdminHandler) DeleteProject(c *gin.Context) {
 		return
 	}
 
-	err = h.adminService.DeleteProject(projectID)
+	err = h.adminService.DeleteProject(projectID, middleware.AuditActor(c))
 	if err != nil {
+		if errors.Is(err, service.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "项目不存在",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "删除项目失败",
@@ -236,44 +325,6 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 	})
 }
 
-func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
-	userID, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的用户ID",
-		})
-		return
-	}
-
-	var input struct {
-		Role string `json:"role" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的请求数据",
-		})
-		return
-	}
-
-	err = h.adminService.UpdateUserRole(userID, input.Role)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": "更新用户角色失败",
-			"error":   err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"code":    200,
-		"message": "用户角色更新成功",
-	})
-}
-
 // 订单和退款管理
 func (h *AdminHandler) GetRefundRequests(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -311,8 +362,23 @@ func (h *AdminHandler) ProcessRefund(c *gin.Context) {
 	}
 
 	// 直接同意退款
-	err = h.adminService.ProcessRefund(requestID, true, "管理员已同意退款")
+	err = h.adminService.ProcessRefund(requestID, true, "管理员已同意退款", middleware.AuditActor(c))
 	if err != nil {
+		if service.IsInvalidTransition(err) || errors.Is(err, service.ErrRefundRequestProcessed) {
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "当前订单状态不允许退款",
+				"error":   err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrRefundRequestNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "退款申请不存在",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "处理退款申请失败",
@@ -338,8 +404,16 @@ func (h *AdminHandler) CreateShipment(c *gin.Context) {
 		return
 	}
 
-	err := h.adminService.CreateShipmentAndUpdateOrder(&shipment)
+	err := h.adminService.CreateShipmentAndUpdateOrder(&shipment, middleware.AuditActor(c))
 	if err != nil {
+		if service.IsInvalidTransition(err) {
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "当前订单状态不允许发货",
+				"error":   err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "创建发货记录失败",
@@ -378,7 +452,7 @@ func (h *AdminHandler) UpdateShipmentStatus(c *gin.Context) {
 		return
 	}
 
-	err = h.adminService.UpdateShipmentStatus(shipmentID, input.Status, input.TrackingNumber)
+	err = h.adminService.UpdateShipmentStatus(shipmentID, input.Status, input.TrackingNumber, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -0,0 +1,130 @@
+package admin
+
+import (
+	"bytes"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler 处理审计日志查询和导出请求
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService}
+}
+
+// ListAuditLogs 分页查询审计日志，支持按操作者、操作、对象和时间范围过滤
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的查询条件",
+			"error":   err.Error(),
+		})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	entries, total, err := h.auditService.ListAuditLogs(filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取审计日志失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"logs":     entries,
+			"total":    total,
+			"page":     page,
+			"pageSize": pageSize,
+		},
+	})
+}
+
+// ExportAuditLogs 以 CSV 文件导出符合条件的审计日志，超出导出上限时通过 X-Audit-Truncated 头提示
+func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的查询条件",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	_, truncated, err := h.auditService.ExportAuditLogs(filter, &buf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "导出审计日志失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-log-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("X-Audit-Truncated", strconv.FormatBool(truncated))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// parseAuditFilter 解析查询参数，from/to 支持 RFC3339 时间或 YYYY-MM-DD 日期，日期形式的 to 包含当天
+func parseAuditFilter(c *gin.Context) (model.AuditFilter, error) {
+	filter := model.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+	}
+	var err error
+	if value := c.Query("actor_id"); value != "" {
+		if filter.ActorID, err = strconv.Atoi(value); err != nil {
+			return filter, errors.New("actor_id 无效")
+		}
+	}
+	if value := c.Query("target_id"); value != "" {
+		if filter.TargetID, err = strconv.Atoi(value); err != nil {
+			return filter, errors.New("target_id 无效")
+		}
+	}
+	if value := c.Query("from"); value != "" {
+		if filter.From, _, err = parseAuditTime(value); err != nil {
+			return filter, errors.New("from 无效")
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		to, dateOnly, err := parseAuditTime(value)
+		if err != nil {
+			return filter, errors.New("to 无效")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = to
+	}
+	return filter, nil
+}
+
+func parseAuditTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	return t, true, err
+}
@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"crowdfunding-backend/internal/middleware"
+	"crowdfunding-backend/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeRateHandler 处理汇率维护相关请求
+type ExchangeRateHandler struct {
+	exchangeRateService *service.ExchangeRateService
+}
+
+func NewExchangeRateHandler(exchangeRateService *service.ExchangeRateService) *ExchangeRateHandler {
+	return &ExchangeRateHandler{exchangeRateService}
+}
+
+// ListExchangeRates 获取汇率记录，可按币种过滤
+func (h *ExchangeRateHandler) ListExchangeRates(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	rates, total, err := h.exchangeRateService.ListRates(c.Query("base_currency"), c.Query("quote_currency"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取汇率列表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"rates":    rates,
+			"total":    total,
+			"page":     page,
+			"pageSize": pageSize,
+		},
+	})
+}
+
+// CreateExchangeRate 录入汇率，effective_from 为空时立即生效
+func (h *ExchangeRateHandler) CreateExchangeRate(c *gin.Context) {
+	var input struct {
+		BaseCurrency  string    `json:"base_currency" binding:"required"`
+		QuoteCurrency string    `json:"quote_currency" binding:"required"`
+		Rate          string    `json:"rate" binding:"required"`
+		EffectiveFrom time.Time `json:"effective_from"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的请求数据",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rate, err := h.exchangeRateService.CreateRate(input.BaseCurrency, input.QuoteCurrency, input.Rate, input.EffectiveFrom, middleware.AuditActor(c))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCurrency) || errors.Is(err, service.ErrInvalidExchangeRate) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "录入汇率失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    201,
+		"message": "汇率已录入",
+		"data":    rate,
+	})
+}
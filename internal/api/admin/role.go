@@ -0,0 +1,292 @@
+package admin
+
+import (
+	"crowdfunding-backend/internal/middleware"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleHandler 处理角色、权限和用户角色分配的管理请求
+type RoleHandler struct {
+	rbacService *service.RBACService
+}
+
+func NewRoleHandler(rbacService *service.RBACService) *RoleHandler {
+	return &RoleHandler{rbacService}
+}
+
+// MyPermissions 获取当前管理员的权限，供前端决定显示哪些管理功能
+func (h *RoleHandler) MyPermissions(c *gin.Context) {
+	permissions, err := h.rbacService.GetUserPermissions(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取权限失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"permissions": permissions,
+		},
+	})
+}
+
+// ListPermissions 获取可以授予角色的全部权限
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": h.rbacService.ListPermissions(),
+	})
+}
+
+// ListRoles 获取所有角色及其权限
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	roles, err := h.rbacService.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取角色列表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": roles,
+	})
+}
+
+// CreateRole 创建自定义角色
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var input struct {
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description" binding:"max=255"`
+		Permissions []string `json:"permissions" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的请求数据",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	role := &model.Role{
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: input.Permissions,
+	}
+	if err := h.rbacService.CreateRole(role, middleware.AuditActor(c)); err != nil {
+		handleRoleError(c, err, "创建角色失败")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    201,
+		"message": "角色创建成功",
+		"data":    role,
+	})
+}
+
+// UpdateRole 修改自定义角色的描述和权限
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的角色ID",
+		})
+		return
+	}
+
+	var input struct {
+		Description string   `json:"description" binding:"max=255"`
+		Permissions []string `json:"permissions" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的请求数据",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	role, err := h.rbacService.UpdateRole(roleID, input.Description, input.Permissions, middleware.AuditActor(c))
+	if err != nil {
+		handleRoleError(c, err, "修改角色失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "角色修改成功",
+		"data":    role,
+	})
+}
+
+// DeleteRole 删除自定义角色
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的角色ID",
+		})
+		return
+	}
+
+	if err := h.rbacService.DeleteRole(roleID, middleware.AuditActor(c)); err != nil {
+		handleRoleError(c, err, "删除角色失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "角色已删除",
+	})
+}
+
+// GetUserRoles 获取用户拥有的角色
+func (h *RoleHandler) GetUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	roles, err := h.rbacService.GetUserRoles(userID)
+	if err != nil {
+		handleRoleError(c, err, "获取用户角色失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": roles,
+	})
+}
+
+// AssignRole 为用户分配角色
+func (h *RoleHandler) AssignRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的请求数据",
+		})
+		return
+	}
+
+	if err := h.rbacService.AssignRole(userID, input.Role, middleware.AuditActor(c)); err != nil {
+		handleRoleError(c, err, "分配角色失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "角色分配成功",
+	})
+}
+
+// RevokeRole 收回用户的角色
+func (h *RoleHandler) RevokeRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.rbacService.RevokeRole(userID, c.Param("role"), middleware.AuditActor(c)); err != nil {
+		handleRoleError(c, err, "收回角色失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "角色已收回",
+	})
+}
+
+// SetUserRole 将用户的角色替换为指定角色，role 为 user 时收回全部角色
+func (h *RoleHandler) SetUserRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的请求数据",
+		})
+		return
+	}
+
+	if err := h.rbacService.SetUserRole(userID, input.Role, middleware.AuditActor(c)); err != nil {
+		handleRoleError(c, err, "更新用户角色失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "用户角色更新成功",
+	})
+}
+
+func handleRoleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRole), errors.Is(err, service.ErrInvalidPermission):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrPermissionEscalation):
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrRoleNotFound), errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrRoleExists), errors.Is(err, service.ErrSystemRole), errors.Is(err, service.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"crowdfunding-backend/internal/middleware"
+	"crowdfunding-backend/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler 处理管理员查看用户会话和强制下线的请求
+type SessionHandler struct {
+	userService *service.UserService
+}
+
+func NewSessionHandler(userService *service.UserService) *SessionHandler {
+	return &SessionHandler{userService}
+}
+
+// ListUserSessions 获取用户当前的登录会话
+func (h *SessionHandler) ListUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	sessions, err := h.userService.ListUserSessions(userID)
+	if err != nil {
+		h.handleSessionError(c, err, "获取用户会话失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": sessions,
+	})
+}
+
+// ForceLogout 撤销用户的全部会话，强制其在所有设备上重新登录
+func (h *SessionHandler) ForceLogout(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	count, err := h.userService.ForceLogout(userID, middleware.AuditActor(c))
+	if err != nil {
+		h.handleSessionError(c, err, "强制下线失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "用户已被强制下线",
+		"data": gin.H{
+			"revoked": count,
+		},
+	})
+}
+
+func (h *SessionHandler) handleSessionError(c *gin.Context, err error, message string) {
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"code":    500,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
@@ -1,6 +1,7 @@
 package community
 
 import (
+	"crowdfunding-backend/internal/middleware"
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/service"
 	"crowdfunding-backend/internal/storage"
@@ -155,6 +156,24 @@ func (h *CommunityHandler) DeletePost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "帖子删除成功"})
 }
 
+// ModeratePost 管理员删除违规帖子
+func (h *CommunityHandler) ModeratePost(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.Logger.Error("无效的帖子ID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的帖子ID"})
+		return
+	}
+
+	if err := h.communityService.ModeratePost(id, middleware.AuditActor(c)); err != nil {
+		util.Logger.Error("删除帖子失败", zap.Error(err), zap.Int("post_id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除帖子失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "帖子删除成功"})
+}
+
 func (h *CommunityHandler) ListPosts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
@@ -296,6 +315,24 @@ func (h *CommunityHandler) DeleteComment(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "评论删除成功"})
 }
 
+// ModerateComment 管理员删除违规评论
+func (h *CommunityHandler) ModerateComment(c *gin.Context) {
+	commentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.Logger.Error("无效的评论ID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论ID"})
+		return
+	}
+
+	if err := h.communityService.ModerateComment(commentID, middleware.AuditActor(c)); err != nil {
+		util.Logger.Error("删除评论失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除评论失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "评论删除成功"})
+}
+
 func (h *CommunityHandler) LikePost(c *gin.Context) {
 	postID, _ := strconv.Atoi(c.Param("id"))
 	userID, _ := c.Get("user_id")
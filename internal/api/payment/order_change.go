@@ -0,0 +1,148 @@
+package payment
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/service"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ModifyOrder 众筹期间修改已支付订单，提交修改后的完整选择，金额差额自动补扣或退回
+func (h *PaymentHandler) ModifyOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid order ID",
+		})
+		return
+	}
+
+	var input struct {
+		RewardTierID *int             `json:"reward_tier_id"` // 修改后的回报档位，为空表示改为无偿支持
+		Items        []model.CartItem `json:"items"`          // 修改后的加购项及数量
+		BonusAmount  model.Money      `json:"bonus_amount"`   // 修改后的额外支持金额，项目币种
+		AddressID    int              `json:"address_id"`     // 收货地址，所选档位需要发货时必填
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid input data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	payment := &model.Payment{
+		RewardTierID: input.RewardTierID,
+		Items:        input.Items,
+		BonusAmount:  input.BonusAmount,
+	}
+	order, change, err := h.paymentService.ModifyOrder(orderID, c.GetInt("user_id"), payment, input.AddressID)
+	if err != nil {
+		handleOrderChangeError(c, err, orderID, "修改订单失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"order":  order,
+			"change": change,
+		},
+		"message": "订单已修改",
+	})
+}
+
+// CancelOrder 众筹期间取消已支付订单，已支付金额原路退回
+func (h *PaymentHandler) CancelOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid order ID",
+		})
+		return
+	}
+
+	order, change, err := h.paymentService.CancelOrder(orderID, c.GetInt("user_id"))
+	if err != nil {
+		handleOrderChangeError(c, err, orderID, "取消订单失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"order":  order,
+			"change": change,
+		},
+		"message": "支持已取消，退款处理中",
+	})
+}
+
+// GetOrderChanges 获取订单的修改和取消记录
+func (h *PaymentHandler) GetOrderChanges(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid order ID",
+		})
+		return
+	}
+
+	changes, err := h.paymentService.GetOrderChanges(orderID, c.GetInt("user_id"))
+	if err != nil {
+		handleOrderChangeError(c, err, orderID, "获取订单变更记录失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": changes,
+	})
+}
+
+// handleOrderChangeError 将订单修改和取消相关错误映射为 HTTP 状态码
+func handleOrderChangeError(c *gin.Context, err error, orderID int, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrOrderNotFound), errors.Is(err, service.ErrProjectNotFound),
+		errors.Is(err, service.ErrRewardTierNotFound), errors.Is(err, service.ErrRewardAddOnNotFound),
+		errors.Is(err, service.ErrAddressNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrOrderNotOwned):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidCart), errors.Is(err, service.ErrAddOnRequiresReward),
+		errors.Is(err, service.ErrInvalidAmount), errors.Is(err, service.ErrShippingAddressRequired):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrPaymentDeclined):
+		status = http.StatusPaymentRequired
+	case errors.Is(err, service.ErrOrderNotModifiable), errors.Is(err, service.ErrProjectNotLive),
+		errors.Is(err, service.ErrRewardTierSoldOut), errors.Is(err, service.ErrRewardAddOnSoldOut),
+		service.IsInvalidTransition(err):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrAddressNotShippable), errors.Is(err, service.ErrExtraChargeIncomplete):
+		status = http.StatusUnprocessableEntity
+	}
+
+	if status == http.StatusInternalServerError {
+		util.Logger.Error(message, zap.Error(err), zap.Int("order_id", orderID))
+		c.JSON(status, gin.H{
+			"code":    status,
+			"message": message,
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": err.Error(),
+	})
+}
@@ -1,9 +1,11 @@
 package payment
 
 import (
+	"crowdfunding-backend/internal/middleware"
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/service"
 	"crowdfunding-backend/internal/util"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -34,9 +36,13 @@ func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 		return
 	}
 
+	// 订单金额由服务端按档位和加购项目录价格计算，客户端只提交所选内容
 	var input struct {
-		Amount    float64 `json:"amount" binding:"required"`
-		AddressID int     `json:"address_id" binding:"required"`
+		RewardTierID *int             `json:"reward_tier_id"` // 选择的回报档位，为空表示无偿支持
+		Items        []model.CartItem `json:"items"`          // 加购项及数量，需搭配回报档位
+		BonusAmount  model.Money      `json:"bonus_amount"`   // 额外支持金额，项目币种
+		Currency     string           `json:"currency"`       // 支付币种，为空时使用项目币种
+		AddressID    int              `json:"address_id"`     // 收货地址，所选档位需要发货时必填
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -49,50 +55,114 @@ func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 		return
 	}
 
+	if input.Currency != "" {
+		if input.Currency, err = service.NormalizeCurrency(input.Currency); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
 	userID, _ := c.Get("user_id")
 
 	// 创建支付记录
 	payment := &model.Payment{
-		UserID:    userID.(int),
-		ProjectID: projectID,
-		Amount:    input.Amount,
-		Status:    "pending",
+		UserID:       userID.(int),
+		ProjectID:    projectID,
+		RewardTierID: input.RewardTierID,
+		Items:        input.Items,
+		BonusAmount:  input.BonusAmount,
+		Currency:     input.Currency,
+		Status:       "pending",
 	}
 
 	util.Logger.Info("开始创建支付流程",
 		zap.Int("user_id", payment.UserID),
 		zap.Int("project_id", payment.ProjectID),
-		zap.Float64("amount", payment.Amount))
-
-	// 创建订单
-	order := &model.Order{
-		UserID:    payment.UserID,
-		ProjectID: payment.ProjectID,
-		Amount:    payment.Amount,
-		Status:    "pending",
-		AddressID: &input.AddressID,
-	}
-
-	util.Logger.Info("准备创建订单",
-		zap.Int("user_id", order.UserID),
-		zap.Int("project_id", order.ProjectID),
-		zap.Float64("amount", order.Amount),
-		zap.Int("address_id", *order.AddressID))
+		zap.Any("reward_tier_id", payment.RewardTierID),
+		zap.Int("item_count", len(payment.Items)),
+		zap.Int("address_id", input.AddressID))
 
 	// 处理支付和创建订单
-	order, err = h.paymentService.ProcessPayment(payment, input.AddressID)
+	order, err := h.paymentService.ProcessPayment(payment, input.AddressID)
 	if err != nil {
 		util.Logger.Error("处理支付失败",
 			zap.Error(err),
 			zap.Any("payment", payment),
 			zap.Any("order", order))
 
-		// 检查是否是截日期错误
-		if err.Error() == "project has ended, cannot accept new payments" {
+		// 项目不在众筹中或已截止
+		if errors.Is(err, service.ErrProjectNotLive) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "项目不在众筹中或已截止，无法继续支持",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		if errors.Is(err, service.ErrExchangeRateNotFound) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"code":    422,
+				"message": "暂不支持该币种支付",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		switch {
+		case errors.Is(err, service.ErrProjectNotFound), errors.Is(err, service.ErrRewardTierNotFound),
+			errors.Is(err, service.ErrRewardAddOnNotFound), errors.Is(err, service.ErrAddressNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": err.Error(),
+			})
+			return
+		case errors.Is(err, service.ErrRewardTierSoldOut), errors.Is(err, service.ErrRewardAddOnSoldOut):
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": err.Error(),
+			})
+			return
+		case errors.Is(err, service.ErrInvalidCart), errors.Is(err, service.ErrAddOnRequiresReward),
+			errors.Is(err, service.ErrInvalidAmount), errors.Is(err, service.ErrShippingAddressRequired):
 			c.JSON(http.StatusBadRequest, gin.H{
 				"code":    400,
-				"message": "项目已截止，无法继续支持",
+				"message": err.Error(),
+			})
+			return
+		case errors.Is(err, service.ErrAddressNotShippable):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"code":    422,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		if errors.Is(err, service.ErrPaymentDeclined) {
+			c.JSON(http.StatusPaymentRequired, gin.H{
+				"code":    402,
+				"message": "支付被拒绝",
 				"details": err.Error(),
+				"data": gin.H{
+					"order": order,
+				},
+			})
+			return
+		}
+
+		// 订单已创建，扣款结果未知时不能让客户端重试，否则可能重复扣款；客户端应查询订单状态
+		if order != nil {
+			middleware.MarkIdempotentCommitted(c)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "订单已创建，扣款结果待确认，请查询订单状态",
+				"details": err.Error(),
+				"data": gin.H{
+					"order": order,
+				},
 			})
 			return
 		}
@@ -151,6 +221,12 @@ func (h *PaymentHandler) RequestRefundForFailedProject(c *gin.Context) {
 				"code":    409,
 				"message": err.Error(),
 			})
+		case service.IsInvalidTransition(err):
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "当前订单状态不允许申请退款",
+				"error":   err.Error(),
+			})
 		default:
 			util.Logger.Error("申请退款失败",
 				zap.Error(err),
@@ -198,29 +274,84 @@ func (h *PaymentHandler) GetOrder(c *gin.Context) {
 		return
 	}
 
+	// 无需发货的订单可以没有收货地址
+	var address gin.H
+	if order.Address != nil {
+		address = gin.H{
+			"receiver_name": order.Address.ReceiverName,
+			"phone":         order.Address.Phone,
+			"country":       order.Address.Country,
+			"full_address": fmt.Sprintf("%s%s%s%s",
+				order.Address.Province,
+				order.Address.City,
+				order.Address.District,
+				order.Address.DetailAddress),
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"data": gin.H{
-			"id":     order.ID,
-			"status": order.Status,
-			"amount": order.Amount,
+			"id":           order.ID,
+			"status":       order.Status,
+			"subtotal":     order.Subtotal,
+			"shipping_fee": order.ShippingFee,
+			"amount":       order.Amount,
 			"project": gin.H{
 				"id": order.ProjectID,
 			},
-			"address": gin.H{
-				"receiver_name": order.Address.ReceiverName,
-				"phone":         order.Address.Phone,
-				"full_address": fmt.Sprintf("%s%s%s%s",
-					order.Address.Province,
-					order.Address.City,
-					order.Address.District,
-					order.Address.DetailAddress),
-			},
-			"created_at": order.CreatedAt,
+			"address":        address,
+			"reward_tier_id": order.RewardTierID,
+			"items":          order.Items,
+			"created_at":     order.CreatedAt,
 		},
 	})
 }
 
+// GetOrderHistory 获取订单状态流转历史
+func (h *PaymentHandler) GetOrderHistory(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid order ID",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	history, err := h.paymentService.GetOrderHistory(orderID, userID.(int))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrOrderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "Order not found",
+			})
+		case errors.Is(err, service.ErrOrderNotOwned):
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": err.Error(),
+			})
+		default:
+			util.Logger.Error("获取订单状态历史失败",
+				zap.Error(err),
+				zap.Int("order_id", orderID))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to get order history",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": history,
+	})
+}
+
 func (h *PaymentHandler) ListOrders(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	orders, err := h.paymentService.GetOrdersByUser(userID.(int))
@@ -0,0 +1,176 @@
+package payment
+
+import (
+	"crowdfunding-backend/internal/middleware"
+	"crowdfunding-backend/internal/service"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PayoutHandler 处理创作者结算相关请求
+type PayoutHandler struct {
+	payoutService *service.PayoutService
+}
+
+func NewPayoutHandler(payoutService *service.PayoutService) *PayoutHandler {
+	return &PayoutHandler{payoutService}
+}
+
+// GetProjectPayouts 项目创建者查看项目结算记录
+func (h *PayoutHandler) GetProjectPayouts(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的项目ID",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	payouts, err := h.payoutService.GetProjectPayouts(projectID, userID.(int))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "项目不存在",
+			})
+		case errors.Is(err, service.ErrNotProjectCreator):
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": err.Error(),
+			})
+		default:
+			util.Logger.Error("获取项目结算记录失败",
+				zap.Error(err),
+				zap.Int("project_id", projectID))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "获取项目结算记录失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": payouts,
+	})
+}
+
+// ListPayouts 管理员获取结算记录列表
+func (h *PayoutHandler) ListPayouts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	status := c.Query("status")
+
+	payouts, total, err := h.payoutService.ListPayouts(page, pageSize, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取结算记录列表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"payouts":  payouts,
+			"total":    total,
+			"page":     page,
+			"pageSize": pageSize,
+		},
+	})
+}
+
+// ApprovePayout 管理员审批结算记录
+func (h *PayoutHandler) ApprovePayout(c *gin.Context) {
+	payoutID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的结算记录ID",
+		})
+		return
+	}
+
+	payout, err := h.payoutService.ApprovePayout(payoutID, middleware.AuditActor(c))
+	if err != nil {
+		h.handlePayoutError(c, err, payoutID, "审批结算记录失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "结算记录已批准",
+		"data":    payout,
+	})
+}
+
+// MarkPayoutPaid 管理员标记结算记录已打款
+func (h *PayoutHandler) MarkPayoutPaid(c *gin.Context) {
+	payoutID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的结算记录ID",
+		})
+		return
+	}
+
+	var input struct {
+		Reference string `json:"reference" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请提供打款流水号",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	payout, err := h.payoutService.MarkPayoutPaid(payoutID, middleware.AuditActor(c), input.Reference)
+	if err != nil {
+		h.handlePayoutError(c, err, payoutID, "标记结算记录已打款失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "结算记录已标记为已打款",
+		"data":    payout,
+	})
+}
+
+func (h *PayoutHandler) handlePayoutError(c *gin.Context, err error, payoutID int, message string) {
+	switch {
+	case errors.Is(err, service.ErrPayoutNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+	case service.IsInvalidTransition(err):
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "当前结算状态不允许该操作",
+			"error":   err.Error(),
+		})
+	default:
+		util.Logger.Error(message, zap.Error(err), zap.Int("payout_id", payoutID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+}
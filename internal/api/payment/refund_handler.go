@@ -1,6 +1,7 @@
 package payment
 
 import (
+	"crowdfunding-backend/internal/middleware"
 	"crowdfunding-backend/internal/service"
 	"crowdfunding-backend/internal/util"
 	"net/http"
@@ -50,6 +51,14 @@ func (h *RefundHandler) RequestRefund(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	err = h.refundService.RequestRefund(orderID, userID.(int), input.Reason)
 	if err != nil {
+		if service.IsInvalidTransition(err) {
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "当前订单状态不允许申请退款",
+				"error":   err.Error(),
+			})
+			return
+		}
 		util.Logger.Error("申请退款失败",
 			zap.Error(err),
 			zap.Int("order_id", orderID))
@@ -136,8 +145,16 @@ func (h *RefundHandler) ProcessRefund(c *gin.Context) {
 		return
 	}
 
-	err = h.refundService.ProcessRefund(requestID, input.Approved, input.Comment)
+	err = h.refundService.ProcessRefund(requestID, input.Approved, input.Comment, middleware.AuditActor(c))
 	if err != nil {
+		if service.IsInvalidTransition(err) {
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "当前订单状态不允许该操作",
+				"error":   err.Error(),
+			})
+			return
+		}
 		util.Logger.Error("处理退款申请失败",
 			zap.Error(err),
 			zap.Int("request_id", requestID))
@@ -154,3 +171,33 @@ func (h *RefundHandler) ProcessRefund(c *gin.Context) {
 		"message": "Refund request processed successfully",
 	})
 }
+
+// GetProjectRefundReport 获取项目的退款执行报告（管理员）
+func (h *RefundHandler) GetProjectRefundReport(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的项目ID",
+		})
+		return
+	}
+
+	report, err := h.refundService.GetProjectRefundReport(projectID)
+	if err != nil {
+		util.Logger.Error("获取项目退款报告失败",
+			zap.Error(err),
+			zap.Int("project_id", projectID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取项目退款报告失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": report,
+	})
+}
@@ -0,0 +1,78 @@
+package payment
+
+import (
+	"crowdfunding-backend/internal/gateway"
+	"crowdfunding-backend/internal/service"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// WebhookHandler 处理支付渠道的回调请求
+type WebhookHandler struct {
+	paymentService *service.PaymentService
+}
+
+func NewWebhookHandler(paymentService *service.PaymentService) *WebhookHandler {
+	return &WebhookHandler{paymentService}
+}
+
+// HandleWebhook 接收支付渠道回调
+func (h *WebhookHandler) HandleWebhook(c *gin.Context) {
+	provider := c.Param("provider")
+
+	// 签名基于原始请求体计算，必须在解析前读取
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无法读取请求内容",
+		})
+		return
+	}
+
+	duplicate, err := h.paymentService.HandleWebhook(provider, payload, c.GetHeader(gateway.SignatureHeader))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownWebhookProvider):
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "未知的支付渠道",
+			})
+		case errors.Is(err, service.ErrInvalidWebhookSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "签名校验失败",
+			})
+		case errors.Is(err, gateway.ErrInvalidWebhookEvent):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "无效的回调事件",
+			})
+		case errors.Is(err, service.ErrWebhookOrderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "订单不存在",
+			})
+		default:
+			util.Logger.Error("处理支付回调失败",
+				zap.Error(err),
+				zap.String("provider", provider))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "处理支付回调失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":      200,
+		"message":   "ok",
+		"duplicate": duplicate,
+	})
+}
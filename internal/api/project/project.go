@@ -1,9 +1,11 @@
 package project
 
 import (
+	"crowdfunding-backend/internal/middleware"
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/service"
 	"crowdfunding-backend/internal/util"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -78,7 +80,7 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	}
 
 	// 解析最低有奖支持金额
-	minRewardAmount, err := strconv.ParseFloat(minRewardAmountStr, 64)
+	minRewardAmount, err := model.ParseMoney(minRewardAmountStr)
 	if err != nil {
 		util.Logger.Error("解析最低有奖支持金额失败",
 			zap.Error(err),
@@ -92,9 +94,9 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	}
 
 	// 验证最低有奖支持金额
-	if minRewardAmount <= 0 {
+	if !minRewardAmount.IsPositive() {
 		util.Logger.Error("最低有奖支持金额必须大于0",
-			zap.Float64("min_reward_amount", minRewardAmount))
+			zap.Stringer("min_reward_amount", minRewardAmount))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
 			"message": "最低有奖支持金额必须大于0",
@@ -102,6 +104,19 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 		return
 	}
 
+	// 解析项目币种，未填写时使用平台默认币种
+	currency := model.DefaultCurrency
+	if currencyStr := c.PostForm("currency"); currencyStr != "" {
+		currency, err = service.NormalizeCurrency(currencyStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
 	// 解析结束日期
 	endDate, err := time.Parse(time.RFC3339, endDateStr)
 	if err != nil {
@@ -127,10 +142,11 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 		CategoryID:      &categoryID,
 		MinRewardAmount: minRewardAmount,
 	}
+	project.SetCurrency(currency)
 
 	util.Logger.Info("准备创建项目",
 		zap.String("title", project.Title),
-		zap.Float64("min_reward_amount", project.MinRewardAmount),
+		zap.Stringer("min_reward_amount", project.MinRewardAmount),
 		zap.Time("end_date", project.EndDate))
 
 	// 处理项目主图和其他图片
@@ -180,12 +196,14 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 			break
 		}
 
-		amount, err := strconv.ParseFloat(amountStr, 64)
-		if err != nil {
-			util.Logger.Warn("无效的目标金额", zap.Error(err))
+		amount, err := model.ParseMoney(amountStr)
+		if err != nil || !amount.IsPositive() {
+			util.Logger.Warn("无效的目标金额", zap.Error(err), zap.String("amount", amountStr))
 			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的目标金额"})
 			return
 		}
+		// 目标金额按项目币种计
+		amount.Currency = project.Currency
 
 		description := c.PostForm(fmt.Sprintf("goals[%d][description]", i))
 
@@ -214,8 +232,9 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 		})
 	}
 
-	// 处理项目创建
-	if err := h.projectService.CreateProject(project, goals); err != nil {
+	// 处理项目创建，默认保存为草稿，submit=true 时直接提交审核
+	submit, _ := strconv.ParseBool(c.PostForm("submit"))
+	if err := h.projectService.CreateProject(project, goals, submit); err != nil {
 		util.Logger.Error("创建项目失败",
 			zap.Error(err),
 			zap.Any("project", project))
@@ -229,7 +248,7 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 
 	util.Logger.Info("项目创建成功",
 		zap.Int("project_id", project.ID),
-		zap.Float64("min_reward_amount", project.MinRewardAmount))
+		zap.Stringer("min_reward_amount", project.MinRewardAmount))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"code": 201,
@@ -237,6 +256,8 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 			"project_id":        project.ID,
 			"title":             project.Title,
 			"min_reward_amount": project.MinRewardAmount,
+			"currency":          project.Currency,
+			"status":            project.Status,
 			"created_at":        project.CreatedAt,
 		},
 		"message": "Project created successfully",
@@ -253,13 +274,50 @@ func (h *ProjectHandler) GetProject(c *gin.Context) {
 		return
 	}
 
-	project, err := h.projectService.GetProjectByID(id)
+	project, err := h.projectService.GetPublicProject(id)
 	if err != nil {
+		if errors.Is(err, service.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		util.Logger.Error("获取项目失败", zap.Error(err), zap.Int("project_id", id))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project"})
 		return
 	}
 
+	h.respondProjectDetail(c, project)
+}
+
+// PreviewProject 创建者预览自己的项目详情，草稿和审核中的项目也可以查看
+func (h *ProjectHandler) PreviewProject(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.Logger.Warn("无效的项目ID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	project, err := h.projectService.PreviewProject(id, c.GetInt("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotProjectOwner):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			util.Logger.Error("获取项目失败", zap.Error(err), zap.Int("project_id", id))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project"})
+		}
+		return
+	}
+
+	h.respondProjectDetail(c, project)
+}
+
+// respondProjectDetail 补充项目的图片和目标后返回项目详情
+func (h *ProjectHandler) respondProjectDetail(c *gin.Context, project *model.Project) {
+	id := project.ID
+
 	// 获取项目的所有图片
 	images, err := h.projectService.GetProjectImages(id)
 	if err != nil {
@@ -332,7 +390,18 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 		EndDate:     input.EndDate,
 	}
 
-	if err := h.projectService.UpdateProject(project, input.Goals); err != nil {
+	if err := h.projectService.UpdateProject(c.GetInt("user_id"), project, input.Goals); err != nil {
+		switch {
+		case errors.Is(err, service.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, service.ErrNotProjectOwner):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, service.ErrProjectUnderReview), errors.Is(err, service.ErrProjectNotEditable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		util.Logger.Error("更新项目失败", zap.Error(err), zap.Int("project_id", id))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
 		return
@@ -370,7 +439,8 @@ func (h *ProjectHandler) PledgeToProject(c *gin.Context) {
 	}
 
 	var input struct {
-		Amount float64 `json:"amount" binding:"required,gt=0"`
+		Amount   model.Money `json:"amount"`
+		Currency string      `json:"currency"` // 支付币种，为空时使用项目币种
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -378,15 +448,34 @@ func (h *ProjectHandler) PledgeToProject(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !input.Amount.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "支持金额必须大于0"})
+		return
+	}
+	input.Amount.Currency = ""
+	if input.Currency != "" {
+		if input.Amount.Currency, err = service.NormalizeCurrency(input.Currency); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	userID, _ := c.Get("user_id")
 	if err := h.projectService.PledgeToProject(userID.(int), projectID, input.Amount); err != nil {
-		util.Logger.Error("支持项目失败", zap.Error(err), zap.Int("project_id", projectID), zap.Float64("amount", input.Amount))
+		switch {
+		case errors.Is(err, service.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, service.ErrExchangeRateNotFound):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		util.Logger.Error("支持项目失败", zap.Error(err), zap.Int("project_id", projectID), zap.Stringer("amount", input.Amount))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pledge to project"})
 		return
 	}
 
-	util.Logger.Info("成功支持项目", zap.Int("project_id", projectID), zap.Float64("amount", input.Amount))
+	util.Logger.Info("成功支持项目", zap.Int("project_id", projectID), zap.Stringer("amount", input.Amount))
 	c.JSON(http.StatusOK, gin.H{"message": "Successfully pledged to project"})
 }
 
@@ -401,8 +490,10 @@ func (h *ProjectHandler) ReviewProject(c *gin.Context) {
 	}
 
 	var input struct {
-		Approved bool   `json:"approved" binding:"required"`
-		Comment  string `json:"comment"`
+		Decision      string                      `json:"decision"` // approved、changes_requested 或 rejected
+		Approved      *bool                       `json:"approved"` // 兼容只区分通过和驳回的旧请求，未填写 decision 时使用
+		Comment       string                      `json:"comment"`
+		FieldComments []model.ProjectFieldComment `json:"field_comments"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -411,16 +502,88 @@ func (h *ProjectHandler) ReviewProject(c *gin.Context) {
 		return
 	}
 
-	if err := h.projectService.ReviewProject(projectID, input.Approved, input.Comment); err != nil {
-		util.Logger.Error("项目审核失败", zap.Error(err), zap.Int("project_id", projectID), zap.Bool("approved", input.Approved))
+	review := &model.ProjectReview{
+		Decision:      input.Decision,
+		Comment:       input.Comment,
+		FieldComments: input.FieldComments,
+	}
+	if review.Decision == "" && input.Approved != nil {
+		review.Decision = model.ProjectReviewRejected
+		if *input.Approved {
+			review.Decision = model.ProjectReviewApproved
+		}
+	}
+
+	if err := h.projectService.ReviewProject(projectID, review, middleware.AuditActor(c)); err != nil {
+		if errors.Is(err, service.ErrInvalidProjectReview) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if service.IsInvalidTransition(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		util.Logger.Error("项目审核失败", zap.Error(err), zap.Int("project_id", projectID), zap.String("decision", review.Decision))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review project"})
 		return
 	}
 
-	util.Logger.Info("项目审核完成", zap.Int("project_id", projectID), zap.Bool("approved", input.Approved))
+	util.Logger.Info("项目审核完成", zap.Int("project_id", projectID), zap.String("decision", review.Decision))
 	c.JSON(http.StatusOK, gin.H{"message": "Project review completed"})
 }
 
+// SubmitProject 创建者将草稿提交审核，审核要求修改后也通过此接口重新提交
+func (h *ProjectHandler) SubmitProject(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	project, err := h.projectService.SubmitProject(projectID, c.GetInt("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotProjectOwner):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case service.IsInvalidTransition(err):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			util.Logger.Error("提交项目审核失败", zap.Error(err), zap.Int("project_id", projectID))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit project"})
+		}
+		return
+	}
+
+	util.Logger.Info("项目已提交审核", zap.Int("project_id", projectID))
+	c.JSON(http.StatusOK, gin.H{"message": "Project submitted for review", "status": project.Status})
+}
+
+// GetProjectReviews 创建者查看项目的审核记录，包括需要修改的字段和驳回原因
+func (h *ProjectHandler) GetProjectReviews(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	reviews, err := h.projectService.GetProjectReviews(projectID, c.GetInt("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotProjectOwner):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			util.Logger.Error("获取项目审核记录失败", zap.Error(err), zap.Int("project_id", projectID))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project reviews"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, reviews)
+}
+
 // 实现其他必要的处理器方法
 // ...
 
@@ -468,7 +631,7 @@ func (h *ProjectHandler) CreateCategory(c *gin.Context) {
 		return
 	}
 
-	if err := h.projectService.CreateCategory(&category); err != nil {
+	if err := h.projectService.CreateCategory(&category, middleware.AuditActor(c)); err != nil {
 		util.Logger.Error("创建项目分类失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
 		return
@@ -656,7 +819,7 @@ func (h *ProjectHandler) CreateTag(c *gin.Context) {
 
 	util.Logger.Info("开始创建项目标签", zap.String("name", tag.Name))
 
-	if err := h.projectService.CreateTag(&tag); err != nil {
+	if err := h.projectService.CreateTag(&tag, middleware.AuditActor(c)); err != nil {
 		util.Logger.Error("创建项目标签失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
 		return
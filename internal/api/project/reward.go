@@ -0,0 +1,261 @@
+package project
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/service"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RewardHandler 处理项目回报档位和加购项相关请求
+type RewardHandler struct {
+	rewardService *service.RewardService
+}
+
+// NewRewardHandler 创建一个新的 RewardHandler 实例
+func NewRewardHandler(rewardService *service.RewardService) *RewardHandler {
+	return &RewardHandler{rewardService}
+}
+
+// rewardTierInput 创建和修改档位的请求体，价格按项目币种计
+type rewardTierInput struct {
+	Title             string      `json:"title" binding:"required"`
+	Description       string      `json:"description"`
+	Price             model.Money `json:"price"`
+	ImageURL          string      `json:"image_url"`
+	QuantityLimit     *int        `json:"quantity_limit"` // 为空表示不限量
+	EstimatedDelivery string      `json:"estimated_delivery" binding:"required"`
+	ShippingRequired  bool        `json:"shipping_required"`
+}
+
+func (in *rewardTierInput) toRewardTier(projectID int) *model.RewardTier {
+	return &model.RewardTier{
+		ProjectID:         projectID,
+		Title:             in.Title,
+		Description:       in.Description,
+		Price:             in.Price,
+		ImageURL:          in.ImageURL,
+		QuantityLimit:     in.QuantityLimit,
+		EstimatedDelivery: in.EstimatedDelivery,
+		ShippingRequired:  in.ShippingRequired,
+	}
+}
+
+// rewardAddOnInput 创建和修改加购项的请求体，价格按项目币种计
+type rewardAddOnInput struct {
+	Title         string      `json:"title" binding:"required"`
+	Description   string      `json:"description"`
+	Price         model.Money `json:"price"`
+	ImageURL      string      `json:"image_url"`
+	QuantityLimit *int        `json:"quantity_limit"` // 为空表示不限量
+}
+
+func (in *rewardAddOnInput) toRewardAddOn(projectID int) *model.RewardAddOn {
+	return &model.RewardAddOn{
+		ProjectID:     projectID,
+		Title:         in.Title,
+		Description:   in.Description,
+		Price:         in.Price,
+		ImageURL:      in.ImageURL,
+		QuantityLimit: in.QuantityLimit,
+	}
+}
+
+// ListRewardTiers 获取项目的回报档位及剩余数量
+func (h *RewardHandler) ListRewardTiers(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	tiers, err := h.rewardService.ListRewardTiers(projectID)
+	if err != nil {
+		h.handleError(c, err, "获取回报档位失败")
+		return
+	}
+	c.JSON(http.StatusOK, tiers)
+}
+
+// CreateRewardTier 项目创建者新增回报档位
+func (h *RewardHandler) CreateRewardTier(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var input rewardTierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tier := input.toRewardTier(projectID)
+	if err := h.rewardService.CreateRewardTier(c.GetInt("user_id"), tier); err != nil {
+		h.handleError(c, err, "创建回报档位失败")
+		return
+	}
+	c.JSON(http.StatusCreated, tier)
+}
+
+// UpdateRewardTier 项目创建者修改回报档位
+func (h *RewardHandler) UpdateRewardTier(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	tierID, err := strconv.Atoi(c.Param("reward_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的回报档位ID"})
+		return
+	}
+
+	var input rewardTierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tier := input.toRewardTier(projectID)
+	tier.ID = tierID
+	updated, err := h.rewardService.UpdateRewardTier(c.GetInt("user_id"), tier)
+	if err != nil {
+		h.handleError(c, err, "更新回报档位失败")
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteRewardTier 项目创建者删除尚无支持者的回报档位
+func (h *RewardHandler) DeleteRewardTier(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	tierID, err := strconv.Atoi(c.Param("reward_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的回报档位ID"})
+		return
+	}
+
+	if err := h.rewardService.DeleteRewardTier(c.GetInt("user_id"), projectID, tierID); err != nil {
+		h.handleError(c, err, "删除回报档位失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "回报档位已删除"})
+}
+
+// ListRewardAddOns 获取项目的加购项及剩余数量
+func (h *RewardHandler) ListRewardAddOns(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	addOns, err := h.rewardService.ListRewardAddOns(projectID)
+	if err != nil {
+		h.handleError(c, err, "获取加购项失败")
+		return
+	}
+	c.JSON(http.StatusOK, addOns)
+}
+
+// CreateRewardAddOn 项目创建者新增加购项
+func (h *RewardHandler) CreateRewardAddOn(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var input rewardAddOnInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addOn := input.toRewardAddOn(projectID)
+	if err := h.rewardService.CreateRewardAddOn(c.GetInt("user_id"), addOn); err != nil {
+		h.handleError(c, err, "创建加购项失败")
+		return
+	}
+	c.JSON(http.StatusCreated, addOn)
+}
+
+// UpdateRewardAddOn 项目创建者修改加购项
+func (h *RewardHandler) UpdateRewardAddOn(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	addOnID, err := strconv.Atoi(c.Param("add_on_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的加购项ID"})
+		return
+	}
+
+	var input rewardAddOnInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addOn := input.toRewardAddOn(projectID)
+	addOn.ID = addOnID
+	updated, err := h.rewardService.UpdateRewardAddOn(c.GetInt("user_id"), addOn)
+	if err != nil {
+		h.handleError(c, err, "更新加购项失败")
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteRewardAddOn 项目创建者删除尚未被购买的加购项
+func (h *RewardHandler) DeleteRewardAddOn(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	addOnID, err := strconv.Atoi(c.Param("add_on_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的加购项ID"})
+		return
+	}
+
+	if err := h.rewardService.DeleteRewardAddOn(c.GetInt("user_id"), projectID, addOnID); err != nil {
+		h.handleError(c, err, "删除加购项失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "加购项已删除"})
+}
+
+// handleError 将回报档位和加购项相关错误映射为 HTTP 状态码
+func (h *RewardHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrProjectNotFound), errors.Is(err, service.ErrRewardTierNotFound),
+		errors.Is(err, service.ErrRewardAddOnNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotRewardOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidRewardTier), errors.Is(err, service.ErrInvalidRewardAddOn):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRewardTierInUse), errors.Is(err, service.ErrRewardAddOnInUse),
+		errors.Is(err, service.ErrRewardLimitBelowClaimed),
+		errors.Is(err, service.ErrRewardProjectClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		util.Logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
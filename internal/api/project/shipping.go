@@ -0,0 +1,144 @@
+package project
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/service"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ShippingHandler 处理项目配送区域相关请求
+type ShippingHandler struct {
+	shippingService *service.ShippingService
+}
+
+// NewShippingHandler 创建一个新的 ShippingHandler 实例
+func NewShippingHandler(shippingService *service.ShippingService) *ShippingHandler {
+	return &ShippingHandler{shippingService}
+}
+
+// shippingZoneInput 创建和修改配送区域的请求体，运费按项目币种计
+type shippingZoneInput struct {
+	Name    string                 `json:"name" binding:"required"`
+	Regions []model.ShippingRegion `json:"regions" binding:"required"`
+	Rates   []model.ShippingRate   `json:"rates"`
+}
+
+func (in *shippingZoneInput) toShippingZone(projectID int) *model.ShippingZone {
+	return &model.ShippingZone{
+		ProjectID: projectID,
+		Name:      in.Name,
+		Regions:   in.Regions,
+		Rates:     in.Rates,
+	}
+}
+
+// ListShippingZones 获取项目的配送区域及各档位运费
+func (h *ShippingHandler) ListShippingZones(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	zones, err := h.shippingService.ListShippingZones(projectID)
+	if err != nil {
+		h.handleError(c, err, "获取配送区域失败")
+		return
+	}
+	c.JSON(http.StatusOK, zones)
+}
+
+// CreateShippingZone 项目创建者新增配送区域
+func (h *ShippingHandler) CreateShippingZone(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var input shippingZoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone := input.toShippingZone(projectID)
+	if err := h.shippingService.CreateShippingZone(c.GetInt("user_id"), zone); err != nil {
+		h.handleError(c, err, "创建配送区域失败")
+		return
+	}
+	c.JSON(http.StatusCreated, zone)
+}
+
+// UpdateShippingZone 项目创建者修改配送区域
+func (h *ShippingHandler) UpdateShippingZone(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	zoneID, err := strconv.Atoi(c.Param("zone_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的配送区域ID"})
+		return
+	}
+
+	var input shippingZoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone := input.toShippingZone(projectID)
+	zone.ID = zoneID
+	updated, err := h.shippingService.UpdateShippingZone(c.GetInt("user_id"), zone)
+	if err != nil {
+		h.handleError(c, err, "更新配送区域失败")
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteShippingZone 项目创建者删除配送区域
+func (h *ShippingHandler) DeleteShippingZone(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	zoneID, err := strconv.Atoi(c.Param("zone_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的配送区域ID"})
+		return
+	}
+
+	if err := h.shippingService.DeleteShippingZone(c.GetInt("user_id"), projectID, zoneID); err != nil {
+		h.handleError(c, err, "删除配送区域失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "配送区域已删除"})
+}
+
+// handleError 将配送区域相关错误映射为 HTTP 状态码
+func (h *ShippingHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrProjectNotFound), errors.Is(err, service.ErrShippingZoneNotFound),
+		errors.Is(err, service.ErrRewardTierNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotShippingOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidShippingZone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrShippingRegionConflict), errors.Is(err, service.ErrRewardProjectClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		util.Logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/service"
 	"crowdfunding-backend/internal/util"
+	stderrors "errors"
 	"unicode"
 
 	"github.com/gin-gonic/gin"
@@ -77,19 +78,129 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	user, err := h.userService.Login(loginData.Email, loginData.Password)
 	if err != nil {
-		errors.HandleError(c, errors.Wrap(errors.ErrInvalidCredentials, "登录失败", err))
+		handleLoginError(c, err)
+		return
+	}
+	// 供登录防护中间件记录审计
+	c.Set("user_id", user.ID)
+
+	if respondMFAChallenge(c, h.userService, user.ID) {
 		return
 	}
 
-	token, err := util.GenerateToken(user.ID)
+	tokens, err := h.userService.CreateSession(user.ID, c.Request.UserAgent(), c.ClientIP(), false)
 	if err != nil {
 		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "生成令牌失败", err))
 		return
 	}
 
 	errors.HandleSuccess(c, gin.H{
-		"token": token,
-		"user":  user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+	}, "登录成功")
+}
+
+// handleLoginError 停用或注销的账户返回 403，其余登录失败返回 401
+func handleLoginError(c *gin.Context, err error) {
+	switch {
+	case stderrors.Is(err, service.ErrAccountSuspended):
+		errors.HandleError(c, errors.New(errors.ErrForbidden, "账户已被停用，请联系客服"))
+	case stderrors.Is(err, service.ErrAccountDeleted):
+		errors.HandleError(c, errors.New(errors.ErrForbidden, "账户已注销，恢复期内可以通过恢复接口重新激活"))
+	default:
+		errors.HandleError(c, errors.Wrap(errors.ErrInvalidCredentials, "登录失败", err))
+	}
+}
+
+// RestoreAccount 恢复期内凭邮箱和密码恢复已注销的账户
+func (h *AuthHandler) RestoreAccount(c *gin.Context) {
+	var input struct {
+		Email    string `json:"email" binding:"required,email"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrValidation, "无效的请求数据", err))
+		return
+	}
+
+	user, err := h.userService.RestoreAccount(input.Email, input.Password)
+	if err != nil {
+		if stderrors.Is(err, service.ErrAccountNotRestorable) {
+			errors.HandleError(c, errors.New(errors.ErrResourceConflict, err.Error()))
+			return
+		}
+		if appErr, ok := err.(*errors.AppError); ok {
+			errors.HandleError(c, appErr)
+			return
+		}
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "恢复账户失败", err))
+		return
+	}
+	c.Set("user_id", user.ID)
+
+	errors.HandleSuccess(c, gin.H{
+		"user": user,
+	}, "账户已恢复，请重新登录")
+}
+
+// respondMFAChallenge 已开启两步验证的用户在密码或第三方登录验证通过后返回挑战令牌，返回 true 表示已写入响应
+func respondMFAChallenge(c *gin.Context, userService service.UserServiceInterface, userID int) bool {
+	enabled, err := userService.IsMFAEnabled(userID)
+	if err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "查询两步验证状态失败", err))
+		return true
+	}
+	if !enabled {
+		return false
+	}
+
+	challenge, err := userService.CreateMFAChallenge(userID)
+	if err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "生成两步验证令牌失败", err))
+		return true
+	}
+	errors.HandleSuccess(c, gin.H{
+		"mfa_required": true,
+		"mfa_token":    challenge,
+	}, "请输入两步验证码")
+	return true
+}
+
+// MFALogin 两步登录的第二步，提交挑战令牌和验证器验证码（或恢复码）换取访问令牌
+func (h *AuthHandler) MFALogin(c *gin.Context) {
+	var input struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrValidation, "无效的请求数据", err))
+		return
+	}
+
+	user, tokens, err := h.userService.CompleteMFALogin(input.MFAToken, input.Code, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		switch {
+		case stderrors.Is(err, service.ErrInvalidMFAChallenge):
+			errors.HandleError(c, errors.Wrap(errors.ErrTokenExpired, "两步验证已过期，请重新登录", err))
+		case stderrors.Is(err, service.ErrInvalidMFACode), stderrors.Is(err, service.ErrMFANotEnrolled):
+			errors.HandleError(c, errors.Wrap(errors.ErrInvalidCredentials, "验证码错误", err))
+		case stderrors.Is(err, service.ErrAccountSuspended), stderrors.Is(err, service.ErrAccountDeleted):
+			handleLoginError(c, err)
+		default:
+			errors.HandleError(c, errors.Wrap(errors.ErrInternal, "两步验证失败", err))
+		}
+		return
+	}
+
+	c.Set("user_id", user.ID)
+
+	errors.HandleSuccess(c, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
 	}, "登录成功")
 }
 
@@ -130,6 +241,10 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	}
 
 	if err := h.userService.ResetPassword(resetData.Token, resetData.NewPassword); err != nil {
+		if stderrors.Is(err, service.ErrInvalidVerificationToken) {
+			errors.HandleError(c, errors.New(errors.ErrInvalidToken, err.Error()))
+			return
+		}
 		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "重置密码失败", err))
 		return
 	}
@@ -137,10 +252,10 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	errors.HandleSuccess(c, nil, "密码重置成功")
 }
 
-// Logout 处理用户登出
+// Logout 处理用户登出，撤销当前会话
 func (h *AuthHandler) Logout(c *gin.Context) {
-	userID := c.GetInt("user_id")
-	if err := h.userService.Logout(userID); err != nil {
+	sessionID := c.GetString("session_id")
+	if err := h.userService.Logout(sessionID); err != nil {
 		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "登出失败", err))
 		return
 	}
@@ -156,28 +271,48 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	}
 
 	if err := h.userService.VerifyEmail(token); err != nil {
-		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "验证邮箱失败", err))
+		switch {
+		case stderrors.Is(err, service.ErrInvalidVerificationToken):
+			errors.HandleError(c, errors.New(errors.ErrInvalidToken, err.Error()))
+		case stderrors.Is(err, service.ErrEmailAlreadyVerified):
+			errors.HandleError(c, errors.New(errors.ErrResourceExists, err.Error()))
+		default:
+			errors.HandleError(c, errors.Wrap(errors.ErrInternal, "验证邮箱失败", err))
+		}
 		return
 	}
 
 	errors.HandleSuccess(c, nil, "邮箱验证成功")
 }
 
-// RefreshToken 处理令牌刷新
+// RefreshToken 用刷新令牌换取新的访问令牌，刷新令牌同时轮换
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	tokenString := c.GetHeader("Authorization")
-	if tokenString == "" {
-		errors.HandleError(c, errors.New(errors.ErrUnauthorized, "缺少令牌"))
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrValidation, "缺少刷新令牌", err))
 		return
 	}
 
-	newToken, err := util.RefreshToken(tokenString)
+	tokens, err := h.userService.RefreshSession(input.RefreshToken, c.ClientIP())
 	if err != nil {
-		errors.HandleError(c, errors.Wrap(errors.ErrUnauthorized, "刷新令牌失败", err))
+		switch {
+		case stderrors.Is(err, service.ErrRefreshTokenReused):
+			errors.HandleError(c, errors.Wrap(errors.ErrInvalidToken, "刷新令牌已失效，请重新登录", err))
+		case stderrors.Is(err, service.ErrInvalidRefreshToken):
+			errors.HandleError(c, errors.Wrap(errors.ErrInvalidToken, "刷新令牌无效或已过期", err))
+		default:
+			errors.HandleError(c, errors.Wrap(errors.ErrInternal, "刷新令牌失败", err))
+		}
 		return
 	}
 
-	errors.HandleSuccess(c, gin.H{"token": newToken}, "令牌刷新成功")
+	errors.HandleSuccess(c, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	}, "令牌刷新成功")
 }
 
 func isPasswordStrong(password string) bool {
@@ -219,23 +354,33 @@ func (h *AuthHandler) AdminLogin(c *gin.Context) {
 
 	user, err := h.userService.Login(loginData.Email, loginData.Password)
 	if err != nil {
-		errors.HandleError(c, errors.Wrap(errors.ErrInvalidCredentials, "登录失败", err))
+		handleLoginError(c, err)
 		return
 	}
+	// 供登录防护中间件记录审计
+	c.Set("user_id", user.ID)
 
-	if user.Role != "admin" {
+	if !user.IsStaff() {
 		errors.HandleError(c, errors.New(errors.ErrForbidden, "需要管理员权限"))
 		return
 	}
 
-	token, err := util.GenerateToken(user.ID)
+	if respondMFAChallenge(c, h.userService, user.ID) {
+		return
+	}
+
+	// 未开启两步验证的管理员只能登录完成绑定，管理员路由在绑定前不可访问
+	tokens, err := h.userService.CreateSession(user.ID, c.Request.UserAgent(), c.ClientIP(), false)
 	if err != nil {
 		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "生成令牌失败", err))
 		return
 	}
 
 	errors.HandleSuccess(c, gin.H{
-		"token": token,
+		"token":                   tokens.AccessToken,
+		"refresh_token":           tokens.RefreshToken,
+		"expires_in":              tokens.ExpiresIn,
+		"mfa_enrollment_required": true,
 		"user": gin.H{
 			"id":         user.ID,
 			"username":   user.Username,
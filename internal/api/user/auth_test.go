@@ -31,6 +31,11 @@ func (m *MockUserService) Login(email, password string) (*model.User, error) {
 	return args.Get(0).(*model.User), args.Error(1)
 }
 
+func (m *MockUserService) RestoreAccount(email, password string) (*model.User, error) {
+	args := m.Called(email, password)
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
 func (m *MockUserService) GetUserByID(id int) (*model.User, error) {
 	args := m.Called(id)
 	return args.Get(0).(*model.User), args.Error(1)
@@ -56,14 +61,43 @@ func (m *MockUserService) ResetPassword(token, newPassword string) error {
 	return args.Error(0)
 }
 
-func (m *MockUserService) Logout(userID int) error {
+func (m *MockUserService) CreateSession(userID int, device, ip string, mfaVerified bool) (*model.TokenPair, error) {
+	args := m.Called(userID, device, ip, mfaVerified)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TokenPair), args.Error(1)
+}
+
+func (m *MockUserService) IsMFAEnabled(userID int) (bool, error) {
 	args := m.Called(userID)
-	return args.Error(0)
+	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockUserService) IsTokenBlacklisted(token string) bool {
-	args := m.Called(token)
-	return args.Bool(0)
+func (m *MockUserService) CreateMFAChallenge(userID int) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserService) CompleteMFALogin(challengeToken, code, device, ip string) (*model.User, *model.TokenPair, error) {
+	args := m.Called(challengeToken, code, device, ip)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*model.User), args.Get(1).(*model.TokenPair), args.Error(2)
+}
+
+func (m *MockUserService) RefreshSession(refreshToken, ip string) (*model.TokenPair, error) {
+	args := m.Called(refreshToken, ip)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TokenPair), args.Error(1)
+}
+
+func (m *MockUserService) Logout(sessionID string) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
 }
 
 // 确保 MockUserService 实现了 UserServiceInterface
@@ -118,6 +152,13 @@ func TestLogin(t *testing.T) {
 	// 模拟成功登录
 	mockUser := &model.User{ID: 1, Email: "test@example.com"}
 	mockService.On("Login", "test@example.com", "password123").Return(mockUser, nil)
+	mockService.On("IsMFAEnabled", 1).Return(false, nil)
+	mockService.On("CreateSession", 1, mock.Anything, mock.Anything, false).Return(&model.TokenPair{
+		AccessToken:  "access",
+		RefreshToken: "session.secret",
+		ExpiresIn:    900,
+		SessionID:    "session",
+	}, nil)
 
 	body := []byte(`{"email": "test@example.com", "password": "password123"}`)
 	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"crowdfunding-backend/internal/errors"
+	"crowdfunding-backend/internal/service"
+	"crowdfunding-backend/internal/util"
+	stderrors "errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ExportHandler 处理个人数据导出请求
+type ExportHandler struct {
+	exportService *service.DataExportService
+}
+
+func NewExportHandler(exportService *service.DataExportService) *ExportHandler {
+	return &ExportHandler{exportService}
+}
+
+// RequestExport 申请导出个人数据，压缩包在后台生成，完成后通过邮件发送下载链接
+func (h *ExportHandler) RequestExport(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	export, err := h.exportService.RequestExport(userID)
+	if err != nil {
+		if stderrors.Is(err, service.ErrExportInProgress) {
+			errors.HandleError(c, errors.New(errors.ErrResourceConflict, err.Error()))
+			return
+		}
+		if stderrors.Is(err, service.ErrExportRateLimited) {
+			errors.HandleError(c, errors.New(errors.ErrTooManyRequests, err.Error()))
+			return
+		}
+		util.Logger.Error("创建个人数据导出任务失败", zap.Error(err), zap.Int("user_id", userID))
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "申请导出个人数据失败", err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    http.StatusAccepted,
+		"message": "数据导出已开始，完成后会将下载链接发送到您的邮箱",
+		"data":    export,
+	})
+}
+
+// DownloadExport 通过邮件中的限时链接下载导出的压缩包
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	path, filename, err := h.exportService.OpenExport(c.Query("token"))
+	if err != nil {
+		if stderrors.Is(err, service.ErrInvalidExportToken) {
+			errors.HandleError(c, errors.New(errors.ErrInvalidToken, err.Error()))
+			return
+		}
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "下载导出文件失败", err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(path, filename)
+}
@@ -0,0 +1,115 @@
+package user
+
+import (
+	"crowdfunding-backend/internal/errors"
+	"crowdfunding-backend/internal/service"
+	"crowdfunding-backend/internal/util"
+	stderrors "errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// MFAHandler 处理两步验证的开启、关闭和恢复码请求
+type MFAHandler struct {
+	mfaService  *service.MFAService
+	userService *service.UserService
+}
+
+func NewMFAHandler(mfaService *service.MFAService, userService *service.UserService) *MFAHandler {
+	return &MFAHandler{mfaService, userService}
+}
+
+type mfaCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// GetStatus 获取当前用户的两步验证状态
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	status, err := h.mfaService.GetStatus(c.GetInt("user_id"))
+	if err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "获取两步验证状态失败", err))
+		return
+	}
+	errors.HandleSuccess(c, status, "获取两步验证状态成功")
+}
+
+// Enroll 生成 TOTP 密钥和扫码地址，需调用 Verify 确认后生效
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	enrollment, err := h.mfaService.Enroll(c.GetInt("user_id"))
+	if err != nil {
+		h.handleMFAError(c, err, "生成两步验证密钥失败")
+		return
+	}
+	errors.HandleSuccess(c, enrollment, "请使用验证器扫描二维码并输入验证码")
+}
+
+// Verify 确认密钥并开启两步验证，返回只展示一次的恢复码
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var input mfaCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrValidation, "请输入验证码", err))
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmEnrollment(c.GetInt("user_id"), input.Code)
+	if err != nil {
+		h.handleMFAError(c, err, "开启两步验证失败")
+		return
+	}
+
+	// 当前会话刚刚完成了验证，无需重新登录即可访问需要两步验证的路由
+	if err := h.userService.MarkSessionMFAVerified(c.GetString("session_id")); err != nil {
+		util.Logger.Warn("标记会话两步验证状态失败", zap.Error(err), zap.Int("user_id", c.GetInt("user_id")))
+	}
+
+	errors.HandleSuccess(c, gin.H{"recovery_codes": codes}, "两步验证已开启，请妥善保存恢复码")
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var input mfaCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrValidation, "请输入验证码", err))
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.GetInt("user_id"), input.Code)
+	if err != nil {
+		h.handleMFAError(c, err, "重新生成恢复码失败")
+		return
+	}
+	errors.HandleSuccess(c, gin.H{"recovery_codes": codes}, "恢复码已重新生成")
+}
+
+// Disable 关闭两步验证
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var input mfaCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrValidation, "请输入验证码", err))
+		return
+	}
+
+	if err := h.mfaService.Disable(c.GetInt("user_id"), input.Code); err != nil {
+		h.handleMFAError(c, err, "关闭两步验证失败")
+		return
+	}
+	errors.HandleSuccess(c, nil, "两步验证已关闭")
+}
+
+func (h *MFAHandler) handleMFAError(c *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, service.ErrMFAAlreadyEnabled):
+		errors.HandleError(c, errors.Wrap(errors.ErrResourceExists, "已开启两步验证", err))
+	case stderrors.Is(err, service.ErrMFANotEnrolled), stderrors.Is(err, service.ErrMFAEnrollmentNotFound):
+		errors.HandleError(c, errors.Wrap(errors.ErrResourceNotFound, err.Error(), err))
+	case stderrors.Is(err, service.ErrInvalidMFACode):
+		errors.HandleError(c, errors.Wrap(errors.ErrValidation, "验证码错误", err))
+	case stderrors.Is(err, service.ErrAdminMFARequired):
+		errors.HandleError(c, errors.Wrap(errors.ErrForbidden, "管理员不能关闭两步验证", err))
+	case stderrors.Is(err, service.ErrUserNotFound):
+		errors.HandleError(c, errors.Wrap(errors.ErrUserNotFound, "用户不存在", err))
+	default:
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, message, err))
+	}
+}
@@ -0,0 +1,184 @@
+package user
+
+import (
+	"crowdfunding-backend/config"
+	"crowdfunding-backend/internal/errors"
+	"crowdfunding-backend/internal/service"
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// oidcBindingCookie 保存发起授权的浏览器标识，回调时校验，防止授权链接被转发给他人使用
+	oidcBindingCookie = "oidc_binding"
+	// oidcBindingCookieMaxAge 与 state 的有效期一致
+	oidcBindingCookieMaxAge = 10 * 60
+	oidcBindingCookiePath   = "/api"
+)
+
+// OIDCHandler 处理第三方登录（OpenID Connect）以及第三方账户的绑定和解绑
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+	userService service.UserServiceInterface
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService, userService service.UserServiceInterface) *OIDCHandler {
+	return &OIDCHandler{oidcService, userService}
+}
+
+// ListProviders 获取可用的第三方登录方式
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	errors.HandleSuccess(c, gin.H{
+		"providers": h.oidcService.Providers(),
+	}, "获取第三方登录方式成功")
+}
+
+// StartLogin 返回提供方的授权地址，前端跳转后由提供方回调到前端页面
+func (h *OIDCHandler) StartLogin(c *gin.Context) {
+	authorization, err := h.oidcService.StartLogin(c.Param("provider"))
+	if err != nil {
+		handleOIDCError(c, err)
+		return
+	}
+	setOIDCBindingCookie(c, authorization.Binding, oidcBindingCookieMaxAge)
+	errors.HandleSuccess(c, gin.H{
+		"authorization_url": authorization.URL,
+	}, "请跳转到第三方登录页面")
+}
+
+// oidcCallbackInput 前端提交的提供方回调参数
+type oidcCallbackInput struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// setOIDCBindingCookie 设置或清除（maxAge 为 -1）浏览器绑定 Cookie
+func setOIDCBindingCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	secure := strings.HasPrefix(config.AppConfig.BackendURL, "https://")
+	c.SetCookie(oidcBindingCookie, value, maxAge, oidcBindingCookiePath, "", secure, true)
+}
+
+// bindOIDCCallback 解析回调参数并取出浏览器绑定 Cookie，Cookie 只能使用一次
+func bindOIDCCallback(c *gin.Context) (*oidcCallbackInput, string, bool) {
+	var input oidcCallbackInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrValidation, "无效的请求数据", err))
+		return nil, "", false
+	}
+	binding, _ := c.Cookie(oidcBindingCookie)
+	setOIDCBindingCookie(c, "", -1)
+	return &input, binding, true
+}
+
+// Callback 前端将提供方回调中的 code 和 state 提交到此接口完成第三方登录，
+// 需由发起授权的同一个浏览器提交
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	input, binding, ok := bindOIDCCallback(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.oidcService.Callback(c.Param("provider"), input.Code, input.State, binding)
+	if err != nil {
+		handleOIDCError(c, err)
+		return
+	}
+
+	user := result.User
+	// 供登录防护中间件记录审计
+	c.Set("user_id", user.ID)
+
+	if respondMFAChallenge(c, h.userService, user.ID) {
+		return
+	}
+
+	tokens, err := h.userService.CreateSession(user.ID, c.Request.UserAgent(), c.ClientIP(), false)
+	if err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "生成令牌失败", err))
+		return
+	}
+
+	errors.HandleSuccess(c, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+		"created":       result.Created,
+	}, "登录成功")
+}
+
+// ListIdentities 获取当前用户绑定的第三方账户
+func (h *OIDCHandler) ListIdentities(c *gin.Context) {
+	identities, err := h.oidcService.ListIdentities(c.GetInt("user_id"))
+	if err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "获取第三方账户失败", err))
+		return
+	}
+	errors.HandleSuccess(c, identities, "获取第三方账户成功")
+}
+
+// StartLink 已登录用户绑定第三方账户，返回提供方的授权地址
+func (h *OIDCHandler) StartLink(c *gin.Context) {
+	authorization, err := h.oidcService.StartLink(c.Param("provider"), c.GetInt("user_id"))
+	if err != nil {
+		handleOIDCError(c, err)
+		return
+	}
+	setOIDCBindingCookie(c, authorization.Binding, oidcBindingCookieMaxAge)
+	errors.HandleSuccess(c, gin.H{
+		"authorization_url": authorization.URL,
+	}, "请跳转到第三方登录页面完成绑定")
+}
+
+// LinkCallback 已登录用户将绑定流程中提供方回调的 code 和 state 提交到此接口，
+// 只有发起绑定的用户在同一个浏览器中才能完成绑定
+func (h *OIDCHandler) LinkCallback(c *gin.Context) {
+	input, binding, ok := bindOIDCCallback(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.oidcService.LinkCallback(c.Param("provider"), input.Code, input.State, binding, c.GetInt("user_id"))
+	if err != nil {
+		handleOIDCError(c, err)
+		return
+	}
+	errors.HandleSuccess(c, gin.H{
+		"identity": result.Identity,
+	}, "第三方账户绑定成功")
+}
+
+// Unlink 解绑第三方账户
+func (h *OIDCHandler) Unlink(c *gin.Context) {
+	if err := h.oidcService.Unlink(c.GetInt("user_id"), c.Param("provider")); err != nil {
+		handleOIDCError(c, err)
+		return
+	}
+	errors.HandleSuccess(c, nil, "第三方账户已解绑")
+}
+
+func handleOIDCError(c *gin.Context, err error) {
+	switch {
+	case stderrors.Is(err, service.ErrOIDCProviderNotFound), stderrors.Is(err, service.ErrIdentityNotLinked):
+		errors.HandleError(c, errors.New(errors.ErrResourceNotFound, err.Error()))
+	case stderrors.Is(err, service.ErrInvalidOIDCState):
+		errors.HandleError(c, errors.New(errors.ErrInvalidToken, err.Error()))
+	case stderrors.Is(err, service.ErrInvalidIDToken):
+		errors.HandleError(c, errors.Wrap(errors.ErrInvalidCredentials, "第三方登录验证失败", err))
+	case stderrors.Is(err, service.ErrOIDCEmailRequired):
+		errors.HandleError(c, errors.New(errors.ErrBadRequest, err.Error()))
+	case stderrors.Is(err, service.ErrOIDCEmailInUse), stderrors.Is(err, service.ErrIdentityAlreadyLinked),
+		stderrors.Is(err, service.ErrLastLoginMethod):
+		errors.HandleError(c, errors.New(errors.ErrResourceConflict, err.Error()))
+	case stderrors.Is(err, service.ErrAccountSuspended), stderrors.Is(err, service.ErrAccountDeleted):
+		handleLoginError(c, err)
+	case stderrors.Is(err, service.ErrUserNotFound):
+		errors.HandleError(c, errors.New(errors.ErrUserNotFound, err.Error()))
+	default:
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "第三方登录失败", err))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crowdfunding-backend/internal/service"
 	"crowdfunding-backend/internal/storage"
 	"crowdfunding-backend/internal/util"
+	stderrors "errors"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
@@ -79,6 +80,39 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 	}, "资料更新成功")
 }
 
+// ChangePassword 修改密码，需要提供当前密码，修改后其他设备上的会话全部失效
+func (h *ProfileHandler) ChangePassword(c *gin.Context) {
+	var input struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrValidation, "无效的请求数据", err))
+		return
+	}
+	if !isPasswordStrong(input.NewPassword) {
+		errors.HandleError(c, errors.New(errors.ErrWeakPassword, "新密码强度不足"))
+		return
+	}
+
+	userID := c.GetInt("user_id")
+	err := h.userService.ChangePassword(userID, c.GetString("session_id"), input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, service.ErrIncorrectPassword):
+			errors.HandleError(c, errors.New(errors.ErrInvalidCredentials, err.Error()))
+		case stderrors.Is(err, service.ErrUserNotFound):
+			errors.HandleError(c, errors.New(errors.ErrUserNotFound, err.Error()))
+		default:
+			util.Logger.Error("修改密码失败", zap.Error(err), zap.Int("user_id", userID))
+			errors.HandleError(c, errors.Wrap(errors.ErrInternal, "修改密码失败", err))
+		}
+		return
+	}
+
+	errors.HandleSuccess(c, nil, "密码修改成功，其他设备已退出登录")
+}
+
 func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
@@ -115,11 +149,18 @@ func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
 func (h *ProfileHandler) DeleteAccount(c *gin.Context) {
 	userID := c.GetInt("user_id")
 
-	if err := h.userService.DeleteAccount(userID); err != nil {
+	restoreBefore, err := h.userService.DeleteAccount(userID)
+	if err != nil {
+		if stderrors.Is(err, service.ErrInvalidAccountState) {
+			errors.HandleError(c, errors.New(errors.ErrResourceConflict, err.Error()))
+			return
+		}
 		util.Logger.Error("注销账户失败", zap.Error(err))
 		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "注销账户失败", err))
 		return
 	}
 
-	errors.HandleSuccess(c, nil, "账户已成功注销")
+	errors.HandleSuccess(c, gin.H{
+		"restore_before": restoreBefore,
+	}, "账户已成功注销，恢复期内可以重新激活")
 }
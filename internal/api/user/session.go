@@ -0,0 +1,52 @@
+package user
+
+import (
+	"crowdfunding-backend/internal/errors"
+	"crowdfunding-backend/internal/service"
+	stderrors "errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler 处理用户查看和撤销登录会话的请求
+type SessionHandler struct {
+	userService *service.UserService
+}
+
+func NewSessionHandler(userService *service.UserService) *SessionHandler {
+	return &SessionHandler{userService}
+}
+
+// ListSessions 获取当前用户已登录的设备
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.userService.ListSessions(c.GetInt("user_id"), c.GetString("session_id"))
+	if err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "获取登录会话失败", err))
+		return
+	}
+	errors.HandleSuccess(c, sessions, "获取登录会话成功")
+}
+
+// RevokeSession 撤销指定会话，该设备需要重新登录
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	err := h.userService.RevokeSession(c.GetInt("user_id"), c.Param("id"))
+	if err != nil {
+		if stderrors.Is(err, service.ErrSessionNotFound) {
+			errors.HandleError(c, errors.Wrap(errors.ErrResourceNotFound, "会话不存在或已失效", err))
+			return
+		}
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "撤销会话失败", err))
+		return
+	}
+	errors.HandleSuccess(c, nil, "会话已撤销")
+}
+
+// RevokeOtherSessions 撤销除当前设备以外的全部会话
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	count, err := h.userService.RevokeOtherSessions(c.GetInt("user_id"), c.GetString("session_id"))
+	if err != nil {
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "撤销其他会话失败", err))
+		return
+	}
+	errors.HandleSuccess(c, gin.H{"revoked": count}, "其他设备已全部下线")
+}
@@ -0,0 +1,38 @@
+package user
+
+import (
+	"crowdfunding-backend/internal/errors"
+	"crowdfunding-backend/internal/service"
+	stderrors "errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UnlockHandler 处理解锁邮件中的链接
+type UnlockHandler struct {
+	protection *service.LoginProtectionService
+}
+
+func NewUnlockHandler(protection *service.LoginProtectionService) *UnlockHandler {
+	return &UnlockHandler{protection}
+}
+
+// UnlockAccount 通过解锁令牌解除因登录失败过多导致的账户锁定
+func (h *UnlockHandler) UnlockAccount(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		errors.HandleError(c, errors.New(errors.ErrValidation, "缺少解锁令牌"))
+		return
+	}
+
+	if err := h.protection.Unlock(token, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		if stderrors.Is(err, service.ErrInvalidUnlockToken) {
+			errors.HandleError(c, errors.Wrap(errors.ErrInvalidToken, "解锁链接无效或已使用", err))
+			return
+		}
+		errors.HandleError(c, errors.Wrap(errors.ErrInternal, "解锁账户失败", err))
+		return
+	}
+
+	errors.HandleSuccess(c, nil, "账户已解锁")
+}
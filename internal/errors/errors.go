@@ -29,6 +29,7 @@ const (
 	ErrResourceNotFound
 	ErrResourceExists
 	ErrResourceConflict
+	ErrTooManyRequests
 )
 
 // 定义业务相关错误码 (4000-4999)
@@ -41,6 +41,7 @@ var errorStatusMap = map[ErrorCode]int{
 	ErrResourceNotFound: http.StatusNotFound,
 	ErrResourceExists:   http.StatusConflict,
 	ErrResourceConflict: http.StatusConflict,
+	ErrTooManyRequests:  http.StatusTooManyRequests,
 
 	// 业务错误 (4000-4999)
 	ErrUserNotFound:      http.StatusNotFound,
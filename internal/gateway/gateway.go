@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"crowdfunding-backend/internal/model"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IntentStatus 支付意图状态
+type IntentStatus string
+
+const (
+	IntentRequiresCapture IntentStatus = "requires_capture" // 已创建，等待扣款
+	IntentProcessing      IntentStatus = "processing"       // 扣款处理中，结果异步通知
+	IntentSucceeded       IntentStatus = "succeeded"        // 扣款成功
+	IntentFailed          IntentStatus = "failed"           // 扣款失败
+	IntentRefunded        IntentStatus = "refunded"         // 已全额退款
+)
+
+var (
+	ErrIntentNotFound   = errors.New("payment intent not found")
+	ErrInvalidState     = errors.New("payment intent is not in a valid state for this operation")
+	ErrCaptureDeclined  = errors.New("payment capture declined")
+	ErrRefundExceeded   = errors.New("refund amount exceeds captured amount")
+	ErrCurrencyMismatch = errors.New("currency does not match payment intent")
+)
+
+// IntentRequest 创建支付意图的请求参数
+type IntentRequest struct {
+	Amount      model.Money
+	OrderNumber string
+	Metadata    map[string]string
+}
+
+// Intent 支付渠道侧的一笔支付意图
+type Intent struct {
+	ID             string
+	Amount         model.Money
+	Status         IntentStatus
+	RefundedAmount model.Money
+	CreatedAt      time.Time
+}
+
+// Refund 支付渠道侧的一笔退款
+type Refund struct {
+	ID        string
+	IntentID  string
+	Amount    model.Money
+	CreatedAt time.Time
+}
+
+// PaymentGateway 支付渠道抽象，所有支付服务商都需要实现该接口
+type PaymentGateway interface {
+	// Name 返回渠道标识，会记录在订单上
+	Name() string
+	// CreateIntent 创建支付意图
+	CreateIntent(req *IntentRequest) (*Intent, error)
+	// Capture 对支付意图扣款
+	Capture(intentID string) (*Intent, error)
+	// Refund 对已扣款的支付意图发起退款
+	Refund(intentID string, amount model.Money) (*Refund, error)
+	// QueryStatus 查询支付意图的最新状态
+	QueryStatus(intentID string) (*Intent, error)
+}
+
+// New 根据配置的渠道名称创建支付渠道
+func New(provider string) (PaymentGateway, error) {
+	switch provider {
+	case "", "sandbox":
+		return NewSandboxGateway(), nil
+	default:
+		return nil, fmt.Errorf("unsupported payment provider: %s", provider)
+	}
+}
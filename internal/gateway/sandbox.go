@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"crowdfunding-backend/internal/model"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SandboxGateway 本地沙箱支付渠道，数据保存在内存中，用于开发和测试
+type SandboxGateway struct {
+	mu             sync.Mutex
+	intents        map[string]*Intent
+	seq            int
+	declineCapture bool
+	asyncCapture   bool
+}
+
+func NewSandboxGateway() *SandboxGateway {
+	return &SandboxGateway{intents: make(map[string]*Intent)}
+}
+
+func (g *SandboxGateway) Name() string {
+	return "sandbox"
+}
+
+// SetDeclineCapture 设置后续扣款是否被拒绝，用于模拟支付失败
+func (g *SandboxGateway) SetDeclineCapture(decline bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.declineCapture = decline
+}
+
+// SetAsyncCapture 设置扣款是否异步完成，开启后扣款返回处理中，需调用 Settle 模拟渠道回调结果
+func (g *SandboxGateway) SetAsyncCapture(async bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.asyncCapture = async
+}
+
+// Settle 完成处理中的扣款，模拟支付渠道异步确认
+func (g *SandboxGateway) Settle(intentID string, succeed bool) (*Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	if intent.Status != IntentProcessing {
+		return nil, ErrInvalidState
+	}
+	if succeed {
+		intent.Status = IntentSucceeded
+	} else {
+		intent.Status = IntentFailed
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+func (g *SandboxGateway) CreateIntent(req *IntentRequest) (*Intent, error) {
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("invalid amount: %s", req.Amount)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.seq++
+	intent := &Intent{
+		ID:             fmt.Sprintf("sb_pi_%d_%d", time.Now().UnixNano(), g.seq),
+		Amount:         req.Amount,
+		RefundedAmount: model.NewMoney(0, req.Amount.Currency),
+		Status:         IntentRequiresCapture,
+		CreatedAt:      time.Now(),
+	}
+	g.intents[intent.ID] = intent
+
+	copied := *intent
+	return &copied, nil
+}
+
+func (g *SandboxGateway) Capture(intentID string) (*Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	if intent.Status != IntentRequiresCapture {
+		return nil, ErrInvalidState
+	}
+
+	if g.declineCapture {
+		intent.Status = IntentFailed
+		copied := *intent
+		return &copied, ErrCaptureDeclined
+	}
+
+	if g.asyncCapture {
+		intent.Status = IntentProcessing
+	} else {
+		intent.Status = IntentSucceeded
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+func (g *SandboxGateway) Refund(intentID string, amount model.Money) (*Refund, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	if intent.Status != IntentSucceeded {
+		return nil, ErrInvalidState
+	}
+	if amount.Currency != intent.Amount.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+	refunded := intent.RefundedAmount.Add(amount)
+	if !amount.IsPositive() || refunded.Cmp(intent.Amount) > 0 {
+		return nil, ErrRefundExceeded
+	}
+
+	intent.RefundedAmount = refunded
+	if intent.RefundedAmount.Cmp(intent.Amount) >= 0 {
+		intent.Status = IntentRefunded
+	}
+
+	g.seq++
+	return &Refund{
+		ID:        fmt.Sprintf("sb_re_%d_%d", time.Now().UnixNano(), g.seq),
+		IntentID:  intentID,
+		Amount:    amount,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (g *SandboxGateway) QueryStatus(intentID string) (*Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	copied := *intent
+	return &copied, nil
+}
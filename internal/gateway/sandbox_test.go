@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"crowdfunding-backend/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func cny(cents int64) model.Money {
+	return model.NewMoney(cents, "CNY")
+}
+
+func TestSandboxCaptureAndRefund(t *testing.T) {
+	g := NewSandboxGateway()
+
+	intent, err := g.CreateIntent(&IntentRequest{Amount: cny(10000), OrderNumber: "ORD-2024-0001"})
+	assert.NoError(t, err)
+	assert.Equal(t, IntentRequiresCapture, intent.Status)
+
+	captured, err := g.Capture(intent.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, IntentSucceeded, captured.Status)
+
+	// 重复扣款应被拒绝
+	_, err = g.Capture(intent.ID)
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	_, err = g.Refund(intent.ID, cny(4000))
+	assert.NoError(t, err)
+	_, err = g.Refund(intent.ID, cny(7000))
+	assert.ErrorIs(t, err, ErrRefundExceeded)
+	_, err = g.Refund(intent.ID, model.NewMoney(6000, "USD"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = g.Refund(intent.ID, cny(6000))
+	assert.NoError(t, err)
+
+	status, err := g.QueryStatus(intent.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, IntentRefunded, status.Status)
+	assert.Equal(t, cny(10000), status.RefundedAmount)
+}
+
+func TestSandboxDeclineCapture(t *testing.T) {
+	g := NewSandboxGateway()
+	g.SetDeclineCapture(true)
+
+	intent, err := g.CreateIntent(&IntentRequest{Amount: cny(5000)})
+	assert.NoError(t, err)
+
+	captured, err := g.Capture(intent.ID)
+	assert.ErrorIs(t, err, ErrCaptureDeclined)
+	assert.Equal(t, IntentFailed, captured.Status)
+
+	_, err = g.Refund(intent.ID, cny(5000))
+	assert.ErrorIs(t, err, ErrInvalidState)
+}
+
+func TestSandboxAsyncCapture(t *testing.T) {
+	g := NewSandboxGateway()
+	g.SetAsyncCapture(true)
+
+	intent, err := g.CreateIntent(&IntentRequest{Amount: cny(2000)})
+	assert.NoError(t, err)
+
+	captured, err := g.Capture(intent.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, IntentProcessing, captured.Status)
+
+	settled, err := g.Settle(intent.ID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, IntentSucceeded, settled.Status)
+
+	_, err = g.Settle(intent.ID, false)
+	assert.ErrorIs(t, err, ErrInvalidState)
+}
+
+func TestSandboxUnknownIntent(t *testing.T) {
+	g := NewSandboxGateway()
+
+	_, err := g.QueryStatus("missing")
+	assert.ErrorIs(t, err, ErrIntentNotFound)
+	_, err = g.Capture("missing")
+	assert.ErrorIs(t, err, ErrIntentNotFound)
+}
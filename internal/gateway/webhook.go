@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+// 支付回调事件类型
+const (
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentFailed    = "payment.failed"
+)
+
+// SignatureHeader 支付渠道回调时携带签名的请求头
+const SignatureHeader = "X-Webhook-Signature"
+
+var ErrInvalidWebhookEvent = errors.New("invalid webhook event")
+
+// WebhookEvent 支付渠道回调事件
+type WebhookEvent struct {
+	ID       string `json:"id"`        // 渠道侧事件ID，用于去重
+	Type     string `json:"type"`      // 事件类型
+	IntentID string `json:"intent_id"` // 关联的支付意图ID
+}
+
+// SignPayload 使用共享密钥对回调内容计算 HMAC-SHA256 签名（十六进制）
+func SignPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature 校验回调签名，使用常量时间比较
+func VerifySignature(secret string, payload []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	expected, err := hex.DecodeString(SignPayload(secret, payload))
+	if err != nil {
+		return false
+	}
+	actual, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, actual)
+}
+
+// ParseWebhookEvent 解析回调内容
+func ParseWebhookEvent(payload []byte) (*WebhookEvent, error) {
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, ErrInvalidWebhookEvent
+	}
+	if event.ID == "" || event.Type == "" || event.IntentID == "" {
+		return nil, ErrInvalidWebhookEvent
+	}
+	return &event, nil
+}
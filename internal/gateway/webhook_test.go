@@ -0,0 +1,31 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"payment.succeeded","intent_id":"sb_pi_1"}`)
+	signature := SignPayload("secret", payload)
+
+	assert.True(t, VerifySignature("secret", payload, signature))
+	assert.False(t, VerifySignature("other-secret", payload, signature))
+	assert.False(t, VerifySignature("secret", []byte(`{"id":"evt_2"}`), signature))
+	assert.False(t, VerifySignature("secret", payload, "not-hex"))
+	assert.False(t, VerifySignature("", payload, signature))
+}
+
+func TestParseWebhookEvent(t *testing.T) {
+	event, err := ParseWebhookEvent([]byte(`{"id":"evt_1","type":"payment.failed","intent_id":"sb_pi_1"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "evt_1", event.ID)
+	assert.Equal(t, EventPaymentFailed, event.Type)
+
+	_, err = ParseWebhookEvent([]byte(`{"id":"evt_1"}`))
+	assert.ErrorIs(t, err, ErrInvalidWebhookEvent)
+
+	_, err = ParseWebhookEvent([]byte(`not json`))
+	assert.ErrorIs(t, err, ErrInvalidWebhookEvent)
+}
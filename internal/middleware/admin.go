@@ -3,14 +3,18 @@ package middleware
 import (
 	"crowdfunding-backend/internal/service"
 	"crowdfunding-backend/internal/util"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-// AdminMiddleware 确保只有管理员可以访问某些路由
-func AdminMiddleware(userService *service.UserService) gin.HandlerFunc {
+// permissionsContextKey 管理员中间件加载的权限列表，供 RequirePermission 复用
+const permissionsContextKey = "permissions"
+
+// AdminMiddleware 确保只有管理员和拥有运营角色的用户可以进入管理后台，具体操作由 RequirePermission 校验
+func AdminMiddleware(userService *service.UserService, rbacService *service.RBACService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		util.Logger.Info("进入管理员中间件",
 			zap.String("path", c.Request.URL.Path),
@@ -29,7 +33,7 @@ func AdminMiddleware(userService *service.UserService) gin.HandlerFunc {
 		}
 
 		user, err := userService.GetUserByID(userID.(int))
-		if err != nil || user.Role != "admin" {
+		if err != nil || !user.IsStaff() {
 			util.Logger.Warn("非管理员访问",
 				zap.Int("user_id", userID.(int)),
 				zap.Error(err))
@@ -42,8 +46,85 @@ func AdminMiddleware(userService *service.UserService) gin.HandlerFunc {
 			return
 		}
 
+		// 管理员必须开启两步验证，且当前会话已通过两步验证
+		if err := userService.CheckAdminMFA(user.ID, c.GetString("session_id")); err != nil {
+			util.Logger.Warn("管理员两步验证未通过",
+				zap.Int("user_id", user.ID),
+				zap.Error(err))
+			switch {
+			case errors.Is(err, service.ErrAdminMFARequired):
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":    403,
+					"message": "请先开启两步验证",
+					"error":   "MFA enrollment required",
+				})
+			case errors.Is(err, service.ErrMFAVerificationMissing):
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":    403,
+					"message": "请通过两步验证重新登录",
+					"error":   "MFA verification required",
+				})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"code":    500,
+					"message": "校验两步验证状态失败",
+					"error":   err.Error(),
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		permissions, err := rbacService.GetUserPermissions(user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "获取权限失败",
+				"error":   err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		c.Set(permissionsContextKey, permissions)
+
 		util.Logger.Info("管理员验证通过",
 			zap.Int("user_id", userID.(int)))
 		c.Next()
 	}
 }
+
+// RequirePermission 要求当前用户拥有指定权限，例如 RequirePermission(rbacService, "refund.approve")
+func RequirePermission(rbacService *service.RBACService, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, ok := c.Get(permissionsContextKey)
+		if !ok {
+			loaded, err := rbacService.GetUserPermissions(c.GetInt("user_id"))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"code":    500,
+					"message": "获取权限失败",
+					"error":   err.Error(),
+				})
+				c.Abort()
+				return
+			}
+			permissions = loaded
+			c.Set(permissionsContextKey, loaded)
+		}
+
+		if !service.HasPermission(permissions.([]string), permission) {
+			util.Logger.Warn("权限不足",
+				zap.Int("user_id", c.GetInt("user_id")),
+				zap.String("permission", permission),
+				zap.String("path", c.Request.URL.Path))
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "权限不足",
+				"error":   "Permission required: " + permission,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
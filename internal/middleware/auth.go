@@ -5,6 +5,7 @@ import (
 	"crowdfunding-backend/internal/errors"
 	"crowdfunding-backend/internal/service"
 	"crowdfunding-backend/internal/util"
+	stderrors "errors"
 	"strings"
 	"time"
 
@@ -37,20 +38,29 @@ func AuthMiddleware(userService *service.UserService) gin.HandlerFunc {
 			return
 		}
 
-		if userService.IsTokenBlacklisted(parts[1]) {
-			errors.HandleError(c, errors.New(errors.ErrUnauthorized, "令牌已被撤销"))
+		userID, sessionID, err := util.ParseAccessToken(parts[1])
+		if err != nil {
+			errors.HandleError(c, errors.Wrap(errors.ErrUnauthorized, "无效或过期的令牌", err))
 			c.Abort()
 			return
 		}
 
-		userID, err := util.ValidateToken(parts[1])
-		if err != nil {
-			errors.HandleError(c, errors.Wrap(errors.ErrUnauthorized, "无效或过期的令牌", err))
+		// 访问令牌本身未过期，但所属会话可能已注销或被撤销
+		if err := userService.ValidateSession(sessionID, userID, c.ClientIP()); err != nil {
+			switch {
+			case stderrors.Is(err, service.ErrSessionRevoked):
+				errors.HandleError(c, errors.Wrap(errors.ErrUnauthorized, "令牌已被撤销", err))
+			case stderrors.Is(err, service.ErrAccountSuspended), stderrors.Is(err, service.ErrAccountDeleted):
+				errors.HandleError(c, errors.Wrap(errors.ErrForbidden, "账户不可用", err))
+			default:
+				errors.HandleError(c, errors.Wrap(errors.ErrInternal, "校验会话失败", err))
+			}
 			c.Abort()
 			return
 		}
 
 		c.Set("user_id", userID)
+		c.Set("session_id", sessionID)
 
 		select {
 		case <-ctx.Done():
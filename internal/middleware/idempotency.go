@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"crowdfunding-backend/internal/service"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader 客户端提供的幂等键请求头
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader 标记响应是回放的首次请求结果
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+
+	idempotentCommittedContextKey = "idempotent_committed"
+)
+
+// MarkIdempotentCommitted 标记请求已提交了不可重复执行的操作（例如已创建订单并发起扣款）。
+// 被标记的请求即使返回服务端错误也会保存响应，客户端使用同一个键重试时回放该响应而不是重新执行
+func MarkIdempotentCommitted(c *gin.Context) {
+	c.Set(idempotentCommittedContextKey, true)
+}
+
+// idempotencyWriter 在写出响应的同时保留一份响应体
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware 处理 Idempotency-Key 请求头，需在 AuthMiddleware 之后使用。
+// 同一用户在有效期内重复使用相同的键时回放首次请求的响应，键被用于内容不同的请求时拒绝处理。
+// 未携带请求头的请求不受影响
+func IdempotencyMiddleware(idempotencyService *service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Idempotency-Key 长度不能超过255个字符",
+			})
+			return
+		}
+
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "需要认证",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "读取请求体失败",
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		requestHash := service.HashRequest(c.Request.Method, c.Request.URL.Path, body)
+		record, replay, err := idempotencyService.Begin(userID.(int), key, requestHash)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrIdempotencyKeyReused):
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+					"code":    422,
+					"message": err.Error(),
+				})
+			case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+					"code":    409,
+					"message": err.Error(),
+				})
+			default:
+				util.Logger.Error("处理幂等键失败", zap.Error(err), zap.Int("user_id", userID.(int)))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"code":    500,
+					"message": "处理幂等键失败",
+					"error":   err.Error(),
+				})
+			}
+			return
+		}
+
+		if replay {
+			util.Logger.Info("回放幂等请求",
+				zap.Int("user_id", userID.(int)),
+				zap.String("path", c.Request.URL.Path),
+				zap.Int("status", record.ResponseStatus))
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.ResponseStatus, record.ResponseContentType, record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				// 已提交操作的请求保持处理中状态，直到过期前都不允许重试
+				if c.GetBool(idempotentCommittedContextKey) {
+					panic(r)
+				}
+				if err := idempotencyService.Release(record); err != nil {
+					util.Logger.Error("释放幂等键失败", zap.Error(err), zap.Int("record_id", record.ID))
+				}
+				panic(r)
+			}
+		}()
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// 尚未提交任何操作的服务端错误释放幂等键，允许客户端使用同一个键重试；
+		// 已提交操作的请求保存响应，避免重试时重复创建订单和扣款
+		status := writer.Status()
+		if status >= http.StatusInternalServerError && !c.GetBool(idempotentCommittedContextKey) {
+			if err := idempotencyService.Release(record); err != nil {
+				util.Logger.Error("释放幂等键失败", zap.Error(err), zap.Int("record_id", record.ID))
+			}
+			return
+		}
+		if err := idempotencyService.Complete(record, status, writer.Header().Get("Content-Type"), writer.body.Bytes()); err != nil {
+			util.Logger.Error("保存幂等请求响应失败", zap.Error(err), zap.Int("record_id", record.ID))
+		}
+	}
+}
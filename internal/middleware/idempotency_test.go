@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/service"
+	"crowdfunding-backend/internal/util"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryIdempotencyRepository 是 IdempotencyRepository 接口的内存实现，按 (user_id, key) 唯一
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	nextID  int
+	records map[string]*model.IdempotencyRecord
+}
+
+func newMemoryIdempotencyRepository() *memoryIdempotencyRepository {
+	return &memoryIdempotencyRepository{records: make(map[string]*model.IdempotencyRecord)}
+}
+
+func idempotencyMapKey(userID int, key string) string {
+	return fmt.Sprintf("%d:%s", userID, key)
+}
+
+func (r *memoryIdempotencyRepository) CreateIdempotencyRecord(record *model.IdempotencyRecord) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := idempotencyMapKey(record.UserID, record.Key)
+	if _, ok := r.records[k]; ok {
+		return false, nil
+	}
+	r.nextID++
+	record.ID = r.nextID
+	stored := *record
+	r.records[k] = &stored
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) GetIdempotencyRecord(userID int, key string) (*model.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[idempotencyMapKey(userID, key)]
+	if !ok {
+		return nil, nil
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (r *memoryIdempotencyRepository) CompleteIdempotencyRecord(id, status int, contentType string, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, record := range r.records {
+		if record.ID == id {
+			record.Status = service.IdempotencyStatusCompleted
+			record.ResponseStatus = status
+			record.ResponseContentType = contentType
+			record.ResponseBody = append([]byte(nil), body...)
+		}
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteIdempotencyRecord(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k, record := range r.records {
+		if record.ID == id {
+			delete(r.records, k)
+		}
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpiredIdempotencyRecords() (int64, error) {
+	return 0, nil
+}
+
+func newIdempotencyTestRouter(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	util.Logger = zap.NewNop()
+
+	idempotencyService := service.NewIdempotencyService(newMemoryIdempotencyRepository(), time.Hour)
+	router := gin.New()
+	router.POST("/payments", func(c *gin.Context) {
+		c.Set("user_id", 1)
+		c.Next()
+	}, IdempotencyMiddleware(idempotencyService), handler)
+	return router
+}
+
+func postWithKey(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyMiddlewareReplay(t *testing.T) {
+	var calls int32
+	router := newIdempotencyTestRouter(func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusCreated, gin.H{"order_id": n})
+	})
+
+	first := postWithKey(router, "key-1", `{"amount":100}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	second := postWithKey(router, "key-1", `{"amount":100}`)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// 同一个键用于内容不同的请求
+	reused := postWithKey(router, "key-1", `{"amount":200}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyMiddlewareInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := newIdempotencyTestRouter(func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- postWithKey(router, "key-1", `{}`)
+	}()
+	<-started
+
+	// 首次请求仍在处理中，使用相同键的请求被拒绝
+	concurrent := postWithKey(router, "key-1", `{}`)
+	assert.Equal(t, http.StatusConflict, concurrent.Code)
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+}
+
+func TestIdempotencyMiddlewareServerError(t *testing.T) {
+	var calls int32
+	router := newIdempotencyTestRouter(func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database unavailable"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	// 未提交任何操作的服务端错误释放幂等键，重试会重新执行
+	assert.Equal(t, http.StatusInternalServerError, postWithKey(router, "key-1", `{}`).Code)
+	retry := postWithKey(router, "key-1", `{}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Empty(t, retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyMiddlewareCommittedServerError(t *testing.T) {
+	var calls int32
+	router := newIdempotencyTestRouter(func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		MarkIdempotentCommitted(c)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "capture timeout", "order_id": 7})
+	})
+
+	// 订单已创建后的服务端错误保存响应，重试时回放而不是重复扣款
+	first := postWithKey(router, "key-1", `{}`)
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	retry := postWithKey(router, "key-1", `{}`)
+	assert.Equal(t, http.StatusInternalServerError, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求ID头，客户端或网关传入时沿用，否则由服务端生成，并在响应中返回
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDContextKey = "request_id"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestIDMiddleware 为每个请求分配请求ID，用于关联日志和审计记录
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			buf := make([]byte, 16)
+			if _, err := rand.Read(buf); err == nil {
+				requestID = hex.EncodeToString(buf)
+			} else {
+				requestID = ""
+			}
+		}
+		c.Set(requestIDContextKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// AuditActor 当前请求的操作者，用于写入审计日志
+func AuditActor(c *gin.Context) model.AuditActor {
+	return model.AuditActor{
+		UserID:    c.GetInt("user_id"),
+		IP:        c.ClientIP(),
+		RequestID: c.GetString(requestIDContextKey),
+	}
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 审计操作类型，格式为 对象.操作
+const (
+	AuditActionProjectReview      = "project.review"
+	AuditActionProjectStatus      = "project.status"
+	AuditActionProjectDelete      = "project.delete"
+	AuditActionRoleCreate         = "role.create"
+	AuditActionRoleUpdate         = "role.update"
+	AuditActionRoleDelete         = "role.delete"
+	AuditActionUserRoleAssign     = "user.role_assign"
+	AuditActionUserRoleRevoke     = "user.role_revoke"
+	AuditActionUserRoleSet        = "user.role_set"
+	AuditActionUserSuspend        = "user.suspend"
+	AuditActionUserUnsuspend      = "user.unsuspend"
+	AuditActionRefundProcess      = "refund.process"
+	AuditActionShipmentCreate     = "shipment.create"
+	AuditActionShipmentUpdate     = "shipment.update"
+	AuditActionPayoutApprove      = "payout.approve"
+	AuditActionPayoutPaid         = "payout.paid"
+	AuditActionUserForceLogout    = "user.force_logout"
+	AuditActionExchangeRateCreate = "exchange_rate.create"
+	AuditActionPostDelete         = "post.delete"
+	AuditActionCommentDelete      = "comment.delete"
+	AuditActionCategoryCreate     = "category.create"
+	AuditActionTagCreate          = "tag.create"
+)
+
+// 审计对象类型
+const (
+	AuditTargetProject       = "project"
+	AuditTargetRole          = "role"
+	AuditTargetUser          = "user"
+	AuditTargetRefundRequest = "refund_request"
+	AuditTargetShipment      = "shipment"
+	AuditTargetPayout        = "payout"
+	AuditTargetExchangeRate  = "exchange_rate"
+	AuditTargetPost          = "post"
+	AuditTargetComment       = "comment"
+	AuditTargetCategory      = "category"
+	AuditTargetTag           = "tag"
+)
+
+// AuditEntry 一条管理操作审计记录，与操作本身在同一事务中写入，写入后不可修改
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	ActorID    int             `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   int             `json:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// SetChange 记录操作前后的数据快照，为 nil 的一方不记录
+func (e *AuditEntry) SetChange(before, after interface{}) {
+	e.Before = auditSnapshot(before)
+	e.After = auditSnapshot(after)
+}
+
+func auditSnapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// AuditActor 发起管理操作的用户及请求来源
+type AuditActor struct {
+	UserID    int
+	IP        string
+	RequestID string
+}
+
+// Entry 以该操作者创建一条审计记录
+func (a AuditActor) Entry(action, targetType string, targetID int) *AuditEntry {
+	return &AuditEntry{
+		ActorID:    a.UserID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         a.IP,
+		RequestID:  a.RequestID,
+	}
+}
+
+// AuditFilter 审计日志查询条件，零值表示不限
+type AuditFilter struct {
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   int
+	From       time.Time
+	To         time.Time
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// AuthThrottle 登录失败计数
+type AuthThrottle struct {
+	Scope         string
+	Key           string
+	Failures      int
+	LastFailureAt *time.Time
+	NextAttemptAt *time.Time // 指数退避，此时间之前拒绝请求
+	LockedUntil   *time.Time
+}
+
+// LoginAuditEntry 登录审计记录
+type LoginAuditEntry struct {
+	ID        int64     `json:"id"`
+	UserID    *int      `json:"user_id,omitempty"`
+	Email     string    `json:"email"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Action    string    `json:"action"`
+	Result    string    `json:"result"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// 数据导出任务状态
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+	DataExportExpired = "expired"
+)
+
+// DataExport 用户个人数据导出任务
+type DataExport struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"user_id"`
+	Status       string     `json:"status"`
+	FilePath     string     `json:"-"`
+	TokenHash    string     `json:"-"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// PersonalData 导出给用户的全部个人数据，每个字段对应压缩包中的一个 JSON 文件
+type PersonalData struct {
+	Profile        *User            `json:"profile"`
+	Addresses      []*UserAddress   `json:"addresses"`
+	Pledges        []*Pledge        `json:"pledges"`
+	Orders         []*Order         `json:"orders"`
+	RefundRequests []*RefundRequest `json:"refund_requests"`
+	Shipments      []*Shipment      `json:"shipments"`
+	Posts          []*Post          `json:"posts"`
+	Comments       []*Comment       `json:"comments"`
+	Likes          []*Like          `json:"likes"`
+	Follows        []*Follow        `json:"follows"`
+}
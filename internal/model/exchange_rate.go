@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// ExchangeRate 汇率记录，表示 1 个 BaseCurrency 兑换 Rate 个 QuoteCurrency
+type ExchangeRate struct {
+	ID            int       `json:"id"`
+	BaseCurrency  string    `json:"base_currency"`
+	QuoteCurrency string    `json:"quote_currency"`
+	Rate          string    `json:"rate"`           // 十进制字符串，避免浮点误差
+	EffectiveFrom time.Time `json:"effective_from"` // 生效时间
+	CreatedBy     *int      `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// IdempotencyRecord 幂等键记录，保存首次请求的响应用于重试时回放
+type IdempotencyRecord struct {
+	ID                  int
+	UserID              int
+	Key                 string
+	RequestHash         string // 请求方法、路径和请求体的 SHA-256
+	Status              string // processing / completed
+	ResponseStatus      int
+	ResponseContentType string
+	ResponseBody        []byte
+	CreatedAt           time.Time
+	ExpiresAt           time.Time
+}
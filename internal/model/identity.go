@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// UserIdentity 用户绑定的第三方登录身份，provider + subject 唯一对应一个用户
+type UserIdentity struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"-"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// OIDCLoginState 发起 OIDC 授权时保存的一次性 state，回调时校验并删除
+type OIDCLoginState struct {
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	BindingHash  string // 发起授权的浏览器 Cookie 的 SHA-256，回调时校验
+	UserID       int    // 非 0 表示已登录用户绑定第三方身份
+	ExpiresAt    time.Time
+}
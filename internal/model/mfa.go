@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// UserMFA 用户的 TOTP 两步验证配置
+type UserMFA struct {
+	UserID       int
+	Secret       string     // Base32 编码的密钥
+	EnabledAt    *time.Time // 为空表示尚未完成首次验证
+	LastUsedStep int64      // 最近一次通过验证的时间步
+	CreatedAt    time.Time
+}
+
+// MFAEnrollment 开启两步验证时返回给客户端的密钥，provisioning_uri 可直接生成二维码
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFAStatus 两步验证状态
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
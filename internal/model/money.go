@@ -0,0 +1,224 @@
+package model
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency 平台默认币种
+const DefaultCurrency = "CNY"
+
+// ErrInvalidMoney 金额格式错误
+var ErrInvalidMoney = errors.New("无效的金额，最多保留两位小数")
+
+// Money 金额，以最小货币单位（分）保存，所有加减和比例计算都是精确的整数运算。
+// 数据库中对应 DECIMAL(10,2) 列，JSON 中序列化为保留两位小数的数字
+type Money struct {
+	Cents    int64  // 最小货币单位金额
+	Currency string // ISO 4217 币种代码
+}
+
+// NewMoney 按最小货币单位创建金额
+func NewMoney(cents int64, currency string) Money {
+	return Money{Cents: cents, Currency: currency}
+}
+
+// ParseMoney 解析形如 "12"、"12.3"、"-12.34" 的十进制金额，不经过浮点数转换
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "-") {
+		negative = true
+		s = s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" && (!hasFrac || frac == "") {
+		return Money{}, ErrInvalidMoney
+	}
+	if len(frac) > 2 {
+		// 允许数据库返回的多余的零，例如 DECIMAL(10,4)
+		if strings.Trim(frac[2:], "0") != "" {
+			return Money{}, ErrInvalidMoney
+		}
+		frac = frac[:2]
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	units, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil || units > math.MaxInt64/100 {
+		return Money{}, ErrInvalidMoney
+	}
+	cents, err := strconv.ParseUint(frac, 10, 8)
+	if err != nil {
+		return Money{}, ErrInvalidMoney
+	}
+
+	total := int64(units)*100 + int64(cents)
+	if negative {
+		total = -total
+	}
+	return NewMoney(total, DefaultCurrency), nil
+}
+
+// IsValidCurrency 判断是否为三位大写字母的 ISO 4217 币种代码
+func IsValidCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// String 返回保留两位小数的金额，不含币种
+func (m Money) String() string {
+	cents := m.Cents
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// currency 返回两个金额中已设置的币种
+func (m Money) currency(other Money) string {
+	if m.Currency != "" {
+		return m.Currency
+	}
+	return other.Currency
+}
+
+// Add 金额相加，调用方需保证币种一致
+func (m Money) Add(other Money) Money {
+	return Money{Cents: m.Cents + other.Cents, Currency: m.currency(other)}
+}
+
+// Sub 金额相减，调用方需保证币种一致
+func (m Money) Sub(other Money) Money {
+	return Money{Cents: m.Cents - other.Cents, Currency: m.currency(other)}
+}
+
+// MulBasisPoints 按万分比计算金额，结果四舍五入到分，例如 500 表示 5%
+func (m Money) MulBasisPoints(basisPoints int64) Money {
+	product := m.Cents * basisPoints
+	half := int64(5000)
+	if product < 0 {
+		half = -half
+	}
+	return Money{Cents: (product + half) / 10000, Currency: m.Currency}
+}
+
+// Convert 按汇率折算为另一币种，rate 表示 1 单位当前币种兑换的目标币种数量，结果四舍五入到分
+func (m Money) Convert(rate *big.Rat, currency string) Money {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Cents), rate)
+	num := new(big.Int).Abs(product.Num())
+	den := product.Denom()
+	// 四舍五入：(2*num + den) / (2*den)
+	rounded := new(big.Int).Add(new(big.Int).Lsh(num, 1), den)
+	rounded.Quo(rounded, new(big.Int).Lsh(den, 1))
+	if product.Sign() < 0 {
+		rounded.Neg(rounded)
+	}
+	return Money{Cents: rounded.Int64(), Currency: currency}
+}
+
+// Cmp 比较金额大小，返回 -1、0、1
+func (m Money) Cmp(other Money) int {
+	switch {
+	case m.Cents < other.Cents:
+		return -1
+	case m.Cents > other.Cents:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) IsZero() bool     { return m.Cents == 0 }
+func (m Money) IsPositive() bool { return m.Cents > 0 }
+func (m Money) IsNegative() bool { return m.Cents < 0 }
+
+// ProgressPercent 计算已筹金额占目标金额的百分比，保留两位小数
+func ProgressPercent(raised, goal Money) float64 {
+	if goal.Cents <= 0 {
+		return 0
+	}
+	return float64(raised.Cents*10000/goal.Cents) / 100
+}
+
+// MarshalJSON 序列化为两位小数的 JSON 数字
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 支持 JSON 数字和字符串两种写法，直接解析原始文本以保证精度
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*m = Money{}
+		return nil
+	}
+	text := string(bytes.Trim(data, `"`))
+	if strings.ContainsAny(text, "eE") {
+		return ErrInvalidMoney
+	}
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	if m.Currency != "" {
+		parsed.Currency = m.Currency
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan 从 DECIMAL 列读取金额
+func (m *Money) Scan(value interface{}) error {
+	var parsed Money
+	var err error
+	switch v := value.(type) {
+	case nil:
+		parsed = NewMoney(0, DefaultCurrency)
+	case []byte:
+		parsed, err = ParseMoney(string(v))
+	case string:
+		parsed, err = ParseMoney(v)
+	case int64:
+		parsed = NewMoney(v*100, DefaultCurrency)
+	case float64:
+		parsed, err = ParseMoney(strconv.FormatFloat(v, 'f', 2, 64))
+	default:
+		return fmt.Errorf("无法将 %T 转换为金额", value)
+	}
+	if err != nil {
+		return err
+	}
+	if m.Currency != "" {
+		parsed.Currency = m.Currency
+	}
+	*m = parsed
+	return nil
+}
+
+// Value 以十进制字符串写入 DECIMAL 列
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
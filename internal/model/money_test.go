@@ -0,0 +1,112 @@
+package model
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input string
+		cents int64
+		ok    bool
+	}{
+		{"12", 1200, true},
+		{"12.3", 1230, true},
+		{"12.34", 1234, true},
+		{"0.1", 10, true},
+		{".5", 50, true},
+		{"-8.05", -805, true},
+		{"100.5000", 10050, true},
+		{"12.345", 0, false},
+		{"abc", 0, false},
+		{"", 0, false},
+		{"-", 0, false},
+	}
+
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.input)
+		if !tt.ok {
+			assert.ErrorIs(t, err, ErrInvalidMoney, tt.input)
+			continue
+		}
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.cents, m.Cents, tt.input)
+		assert.Equal(t, DefaultCurrency, m.Currency, tt.input)
+	}
+}
+
+func TestMoneyArithmeticIsExact(t *testing.T) {
+	// 0.1 + 0.2 在浮点数下不等于 0.3
+	sum := NewMoney(10, "CNY").Add(NewMoney(20, "CNY"))
+	assert.Equal(t, "0.30", sum.String())
+
+	total := NewMoney(0, "CNY")
+	for i := 0; i < 1000; i++ {
+		total = total.Add(NewMoney(1, "CNY"))
+	}
+	assert.Equal(t, int64(1000), total.Cents)
+
+	assert.Equal(t, int64(350), NewMoney(9999, "CNY").MulBasisPoints(350).Cents)
+	assert.Equal(t, int64(-350), NewMoney(-9999, "CNY").MulBasisPoints(350).Cents)
+	assert.Equal(t, "-0.05", NewMoney(-5, "CNY").String())
+	assert.Equal(t, 1, NewMoney(101, "CNY").Cmp(NewMoney(100, "CNY")))
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var input struct {
+		Amount Money `json:"amount"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": 19.99}`), &input))
+	assert.Equal(t, int64(1999), input.Amount.Cents)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": "5"}`), &input))
+	assert.Equal(t, int64(500), input.Amount.Cents)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 1.999}`), &input))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 1e3}`), &input))
+
+	data, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{NewMoney(123456, "CNY")})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount": 1234.56}`, string(data))
+}
+
+func TestMoneyScanAndValue(t *testing.T) {
+	var m Money
+	assert.NoError(t, m.Scan([]byte("1024.50")))
+	assert.Equal(t, int64(102450), m.Cents)
+
+	assert.NoError(t, m.Scan(nil))
+	assert.True(t, m.IsZero())
+
+	value, err := NewMoney(705, "CNY").Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "7.05", value)
+}
+
+func TestMoneyConvert(t *testing.T) {
+	rate, _ := new(big.Rat).SetString("7.12345678")
+	converted := NewMoney(1000, "USD").Convert(rate, "CNY")
+	assert.Equal(t, int64(7123), converted.Cents)
+	assert.Equal(t, "CNY", converted.Currency)
+
+	// 0.005 四舍五入为 0.01
+	half, _ := new(big.Rat).SetString("0.5")
+	assert.Equal(t, int64(1), NewMoney(1, "USD").Convert(half, "CNY").Cents)
+	assert.Equal(t, int64(-1), NewMoney(-1, "USD").Convert(half, "CNY").Cents)
+
+	assert.True(t, IsValidCurrency("USD"))
+	assert.False(t, IsValidCurrency("usd"))
+	assert.False(t, IsValidCurrency("US"))
+}
+
+func TestProgressPercent(t *testing.T) {
+	assert.Equal(t, 33.33, ProgressPercent(NewMoney(10000, "CNY"), NewMoney(30000, "CNY")))
+	assert.Equal(t, 150.0, ProgressPercent(NewMoney(15000, "CNY"), NewMoney(10000, "CNY")))
+	assert.Equal(t, 0.0, ProgressPercent(NewMoney(15000, "CNY"), NewMoney(0, "CNY")))
+}
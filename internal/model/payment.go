@@ -3,19 +3,23 @@ package model
 import "time"
 
 type Payment struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	ProjectID int       `json:"project_id"`
-	Amount    float64   `json:"amount"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           int        `json:"id"`
+	UserID       int        `json:"user_id"`
+	ProjectID    int        `json:"project_id"`
+	RewardTierID *int       `json:"reward_tier_id,omitempty"` // 选择的回报档位，为空表示无偿支持
+	Items        []CartItem `json:"items,omitempty"`          // 加购项
+	BonusAmount  Money      `json:"bonus_amount"`             // 档位和加购之外的额外支持金额，项目币种
+	Currency     string     `json:"currency"`                 // 支付币种，为空时使用项目币种
+	Amount       Money      `json:"amount"`                   // 服务端按目录价格计算的支付币种金额
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 type Refund struct {
 	ID        int       `json:"id"`
 	PaymentID int       `json:"payment_id"`
-	Amount    float64   `json:"amount"`
+	Amount    Money     `json:"amount"`
 	Reason    string    `json:"reason"`
 	Status    string    `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
@@ -24,19 +28,59 @@ type Refund struct {
 
 // Order 订单模型
 type Order struct {
-	ID          int          `json:"id"`
-	OrderNumber string       `json:"order_number"`
-	UserID      int          `json:"user_id"`
-	ProjectID   int          `json:"project_id"`
-	PledgeID    int          `json:"pledge_id"`
-	Amount      float64      `json:"amount"`
-	Status      string       `json:"status"`
-	IsReward    bool         `json:"is_reward"`
-	AddressID   *int         `json:"address_id,omitempty"`
-	Address     *UserAddress `json:"address,omitempty"`
-	Shipment    *Shipment    `json:"shipment,omitempty"`
-	CreatedAt   time.Time    `json:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at"`
+	ID               int          `json:"id"`
+	OrderNumber      string       `json:"order_number"`
+	UserID           int          `json:"user_id"`
+	ProjectID        int          `json:"project_id"`
+	PledgeID         int          `json:"pledge_id"`
+	Subtotal         Money        `json:"subtotal"`          // 档位、加购和额外支持金额合计，项目币种
+	ShippingFee      Money        `json:"shipping_fee"`      // 运费，项目币种
+	Amount           Money        `json:"amount"`            // 订单总额 = Subtotal + ShippingFee，项目币种
+	Currency         string       `json:"currency"`          // 项目币种
+	OriginalAmount   Money        `json:"original_amount"`   // 实际扣款金额
+	OriginalCurrency string       `json:"original_currency"` // 实际扣款币种
+	ExchangeRate     string       `json:"exchange_rate"`     // 下单时使用的汇率，1 单位扣款币种兑换的项目币种数量
+	Status           string       `json:"status"`
+	IsReward         bool         `json:"is_reward"`
+	RewardTierID     *int         `json:"reward_tier_id,omitempty"` // 选择的回报档位
+	RewardTitle      string       `json:"reward_title,omitempty"`
+	AddressID        *int         `json:"address_id,omitempty"`
+	Address          *UserAddress `json:"address,omitempty"`
+	Shipment         *Shipment    `json:"shipment,omitempty"`
+	Items            []OrderItem  `json:"items,omitempty"`             // 订单明细，各项小计之和等于 Subtotal
+	PaymentProvider  string       `json:"payment_provider,omitempty"`  // 支付渠道
+	PaymentIntentID  string       `json:"payment_intent_id,omitempty"` // 支付渠道侧的支付意图ID
+	PaidAt           *time.Time   `json:"paid_at,omitempty"`           // 扣款成功时间
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+// ApplyCurrency 将币种字段同步到订单金额
+func (o *Order) ApplyCurrency() {
+	o.Subtotal.Currency = o.Currency
+	o.ShippingFee.Currency = o.Currency
+	o.Amount.Currency = o.Currency
+	o.OriginalAmount.Currency = o.OriginalCurrency
+}
+
+// 订单明细类型
+const (
+	OrderItemTypeReward = "reward"
+	OrderItemTypeAddOn  = "add_on"
+	OrderItemTypeBonus  = "bonus"
+)
+
+// OrderItem 订单明细，下单时按目录价格记录，金额为项目币种
+type OrderItem struct {
+	ID           int    `json:"id"`
+	OrderID      int    `json:"order_id"`
+	ItemType     string `json:"item_type"`
+	RewardTierID *int   `json:"reward_tier_id,omitempty"`
+	AddOnID      *int   `json:"add_on_id,omitempty"`
+	Title        string `json:"title"`
+	UnitPrice    Money  `json:"unit_price"`
+	Quantity     int    `json:"quantity"`
+	Subtotal     Money  `json:"subtotal"`
 }
 
 // RefundRequest 退款申请模型
@@ -70,3 +114,137 @@ type Shipment struct {
 	UpdatedAt           time.Time    `json:"updated_at"`
 	Address             *UserAddress `json:"address,omitempty"`
 }
+
+// PaymentWebhookEvent 已处理的支付渠道回调事件，用于按渠道事件ID去重
+type PaymentWebhookEvent struct {
+	ID                  int       `json:"id"`
+	Provider            string    `json:"provider"`
+	EventID             string    `json:"event_id"`
+	EventType           string    `json:"event_type"`
+	OrderID             *int      `json:"order_id,omitempty"`
+	Payload             string    `json:"payload"`
+	NeedsReconciliation bool      `json:"needs_reconciliation"` // 渠道扣款成功但订单未入账，需人工对账退款
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// OrderStatusHistory 订单状态流转记录
+type OrderStatusHistory struct {
+	ID         int       `json:"id"`
+	OrderID    int       `json:"order_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Actor      string    `json:"actor"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ProjectPayout 项目众筹成功后给创作者的结算记录
+// 应付金额 NetAmount = GrossAmount - RefundAmount - FeeAmount
+type ProjectPayout struct {
+	ID               int        `json:"id"`
+	ProjectID        int        `json:"project_id"`
+	CreatorID        int        `json:"creator_id"`
+	GrossAmount      Money      `json:"gross_amount"`  // 已结算订单总额
+	RefundAmount     Money      `json:"refund_amount"` // 已退款订单金额
+	FeePercent       float64    `json:"fee_percent"`   // 平台服务费比例
+	FeeAmount        Money      `json:"fee_amount"`    // 平台服务费
+	NetAmount        Money      `json:"net_amount"`    // 应付创作者金额
+	Currency         string     `json:"currency"`      // 项目币种
+	Status           string     `json:"status"`
+	ApprovedBy       *int       `json:"approved_by,omitempty"`
+	ApprovedAt       *time.Time `json:"approved_at,omitempty"`
+	PaidBy           *int       `json:"paid_by,omitempty"`
+	PaidAt           *time.Time `json:"paid_at,omitempty"`
+	PaymentReference string     `json:"payment_reference,omitempty"` // 打款流水号
+	ProjectTitle     string     `json:"project_title,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// OrderRefund 通过支付渠道执行的订单退款
+type OrderRefund struct {
+	ID              int        `json:"id"`
+	OrderID         int        `json:"order_id"`
+	ProjectID       int        `json:"project_id"`
+	RefundRequestID *int       `json:"refund_request_id,omitempty"`
+	OrderChangeID   *int       `json:"order_change_id,omitempty"` // 订单变更产生的部分退款，成功后不改变订单状态
+	Provider        string     `json:"provider"`
+	PaymentIntentID string     `json:"payment_intent_id"`
+	Amount          Money      `json:"amount"`         // 退款金额，与扣款币种一致
+	Currency        string     `json:"currency"`       // 退款币种
+	ProjectAmount   Money      `json:"project_amount"` // 折算为项目币种的退款金额
+	ProjectCurrency string     `json:"project_currency"`
+	Status          string     `json:"status"`
+	Attempts        int        `json:"attempts"`
+	LastError       string     `json:"last_error,omitempty"`
+	GatewayRefundID string     `json:"gateway_refund_id,omitempty"`
+	NextAttemptAt   *time.Time `json:"next_attempt_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// 订单变更类型
+const (
+	OrderChangeModify = "modify"
+	OrderChangeCancel = "cancel"
+)
+
+// 订单变更状态。有补扣款的修改先记录为待确认，扣款成功并应用到订单后变为已生效
+const (
+	OrderChangeStatusPending = "pending"
+	OrderChangeStatusApplied = "applied"
+	OrderChangeStatusFailed  = "failed"
+)
+
+// OrderChange 众筹期间支持者对订单的一次修改或取消
+type OrderChange struct {
+	ID              int       `json:"id"`
+	OrderID         int       `json:"order_id"`
+	ChangeType      string    `json:"change_type"`
+	Status          string    `json:"status"`
+	Actor           string    `json:"actor"`
+	OldRewardTierID *int      `json:"old_reward_tier_id,omitempty"`
+	NewRewardTierID *int      `json:"new_reward_tier_id,omitempty"`
+	OldAmount       Money     `json:"old_amount"`                  // 项目币种
+	NewAmount       Money     `json:"new_amount"`                  // 项目币种，取消时为0
+	AmountDelta     Money     `json:"amount_delta"`                // 项目币种差额，正数为补扣款，负数为退款
+	OriginalDelta   Money     `json:"original_delta"`              // 扣款币种差额，按下单汇率折算
+	PaymentIntentID string    `json:"payment_intent_id,omitempty"` // 补扣款的支付意图
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// OrderPaymentIntent 订单的一笔扣款及其已发起的退款金额，均为扣款币种
+type OrderPaymentIntent struct {
+	Provider string
+	IntentID string
+	Captured Money
+	Refunded Money
+}
+
+// Refundable 该笔扣款剩余可退金额
+func (p OrderPaymentIntent) Refundable() Money {
+	return p.Captured.Sub(p.Refunded)
+}
+
+// ProjectRefundReport 项目退款执行情况汇总
+type ProjectRefundReport struct {
+	ProjectID      int                  `json:"project_id"`
+	Currency       string               `json:"currency"` // 汇总金额均以项目币种计
+	TotalRefunds   int                  `json:"total_refunds"`
+	SucceededCount int                  `json:"succeeded_count"`
+	PendingCount   int                  `json:"pending_count"`
+	FailedCount    int                  `json:"failed_count"`
+	TotalAmount    Money                `json:"total_amount"`
+	RefundedAmount Money                `json:"refunded_amount"`
+	PendingAmount  Money                `json:"pending_amount"`
+	FailedAmount   Money                `json:"failed_amount"`
+	Refunds        []*OrderRefund       `json:"refunds"`
+	Errors         []ProjectRefundError `json:"errors,omitempty"` // 本次自动退款中处理失败的订单
+}
+
+// ProjectRefundError 项目自动退款中单个订单的处理错误
+type ProjectRefundError struct {
+	OrderID int    `json:"order_id"`
+	Error   string `json:"error"`
+}
@@ -10,10 +10,11 @@ type Project struct {
 	Description     string         `json:"description"`
 	CreatorID       int            `json:"creator_id"`
 	Status          string         `json:"status"`
-	TotalAmount     float64        `json:"total_amount"`      // 已筹集金额
-	TotalGoalAmount float64        `json:"total_goal_amount"` // 所有目标金额之和
+	TotalAmount     Money          `json:"total_amount"`      // 已筹集金额
+	TotalGoalAmount Money          `json:"total_goal_amount"` // 所有目标金额之和
 	Progress        float64        `json:"progress"`          // 筹款进度（百分比）
-	MinRewardAmount float64        `json:"min_reward_amount"` // 最低有奖支持金额
+	MinRewardAmount Money          `json:"min_reward_amount"` // 最低有奖支持金额
+	Currency        string         `json:"currency"`          // 项目币种，金额字段均以此币种计
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	EndDate         time.Time      `json:"end_date"`
@@ -25,10 +26,21 @@ type Project struct {
 	Creator         *User          `json:"creator,omitempty"`
 }
 
+// SetCurrency 设置项目币种，并同步到项目的各项金额
+func (p *Project) SetCurrency(currency string) {
+	p.Currency = currency
+	p.TotalAmount.Currency = currency
+	p.TotalGoalAmount.Currency = currency
+	p.MinRewardAmount.Currency = currency
+	for i := range p.Goals {
+		p.Goals[i].Amount.Currency = currency
+	}
+}
+
 type ProjectGoal struct {
 	ID          int            `json:"id"`
 	ProjectID   int            `json:"project_id"`
-	Amount      float64        `json:"amount"`
+	Amount      Money          `json:"amount"`
 	Description string         `json:"description"`
 	IsReached   bool           `json:"is_reached"`
 	Progress    float64        `json:"progress"` // 该目标的达成进度
@@ -45,14 +57,24 @@ type ProjectImage struct {
 }
 
 type Pledge struct {
-	ID        int          `json:"id"`
-	UserID    int          `json:"user_id"`
-	ProjectID int          `json:"project_id"`
-	Amount    float64      `json:"amount"`
-	Status    string       `json:"status"`
-	AddressID *int         `json:"address_id,omitempty"`
-	Address   *UserAddress `json:"address,omitempty"`
-	CreatedAt time.Time    `json:"created_at"`
+	ID               int          `json:"id"`
+	UserID           int          `json:"user_id"`
+	ProjectID        int          `json:"project_id"`
+	Amount           Money        `json:"amount"`            // 折算为项目币种的金额
+	Currency         string       `json:"currency"`          // 项目币种
+	OriginalAmount   Money        `json:"original_amount"`   // 支付币种原始金额
+	OriginalCurrency string       `json:"original_currency"` // 支付币种
+	Status           string       `json:"status"`
+	RewardTierID     *int         `json:"reward_tier_id,omitempty"` // 选择的回报档位
+	AddressID        *int         `json:"address_id,omitempty"`
+	Address          *UserAddress `json:"address,omitempty"`
+	CreatedAt        time.Time    `json:"created_at"`
+}
+
+// ApplyCurrency 将币种字段同步到支持金额
+func (p *Pledge) ApplyCurrency() {
+	p.Amount.Currency = p.Currency
+	p.OriginalAmount.Currency = p.OriginalCurrency
 }
 
 type ProjectCategory struct {
@@ -88,9 +110,33 @@ type ProjectFilters struct {
 	Keyword   string    `json:"keyword"`
 	Category  int       `json:"category"`
 	Status    string    `json:"status"`
-	MinAmount float64   `json:"min_amount"`
-	MaxAmount float64   `json:"max_amount"`
+	MinAmount Money     `json:"min_amount"`
+	MaxAmount Money     `json:"max_amount"`
 	StartDate time.Time `json:"start_date"`
 	EndDate   time.Time `json:"end_date"`
 	Tags      []int     `json:"tags"`
 }
+
+// 项目审核结论
+const (
+	ProjectReviewApproved         = "approved"          // 通过，项目上线
+	ProjectReviewChangesRequested = "changes_requested" // 需要修改，项目退回草稿
+	ProjectReviewRejected         = "rejected"          // 驳回
+)
+
+// ProjectReview 项目审核记录，创建者根据意见修改后重新提交
+type ProjectReview struct {
+	ID            int                   `json:"id"`
+	ProjectID     int                   `json:"project_id"`
+	ReviewerID    int                   `json:"reviewer_id"`
+	Decision      string                `json:"decision"`
+	Comment       string                `json:"comment"`
+	FieldComments []ProjectFieldComment `json:"field_comments"`
+	CreatedAt     time.Time             `json:"created_at"`
+}
+
+// ProjectFieldComment 针对项目某个字段的审核意见，Field 如 title、description、goals
+type ProjectFieldComment struct {
+	Field   string `json:"field"`
+	Comment string `json:"comment"`
+}
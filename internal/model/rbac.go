@@ -0,0 +1,84 @@
+package model
+
+import "time"
+
+// 权限标识，格式为 资源.操作。权限固定在代码中，角色拥有哪些权限保存在数据库
+const (
+	PermissionAll                = "*" // 管理员拥有全部权限
+	PermissionProjectRead        = "project.read"
+	PermissionProjectReview      = "project.review"
+	PermissionProjectManage      = "project.manage"
+	PermissionUserRead           = "user.read"
+	PermissionUserManage         = "user.manage"
+	PermissionRoleManage         = "role.manage"
+	PermissionOrderRead          = "order.read"
+	PermissionRefundApprove      = "refund.approve"
+	PermissionShipmentManage     = "shipment.manage"
+	PermissionPayoutRead         = "payout.read"
+	PermissionPayoutApprove      = "payout.approve"
+	PermissionExchangeRateRead   = "exchange_rate.read"
+	PermissionExchangeRateManage = "exchange_rate.manage"
+	PermissionCommunityModerate  = "community.moderate"
+	PermissionStatsRead          = "stats.read"
+	PermissionAuditRead          = "audit.read"
+)
+
+// AllPermissions 可以授予角色的全部权限
+var AllPermissions = []string{
+	PermissionProjectRead,
+	PermissionProjectReview,
+	PermissionProjectManage,
+	PermissionUserRead,
+	PermissionUserManage,
+	PermissionRoleManage,
+	PermissionOrderRead,
+	PermissionRefundApprove,
+	PermissionShipmentManage,
+	PermissionPayoutRead,
+	PermissionPayoutApprove,
+	PermissionExchangeRateRead,
+	PermissionExchangeRateManage,
+	PermissionCommunityModerate,
+	PermissionStatsRead,
+	PermissionAuditRead,
+}
+
+// IsValidPermission 判断是否为系统定义的权限
+func IsValidPermission(permission string) bool {
+	if permission == PermissionAll {
+		return true
+	}
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// 内置角色
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator" // 社区管理
+	RoleReviewer  = "reviewer"  // 项目审核
+	RoleFinance   = "finance"   // 退款和结算
+	RoleSupport   = "support"   // 客服，只读订单
+)
+
+// users.role 由角色分配推导：拥有 admin 角色为 admin，拥有其他角色为 staff，否则为 user
+const (
+	UserRoleUser  = "user"
+	UserRoleStaff = "staff"
+	UserRoleAdmin = "admin"
+)
+
+// Role 角色及其拥有的权限
+type Role struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsSystem    bool      `json:"is_system"` // 内置角色不能修改或删除
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// RewardTierDeliveryLayout 预计发货月份格式
+const RewardTierDeliveryLayout = "2006-01"
+
+// RewardTier 项目回报档位，支持者选择档位下单，限量档位在支付事务中占用库存
+type RewardTier struct {
+	ID                int       `json:"id"`
+	ProjectID         int       `json:"project_id"`
+	Title             string    `json:"title"`
+	Description       string    `json:"description"`
+	Price             Money     `json:"price"` // 档位最低支持金额，项目币种
+	ImageURL          string    `json:"image_url,omitempty"`
+	QuantityLimit     *int      `json:"quantity_limit,omitempty"` // 为空表示不限量
+	QuantityClaimed   int       `json:"quantity_claimed"`         // 已被待支付和已支付订单占用的数量
+	Remaining         *int      `json:"remaining,omitempty"`      // 剩余数量，不限量时为空
+	EstimatedDelivery string    `json:"estimated_delivery"`       // 预计发货月份，格式 YYYY-MM
+	ShippingRequired  bool      `json:"shipping_required"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// ApplyRemaining 根据限量和已占用数量计算剩余数量
+func (t *RewardTier) ApplyRemaining() {
+	t.Remaining = remainingQuantity(t.QuantityLimit, t.QuantityClaimed)
+}
+
+// SoldOut 限量档位已无剩余
+func (t *RewardTier) SoldOut() bool {
+	return t.QuantityLimit != nil && t.QuantityClaimed >= *t.QuantityLimit
+}
+
+// RewardAddOn 项目加购项，需搭配回报档位购买，限量加购项在支付事务中按数量占用库存
+type RewardAddOn struct {
+	ID              int       `json:"id"`
+	ProjectID       int       `json:"project_id"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	Price           Money     `json:"price"` // 单价，项目币种
+	ImageURL        string    `json:"image_url,omitempty"`
+	QuantityLimit   *int      `json:"quantity_limit,omitempty"` // 为空表示不限量
+	QuantityClaimed int       `json:"quantity_claimed"`
+	Remaining       *int      `json:"remaining,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ApplyRemaining 根据限量和已占用数量计算剩余数量
+func (a *RewardAddOn) ApplyRemaining() {
+	a.Remaining = remainingQuantity(a.QuantityLimit, a.QuantityClaimed)
+}
+
+// remainingQuantity 不限量时返回 nil
+func remainingQuantity(limit *int, claimed int) *int {
+	if limit == nil {
+		return nil
+	}
+	remaining := *limit - claimed
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
+}
+
+// CartItem 支持时选择的加购项及数量
+type CartItem struct {
+	AddOnID  int `json:"add_on_id"`
+	Quantity int `json:"quantity"`
+}
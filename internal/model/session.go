@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// UserSession 登录会话，访问令牌中的 jti 即会话ID
+type UserSession struct {
+	ID               string     `json:"id"`
+	UserID           int        `json:"user_id"`
+	RefreshTokenHash string     `json:"-"`
+	PreviousHash     string     `json:"-"` // 上一次轮换前的刷新令牌哈希，用于识别已轮换令牌的重复使用
+	Device           string     `json:"device"`
+	IPAddress        string     `json:"ip_address"`
+	MFAVerifiedAt    *time.Time `json:"mfa_verified_at,omitempty"` // 会话通过两步验证的时间
+	CreatedAt        time.Time  `json:"created_at"`
+	LastSeenAt       time.Time  `json:"last_seen_at"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason     string     `json:"revoke_reason,omitempty"`
+	Current          bool       `json:"current"` // 是否为发起请求的会话，不入库
+}
+
+// IsActive 会话未撤销且未过期
+func (s *UserSession) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// TokenPair 登录或刷新后签发的令牌
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // 访问令牌有效期（秒）
+	SessionID    string `json:"session_id"`
+}
@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// DefaultCountry 地址未填写国家时使用的国家代码
+const DefaultCountry = "CN"
+
+// IsValidCountryCode 判断是否为 ISO 3166-1 两位大写字母国家代码
+func IsValidCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// ShippingZone 项目配送区域，由创作者按国家或省份划分，并为需要发货的回报档位设置运费
+type ShippingZone struct {
+	ID        int              `json:"id"`
+	ProjectID int              `json:"project_id"`
+	Name      string           `json:"name"`
+	Regions   []ShippingRegion `json:"regions"`
+	Rates     []ShippingRate   `json:"rates"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// ShippingRegion 配送区域包含的地区，Province 为空表示整个国家
+type ShippingRegion struct {
+	Country  string `json:"country"`
+	Province string `json:"province,omitempty"`
+}
+
+// ShippingRate 回报档位在配送区域内的运费，项目币种
+type ShippingRate struct {
+	RewardTierID int   `json:"reward_tier_id"`
+	Fee          Money `json:"fee"`
+}
+
+// FeeFor 获取档位在该区域的运费，未设置运费的档位不能配送到该区域
+func (z *ShippingZone) FeeFor(tierID int) (Money, bool) {
+	for _, rate := range z.Rates {
+		if rate.RewardTierID == tierID {
+			return rate.Fee, true
+		}
+	}
+	return Money{}, false
+}
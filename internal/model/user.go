@@ -2,6 +2,14 @@ package model
 
 import "time"
 
+// 账户状态
+const (
+	UserStatusUnverified = "unverified" // 已注册，邮箱未验证
+	UserStatusActive     = "active"
+	UserStatusSuspended  = "suspended" // 被管理员停用，不能登录
+	UserStatusDeleted    = "deleted"   // 用户已注销，恢复期内可以恢复
+)
+
 // User 结构体表示用户模型
 type User struct {
 	ID           int        `json:"id"`
@@ -12,17 +20,30 @@ type User struct {
 	Bio          string     `json:"bio"`
 	Role         string     `json:"role"`
 	IsVerified   bool       `json:"is_verified"`
+	Status       string     `json:"status"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
+	SuspendedAt  *time.Time `json:"suspended_at,omitempty"`
 	DeletedAt    *time.Time `json:"deleted_at"`
 }
 
+// CanLogin 未停用且未注销的账户可以登录
+func (u *User) CanLogin() bool {
+	return u.Status != UserStatusSuspended && u.Status != UserStatusDeleted
+}
+
+// IsStaff 管理员和拥有运营角色的用户可以进入管理后台，必须开启两步验证
+func (u *User) IsStaff() bool {
+	return u.Role == UserRoleAdmin || u.Role == UserRoleStaff
+}
+
 // UserAddress 用户地址模型
 type UserAddress struct {
 	ID            int       `json:"id"`
 	UserID        int       `json:"user_id"`
 	ReceiverName  string    `json:"receiver_name"`
 	Phone         string    `json:"phone"`
+	Country       string    `json:"country"` // ISO 3166-1 两位国家代码，默认 CN
 	Province      string    `json:"province"`
 	City          string    `json:"city"`
 	District      string    `json:"district"`
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// 验证令牌类型，与 user_verifications.type 枚举一致
+const (
+	VerificationTypeEmail         = "email"
+	VerificationTypePasswordReset = "password_reset"
+)
+
+// UserVerification 邮箱验证或密码重置令牌，数据库中只保存令牌的哈希
+type UserVerification struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	TokenHash string     `json:"-"`
+	Type      string     `json:"type"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsUsable 令牌未使用、未撤销且未过期
+func (v *UserVerification) IsUsable(now time.Time) bool {
+	return v.UsedAt == nil && v.RevokedAt == nil && now.Before(v.ExpiresAt)
+}
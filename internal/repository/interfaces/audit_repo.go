@@ -0,0 +1,8 @@
+package interfaces
+
+import "crowdfunding-backend/internal/model"
+
+// AuditRepository 审计日志查询。审计记录由各个管理操作在自身事务中写入，这里不提供修改和删除
+type AuditRepository interface {
+	ListAuditLogs(filter model.AuditFilter, page, pageSize int) ([]*model.AuditEntry, int, error)
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"crowdfunding-backend/internal/model"
+	"time"
+)
+
+type AuthThrottleRepository interface {
+	GetThrottle(scope, key string) (*model.AuthThrottle, error)
+	IncrementFailures(scope, key string, windowStart time.Time) (int, error)
+	UpdateThrottle(scope, key string, nextAttemptAt, lockedUntil *time.Time, unlockTokenHash string) error
+	ResetThrottle(scope, key string) error
+	ResetThrottleByUnlockToken(tokenHash string) (string, error)
+	CreateLoginAudit(entry *model.LoginAuditEntry) error
+}
@@ -7,11 +7,11 @@ type CommunityRepository interface {
 	CreatePost(post *model.Post, images []string) error
 	GetPostByID(id int) (*model.Post, error)
 	UpdatePost(post *model.Post) error
-	DeletePost(id int) error
+	DeletePost(id int, audit *model.AuditEntry) error
 	ListPosts(page, pageSize int) ([]*model.Post, int, error)
 	CreateComment(comment *model.Comment) error
 	GetCommentsByPostID(postID, page, pageSize int) ([]*model.Comment, error)
-	DeleteComment(id int) error
+	DeleteComment(id int, audit *model.AuditEntry) error
 	CreateLike(like *model.Like) error
 	DeleteLike(userID, postID int) error
 	GetLikeCount(postID int) (int, error)
@@ -30,4 +30,7 @@ type CommunityRepository interface {
 	GetFollowersPosts(userID int, page, pageSize int) ([]*model.Post, int, error)
 	GetCommentByID(id int) (*model.Comment, error)
 	GetUserByID(id int) (*model.User, error)
+	GetCommentsByUser(userID int) ([]*model.Comment, error)
+	GetLikesByUser(userID int) ([]*model.Like, error)
+	GetFollowsByUser(userID int) ([]*model.Follow, error)
 }
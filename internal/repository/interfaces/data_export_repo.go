@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"crowdfunding-backend/internal/model"
+	"time"
+)
+
+type DataExportRepository interface {
+	CreateExport(export *model.DataExport) error
+	GetPendingExportByUser(userID int) (*model.DataExport, error)
+	CountExportsSince(userID int, since time.Time) (int, error)
+	GetExportByToken(tokenHash string) (*model.DataExport, error)
+	MarkExportReady(exportID int, filePath, tokenHash string, expiresAt time.Time) error
+	MarkExportFailed(exportID int, message string) error
+	GetExpiredExports(limit int) ([]*model.DataExport, error)
+	MarkExportExpired(exportID int) error
+}
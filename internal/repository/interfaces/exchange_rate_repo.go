@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"crowdfunding-backend/internal/model"
+	"time"
+)
+
+type ExchangeRateRepository interface {
+	CreateExchangeRate(rate *model.ExchangeRate, audit *model.AuditEntry) error
+	GetEffectiveRate(base, quote string, at time.Time) (*model.ExchangeRate, error)
+	ListExchangeRates(base, quote string, page, pageSize int) ([]*model.ExchangeRate, int, error)
+}
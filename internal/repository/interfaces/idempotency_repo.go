@@ -0,0 +1,11 @@
+package interfaces
+
+import "crowdfunding-backend/internal/model"
+
+type IdempotencyRepository interface {
+	CreateIdempotencyRecord(record *model.IdempotencyRecord) (bool, error)
+	GetIdempotencyRecord(userID int, key string) (*model.IdempotencyRecord, error)
+	CompleteIdempotencyRecord(id, status int, contentType string, body []byte) error
+	DeleteIdempotencyRecord(id int) error
+	DeleteExpiredIdempotencyRecords() (int64, error)
+}
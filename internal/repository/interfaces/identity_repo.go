@@ -0,0 +1,16 @@
+package interfaces
+
+import "crowdfunding-backend/internal/model"
+
+// IdentityRepository 第三方登录身份和 OIDC 授权 state 的存取
+type IdentityRepository interface {
+	CreateLoginState(state *model.OIDCLoginState) error
+	ConsumeLoginState(stateHash string) (*model.OIDCLoginState, error)
+	DeleteExpiredLoginStates() (int64, error)
+	GetIdentity(provider, subject string) (*model.UserIdentity, error)
+	ListIdentities(userID int) ([]*model.UserIdentity, error)
+	CreateIdentity(identity *model.UserIdentity) (bool, error)
+	CreateUserWithIdentity(user *model.User, identity *model.UserIdentity) error
+	TouchIdentity(identityID int) error
+	DeleteIdentity(userID int, provider string) (bool, error)
+}
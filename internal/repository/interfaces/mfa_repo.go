@@ -0,0 +1,14 @@
+package interfaces
+
+import "crowdfunding-backend/internal/model"
+
+type MFARepository interface {
+	GetMFA(userID int) (*model.UserMFA, error)
+	SavePendingMFA(userID int, secret string) error
+	EnableMFA(userID int, step int64, recoveryCodeHashes []string) (bool, error)
+	UseTOTPStep(userID int, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID int, recoveryCodeHashes []string) error
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(userID int) (int, error)
+	DeleteMFA(userID int) error
+}
@@ -1,25 +1,71 @@
 package interfaces
 
-import "crowdfunding-backend/internal/model"
+import (
+	"crowdfunding-backend/internal/model"
+	"database/sql"
+	"time"
+)
 
 type PaymentRepository interface {
 	CreatePayment(payment *model.Payment) error
 	CreateOrder(order *model.Order) error
-	UpdateOrderStatus(orderID int, status string) error
+	CreateOrderItemsTx(tx *sql.Tx, orderID int, items []model.OrderItem) error
+	TransitionOrderStatus(orderID int, from, to, actor, reason string) (bool, error)
+	TransitionOrderStatusTx(tx *sql.Tx, orderID int, from, to, actor, reason string) (bool, error)
+	GetOrderStatusHistory(orderID int) ([]*model.OrderStatusHistory, error)
+	UpdateOrderPaymentIntent(orderID int, provider, intentID string) error
+	ConfirmOrderPayment(orderID int, actor string) (bool, error)
+	MarkOrderPaymentFailed(orderID int, actor, reason string) (bool, error)
+	GetOrderByPaymentIntent(provider, intentID string) (*model.Order, error)
+	WebhookEventExists(provider, eventID string) (bool, error)
+	CreateWebhookEvent(event *model.PaymentWebhookEvent) (bool, error)
 	GetOrderByID(id int) (*model.Order, error)
 	GetOrdersByUser(userID int) ([]*model.Order, error)
 	GetOrdersByProject(projectID int) ([]*model.Order, error)
 	CreateRefundRequest(request *model.RefundRequest) error
-	UpdateRefundRequest(request *model.RefundRequest) error
+	CreateRefundRequestTx(tx *sql.Tx, request *model.RefundRequest) error
+	UpdateRefundRequestTx(tx *sql.Tx, request *model.RefundRequest, audit *model.AuditEntry) (bool, error)
 	GetRefundRequestsByUser(userID int) ([]*model.RefundRequest, error)
 	GetPendingRefundRequests() ([]*model.RefundRequest, error)
 	CreatePledge(pledge *model.Pledge) error
 	GetShipmentByOrderID(orderID int) (*model.Shipment, error)
-	UpdateOrderAfterShipment(orderID int, shipmentID int) error
+	MarkShipmentShippedTx(tx *sql.Tx, shipmentID int) error
 	GetRefundRequestByID(requestID int) (*model.RefundRequest, error)
 	CheckProjectGoalStatus(projectID int) (bool, error)
-	UpdateOrdersToFailedByProject(projectID int) error
+	GetProjectPayoutAmounts(projectID int) (gross, refunded model.Money, err error)
+	CreateProjectPayout(payout *model.ProjectPayout) (bool, error)
+	GetProjectPayout(projectID int) (*model.ProjectPayout, error)
+	GetPayoutByID(payoutID int) (*model.ProjectPayout, error)
+	GetPayoutsByProject(projectID int) ([]*model.ProjectPayout, error)
+	GetPayouts(page, pageSize int, status string) ([]*model.ProjectPayout, int, error)
+	ApprovePayout(payout *model.ProjectPayout, adminID int, audit *model.AuditEntry) (bool, error)
+	MarkPayoutPaid(payoutID, adminID int, reference string, audit *model.AuditEntry) (bool, error)
+	CreateOrderRefund(refund *model.OrderRefund) error
+	CreateOrderRefundTx(tx *sql.Tx, refund *model.OrderRefund) error
+	ClaimOrderRefund(refundID, attempts int, leaseUntil time.Time) (bool, error)
+	UpdateOrderRefund(refund *model.OrderRefund) error
+	GetOrderRefundByOrder(orderID int) (*model.OrderRefund, error)
+	GetDueOrderRefunds(limit int) ([]*model.OrderRefund, error)
+	GetFailedProjectsAwaitingRefund(limit int) ([]int, error)
+	GetOrderRefundsByProject(projectID int) ([]*model.OrderRefund, error)
 	CheckProjectEndDate(projectID int) (bool, error)
 	GetRefundStatus(orderID int) (*model.RefundRequest, error)
 	GetAllRefundRequests(page, pageSize int) ([]*model.RefundRequest, int, error)
+	GetPledgesByUser(userID int) ([]*model.Pledge, error)
+	GetShipmentsByUser(userID int) ([]*model.Shipment, error)
+	LockOrderTx(tx *sql.Tx, orderID int) (*model.Order, error)
+	ReleaseOrderStockTx(tx *sql.Tx, orderID int) error
+	ReplaceOrderItemsTx(tx *sql.Tx, orderID int, items []model.OrderItem) error
+	UpdateOrderSelectionTx(tx *sql.Tx, order *model.Order) error
+	CancelOrderTx(tx *sql.Tx, orderID int, actor, reason string) (bool, error)
+	AddProjectAmountTx(tx *sql.Tx, projectID int, amount model.Money) error
+	CreateOrderChange(change *model.OrderChange) error
+	CreateOrderChangeTx(tx *sql.Tx, change *model.OrderChange) error
+	UpdateOrderChangeIntent(changeID int, intentID string) error
+	UpdateOrderChangeStatus(changeID int, status string) (bool, error)
+	UpdateOrderChangeStatusTx(tx *sql.Tx, changeID int, status string) (bool, error)
+	GetStalePendingOrderChanges(before time.Time, limit int) ([]*model.OrderChange, error)
+	GetOrderChanges(orderID int) ([]*model.OrderChange, error)
+	GetOrderPaymentIntents(orderID int) ([]model.OrderPaymentIntent, error)
+	HasPendingOrderRefunds(orderID int) (bool, error)
 }
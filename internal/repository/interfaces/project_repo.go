@@ -12,13 +12,16 @@ type ProjectRepository interface {
 	UpdateProject(project *model.Project, goals []model.ProjectGoal) error
 	ListProjects(page, pageSize int) ([]model.Project, error)
 	CreatePledge(pledge *model.Pledge) error
-	UpdateProjectStatus(project *model.Project) error
+	TransitionProjectStatus(projectID int, from, to string, audit *model.AuditEntry) (bool, error)
+	ReviewProjectStatus(projectID int, from, to string, review *model.ProjectReview, audit *model.AuditEntry) (bool, error)
+	GetProjectReviews(projectID int) ([]*model.ProjectReview, error)
 	GetProjectGoals(projectID int) ([]model.ProjectGoal, error)
+	MarkReachedGoals(projectID int) (int, error)
 	GetProjectImages(projectID int) ([]model.ProjectImage, error)
 	SearchProjects(filters model.ProjectFilters, page, pageSize int) ([]model.Project, int, error)
-	CreateCategory(category *model.ProjectCategory) error
+	CreateCategory(category *model.ProjectCategory, audit *model.AuditEntry) error
 	GetCategories() ([]model.ProjectCategory, error)
-	CreateTag(tag *model.ProjectTag) error
+	CreateTag(tag *model.ProjectTag, audit *model.AuditEntry) error
 	GetTags() ([]model.ProjectTag, error)
 	AddTagToProject(projectID, tagID int) error
 	RemoveTagFromProject(projectID, tagID int) error
@@ -27,12 +30,13 @@ type ProjectRepository interface {
 	GetProjectUpdates(projectID int) ([]model.ProjectUpdate, error)
 	CreateProjectComment(comment *model.ProjectComment) error
 	GetProjectComments(projectID int, page, pageSize int) ([]model.ProjectComment, error)
-	CreateShipment(shipment *model.Shipment) error
-	UpdateShipment(shipment *model.Shipment) error
+	CreateShipmentTx(tx *sql.Tx, shipment *model.Shipment, audit *model.AuditEntry) error
+	UpdateShipment(shipment *model.Shipment, audit *model.AuditEntry) error
 	GetShipmentsByProject(projectID int) ([]*model.Shipment, error)
 	GetShipmentsByUser(userID int) ([]*model.Shipment, error)
 	GetProjectSuccessfulPledgers(projectID int) ([]*model.Pledge, error)
 	GetExpiredActiveProjects() ([]*model.Project, error)
+	GetUnsettledCompletedProjects() ([]*model.Project, error)
 	GetProjectsForAdmin(page, pageSize int, status, search string) ([]*model.Project, int, error)
-	DeleteProject(projectID int) error
+	DeleteProject(projectID int, audit *model.AuditEntry) error
 }
@@ -0,0 +1,21 @@
+package interfaces
+
+import (
+	"crowdfunding-backend/internal/model"
+	"database/sql"
+)
+
+type RewardRepository interface {
+	ListRewardTiers(projectID int) ([]*model.RewardTier, error)
+	GetRewardTier(id int) (*model.RewardTier, error)
+	CreateRewardTier(tier *model.RewardTier) error
+	UpdateRewardTier(tier *model.RewardTier) (bool, error)
+	DeleteRewardTier(id int) (bool, error)
+	ClaimRewardTierTx(tx *sql.Tx, tierID, projectID int) (bool, error)
+	ListRewardAddOns(projectID int) ([]*model.RewardAddOn, error)
+	GetRewardAddOn(id int) (*model.RewardAddOn, error)
+	CreateRewardAddOn(addOn *model.RewardAddOn) error
+	UpdateRewardAddOn(addOn *model.RewardAddOn) (bool, error)
+	DeleteRewardAddOn(id int) (bool, error)
+	ClaimRewardAddOnTx(tx *sql.Tx, addOnID, projectID, quantity int) (bool, error)
+}
@@ -0,0 +1,19 @@
+package interfaces
+
+import "crowdfunding-backend/internal/model"
+
+// RoleRepository 角色、角色权限和用户角色分配的存取
+type RoleRepository interface {
+	ListRoles() ([]*model.Role, error)
+	GetRoleByID(id int) (*model.Role, error)
+	GetRoleByName(name string) (*model.Role, error)
+	CreateRole(role *model.Role, audit *model.AuditEntry) (bool, error)
+	UpdateRole(role *model.Role, audit *model.AuditEntry) error
+	DeleteRole(id int, audit *model.AuditEntry) error
+	CountRoleMembers(roleID int) (int, error)
+	GetUserRoles(userID int) ([]*model.Role, error)
+	GetUserPermissions(userID int) ([]string, error)
+	AssignRole(userID, roleID, assignedBy int, audit *model.AuditEntry) (bool, error)
+	RevokeRole(userID, roleID int, audit *model.AuditEntry) (bool, error)
+	SetUserRoles(userID int, roleIDs []int, assignedBy int, audit *model.AuditEntry) error
+}
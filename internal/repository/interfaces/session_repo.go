@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"crowdfunding-backend/internal/model"
+	"time"
+)
+
+type SessionRepository interface {
+	CreateSession(session *model.UserSession) error
+	GetSession(sessionID string) (*model.UserSession, error)
+	GetActiveSessionsByUser(userID int) ([]*model.UserSession, error)
+	RotateRefreshToken(sessionID, oldHash, newHash string, expiresAt time.Time, ip string) (bool, error)
+	TouchSession(sessionID, ip string) error
+	MarkSessionMFAVerified(sessionID string) error
+	RevokeSession(sessionID, reason string) (bool, error)
+	RevokeUserSessions(userID int, reason string) (int64, error)
+	AdminRevokeUserSessions(userID int, reason string, audit *model.AuditEntry) (int64, error)
+	RevokeOtherSessions(userID int, keepSessionID, reason string) (int64, error)
+	DeleteExpiredSessions() (int64, error)
+}
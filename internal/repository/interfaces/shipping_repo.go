@@ -0,0 +1,11 @@
+package interfaces
+
+import "crowdfunding-backend/internal/model"
+
+type ShippingRepository interface {
+	ListShippingZones(projectID int) ([]*model.ShippingZone, error)
+	GetShippingZone(id int) (*model.ShippingZone, error)
+	CreateShippingZone(zone *model.ShippingZone) (bool, error)
+	UpdateShippingZone(zone *model.ShippingZone) (bool, error)
+	DeleteShippingZone(id int) error
+}
@@ -1,6 +1,9 @@
 package interfaces
 
-import "crowdfunding-backend/internal/model"
+import (
+	"crowdfunding-backend/internal/model"
+	"time"
+)
 
 // UserRepository 接口定义了用户仓库应该实现的方法
 type UserRepository interface {
@@ -18,4 +21,11 @@ type UserRepository interface {
 	GetAddressByID(id int) (*model.UserAddress, error)
 	ListUserAddresses(userID int) ([]*model.UserAddress, error)
 	SetDefaultAddress(userID, addressID int) error
+	GetUserStatus(id int) (string, error)
+	SuspendUser(id int, reason string, audit *model.AuditEntry) (bool, error)
+	UnsuspendUser(id int, audit *model.AuditEntry) (bool, error)
+	SoftDeleteUser(id int) (bool, error)
+	RestoreUser(id int, deletedAfter time.Time) (bool, error)
+	FindUsersPendingPurge(deletedBefore time.Time, limit int) ([]int, error)
+	AnonymizeUser(id int) (bool, error)
 }
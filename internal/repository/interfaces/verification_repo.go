@@ -0,0 +1,12 @@
+package interfaces
+
+import "crowdfunding-backend/internal/model"
+
+type VerificationRepository interface {
+	CreateVerification(verification *model.UserVerification) error
+	GetVerification(tokenHash, verificationType string) (*model.UserVerification, error)
+	ConfirmEmail(verificationID, userID int) (bool, error)
+	ResetPassword(verificationID, userID int, passwordHash, revokeReason string) (bool, error)
+	UpdatePassword(userID int, passwordHash, keepSessionID, revokeReason string) error
+	DeleteExpiredVerifications() (int64, error)
+}
@@ -0,0 +1,121 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db}
+}
+
+const auditColumns = `id, actor_id, action, target_type, target_id, before_data, after_data, ip, request_id, created_at`
+
+func scanAuditEntry(scanner interface{ Scan(...interface{}) error }) (*model.AuditEntry, error) {
+	var entry model.AuditEntry
+	var before, after []byte
+	err := scanner.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.TargetType, &entry.TargetID,
+		&before, &after, &entry.IP, &entry.RequestID, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	entry.Before = before
+	entry.After = after
+	return &entry, nil
+}
+
+// insertAuditLog 在操作所在的事务中写入审计记录，entry 为 nil 时不记录（例如系统任务触发的操作）
+func insertAuditLog(tx *sql.Tx, entry *model.AuditEntry) error {
+	if entry == nil {
+		return nil
+	}
+	_, err := tx.Exec(`
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, before_data, after_data, ip, request_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ActorID, entry.Action, entry.TargetType, entry.TargetID,
+		nullableJSON(entry.Before), nullableJSON(entry.After), entry.IP, entry.RequestID)
+	if err != nil {
+		util.Logger.Error("写入审计日志失败",
+			zap.Error(err),
+			zap.String("action", entry.Action),
+			zap.Int("target_id", entry.TargetID))
+	}
+	return err
+}
+
+func nullableJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+// ListAuditLogs 按条件分页查询审计日志，按时间倒序
+func (r *AuditRepository) ListAuditLogs(filter model.AuditFilter, page, pageSize int) ([]*model.AuditEntry, int, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.ActorID > 0 {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID > 0 {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, filter.TargetID)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM audit_log `+where, args...).Scan(&total); err != nil {
+		util.Logger.Error("统计审计日志失败", zap.Error(err))
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT `+auditColumns+`
+		FROM audit_log
+		`+where+`
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		util.Logger.Error("查询审计日志失败", zap.Error(err))
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []*model.AuditEntry{}
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
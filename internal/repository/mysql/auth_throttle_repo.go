@@ -0,0 +1,129 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type AuthThrottleRepository struct {
+	db *sql.DB
+}
+
+func NewAuthThrottleRepository(db *sql.DB) *AuthThrottleRepository {
+	return &AuthThrottleRepository{db}
+}
+
+// GetThrottle 获取失败计数，不存在时返回 nil
+func (r *AuthThrottleRepository) GetThrottle(scope, key string) (*model.AuthThrottle, error) {
+	throttle := model.AuthThrottle{Scope: scope, Key: key}
+	var lastFailureAt, nextAttemptAt, lockedUntil sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT failures, last_failure_at, next_attempt_at, locked_until
+		FROM auth_throttles
+		WHERE scope = ? AND throttle_key = ?`, scope, key).Scan(
+		&throttle.Failures, &lastFailureAt, &nextAttemptAt, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("查询登录失败计数失败", zap.Error(err), zap.String("scope", scope))
+		return nil, err
+	}
+	if lastFailureAt.Valid {
+		throttle.LastFailureAt = &lastFailureAt.Time
+	}
+	if nextAttemptAt.Valid {
+		throttle.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if lockedUntil.Valid {
+		throttle.LockedUntil = &lockedUntil.Time
+	}
+	return &throttle, nil
+}
+
+// IncrementFailures 失败次数加一并返回最新次数，上次失败早于 windowStart 时重新计数
+func (r *AuthThrottleRepository) IncrementFailures(scope, key string, windowStart time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO auth_throttles (scope, throttle_key, failures, last_failure_at)
+		VALUES (?, ?, 1, NOW())
+		ON DUPLICATE KEY UPDATE
+			failures = IF(last_failure_at IS NULL OR last_failure_at < ?, 1, failures + 1),
+			last_failure_at = NOW()`,
+		scope, key, windowStart)
+	if err != nil {
+		return 0, err
+	}
+
+	var failures int
+	if err := tx.QueryRow(`
+		SELECT failures FROM auth_throttles
+		WHERE scope = ? AND throttle_key = ?`, scope, key).Scan(&failures); err != nil {
+		return 0, err
+	}
+	return failures, tx.Commit()
+}
+
+// UpdateThrottle 设置退避截止时间和锁定截止时间，unlockTokenHash 为空时保留原值
+func (r *AuthThrottleRepository) UpdateThrottle(scope, key string, nextAttemptAt, lockedUntil *time.Time, unlockTokenHash string) error {
+	_, err := r.db.Exec(`
+		UPDATE auth_throttles
+		SET next_attempt_at = ?, locked_until = ?, unlock_token_hash = COALESCE(NULLIF(?, ''), unlock_token_hash)
+		WHERE scope = ? AND throttle_key = ?`,
+		nextAttemptAt, lockedUntil, unlockTokenHash, scope, key)
+	return err
+}
+
+// ResetThrottle 清除失败计数
+func (r *AuthThrottleRepository) ResetThrottle(scope, key string) error {
+	_, err := r.db.Exec(`DELETE FROM auth_throttles WHERE scope = ? AND throttle_key = ?`, scope, key)
+	return err
+}
+
+// ResetThrottleByUnlockToken 通过解锁令牌清除账户锁定，返回被解锁的邮箱，令牌无效时返回空字符串
+func (r *AuthThrottleRepository) ResetThrottleByUnlockToken(tokenHash string) (string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var key string
+	err = tx.QueryRow(`
+		SELECT throttle_key FROM auth_throttles
+		WHERE scope = 'account' AND unlock_token_hash = ?
+		FOR UPDATE`, tokenHash).Scan(&key)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM auth_throttles WHERE scope = 'account' AND throttle_key = ?`, key); err != nil {
+		return "", err
+	}
+	return key, tx.Commit()
+}
+
+// CreateLoginAudit 写入登录审计记录
+func (r *AuthThrottleRepository) CreateLoginAudit(entry *model.LoginAuditEntry) error {
+	result, err := r.db.Exec(`
+		INSERT INTO login_audit_log (user_id, email, ip_address, user_agent, action, result)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.UserID, entry.Email, entry.IPAddress, entry.UserAgent, entry.Action, entry.Result)
+	if err != nil {
+		return err
+	}
+	entry.ID, err = result.LastInsertId()
+	return err
+}
@@ -133,15 +133,28 @@ func (r *communityRepository) UpdatePost(post *model.Post) error {
 	return nil
 }
 
-func (r *communityRepository) DeletePost(id int) error {
+// DeletePost 删除帖子，audit 不为 nil 时在同一事务中记录审计日志
+func (r *communityRepository) DeletePost(id int, audit *model.AuditEntry) error {
 	util.Logger.Info("开始删除帖子", zap.Int("post_id", id))
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `DELETE FROM posts WHERE id = ?`
-	_, err := r.db.Exec(query, id)
+	_, err = tx.Exec(query, id)
 	if err != nil {
 		util.Logger.Error("删除帖子失败", zap.Error(err), zap.Int("post_id", id))
 		return err
 	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
 	util.Logger.Info("帖子删除成功", zap.Int("post_id", id))
 	return nil
@@ -258,15 +271,28 @@ func (r *communityRepository) GetCommentsByPostID(postID, page, pageSize int) ([
 	return comments, nil
 }
 
-func (r *communityRepository) DeleteComment(id int) error {
+// DeleteComment 删除评论，audit 不为 nil 时在同一事务中记录审计日志
+func (r *communityRepository) DeleteComment(id int, audit *model.AuditEntry) error {
 	util.Logger.Info("开始删除评论", zap.Int("comment_id", id))
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `DELETE FROM comments WHERE id = ?`
-	_, err := r.db.Exec(query, id)
+	_, err = tx.Exec(query, id)
 	if err != nil {
 		util.Logger.Error("删除评论失败", zap.Error(err), zap.Int("comment_id", id))
 		return err
 	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
 	util.Logger.Info("评论除成功", zap.Int("comment_id", id))
 	return nil
@@ -858,3 +884,73 @@ func (r *communityRepository) GetUserByID(id int) (*model.User, error) {
 
 	return &user, nil
 }
+
+// GetCommentsByUser 获取用户发表的全部评论，用于个人数据导出
+func (r *communityRepository) GetCommentsByUser(userID int) ([]*model.Comment, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, post_id, parent_id, content, COALESCE(image_url, ''), created_at, updated_at
+		FROM comments
+		WHERE user_id = ?
+		ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+	for rows.Next() {
+		var comment model.Comment
+		if err := rows.Scan(&comment.ID, &comment.UserID, &comment.PostID, &comment.ParentID,
+			&comment.Content, &comment.ImageURL, &comment.CreatedAt, &comment.UpdatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, &comment)
+	}
+	return comments, rows.Err()
+}
+
+// GetLikesByUser 获取用户的全部点赞记录，用于个人数据导出
+func (r *communityRepository) GetLikesByUser(userID int) ([]*model.Like, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, post_id, created_at
+		FROM likes
+		WHERE user_id = ?
+		ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var likes []*model.Like
+	for rows.Next() {
+		var like model.Like
+		if err := rows.Scan(&like.ID, &like.UserID, &like.PostID, &like.CreatedAt); err != nil {
+			return nil, err
+		}
+		likes = append(likes, &like)
+	}
+	return likes, rows.Err()
+}
+
+// GetFollowsByUser 获取用户关注和被关注的全部记录，用于个人数据导出
+func (r *communityRepository) GetFollowsByUser(userID int) ([]*model.Follow, error) {
+	rows, err := r.db.Query(`
+		SELECT id, follower_id, followed_id, created_at
+		FROM follows
+		WHERE follower_id = ? OR followed_id = ?
+		ORDER BY created_at`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var follows []*model.Follow
+	for rows.Next() {
+		var follow model.Follow
+		if err := rows.Scan(&follow.ID, &follow.FollowerID, &follow.FollowedID, &follow.CreatedAt); err != nil {
+			return nil, err
+		}
+		follows = append(follows, &follow)
+	}
+	return follows, rows.Err()
+}
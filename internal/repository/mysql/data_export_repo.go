@@ -0,0 +1,143 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"database/sql"
+	"time"
+)
+
+type DataExportRepository struct {
+	db *sql.DB
+}
+
+func NewDataExportRepository(db *sql.DB) *DataExportRepository {
+	return &DataExportRepository{db}
+}
+
+const dataExportColumns = `id, user_id, status, file_path, token_hash, error_message, expires_at, created_at, completed_at`
+
+func scanDataExport(scanner interface{ Scan(...interface{}) error }) (*model.DataExport, error) {
+	var export model.DataExport
+	var filePath, tokenHash, errorMessage sql.NullString
+	var expiresAt, completedAt sql.NullTime
+	err := scanner.Scan(&export.ID, &export.UserID, &export.Status, &filePath, &tokenHash,
+		&errorMessage, &expiresAt, &export.CreatedAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+	export.FilePath = filePath.String
+	export.TokenHash = tokenHash.String
+	export.ErrorMessage = errorMessage.String
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	return &export, nil
+}
+
+// CreateExport 创建待处理的导出任务
+func (r *DataExportRepository) CreateExport(export *model.DataExport) error {
+	result, err := r.db.Exec(`
+		INSERT INTO data_exports (user_id, status) VALUES (?, ?)`,
+		export.UserID, model.DataExportPending)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	export.ID = int(id)
+	export.Status = model.DataExportPending
+	export.CreatedAt = time.Now()
+	return nil
+}
+
+// GetPendingExportByUser 获取用户正在生成的导出任务，超过一小时仍未完成的任务视为已中断
+func (r *DataExportRepository) GetPendingExportByUser(userID int) (*model.DataExport, error) {
+	export, err := scanDataExport(r.db.QueryRow(`
+		SELECT `+dataExportColumns+`
+		FROM data_exports
+		WHERE user_id = ? AND status = 'pending' AND created_at > NOW() - INTERVAL 1 HOUR
+		ORDER BY id DESC
+		LIMIT 1`, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return export, err
+}
+
+// CountExportsSince 统计用户在 since 之后申请且未失败的导出任务数
+func (r *DataExportRepository) CountExportsSince(userID int, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM data_exports
+		WHERE user_id = ? AND status != 'failed' AND created_at > ?`, userID, since).Scan(&count)
+	return count, err
+}
+
+// GetExportByToken 按下载令牌哈希查找导出任务，不存在时返回 nil
+func (r *DataExportRepository) GetExportByToken(tokenHash string) (*model.DataExport, error) {
+	export, err := scanDataExport(r.db.QueryRow(`
+		SELECT `+dataExportColumns+`
+		FROM data_exports
+		WHERE token_hash = ?`, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return export, err
+}
+
+// MarkExportReady 压缩包已生成，记录文件路径和下载令牌
+func (r *DataExportRepository) MarkExportReady(exportID int, filePath, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE data_exports
+		SET status = 'ready', file_path = ?, token_hash = ?, expires_at = ?, completed_at = NOW()
+		WHERE id = ?`, filePath, tokenHash, expiresAt, exportID)
+	return err
+}
+
+// MarkExportFailed 记录导出失败原因
+func (r *DataExportRepository) MarkExportFailed(exportID int, message string) error {
+	if len(message) > 255 {
+		message = message[:255]
+	}
+	_, err := r.db.Exec(`
+		UPDATE data_exports SET status = 'failed', error_message = ?, completed_at = NOW()
+		WHERE id = ?`, message, exportID)
+	return err
+}
+
+// GetExpiredExports 获取下载链接已过期、文件尚未删除的导出任务
+func (r *DataExportRepository) GetExpiredExports(limit int) ([]*model.DataExport, error) {
+	rows, err := r.db.Query(`
+		SELECT `+dataExportColumns+`
+		FROM data_exports
+		WHERE status = 'ready' AND expires_at <= NOW()
+		ORDER BY expires_at
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []*model.DataExport
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+	return exports, rows.Err()
+}
+
+// MarkExportExpired 文件已删除，下载令牌作废
+func (r *DataExportRepository) MarkExportExpired(exportID int) error {
+	_, err := r.db.Exec(`
+		UPDATE data_exports SET status = 'expired', file_path = NULL, token_hash = NULL
+		WHERE id = ?`, exportID)
+	return err
+}
@@ -0,0 +1,133 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ExchangeRateRepository struct {
+	db *sql.DB
+}
+
+func NewExchangeRateRepository(db *sql.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db}
+}
+
+const exchangeRateColumns = `id, base_currency, quote_currency, rate, effective_from, created_by, created_at`
+
+func scanExchangeRate(scanner interface{ Scan(...interface{}) error }) (*model.ExchangeRate, error) {
+	var rate model.ExchangeRate
+	var createdBy sql.NullInt64
+	err := scanner.Scan(&rate.ID, &rate.BaseCurrency, &rate.QuoteCurrency, &rate.Rate,
+		&rate.EffectiveFrom, &createdBy, &rate.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		rate.CreatedBy = &id
+	}
+	return &rate, nil
+}
+
+// CreateExchangeRate 新增汇率记录并记录审计日志，汇率只追加不修改，调整汇率时录入新的生效时间
+func (r *ExchangeRateRepository) CreateExchangeRate(rate *model.ExchangeRate, audit *model.AuditEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO exchange_rates (base_currency, quote_currency, rate, effective_from, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, NOW())`,
+		rate.BaseCurrency, rate.QuoteCurrency, rate.Rate, rate.EffectiveFrom, rate.CreatedBy)
+	if err != nil {
+		util.Logger.Error("创建汇率记录失败",
+			zap.Error(err),
+			zap.String("base_currency", rate.BaseCurrency),
+			zap.String("quote_currency", rate.QuoteCurrency))
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if audit != nil {
+		audit.TargetID = int(id)
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	rate.ID = int(id)
+	rate.CreatedAt = time.Now()
+	return nil
+}
+
+// GetEffectiveRate 获取指定时间点生效的汇率，不存在时返回 nil
+func (r *ExchangeRateRepository) GetEffectiveRate(base, quote string, at time.Time) (*model.ExchangeRate, error) {
+	row := r.db.QueryRow(`
+		SELECT `+exchangeRateColumns+`
+		FROM exchange_rates
+		WHERE base_currency = ? AND quote_currency = ? AND effective_from <= ?
+		ORDER BY effective_from DESC, id DESC
+		LIMIT 1`, base, quote, at)
+	rate, err := scanExchangeRate(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("查询汇率失败",
+			zap.Error(err),
+			zap.String("base_currency", base),
+			zap.String("quote_currency", quote))
+		return nil, err
+	}
+	return rate, nil
+}
+
+// ListExchangeRates 分页获取汇率记录，base、quote 为空时不过滤
+func (r *ExchangeRateRepository) ListExchangeRates(base, quote string, page, pageSize int) ([]*model.ExchangeRate, int, error) {
+	where := " WHERE 1=1"
+	var args []interface{}
+	if base != "" {
+		where += " AND base_currency = ?"
+		args = append(args, base)
+	}
+	if quote != "" {
+		where += " AND quote_currency = ?"
+		args = append(args, quote)
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM exchange_rates"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT `+exchangeRateColumns+`
+		FROM exchange_rates`+where+`
+		ORDER BY base_currency, quote_currency, effective_from DESC
+		LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	rates := []*model.ExchangeRate{}
+	for rows.Next() {
+		rate, err := scanExchangeRate(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, total, rows.Err()
+}
@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db}
+}
+
+// CreateIdempotencyRecord 占用幂等键，返回 false 表示该用户的同名键已存在
+func (r *IdempotencyRepository) CreateIdempotencyRecord(record *model.IdempotencyRecord) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT IGNORE INTO idempotency_keys (user_id, idempotency_key, request_hash, status, created_at, expires_at)
+		VALUES (?, ?, ?, ?, NOW(), ?)`,
+		record.UserID, record.Key, record.RequestHash, record.Status, record.ExpiresAt)
+	if err != nil {
+		util.Logger.Error("创建幂等键记录失败", zap.Error(err), zap.Int("user_id", record.UserID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	record.ID = int(id)
+	return true, nil
+}
+
+// GetIdempotencyRecord 获取用户的幂等键记录，不存在时返回 nil
+func (r *IdempotencyRepository) GetIdempotencyRecord(userID int, key string) (*model.IdempotencyRecord, error) {
+	var record model.IdempotencyRecord
+	var responseStatus sql.NullInt64
+	var contentType sql.NullString
+	err := r.db.QueryRow(`
+		SELECT id, user_id, idempotency_key, request_hash, status,
+			   response_status, response_content_type, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ?`, userID, key).Scan(
+		&record.ID, &record.UserID, &record.Key, &record.RequestHash, &record.Status,
+		&responseStatus, &contentType, &record.ResponseBody, &record.CreatedAt, &record.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("查询幂等键记录失败", zap.Error(err), zap.Int("user_id", userID))
+		return nil, err
+	}
+	record.ResponseStatus = int(responseStatus.Int64)
+	record.ResponseContentType = contentType.String
+	return &record, nil
+}
+
+// CompleteIdempotencyRecord 保存首次请求的响应
+func (r *IdempotencyRepository) CompleteIdempotencyRecord(id, status int, contentType string, body []byte) error {
+	_, err := r.db.Exec(`
+		UPDATE idempotency_keys
+		SET status = 'completed', response_status = ?, response_content_type = ?, response_body = ?
+		WHERE id = ?`, status, contentType, body, id)
+	return err
+}
+
+// DeleteIdempotencyRecord 释放幂等键
+func (r *IdempotencyRepository) DeleteIdempotencyRecord(id int) error {
+	_, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE id = ?`, id)
+	return err
+}
+
+// DeleteExpiredIdempotencyRecords 清理已过期的幂等键
+func (r *IdempotencyRepository) DeleteExpiredIdempotencyRecords() (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,215 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"database/sql"
+	"time"
+)
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{db}
+}
+
+const identityColumns = `id, user_id, provider, subject, email, created_at, last_login_at`
+
+func scanIdentity(scanner interface{ Scan(...interface{}) error }) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	var email sql.NullString
+	var lastLoginAt sql.NullTime
+	err := scanner.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+		&email, &identity.CreatedAt, &lastLoginAt)
+	if err != nil {
+		return nil, err
+	}
+	identity.Email = email.String
+	if lastLoginAt.Valid {
+		identity.LastLoginAt = &lastLoginAt.Time
+	}
+	return &identity, nil
+}
+
+// CreateLoginState 保存发起授权时生成的 state
+func (r *IdentityRepository) CreateLoginState(state *model.OIDCLoginState) error {
+	var userID sql.NullInt64
+	if state.UserID != 0 {
+		userID = sql.NullInt64{Int64: int64(state.UserID), Valid: true}
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, binding_hash, user_id, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		state.StateHash, state.Provider, state.Nonce, state.CodeVerifier, state.BindingHash, userID, state.ExpiresAt)
+	return err
+}
+
+// ConsumeLoginState 取出并删除 state，不存在或已过期时返回 nil，并发回调中只有一个能取到
+func (r *IdentityRepository) ConsumeLoginState(stateHash string) (*model.OIDCLoginState, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var state model.OIDCLoginState
+	var userID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT state_hash, provider, nonce, code_verifier, binding_hash, user_id, expires_at
+		FROM oidc_login_states
+		WHERE state_hash = ?
+		FOR UPDATE`, stateHash).Scan(
+		&state.StateHash, &state.Provider, &state.Nonce, &state.CodeVerifier, &state.BindingHash, &userID, &state.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM oidc_login_states WHERE state_hash = ?`, stateHash); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if !time.Now().Before(state.ExpiresAt) {
+		return nil, nil
+	}
+	state.UserID = int(userID.Int64)
+	return &state, nil
+}
+
+// DeleteExpiredLoginStates 删除未完成授权而过期的 state
+func (r *IdentityRepository) DeleteExpiredLoginStates() (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM oidc_login_states WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetIdentity 按提供方和 subject 查找身份，不存在时返回 nil
+func (r *IdentityRepository) GetIdentity(provider, subject string) (*model.UserIdentity, error) {
+	identity, err := scanIdentity(r.db.QueryRow(`
+		SELECT `+identityColumns+`
+		FROM user_identities
+		WHERE provider = ? AND subject = ?`, provider, subject))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return identity, err
+}
+
+// ListIdentities 获取用户绑定的所有第三方身份
+func (r *IdentityRepository) ListIdentities(userID int) ([]*model.UserIdentity, error) {
+	rows, err := r.db.Query(`
+		SELECT `+identityColumns+`
+		FROM user_identities
+		WHERE user_id = ?
+		ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*model.UserIdentity
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// CreateIdentity 为已有用户绑定身份，返回 false 表示该身份已被绑定或用户已绑定同一提供方
+func (r *IdentityRepository) CreateIdentity(identity *model.UserIdentity) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT IGNORE INTO user_identities (user_id, provider, subject, email)
+		VALUES (?, ?, ?, ?)`,
+		identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	identity.ID = int(id)
+	identity.CreatedAt = time.Now()
+	return true, nil
+}
+
+// CreateUserWithIdentity 首次第三方登录时同时创建用户和身份，用户没有本地密码
+func (r *IdentityRepository) CreateUserWithIdentity(user *model.User, identity *model.UserIdentity) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO users (username, email, password_hash, avatar_url, bio, is_verified, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		user.Username, user.Email, user.PasswordHash, user.AvatarURL, user.Bio, user.IsVerified, user.Status)
+	if err != nil {
+		return err
+	}
+	userID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	result, err = tx.Exec(`
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+		VALUES (?, ?, ?, ?, NOW())`,
+		userID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return err
+	}
+	identityID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.ID = int(userID)
+	user.Role = "user"
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	identity.ID = int(identityID)
+	identity.UserID = user.ID
+	identity.CreatedAt = now
+	identity.LastLoginAt = &now
+	return nil
+}
+
+// TouchIdentity 记录通过该身份登录的时间
+func (r *IdentityRepository) TouchIdentity(identityID int) error {
+	_, err := r.db.Exec(`UPDATE user_identities SET last_login_at = NOW() WHERE id = ?`, identityID)
+	return err
+}
+
+// DeleteIdentity 解除用户与提供方的绑定，返回 false 表示未绑定
+func (r *IdentityRepository) DeleteIdentity(userID int, provider string) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM user_identities WHERE user_id = ? AND provider = ?`, userID, provider)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
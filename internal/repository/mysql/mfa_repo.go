@@ -0,0 +1,166 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+type MFARepository struct {
+	db *sql.DB
+}
+
+func NewMFARepository(db *sql.DB) *MFARepository {
+	return &MFARepository{db}
+}
+
+// GetMFA 获取用户的两步验证配置，不存在时返回 nil
+func (r *MFARepository) GetMFA(userID int) (*model.UserMFA, error) {
+	var mfa model.UserMFA
+	var enabledAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT user_id, secret, enabled_at, last_used_step, created_at
+		FROM user_mfa
+		WHERE user_id = ?`, userID).Scan(
+		&mfa.UserID, &mfa.Secret, &enabledAt, &mfa.LastUsedStep, &mfa.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("查询两步验证配置失败", zap.Error(err), zap.Int("user_id", userID))
+		return nil, err
+	}
+	if enabledAt.Valid {
+		mfa.EnabledAt = &enabledAt.Time
+	}
+	return &mfa, nil
+}
+
+// SavePendingMFA 保存待验证的密钥，已开启两步验证的记录不会被覆盖
+func (r *MFARepository) SavePendingMFA(userID int, secret string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO user_mfa (user_id, secret, enabled_at, last_used_step)
+		VALUES (?, ?, NULL, 0)
+		ON DUPLICATE KEY UPDATE
+			secret = IF(enabled_at IS NULL, VALUES(secret), secret),
+			last_used_step = IF(enabled_at IS NULL, 0, last_used_step)`,
+		userID, secret)
+	return err
+}
+
+// EnableMFA 完成首次验证，开启两步验证并生成恢复码
+// 返回 false 表示两步验证已被其他请求开启，此时不替换已有的恢复码
+func (r *MFARepository) EnableMFA(userID int, step int64, recoveryCodeHashes []string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE user_mfa SET enabled_at = NOW(), last_used_step = ?
+		WHERE user_id = ? AND enabled_at IS NULL`, step, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UseTOTPStep 记录已使用的时间步，返回 false 表示该时间步或更晚的验证码已被使用过
+func (r *MFARepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_mfa SET last_used_step = ?
+		WHERE user_id = ? AND enabled_at IS NOT NULL AND last_used_step < ?`, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ReplaceRecoveryCodes 重新生成恢复码，旧的恢复码全部作废
+func (r *MFARepository) ReplaceRecoveryCodes(userID int, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int, recoveryCodeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM user_mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(`
+			INSERT INTO user_mfa_recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode 使用恢复码，返回 false 表示恢复码不存在或已使用
+func (r *MFARepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+		LIMIT 1`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// CountUnusedRecoveryCodes 统计未使用的恢复码数量
+func (r *MFARepository) CountUnusedRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM user_mfa_recovery_codes
+		WHERE user_id = ? AND used_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// DeleteMFA 关闭两步验证，删除密钥和恢复码
+func (r *MFARepository) DeleteMFA(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_mfa WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -22,7 +22,7 @@ func (r *PaymentRepository) CreatePayment(payment *model.Payment) error {
 	util.Logger.Info("开始创建支付记录",
 		zap.Int("user_id", payment.UserID),
 		zap.Int("project_id", payment.ProjectID),
-		zap.Float64("amount", payment.Amount),
+		zap.Stringer("amount", payment.Amount),
 		zap.String("status", payment.Status))
 
 	// 设置创建时间
@@ -76,15 +76,15 @@ func (r *PaymentRepository) CreateOrder(order *model.Order) error {
 	util.Logger.Info("开始创建订单",
 		zap.Int("user_id", order.UserID),
 		zap.Int("project_id", order.ProjectID),
-		zap.Float64("amount", order.Amount),
+		zap.Stringer("amount", order.Amount),
 		zap.Any("address_id", order.AddressID))
 
 	// 验证必要字段
-	if order.UserID == 0 || order.ProjectID == 0 || order.Amount <= 0 {
+	if order.UserID == 0 || order.ProjectID == 0 || !order.Amount.IsPositive() {
 		util.Logger.Error("订单参数验证失败",
 			zap.Int("user_id", order.UserID),
 			zap.Int("project_id", order.ProjectID),
-			zap.Float64("amount", order.Amount))
+			zap.Stringer("amount", order.Amount))
 		return fmt.Errorf("invalid order parameters")
 	}
 
@@ -106,9 +106,20 @@ func (r *PaymentRepository) CreateOrder(order *model.Order) error {
 		order.Status = "pending"
 	}
 
+	// 未经汇率折算的订单按原币种保存
+	if order.Currency == "" {
+		order.Currency = order.Amount.Currency
+	}
+	if order.OriginalCurrency == "" {
+		order.OriginalAmount = order.Amount
+		order.OriginalCurrency = order.Currency
+		order.ExchangeRate = "1"
+	}
+
 	// 先插入订单获取ID
-	query := `INSERT INTO orders (order_number, user_id, project_id, pledge_id, amount, status, address_id, created_at)
-			  VALUES ('TEMP', ?, ?, ?, ?, ?, ?, NOW())`
+	query := `INSERT INTO orders (order_number, user_id, project_id, pledge_id, amount, currency,
+			  original_amount, original_currency, exchange_rate, status, address_id, created_at)
+			  VALUES ('TEMP', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())`
 
 	util.Logger.Debug("执行订单插入SQL",
 		zap.String("query", query),
@@ -123,7 +134,8 @@ func (r *PaymentRepository) CreateOrder(order *model.Order) error {
 
 	result, err := tx.Exec(query,
 		order.UserID, order.ProjectID, order.PledgeID,
-		order.Amount, order.Status, order.AddressID)
+		order.Amount, order.Currency, order.OriginalAmount, order.OriginalCurrency,
+		order.ExchangeRate, order.Status, order.AddressID)
 	if err != nil {
 		util.Logger.Error("插入订单记录失败",
 			zap.Error(err),
@@ -177,20 +189,402 @@ func generateOrderNumber(orderID int) string {
 	return fmt.Sprintf("ORD-%d-%04d", year, orderID)
 }
 
-func (r *PaymentRepository) UpdateOrderStatus(orderID int, status string) error {
-	query := `UPDATE orders SET status = ?, updated_at = NOW() WHERE id = ?`
-	_, err := r.db.Exec(query, status, orderID)
+// TransitionOrderStatus 在订单仍处于 from 状态时将其更新为 to，并记录流转历史
+// 返回 false 表示订单当前状态已不是 from
+func (r *PaymentRepository) TransitionOrderStatus(orderID int, from, to, actor, reason string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		util.Logger.Error("开始事务失败", zap.Error(err))
+		return false, err
+	}
+	defer tx.Rollback()
+
+	updated, err := r.TransitionOrderStatusTx(tx, orderID, from, to, actor, reason)
+	if err != nil || !updated {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		util.Logger.Error("提交事务失败", zap.Error(err))
+		return false, err
+	}
+	return true, nil
+}
+
+// TransitionOrderStatusTx 在事务中流转订单状态并记录历史，订单当前状态不是 from 时返回 false
+func (r *PaymentRepository) TransitionOrderStatusTx(tx *sql.Tx, orderID int, from, to, actor, reason string) (bool, error) {
+	result, err := tx.Exec(`
+		UPDATE orders SET status = ?, updated_at = NOW()
+		WHERE id = ? AND status = ?`, to, orderID, from)
+	if err != nil {
+		util.Logger.Error("更新订单状态失败", zap.Error(err), zap.Int("order_id", orderID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if to == "refunded" {
+		_, err = tx.Exec(`
+			UPDATE pledges SET status = 'refunded'
+			WHERE id = (SELECT pledge_id FROM orders WHERE id = ?)`, orderID)
+		if err != nil {
+			util.Logger.Error("更新支持记录状态失败", zap.Error(err), zap.Int("order_id", orderID))
+			return false, err
+		}
+	}
+
+	if to == "refunded" || to == "failed" {
+		if err := releaseRewardStock(tx, orderID); err != nil {
+			return false, err
+		}
+	}
+
+	if err := insertOrderStatusHistory(tx, orderID, from, to, actor, reason); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// insertOrderStatusHistory 在事务中记录一次订单状态流转
+func insertOrderStatusHistory(tx *sql.Tx, orderID int, from, to, actor, reason string) error {
+	_, err := tx.Exec(`
+		INSERT INTO order_status_history (order_id, from_status, to_status, actor, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, NOW())`, orderID, from, to, actor, reason)
+	if err != nil {
+		util.Logger.Error("记录订单状态历史失败",
+			zap.Error(err),
+			zap.Int("order_id", orderID),
+			zap.String("from", from),
+			zap.String("to", to))
+	}
+	return err
+}
+
+// addProjectAmount 调整项目已筹金额并重新计算进度，amount 为负数时扣减，进度按分精确计算
+func addProjectAmount(tx *sql.Tx, projectID int, amount model.Money) error {
+	var totalAmount, totalGoalAmount model.Money
+	err := tx.QueryRow(`SELECT total_amount, total_goal_amount FROM projects WHERE id = ? FOR UPDATE`, projectID).
+		Scan(&totalAmount, &totalGoalAmount)
+	if err != nil {
+		util.Logger.Error("查询项目金额失败", zap.Error(err), zap.Int("project_id", projectID))
+		return err
+	}
+	totalAmount = totalAmount.Add(amount)
+
+	_, err = tx.Exec(`
+		UPDATE projects
+		SET total_amount = ?, progress = ?, updated_at = NOW()
+		WHERE id = ?`, totalAmount, model.ProgressPercent(totalAmount, totalGoalAmount), projectID)
+	if err != nil {
+		util.Logger.Error("更新项目金额失败", zap.Error(err), zap.Int("project_id", projectID))
+	}
+	return err
+}
+
+// releaseRewardStock 在事务中释放订单占用的档位和加购项库存，订单退款或支付失败时调用
+func releaseRewardStock(tx *sql.Tx, orderID int) error {
+	_, err := tx.Exec(`
+		UPDATE reward_tiers rt
+		JOIN orders o ON o.reward_tier_id = rt.id
+		SET rt.quantity_claimed = rt.quantity_claimed - 1
+		WHERE o.id = ? AND rt.quantity_claimed > 0`, orderID)
+	if err != nil {
+		util.Logger.Error("释放档位库存失败", zap.Error(err), zap.Int("order_id", orderID))
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE reward_add_ons ra
+		JOIN order_items oi ON oi.add_on_id = ra.id
+		SET ra.quantity_claimed = GREATEST(ra.quantity_claimed - oi.quantity, 0)
+		WHERE oi.order_id = ?`, orderID)
+	if err != nil {
+		util.Logger.Error("释放加购项库存失败", zap.Error(err), zap.Int("order_id", orderID))
+	}
+	return err
+}
+
+// CreateOrderItemsTx 在下单事务中写入订单明细
+func (r *PaymentRepository) CreateOrderItemsTx(tx *sql.Tx, orderID int, items []model.OrderItem) error {
+	for i := range items {
+		item := &items[i]
+		result, err := tx.Exec(`
+			INSERT INTO order_items (order_id, item_type, reward_tier_id, add_on_id, title, unit_price, quantity, subtotal)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			orderID, item.ItemType, item.RewardTierID, item.AddOnID, item.Title, item.UnitPrice, item.Quantity, item.Subtotal)
+		if err != nil {
+			util.Logger.Error("写入订单明细失败", zap.Error(err), zap.Int("order_id", orderID))
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		item.ID = int(id)
+		item.OrderID = orderID
+	}
+	return nil
+}
+
+// getOrderItems 获取订单明细，金额币种与订单的项目币种一致
+func (r *PaymentRepository) getOrderItems(orderID int, currency string) ([]model.OrderItem, error) {
+	rows, err := r.db.Query(`
+		SELECT id, order_id, item_type, reward_tier_id, add_on_id, title, unit_price, quantity, subtotal
+		FROM order_items
+		WHERE order_id = ?
+		ORDER BY id ASC`, orderID)
+	if err != nil {
+		util.Logger.Error("查询订单明细失败", zap.Error(err), zap.Int("order_id", orderID))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []model.OrderItem
+	for rows.Next() {
+		var item model.OrderItem
+		var rewardTierID, addOnID sql.NullInt64
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ItemType, &rewardTierID, &addOnID,
+			&item.Title, &item.UnitPrice, &item.Quantity, &item.Subtotal); err != nil {
+			return nil, err
+		}
+		if rewardTierID.Valid {
+			id := int(rewardTierID.Int64)
+			item.RewardTierID = &id
+		}
+		if addOnID.Valid {
+			id := int(addOnID.Int64)
+			item.AddOnID = &id
+		}
+		item.UnitPrice.Currency = currency
+		item.Subtotal.Currency = currency
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetOrderStatusHistory 获取订单状态流转历史，按时间正序
+func (r *PaymentRepository) GetOrderStatusHistory(orderID int) ([]*model.OrderStatusHistory, error) {
+	rows, err := r.db.Query(`
+		SELECT id, order_id, from_status, to_status, actor, COALESCE(reason, ''), created_at
+		FROM order_status_history
+		WHERE order_id = ?
+		ORDER BY created_at ASC, id ASC`, orderID)
+	if err != nil {
+		util.Logger.Error("查询订单状态历史失败", zap.Error(err), zap.Int("order_id", orderID))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*model.OrderStatusHistory
+	for rows.Next() {
+		var h model.OrderStatusHistory
+		if err := rows.Scan(&h.ID, &h.OrderID, &h.FromStatus, &h.ToStatus,
+			&h.Actor, &h.Reason, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, &h)
+	}
+	return history, rows.Err()
+}
+
+// UpdateOrderPaymentIntent 记录订单对应的支付渠道和支付意图
+func (r *PaymentRepository) UpdateOrderPaymentIntent(orderID int, provider, intentID string) error {
+	query := `UPDATE orders SET payment_provider = ?, payment_intent_id = ?, updated_at = NOW() WHERE id = ?`
+	_, err := r.db.Exec(query, provider, intentID, orderID)
+	if err != nil {
+		util.Logger.Error("记录支付意图失败",
+			zap.Error(err),
+			zap.Int("order_id", orderID),
+			zap.String("intent_id", intentID))
+	}
 	return err
 }
 
+// ConfirmOrderPayment 支付渠道确认扣款后将订单置为已支付，并计入项目已筹金额
+// 仅处理待支付订单，返回 false 表示订单已不是待支付状态（例如重复确认）
+func (r *PaymentRepository) ConfirmOrderPayment(orderID int, actor string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		util.Logger.Error("开始事务失败", zap.Error(err))
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE orders
+		SET status = 'paid', paid_at = NOW(), updated_at = NOW()
+		WHERE id = ? AND status = 'pending'`, orderID)
+	if err != nil {
+		util.Logger.Error("更新订单支付状态失败", zap.Error(err), zap.Int("order_id", orderID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	var pledgeID, projectID int
+	var amount model.Money
+	err = tx.QueryRow(`SELECT pledge_id, project_id, amount FROM orders WHERE id = ?`, orderID).
+		Scan(&pledgeID, &projectID, &amount)
+	if err != nil {
+		util.Logger.Error("查询订单失败", zap.Error(err), zap.Int("order_id", orderID))
+		return false, err
+	}
+
+	_, err = tx.Exec(`UPDATE pledges SET status = 'completed' WHERE id = ?`, pledgeID)
+	if err != nil {
+		util.Logger.Error("更新支持记录状态失败", zap.Error(err), zap.Int("pledge_id", pledgeID))
+		return false, err
+	}
+
+	if err := insertOrderStatusHistory(tx, orderID, "pending", "paid", actor, "支付渠道确认扣款"); err != nil {
+		return false, err
+	}
+
+	// 只有扣款成功的金额才计入项目已筹金额
+	if err := addProjectAmount(tx, projectID, amount); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		util.Logger.Error("提交事务失败", zap.Error(err))
+		return false, err
+	}
+
+	util.Logger.Info("订单支付已确认",
+		zap.Int("order_id", orderID),
+		zap.Int("project_id", projectID),
+		zap.Stringer("amount", amount))
+	return true, nil
+}
+
+// MarkOrderPaymentFailed 将待支付订单置为支付失败
+// 返回 false 表示订单已不是待支付状态
+func (r *PaymentRepository) MarkOrderPaymentFailed(orderID int, actor, reason string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		util.Logger.Error("开始事务失败", zap.Error(err))
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE orders SET status = 'failed', updated_at = NOW()
+		WHERE id = ? AND status = 'pending'`, orderID)
+	if err != nil {
+		util.Logger.Error("更新订单支付状态失败", zap.Error(err), zap.Int("order_id", orderID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE pledges SET status = 'failed'
+		WHERE id = (SELECT pledge_id FROM orders WHERE id = ?)`, orderID)
+	if err != nil {
+		util.Logger.Error("更新支持记录状态失败", zap.Error(err), zap.Int("order_id", orderID))
+		return false, err
+	}
+
+	if err := releaseRewardStock(tx, orderID); err != nil {
+		return false, err
+	}
+
+	if err := insertOrderStatusHistory(tx, orderID, "pending", "failed", actor, reason); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		util.Logger.Error("提交事务失败", zap.Error(err))
+		return false, err
+	}
+	return true, nil
+}
+
+// GetOrderByPaymentIntent 通过支付渠道和支付意图ID获取订单
+func (r *PaymentRepository) GetOrderByPaymentIntent(provider, intentID string) (*model.Order, error) {
+	var orderID int
+	err := r.db.QueryRow(`
+		SELECT id FROM orders
+		WHERE payment_provider = ? AND payment_intent_id = ?`, provider, intentID).Scan(&orderID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("通过支付意图查询订单失败",
+			zap.Error(err),
+			zap.String("provider", provider),
+			zap.String("intent_id", intentID))
+		return nil, err
+	}
+	return r.GetOrderByID(orderID)
+}
+
+// WebhookEventExists 检查回调事件是否已处理
+func (r *PaymentRepository) WebhookEventExists(provider, eventID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM payment_webhook_events WHERE provider = ? AND event_id = ?)`,
+		provider, eventID).Scan(&exists)
+	return exists, err
+}
+
+// CreateWebhookEvent 记录已处理的回调事件，返回 false 表示该事件已被记录过
+func (r *PaymentRepository) CreateWebhookEvent(event *model.PaymentWebhookEvent) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT IGNORE INTO payment_webhook_events (provider, event_id, event_type, order_id, payload, needs_reconciliation, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())`,
+		event.Provider, event.EventID, event.EventType, event.OrderID, event.Payload, event.NeedsReconciliation)
+	if err != nil {
+		util.Logger.Error("记录回调事件失败",
+			zap.Error(err),
+			zap.String("provider", event.Provider),
+			zap.String("event_id", event.EventID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	event.ID = int(id)
+	return true, nil
+}
+
 func (r *PaymentRepository) GetOrderByID(id int) (*model.Order, error) {
 	util.Logger.Info("开始获取订单详情", zap.Int("order_id", id))
 
 	query := `
 		SELECT o.id, o.order_number, o.user_id, o.project_id, o.pledge_id, 
-			   o.amount, o.status, o.address_id, o.is_reward, o.created_at, o.updated_at,
-			   a.id, a.user_id, a.receiver_name, a.phone, a.province, a.city, 
-			   a.district, a.detail_address, a.is_default, a.created_at, a.updated_at,
+			   COALESCE(o.subtotal, o.amount), o.shipping_fee,
+			   o.amount, o.currency, COALESCE(o.original_amount, o.amount),
+			   COALESCE(o.original_currency, o.currency), o.exchange_rate,
+			   o.status, o.address_id, o.is_reward, o.reward_tier_id, COALESCE(rt.title, ''),
+			   o.created_at, o.updated_at,
+			   COALESCE(o.payment_provider, ''), COALESCE(o.payment_intent_id, ''), o.paid_at,
+			   COALESCE(a.id, 0), COALESCE(a.user_id, 0), COALESCE(a.receiver_name, ''), COALESCE(a.phone, ''),
+			   COALESCE(a.country, ''), COALESCE(a.province, ''), COALESCE(a.city, ''), COALESCE(a.district, ''),
+			   COALESCE(a.detail_address, ''), COALESCE(a.is_default, FALSE),
+			   COALESCE(a.created_at, o.created_at), COALESCE(a.updated_at, o.updated_at),
 			   COALESCE(s.status, '') as shipment_status,
 			   COALESCE(s.tracking_number, '') as tracking_number,
 			   COALESCE(s.shipping_company, '') as shipping_company,
@@ -198,20 +592,26 @@ func (r *PaymentRepository) GetOrderByID(id int) (*model.Order, error) {
 		FROM orders o
 		LEFT JOIN user_addresses a ON o.address_id = a.id
 		LEFT JOIN shipments s ON o.id = s.order_id
+		LEFT JOIN reward_tiers rt ON o.reward_tier_id = rt.id
 		WHERE o.id = ?`
 
 	var order model.Order
 	var address model.UserAddress
 	var shipment model.Shipment
-	var addressID sql.NullInt64
-	var shippedAt, estimatedDeliveryAt sql.NullTime
+	var addressID, rewardTierID sql.NullInt64
+	var paidAt, shippedAt, estimatedDeliveryAt sql.NullTime
 	var shipmentStatus, trackingNumber, shippingCompany string
 
 	err := r.db.QueryRow(query, id).Scan(
 		&order.ID, &order.OrderNumber, &order.UserID, &order.ProjectID, &order.PledgeID,
-		&order.Amount, &order.Status, &addressID, &order.IsReward, &order.CreatedAt, &order.UpdatedAt,
+		&order.Subtotal, &order.ShippingFee,
+		&order.Amount, &order.Currency, &order.OriginalAmount,
+		&order.OriginalCurrency, &order.ExchangeRate,
+		&order.Status, &addressID, &order.IsReward, &rewardTierID, &order.RewardTitle,
+		&order.CreatedAt, &order.UpdatedAt,
+		&order.PaymentProvider, &order.PaymentIntentID, &paidAt,
 		&address.ID, &address.UserID, &address.ReceiverName, &address.Phone,
-		&address.Province, &address.City, &address.District, &address.DetailAddress,
+		&address.Country, &address.Province, &address.City, &address.District, &address.DetailAddress,
 		&address.IsDefault, &address.CreatedAt, &address.UpdatedAt,
 		&shipmentStatus, &trackingNumber, &shippingCompany,
 		&shippedAt, &estimatedDeliveryAt)
@@ -226,14 +626,22 @@ func (r *PaymentRepository) GetOrderByID(id int) (*model.Order, error) {
 			zap.Int("order_id", id))
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
+	order.ApplyCurrency()
 
 	// 处理可能为 NULL 的字段
+	if rewardTierID.Valid {
+		tierID := int(rewardTierID.Int64)
+		order.RewardTierID = &tierID
+	}
 	if addressID.Valid {
 		order.AddressID = &[]int{int(addressID.Int64)}[0]
 		order.Address = &address
 	}
 
 	// 处理可能为 NULL 的时间字段
+	if paidAt.Valid {
+		order.PaidAt = &paidAt.Time
+	}
 	if shippedAt.Valid {
 		shipment.ShippedAt = shippedAt.Time
 	}
@@ -251,6 +659,10 @@ func (r *PaymentRepository) GetOrderByID(id int) (*model.Order, error) {
 		order.Shipment = &shipment
 	}
 
+	if order.Items, err = r.getOrderItems(order.ID, order.Currency); err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+
 	util.Logger.Info("成功获取订单详情",
 		zap.Int("order_id", id),
 		zap.String("status", order.Status))
@@ -264,9 +676,14 @@ func (r *PaymentRepository) GetOrdersByUser(userID int) ([]*model.Order, error)
 
 	query := `
 		SELECT o.id, o.order_number, o.user_id, o.project_id, o.pledge_id, 
-			   o.amount, o.status, o.address_id, o.is_reward, o.created_at, o.updated_at,
-			   a.id, a.user_id, a.receiver_name, a.phone, a.province, a.city, 
-			   a.district, a.detail_address, a.is_default, a.created_at, a.updated_at
+			   COALESCE(o.subtotal, o.amount), o.shipping_fee,
+			   o.amount, o.currency, COALESCE(o.original_amount, o.amount),
+			   COALESCE(o.original_currency, o.currency), o.exchange_rate,
+			   o.status, o.address_id, o.is_reward, o.reward_tier_id, o.created_at, o.updated_at,
+			   COALESCE(a.id, 0), COALESCE(a.user_id, 0), COALESCE(a.receiver_name, ''), COALESCE(a.phone, ''),
+			   COALESCE(a.country, ''), COALESCE(a.province, ''), COALESCE(a.city, ''), COALESCE(a.district, ''),
+			   COALESCE(a.detail_address, ''), COALESCE(a.is_default, FALSE),
+			   COALESCE(a.created_at, o.created_at), COALESCE(a.updated_at, o.updated_at)
 		FROM orders o
 		LEFT JOIN user_addresses a ON o.address_id = a.id
 		WHERE o.user_id = ?
@@ -289,13 +706,16 @@ func (r *PaymentRepository) GetOrdersByUser(userID int) ([]*model.Order, error)
 	for rows.Next() {
 		var order model.Order
 		var address model.UserAddress
-		var addressID sql.NullInt64
+		var addressID, rewardTierID sql.NullInt64
 
 		err := rows.Scan(
 			&order.ID, &order.OrderNumber, &order.UserID, &order.ProjectID, &order.PledgeID,
-			&order.Amount, &order.Status, &addressID, &order.IsReward, &order.CreatedAt, &order.UpdatedAt,
+			&order.Subtotal, &order.ShippingFee,
+			&order.Amount, &order.Currency, &order.OriginalAmount,
+			&order.OriginalCurrency, &order.ExchangeRate,
+			&order.Status, &addressID, &order.IsReward, &rewardTierID, &order.CreatedAt, &order.UpdatedAt,
 			&address.ID, &address.UserID, &address.ReceiverName, &address.Phone,
-			&address.Province, &address.City, &address.District, &address.DetailAddress,
+			&address.Country, &address.Province, &address.City, &address.District, &address.DetailAddress,
 			&address.IsDefault, &address.CreatedAt, &address.UpdatedAt,
 		)
 		if err != nil {
@@ -305,6 +725,7 @@ func (r *PaymentRepository) GetOrdersByUser(userID int) ([]*model.Order, error)
 				zap.String("error_type", fmt.Sprintf("%T", err)))
 			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
+		order.ApplyCurrency()
 
 		util.Logger.Debug("成功扫描订单数据",
 			zap.Int("order_id", order.ID),
@@ -312,6 +733,11 @@ func (r *PaymentRepository) GetOrdersByUser(userID int) ([]*model.Order, error)
 			zap.String("status", order.Status),
 			zap.Bool("has_address", addressID.Valid))
 
+		if rewardTierID.Valid {
+			tierID := int(rewardTierID.Int64)
+			order.RewardTierID = &tierID
+		}
+
 		// 只有当地址ID存在时才设置地址信息
 		if addressID.Valid {
 			order.AddressID = &[]int{int(addressID.Int64)}[0]
@@ -340,14 +766,19 @@ func (r *PaymentRepository) GetOrdersByUser(userID int) ([]*model.Order, error)
 
 func (r *PaymentRepository) GetOrdersByProject(projectID int) ([]*model.Order, error) {
 	query := `
-			SELECT o.*, a.*
+			SELECT o.id, o.order_number, o.user_id, o.project_id, o.pledge_id,
+				   COALESCE(o.subtotal, o.amount), o.shipping_fee,
+				   o.amount, o.currency, COALESCE(o.original_amount, o.amount),
+				   COALESCE(o.original_currency, o.currency), o.exchange_rate,
+				   o.status, o.address_id, o.is_reward, o.reward_tier_id, o.created_at, o.updated_at,
+				   COALESCE(o.payment_provider, ''), COALESCE(o.payment_intent_id, ''), o.paid_at
 			FROM orders o
-			LEFT JOIN user_addresses a ON o.address_id = a.id
 			WHERE o.project_id = ?
 			ORDER BY o.created_at DESC`
 
 	rows, err := r.db.Query(query, projectID)
 	if err != nil {
+		util.Logger.Error("查询项目订单失败", zap.Error(err), zap.Int("project_id", projectID))
 		return nil, err
 	}
 	defer rows.Close()
@@ -355,28 +786,52 @@ func (r *PaymentRepository) GetOrdersByProject(projectID int) ([]*model.Order, e
 	var orders []*model.Order
 	for rows.Next() {
 		var order model.Order
-		var address model.UserAddress
+		var addressID, rewardTierID sql.NullInt64
+		var paidAt sql.NullTime
 		err := rows.Scan(
-			&order.ID, &order.UserID, &order.ProjectID, &order.PledgeID,
-			&order.Amount, &order.Status, &order.AddressID, &order.CreatedAt,
+			&order.ID, &order.OrderNumber, &order.UserID, &order.ProjectID, &order.PledgeID,
+			&order.Subtotal, &order.ShippingFee,
+			&order.Amount, &order.Currency, &order.OriginalAmount,
+			&order.OriginalCurrency, &order.ExchangeRate,
+			&order.Status, &addressID, &order.IsReward, &rewardTierID, &order.CreatedAt,
 			&order.UpdatedAt,
-			&address.ID, &address.UserID, &address.ReceiverName, &address.Phone,
-			&address.Province, &address.City, &address.District,
-			&address.DetailAddress, &address.IsDefault,
-			&address.CreatedAt, &address.UpdatedAt)
+			&order.PaymentProvider, &order.PaymentIntentID, &paidAt)
 		if err != nil {
+			util.Logger.Error("扫描项目订单失败", zap.Error(err), zap.Int("project_id", projectID))
 			return nil, err
 		}
-		order.Address = &address
+		order.ApplyCurrency()
+		if addressID.Valid {
+			id := int(addressID.Int64)
+			order.AddressID = &id
+		}
+		if rewardTierID.Valid {
+			tierID := int(rewardTierID.Int64)
+			order.RewardTierID = &tierID
+		}
+		if paidAt.Valid {
+			order.PaidAt = &paidAt.Time
+		}
 		orders = append(orders, &order)
 	}
-	return orders, nil
+	return orders, rows.Err()
 }
 
 func (r *PaymentRepository) CreateRefundRequest(request *model.RefundRequest) error {
+	return insertRefundRequest(r.db, request)
+}
+
+// CreateRefundRequestTx 在事务中创建退款申请
+func (r *PaymentRepository) CreateRefundRequestTx(tx *sql.Tx, request *model.RefundRequest) error {
+	return insertRefundRequest(tx, request)
+}
+
+func insertRefundRequest(execer interface {
+	Exec(string, ...interface{}) (sql.Result, error)
+}, request *model.RefundRequest) error {
 	query := `INSERT INTO refund_requests (order_id, user_id, reason, status, created_at)
 			  VALUES (?, ?, ?, ?, NOW())`
-	result, err := r.db.Exec(query,
+	result, err := execer.Exec(query,
 		request.OrderID, request.UserID, request.Reason, request.Status)
 	if err != nil {
 		return err
@@ -390,13 +845,28 @@ func (r *PaymentRepository) CreateRefundRequest(request *model.RefundRequest) er
 	return nil
 }
 
-func (r *PaymentRepository) UpdateRefundRequest(request *model.RefundRequest) error {
+// UpdateRefundRequestTx 在事务中处理待审批的退款申请并记录审计日志，申请已被处理时返回 false
+func (r *PaymentRepository) UpdateRefundRequestTx(tx *sql.Tx, request *model.RefundRequest, audit *model.AuditEntry) (bool, error) {
 	query := `UPDATE refund_requests 
 			  SET status = ?, admin_comment = ?, updated_at = NOW()
-			  WHERE id = ?`
-	_, err := r.db.Exec(query,
+			  WHERE id = ? AND status = 'pending'`
+	result, err := tx.Exec(query,
 		request.Status, request.AdminComment, request.ID)
-	return err
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 func (r *PaymentRepository) GetRefundRequestsByUser(userID int) ([]*model.RefundRequest, error) {
@@ -406,7 +876,8 @@ func (r *PaymentRepository) GetRefundRequestsByUser(userID int) ([]*model.Refund
 		SELECT r.id, r.order_id, r.user_id, r.reason, r.status, 
 			   COALESCE(r.admin_comment, '') as admin_comment,
 			   r.created_at, r.updated_at,
-			   o.order_number, o.amount,
+			   o.order_number, o.amount, o.currency,
+			   COALESCE(o.original_amount, o.amount), COALESCE(o.original_currency, o.currency),
 			   p.title as project_title
 			FROM refund_requests r
 			LEFT JOIN orders o ON r.order_id = o.id
@@ -432,7 +903,8 @@ func (r *PaymentRepository) GetRefundRequestsByUser(userID int) ([]*model.Refund
 		err := rows.Scan(
 			&req.ID, &req.OrderID, &req.UserID, &req.Reason, &req.Status,
 			&req.AdminComment, &req.CreatedAt, &req.UpdatedAt,
-			&order.OrderNumber, &order.Amount,
+			&order.OrderNumber, &order.Amount, &order.Currency,
+			&order.OriginalAmount, &order.OriginalCurrency,
 			&projectTitle)
 		if err != nil {
 			util.Logger.Error("扫描退款申请数据失败",
@@ -442,6 +914,7 @@ func (r *PaymentRepository) GetRefundRequestsByUser(userID int) ([]*model.Refund
 		}
 
 		order.ID = req.OrderID
+		order.ApplyCurrency()
 		req.Order = &order
 		req.ProjectTitle = projectTitle
 		requests = append(requests, &req)
@@ -468,7 +941,8 @@ func (r *PaymentRepository) GetPendingRefundRequests() ([]*model.RefundRequest,
 		SELECT r.id, r.order_id, r.user_id, r.reason, r.status, 
 			   COALESCE(r.admin_comment, '') as admin_comment,
 			   r.created_at, r.updated_at,
-			   o.order_number, o.amount,
+			   o.order_number, o.amount, o.currency,
+			   COALESCE(o.original_amount, o.amount), COALESCE(o.original_currency, o.currency),
 			   u.username, u.email,
 			   p.title as project_title
 		FROM refund_requests r
@@ -495,7 +969,8 @@ func (r *PaymentRepository) GetPendingRefundRequests() ([]*model.RefundRequest,
 		err := rows.Scan(
 			&req.ID, &req.OrderID, &req.UserID, &req.Reason, &req.Status,
 			&req.AdminComment, &req.CreatedAt, &req.UpdatedAt,
-			&order.OrderNumber, &order.Amount,
+			&order.OrderNumber, &order.Amount, &order.Currency,
+			&order.OriginalAmount, &order.OriginalCurrency,
 			&user.Username, &user.Email,
 			&projectTitle)
 		if err != nil {
@@ -504,6 +979,7 @@ func (r *PaymentRepository) GetPendingRefundRequests() ([]*model.RefundRequest,
 		}
 
 		order.ID = req.OrderID
+		order.ApplyCurrency()
 		req.Order = &order
 		req.User = &user
 		req.ProjectTitle = projectTitle
@@ -517,10 +993,11 @@ func (r *PaymentRepository) GetPendingRefundRequests() ([]*model.RefundRequest,
 }
 
 func (r *PaymentRepository) CreatePledge(pledge *model.Pledge) error {
-	query := `INSERT INTO pledges (user_id, project_id, amount, status, address_id, created_at)
-			  VALUES (?, ?, ?, ?, ?, NOW())`
+	query := `INSERT INTO pledges (user_id, project_id, amount, currency, original_amount, original_currency, status, address_id, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())`
 	result, err := r.db.Exec(query,
-		pledge.UserID, pledge.ProjectID, pledge.Amount,
+		pledge.UserID, pledge.ProjectID, pledge.Amount, pledge.Currency,
+		pledge.OriginalAmount, pledge.OriginalCurrency,
 		pledge.Status, pledge.AddressID)
 	if err != nil {
 		return err
@@ -549,7 +1026,7 @@ func (r *PaymentRepository) GetShipmentByOrderID(orderID int) (*model.Shipment,
 		&shipment.ShippedAt, &shipment.DeliveredAt, &shipment.EstimatedDeliveryAt,
 		&shipment.CreatedAt, &shipment.UpdatedAt,
 		&address.ID, &address.UserID, &address.ReceiverName, &address.Phone,
-		&address.Province, &address.City, &address.District,
+		&address.Country, &address.Province, &address.City, &address.District,
 		&address.DetailAddress, &address.IsDefault,
 		&address.CreatedAt, &address.UpdatedAt)
 
@@ -564,36 +1041,438 @@ func (r *PaymentRepository) GetShipmentByOrderID(orderID int) (*model.Shipment,
 	return &shipment, nil
 }
 
-func (r *PaymentRepository) UpdateOrderAfterShipment(orderID int, shipmentID int) error {
-	tx, err := r.db.Begin()
+// MarkShipmentShippedTx 在事务中将发货记录标记为已发货
+func (r *PaymentRepository) MarkShipmentShippedTx(tx *sql.Tx, shipmentID int) error {
+	_, err := tx.Exec(`
+		UPDATE shipments 
+		SET status = 'shipped', shipped_at = NOW(), updated_at = NOW() 
+		WHERE id = ?`, shipmentID)
+	return err
+}
+
+// GetProjectPayoutAmounts 汇总项目结算订单金额与已批准退款的订单金额
+// 结算订单指众筹成功后进入履约流程的订单，其中已退款的订单同时计入退款金额
+func (r *PaymentRepository) GetProjectPayoutAmounts(projectID int) (gross, refunded model.Money, err error) {
+	err = r.db.QueryRow(`
+		SELECT COALESCE(SUM(o.amount), 0),
+			   COALESCE(SUM(CASE WHEN o.status = 'refunded' AND EXISTS (
+					SELECT 1 FROM refund_requests rr
+					WHERE rr.order_id = o.id AND rr.status = 'approved'
+			   ) THEN o.amount ELSE 0 END), 0)
+		FROM orders o
+		WHERE o.project_id = ?
+			AND o.status IN ('ready_to_ship', 'shipped', 'delivered', 'refund_pending', 'refund_rejected', 'refunded')`,
+		projectID).Scan(&gross, &refunded)
 	if err != nil {
-		return err
+		util.Logger.Error("汇总项目结算金额失败", zap.Error(err), zap.Int("project_id", projectID))
+		return model.Money{}, model.Money{}, err
 	}
-	defer tx.Rollback()
+	return gross, refunded, nil
+}
 
-	// 更新订单状态为已发货
-	_, err = tx.Exec(`
-		UPDATE orders 
-		SET status = 'shipped', updated_at = NOW() 
-		WHERE id = ?`, orderID)
+// CreateProjectPayout 创建创作者结算记录，返回 false 表示该项目已有结算记录
+func (r *PaymentRepository) CreateProjectPayout(payout *model.ProjectPayout) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT IGNORE INTO project_payouts (
+			project_id, creator_id, gross_amount, refund_amount, fee_percent, fee_amount,
+			net_amount, status, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())`,
+		payout.ProjectID, payout.CreatorID, payout.GrossAmount, payout.RefundAmount,
+		payout.FeePercent, payout.FeeAmount, payout.NetAmount, payout.Status)
 	if err != nil {
-		return err
+		util.Logger.Error("创建结算记录失败", zap.Error(err), zap.Int("project_id", payout.ProjectID))
+		return false, err
 	}
-
-	// 更新发货记录状态
-	_, err = tx.Exec(`
-		UPDATE shipments 
-		SET status = 'shipped', shipped_at = NOW(), updated_at = NOW() 
-		WHERE id = ?`, shipmentID)
+	affected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return false, err
 	}
-
-	return tx.Commit()
+	if affected == 0 {
+		return false, nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	payout.ID = int(id)
+	return true, nil
 }
 
-// CheckProjectGoalStatus 检查项目第一个目标是否达成
-func (r *PaymentRepository) CheckProjectGoalStatus(projectID int) (bool, error) {
+const payoutColumns = `
+		pp.id, pp.project_id, pp.creator_id, pp.gross_amount, pp.refund_amount,
+		pp.fee_percent, pp.fee_amount, pp.net_amount, pp.status,
+		pp.approved_by, pp.approved_at, pp.paid_by, pp.paid_at,
+		COALESCE(pp.payment_reference, ''), COALESCE(p.title, ''), COALESCE(p.currency, 'CNY'),
+		pp.created_at, pp.updated_at`
+
+// scanPayout 按 payoutColumns 的列顺序扫描结算记录
+func scanPayout(scanner interface{ Scan(...interface{}) error }) (*model.ProjectPayout, error) {
+	payout := &model.ProjectPayout{}
+	var approvedBy, paidBy sql.NullInt64
+	var approvedAt, paidAt sql.NullTime
+	err := scanner.Scan(
+		&payout.ID, &payout.ProjectID, &payout.CreatorID, &payout.GrossAmount, &payout.RefundAmount,
+		&payout.FeePercent, &payout.FeeAmount, &payout.NetAmount, &payout.Status,
+		&approvedBy, &approvedAt, &paidBy, &paidAt,
+		&payout.PaymentReference, &payout.ProjectTitle, &payout.Currency,
+		&payout.CreatedAt, &payout.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	payout.GrossAmount.Currency = payout.Currency
+	payout.RefundAmount.Currency = payout.Currency
+	payout.FeeAmount.Currency = payout.Currency
+	payout.NetAmount.Currency = payout.Currency
+	if approvedBy.Valid {
+		id := int(approvedBy.Int64)
+		payout.ApprovedBy = &id
+	}
+	if approvedAt.Valid {
+		payout.ApprovedAt = &approvedAt.Time
+	}
+	if paidBy.Valid {
+		id := int(paidBy.Int64)
+		payout.PaidBy = &id
+	}
+	if paidAt.Valid {
+		payout.PaidAt = &paidAt.Time
+	}
+	return payout, nil
+}
+
+// GetProjectPayout 获取项目的结算记录，不存在时返回 nil
+func (r *PaymentRepository) GetProjectPayout(projectID int) (*model.ProjectPayout, error) {
+	row := r.db.QueryRow(`
+		SELECT `+payoutColumns+`
+		FROM project_payouts pp
+		LEFT JOIN projects p ON pp.project_id = p.id
+		WHERE pp.project_id = ?`, projectID)
+	payout, err := scanPayout(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return payout, err
+}
+
+// GetPayoutByID 通过ID获取结算记录，不存在时返回 nil
+func (r *PaymentRepository) GetPayoutByID(payoutID int) (*model.ProjectPayout, error) {
+	row := r.db.QueryRow(`
+		SELECT `+payoutColumns+`
+		FROM project_payouts pp
+		LEFT JOIN projects p ON pp.project_id = p.id
+		WHERE pp.id = ?`, payoutID)
+	payout, err := scanPayout(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return payout, err
+}
+
+// GetPayoutsByProject 获取项目的全部结算记录
+func (r *PaymentRepository) GetPayoutsByProject(projectID int) ([]*model.ProjectPayout, error) {
+	rows, err := r.db.Query(`
+		SELECT `+payoutColumns+`
+		FROM project_payouts pp
+		LEFT JOIN projects p ON pp.project_id = p.id
+		WHERE pp.project_id = ?
+		ORDER BY pp.created_at DESC`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payouts := []*model.ProjectPayout{}
+	for rows.Next() {
+		payout, err := scanPayout(rows)
+		if err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, payout)
+	}
+	return payouts, rows.Err()
+}
+
+// GetPayouts 分页获取结算记录，status 为空时返回全部
+func (r *PaymentRepository) GetPayouts(page, pageSize int, status string) ([]*model.ProjectPayout, int, error) {
+	where := ""
+	args := []interface{}{}
+	if status != "" {
+		where = "WHERE pp.status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM project_payouts pp `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	rows, err := r.db.Query(`
+		SELECT `+payoutColumns+`
+		FROM project_payouts pp
+		LEFT JOIN projects p ON pp.project_id = p.id
+		`+where+`
+		ORDER BY pp.created_at DESC
+		LIMIT ? OFFSET ?`, append(args, pageSize, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	payouts := []*model.ProjectPayout{}
+	for rows.Next() {
+		payout, err := scanPayout(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		payouts = append(payouts, payout)
+	}
+	return payouts, total, rows.Err()
+}
+
+// ApprovePayout 以最新核算的金额批准待审批的结算记录，返回 false 表示记录已不是待审批状态
+func (r *PaymentRepository) ApprovePayout(payout *model.ProjectPayout, adminID int, audit *model.AuditEntry) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE project_payouts
+		SET gross_amount = ?, refund_amount = ?, fee_percent = ?, fee_amount = ?, net_amount = ?,
+			status = 'approved', approved_by = ?, approved_at = NOW(), updated_at = NOW()
+		WHERE id = ? AND status = 'pending'`,
+		payout.GrossAmount, payout.RefundAmount, payout.FeePercent, payout.FeeAmount, payout.NetAmount,
+		adminID, payout.ID)
+	if err != nil {
+		util.Logger.Error("批准结算记录失败", zap.Error(err), zap.Int("payout_id", payout.ID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// MarkPayoutPaid 将已批准的结算记录标记为已打款，返回 false 表示记录不是已批准状态
+func (r *PaymentRepository) MarkPayoutPaid(payoutID, adminID int, reference string, audit *model.AuditEntry) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE project_payouts
+		SET status = 'paid', paid_by = ?, paid_at = NOW(), payment_reference = ?, updated_at = NOW()
+		WHERE id = ? AND status = 'approved'`,
+		adminID, reference, payoutID)
+	if err != nil {
+		util.Logger.Error("标记结算记录已打款失败", zap.Error(err), zap.Int("payout_id", payoutID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// CreateOrderRefund 创建订单退款执行记录
+func (r *PaymentRepository) CreateOrderRefund(refund *model.OrderRefund) error {
+	return insertOrderRefund(r.db, refund)
+}
+
+// CreateOrderRefundTx 在事务中创建退款执行记录
+func (r *PaymentRepository) CreateOrderRefundTx(tx *sql.Tx, refund *model.OrderRefund) error {
+	return insertOrderRefund(tx, refund)
+}
+
+func insertOrderRefund(execer interface {
+	Exec(string, ...interface{}) (sql.Result, error)
+}, refund *model.OrderRefund) error {
+	result, err := execer.Exec(`
+		INSERT INTO order_refunds (
+			order_id, project_id, refund_request_id, order_change_id, provider, payment_intent_id,
+			amount, currency, project_amount, project_currency, status, attempts, next_attempt_at,
+			created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, NOW(), NOW())`,
+		refund.OrderID, refund.ProjectID, refund.RefundRequestID, refund.OrderChangeID, refund.Provider,
+		refund.PaymentIntentID, refund.Amount, refund.Currency, refund.ProjectAmount,
+		refund.ProjectCurrency, refund.Status, refund.NextAttemptAt)
+	if err != nil {
+		util.Logger.Error("创建订单退款记录失败", zap.Error(err), zap.Int("order_id", refund.OrderID))
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	refund.ID = int(id)
+	return nil
+}
+
+// ClaimOrderRefund 占用一次退款执行机会：尝试次数加一并推迟下次执行时间，返回 false 表示已被其他任务占用
+func (r *PaymentRepository) ClaimOrderRefund(refundID, attempts int, leaseUntil time.Time) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE order_refunds
+		SET attempts = attempts + 1, next_attempt_at = ?, updated_at = NOW()
+		WHERE id = ? AND status = 'pending' AND attempts = ?`,
+		leaseUntil, refundID, attempts)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// UpdateOrderRefund 更新订单退款执行结果
+func (r *PaymentRepository) UpdateOrderRefund(refund *model.OrderRefund) error {
+	_, err := r.db.Exec(`
+		UPDATE order_refunds
+		SET status = ?, last_error = ?, gateway_refund_id = ?, next_attempt_at = ?,
+			completed_at = ?, updated_at = NOW()
+		WHERE id = ?`,
+		refund.Status, refund.LastError, refund.GatewayRefundID, refund.NextAttemptAt,
+		refund.CompletedAt, refund.ID)
+	if err != nil {
+		util.Logger.Error("更新订单退款记录失败", zap.Error(err), zap.Int("refund_id", refund.ID))
+	}
+	return err
+}
+
+const orderRefundColumns = `
+		id, order_id, project_id, refund_request_id, order_change_id, provider, payment_intent_id,
+		amount, currency, COALESCE(project_amount, amount), COALESCE(project_currency, currency),
+		status, attempts, COALESCE(last_error, ''), COALESCE(gateway_refund_id, ''),
+		next_attempt_at, completed_at, created_at, updated_at`
+
+// queryOrderRefunds 按 orderRefundColumns 的列顺序查询退款执行记录
+func (r *PaymentRepository) queryOrderRefunds(query string, args ...interface{}) ([]*model.OrderRefund, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := []*model.OrderRefund{}
+	for rows.Next() {
+		refund := &model.OrderRefund{}
+		var requestID, changeID sql.NullInt64
+		var nextAttemptAt, completedAt sql.NullTime
+		err := rows.Scan(
+			&refund.ID, &refund.OrderID, &refund.ProjectID, &requestID, &changeID, &refund.Provider,
+			&refund.PaymentIntentID, &refund.Amount, &refund.Currency, &refund.ProjectAmount,
+			&refund.ProjectCurrency, &refund.Status, &refund.Attempts,
+			&refund.LastError, &refund.GatewayRefundID, &nextAttemptAt, &completedAt,
+			&refund.CreatedAt, &refund.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		refund.Amount.Currency = refund.Currency
+		refund.ProjectAmount.Currency = refund.ProjectCurrency
+		if requestID.Valid {
+			id := int(requestID.Int64)
+			refund.RefundRequestID = &id
+		}
+		if changeID.Valid {
+			id := int(changeID.Int64)
+			refund.OrderChangeID = &id
+		}
+		if nextAttemptAt.Valid {
+			refund.NextAttemptAt = &nextAttemptAt.Time
+		}
+		if completedAt.Valid {
+			refund.CompletedAt = &completedAt.Time
+		}
+		refunds = append(refunds, refund)
+	}
+	return refunds, rows.Err()
+}
+
+// GetOrderRefundByOrder 获取订单最近一次退款执行记录，不存在时返回 nil
+func (r *PaymentRepository) GetOrderRefundByOrder(orderID int) (*model.OrderRefund, error) {
+	refunds, err := r.queryOrderRefunds(`
+		SELECT `+orderRefundColumns+`
+		FROM order_refunds
+		WHERE order_id = ?
+		ORDER BY id DESC
+		LIMIT 1`, orderID)
+	if err != nil || len(refunds) == 0 {
+		return nil, err
+	}
+	return refunds[0], nil
+}
+
+// GetDueOrderRefunds 获取到达重试时间的待执行退款
+func (r *PaymentRepository) GetDueOrderRefunds(limit int) ([]*model.OrderRefund, error) {
+	return r.queryOrderRefunds(`
+		SELECT `+orderRefundColumns+`
+		FROM order_refunds
+		WHERE status = 'pending' AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+		ORDER BY next_attempt_at ASC
+		LIMIT ?`, limit)
+}
+
+// GetFailedProjectsAwaitingRefund 获取仍有订单未处理的众筹失败项目：已扣款订单既未退款也没有批准的退款申请，
+// 或未扣款订单仍未流转为众筹失败
+func (r *PaymentRepository) GetFailedProjectsAwaitingRefund(limit int) ([]int, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT o.project_id
+		FROM orders o
+		JOIN projects p ON p.id = o.project_id
+		WHERE p.status = 'failed'
+			AND (
+				(o.paid_at IS NOT NULL
+					AND o.status NOT IN ('refunded', 'cancelled')
+					AND NOT EXISTS (
+						SELECT 1 FROM refund_requests rr
+						WHERE rr.order_id = o.id AND rr.status = 'approved'))
+				OR (o.paid_at IS NULL AND o.status IN ('pending', 'paid'))
+			)
+		LIMIT ?`, limit)
+	if err != nil {
+		util.Logger.Error("查询待退款的失败项目失败", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projectIDs []int
+	for rows.Next() {
+		var projectID int
+		if err := rows.Scan(&projectID); err != nil {
+			return nil, err
+		}
+		projectIDs = append(projectIDs, projectID)
+	}
+	return projectIDs, rows.Err()
+}
+
+// GetOrderRefundsByProject 获取项目的全部退款执行记录
+func (r *PaymentRepository) GetOrderRefundsByProject(projectID int) ([]*model.OrderRefund, error) {
+	return r.queryOrderRefunds(`
+		SELECT `+orderRefundColumns+`
+		FROM order_refunds
+		WHERE project_id = ?
+		ORDER BY id ASC`, projectID)
+}
+
+// CheckProjectGoalStatus 检查项目第一个目标是否达成
+func (r *PaymentRepository) CheckProjectGoalStatus(projectID int) (bool, error) {
 	query := `
 		SELECT pg.amount <= p.total_amount
 		FROM project_goals pg
@@ -610,25 +1489,18 @@ func (r *PaymentRepository) CheckProjectGoalStatus(projectID int) (bool, error)
 	return isAchieved, nil
 }
 
-// UpdateOrdersToFailedByProject 将项目的所有订单更新为众筹失败状态
-func (r *PaymentRepository) UpdateOrdersToFailedByProject(projectID int) error {
-	query := `
-		UPDATE orders 
-		SET status = 'crowdfunding_failed', 
-			updated_at = NOW() 
-		WHERE project_id = ? 
-		AND status = 'paid'`
-
-	_, err := r.db.Exec(query, projectID)
-	return err
-}
-
 // GetRefundRequestByID 通过ID获取退款申请
 func (r *PaymentRepository) GetRefundRequestByID(requestID int) (*model.RefundRequest, error) {
 	query := `
-		SELECT r.*, o.*
+		SELECT r.id, r.order_id, r.user_id, r.reason, r.status,
+			   COALESCE(r.admin_comment, ''), r.created_at, r.updated_at,
+			   o.id, o.order_number, o.user_id, o.project_id, o.pledge_id,
+			   COALESCE(o.subtotal, o.amount), o.shipping_fee,
+			   o.amount, o.currency, COALESCE(o.original_amount, o.amount),
+			   COALESCE(o.original_currency, o.currency), o.exchange_rate,
+			   o.status, o.created_at, o.updated_at
 		FROM refund_requests r
-		LEFT JOIN orders o ON r.order_id = o.id
+		JOIN orders o ON r.order_id = o.id
 		WHERE r.id = ?`
 
 	var request model.RefundRequest
@@ -637,17 +1509,21 @@ func (r *PaymentRepository) GetRefundRequestByID(requestID int) (*model.RefundRe
 		&request.ID, &request.OrderID, &request.UserID, &request.Reason,
 		&request.Status, &request.AdminComment, &request.CreatedAt,
 		&request.UpdatedAt,
-		&order.ID, &order.UserID, &order.ProjectID, &order.PledgeID,
-		&order.Amount, &order.Status, &order.AddressID,
-		&order.CreatedAt, &order.UpdatedAt)
+		&order.ID, &order.OrderNumber, &order.UserID, &order.ProjectID, &order.PledgeID,
+		&order.Subtotal, &order.ShippingFee,
+		&order.Amount, &order.Currency, &order.OriginalAmount,
+		&order.OriginalCurrency, &order.ExchangeRate,
+		&order.Status, &order.CreatedAt, &order.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
+		util.Logger.Error("查询退款申请失败", zap.Error(err), zap.Int("request_id", requestID))
 		return nil, err
 	}
 
+	order.ApplyCurrency()
 	request.Order = &order
 	return &request, nil
 }
@@ -735,7 +1611,9 @@ func (r *PaymentRepository) GetAllRefundRequests(page, pageSize int) ([]*model.R
 		SELECT r.id, r.order_id, r.user_id, r.reason, r.status, 
 			   COALESCE(r.admin_comment, '') as admin_comment,
 			   r.created_at, r.updated_at,
-			   o.order_number, o.amount, o.project_id,
+			   o.order_number, o.amount, o.currency,
+			   COALESCE(o.original_amount, o.amount), COALESCE(o.original_currency, o.currency),
+			   o.project_id,
 			   u.username, u.email,
 			   p.title as project_title,
 			   p.status as project_status
@@ -764,7 +1642,8 @@ func (r *PaymentRepository) GetAllRefundRequests(page, pageSize int) ([]*model.R
 		err := rows.Scan(
 			&req.ID, &req.OrderID, &req.UserID, &req.Reason, &req.Status,
 			&req.AdminComment, &req.CreatedAt, &req.UpdatedAt,
-			&order.OrderNumber, &order.Amount, &order.ProjectID,
+			&order.OrderNumber, &order.Amount, &order.Currency,
+			&order.OriginalAmount, &order.OriginalCurrency, &order.ProjectID,
 			&user.Username, &user.Email,
 			&projectTitle, &projectStatus)
 		if err != nil {
@@ -772,6 +1651,7 @@ func (r *PaymentRepository) GetAllRefundRequests(page, pageSize int) ([]*model.R
 		}
 
 		order.ID = req.OrderID
+		order.ApplyCurrency()
 		req.Order = &order
 		req.User = &user
 		req.ProjectTitle = projectTitle
@@ -784,3 +1664,398 @@ func (r *PaymentRepository) GetAllRefundRequests(page, pageSize int) ([]*model.R
 
 	return requests, total, nil
 }
+
+// GetPledgesByUser 获取用户的全部支持记录，用于个人数据导出
+func (r *PaymentRepository) GetPledgesByUser(userID int) ([]*model.Pledge, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, project_id, amount, currency,
+			   COALESCE(original_amount, amount), COALESCE(original_currency, currency),
+			   status, address_id, created_at
+		FROM pledges
+		WHERE user_id = ?
+		ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pledges []*model.Pledge
+	for rows.Next() {
+		var p model.Pledge
+		var addressID sql.NullInt64
+		if err := rows.Scan(&p.ID, &p.UserID, &p.ProjectID, &p.Amount, &p.Currency,
+			&p.OriginalAmount, &p.OriginalCurrency, &p.Status, &addressID, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		if addressID.Valid {
+			id := int(addressID.Int64)
+			p.AddressID = &id
+		}
+		p.ApplyCurrency()
+		pledges = append(pledges, &p)
+	}
+	return pledges, rows.Err()
+}
+
+// GetShipmentsByUser 获取用户的全部发货记录，用于个人数据导出
+func (r *PaymentRepository) GetShipmentsByUser(userID int) ([]*model.Shipment, error) {
+	rows, err := r.db.Query(`
+		SELECT id, project_id, user_id, order_id, address_id, status,
+			   COALESCE(tracking_number, ''), COALESCE(shipping_company, ''),
+			   shipped_at, delivered_at, estimated_delivery_at, created_at, updated_at
+		FROM shipments
+		WHERE user_id = ?
+		ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shipments []*model.Shipment
+	for rows.Next() {
+		var s model.Shipment
+		var shippedAt, deliveredAt, estimatedAt sql.NullTime
+		if err := rows.Scan(&s.ID, &s.ProjectID, &s.UserID, &s.OrderID, &s.AddressID, &s.Status,
+			&s.TrackingNumber, &s.ShippingCompany, &shippedAt, &deliveredAt, &estimatedAt,
+			&s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		s.ShippedAt = shippedAt.Time
+		s.DeliveredAt = deliveredAt.Time
+		s.EstimatedDeliveryAt = estimatedAt.Time
+		shipments = append(shipments, &s)
+	}
+	return shipments, rows.Err()
+}
+
+// LockOrderTx 在事务中锁定订单行并读取金额和状态，订单不存在时返回 nil
+func (r *PaymentRepository) LockOrderTx(tx *sql.Tx, orderID int) (*model.Order, error) {
+	var order model.Order
+	var addressID, rewardTierID sql.NullInt64
+	var paidAt sql.NullTime
+	err := tx.QueryRow(`
+		SELECT id, order_number, user_id, project_id, pledge_id,
+			   COALESCE(subtotal, amount), shipping_fee,
+			   amount, currency, COALESCE(original_amount, amount),
+			   COALESCE(original_currency, currency), exchange_rate,
+			   status, address_id, is_reward, reward_tier_id,
+			   COALESCE(payment_provider, ''), COALESCE(payment_intent_id, ''), paid_at,
+			   created_at, updated_at
+		FROM orders
+		WHERE id = ?
+		FOR UPDATE`, orderID).Scan(
+		&order.ID, &order.OrderNumber, &order.UserID, &order.ProjectID, &order.PledgeID,
+		&order.Subtotal, &order.ShippingFee,
+		&order.Amount, &order.Currency, &order.OriginalAmount,
+		&order.OriginalCurrency, &order.ExchangeRate,
+		&order.Status, &addressID, &order.IsReward, &rewardTierID,
+		&order.PaymentProvider, &order.PaymentIntentID, &paidAt,
+		&order.CreatedAt, &order.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("锁定订单失败", zap.Error(err), zap.Int("order_id", orderID))
+		return nil, err
+	}
+	order.ApplyCurrency()
+
+	if addressID.Valid {
+		id := int(addressID.Int64)
+		order.AddressID = &id
+	}
+	if rewardTierID.Valid {
+		id := int(rewardTierID.Int64)
+		order.RewardTierID = &id
+	}
+	if paidAt.Valid {
+		order.PaidAt = &paidAt.Time
+	}
+	return &order, nil
+}
+
+// ReleaseOrderStockTx 在事务中释放订单占用的档位和加购项库存
+func (r *PaymentRepository) ReleaseOrderStockTx(tx *sql.Tx, orderID int) error {
+	return releaseRewardStock(tx, orderID)
+}
+
+// ReplaceOrderItemsTx 在事务中整体替换订单明细
+func (r *PaymentRepository) ReplaceOrderItemsTx(tx *sql.Tx, orderID int, items []model.OrderItem) error {
+	if _, err := tx.Exec(`DELETE FROM order_items WHERE order_id = ?`, orderID); err != nil {
+		util.Logger.Error("删除订单明细失败", zap.Error(err), zap.Int("order_id", orderID))
+		return err
+	}
+	return r.CreateOrderItemsTx(tx, orderID, items)
+}
+
+// UpdateOrderSelectionTx 在事务中更新订单及对应支持记录的档位、地址和金额
+func (r *PaymentRepository) UpdateOrderSelectionTx(tx *sql.Tx, order *model.Order) error {
+	_, err := tx.Exec(`
+		UPDATE orders
+		SET subtotal = ?, shipping_fee = ?, amount = ?, original_amount = ?,
+			is_reward = ?, reward_tier_id = ?, address_id = ?, updated_at = NOW()
+		WHERE id = ?`,
+		order.Subtotal, order.ShippingFee, order.Amount, order.OriginalAmount,
+		order.IsReward, order.RewardTierID, order.AddressID, order.ID)
+	if err != nil {
+		util.Logger.Error("更新订单内容失败", zap.Error(err), zap.Int("order_id", order.ID))
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE pledges
+		SET amount = ?, original_amount = ?, reward_tier_id = ?, address_id = ?
+		WHERE id = ?`,
+		order.Amount, order.OriginalAmount, order.RewardTierID, order.AddressID, order.PledgeID)
+	if err != nil {
+		util.Logger.Error("更新支持记录失败", zap.Error(err), zap.Int("pledge_id", order.PledgeID))
+	}
+	return err
+}
+
+// CancelOrderTx 在事务中将已支付订单标记为已取消：更新支持记录、释放库存并记录状态历史。
+// 订单已不是已支付状态时返回 false
+func (r *PaymentRepository) CancelOrderTx(tx *sql.Tx, orderID int, actor, reason string) (bool, error) {
+	result, err := tx.Exec(`
+		UPDATE orders SET status = 'cancelled', updated_at = NOW()
+		WHERE id = ? AND status = 'paid'`, orderID)
+	if err != nil {
+		util.Logger.Error("取消订单失败", zap.Error(err), zap.Int("order_id", orderID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE pledges SET status = 'cancelled'
+		WHERE id = (SELECT pledge_id FROM orders WHERE id = ?)`, orderID)
+	if err != nil {
+		util.Logger.Error("更新支持记录状态失败", zap.Error(err), zap.Int("order_id", orderID))
+		return false, err
+	}
+	if err := releaseRewardStock(tx, orderID); err != nil {
+		return false, err
+	}
+	if err := insertOrderStatusHistory(tx, orderID, "paid", "cancelled", actor, reason); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AddProjectAmountTx 在事务中调整项目已筹金额和进度
+func (r *PaymentRepository) AddProjectAmountTx(tx *sql.Tx, projectID int, amount model.Money) error {
+	return addProjectAmount(tx, projectID, amount)
+}
+
+// CreateOrderChange 单独记录订单变更，用于补扣款前先保存待确认的变更
+func (r *PaymentRepository) CreateOrderChange(change *model.OrderChange) error {
+	return insertOrderChange(r.db, change)
+}
+
+// CreateOrderChangeTx 在事务中记录订单变更
+func (r *PaymentRepository) CreateOrderChangeTx(tx *sql.Tx, change *model.OrderChange) error {
+	return insertOrderChange(tx, change)
+}
+
+func insertOrderChange(execer interface {
+	Exec(string, ...interface{}) (sql.Result, error)
+}, change *model.OrderChange) error {
+	result, err := execer.Exec(`
+		INSERT INTO order_changes (
+			order_id, change_type, status, actor, old_reward_tier_id, new_reward_tier_id,
+			old_amount, new_amount, amount_delta, original_delta, payment_intent_id, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())`,
+		change.OrderID, change.ChangeType, change.Status, change.Actor, change.OldRewardTierID, change.NewRewardTierID,
+		change.OldAmount, change.NewAmount, change.AmountDelta, change.OriginalDelta, change.PaymentIntentID)
+	if err != nil {
+		util.Logger.Error("记录订单变更失败", zap.Error(err), zap.Int("order_id", change.OrderID))
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	change.ID = int(id)
+	change.CreatedAt = time.Now()
+	return nil
+}
+
+// UpdateOrderChangeIntent 在发起扣款前保存补扣款的支付意图，便于异常中断后对账退回
+func (r *PaymentRepository) UpdateOrderChangeIntent(changeID int, intentID string) error {
+	_, err := r.db.Exec(`
+		UPDATE order_changes SET payment_intent_id = ?
+		WHERE id = ? AND status = 'pending'`, intentID, changeID)
+	if err != nil {
+		util.Logger.Error("保存补扣款支付意图失败", zap.Error(err), zap.Int("order_change_id", changeID))
+	}
+	return err
+}
+
+// UpdateOrderChangeStatus 更新待确认的订单变更状态，返回 false 表示变更已不是待确认状态
+func (r *PaymentRepository) UpdateOrderChangeStatus(changeID int, status string) (bool, error) {
+	return updateOrderChangeStatus(r.db, changeID, status)
+}
+
+// UpdateOrderChangeStatusTx 在事务中更新待确认的订单变更状态
+func (r *PaymentRepository) UpdateOrderChangeStatusTx(tx *sql.Tx, changeID int, status string) (bool, error) {
+	return updateOrderChangeStatus(tx, changeID, status)
+}
+
+func updateOrderChangeStatus(execer interface {
+	Exec(string, ...interface{}) (sql.Result, error)
+}, changeID int, status string) (bool, error) {
+	result, err := execer.Exec(`
+		UPDATE order_changes SET status = ?
+		WHERE id = ? AND status = 'pending'`, status, changeID)
+	if err != nil {
+		util.Logger.Error("更新订单变更状态失败", zap.Error(err), zap.Int("order_change_id", changeID))
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetStalePendingOrderChanges 获取创建时间早于 before 仍待确认的订单变更，用于退回中断的补扣款
+func (r *PaymentRepository) GetStalePendingOrderChanges(before time.Time, limit int) ([]*model.OrderChange, error) {
+	return r.queryOrderChanges(`
+		WHERE c.status = 'pending' AND c.created_at < ?
+		ORDER BY c.id ASC
+		LIMIT ?`, before, limit)
+}
+
+// GetOrderChanges 获取订单的变更记录，按时间先后排列
+func (r *PaymentRepository) GetOrderChanges(orderID int) ([]*model.OrderChange, error) {
+	return r.queryOrderChanges(`
+		WHERE c.order_id = ?
+		ORDER BY c.created_at ASC, c.id ASC`, orderID)
+}
+
+func (r *PaymentRepository) queryOrderChanges(condition string, args ...interface{}) ([]*model.OrderChange, error) {
+	rows, err := r.db.Query(`
+		SELECT c.id, c.order_id, c.change_type, c.status, c.actor, c.old_reward_tier_id, c.new_reward_tier_id,
+			   c.old_amount, c.new_amount, c.amount_delta, c.original_delta,
+			   o.currency, COALESCE(o.original_currency, o.currency), c.payment_intent_id, c.created_at
+		FROM order_changes c
+		JOIN orders o ON o.id = c.order_id
+		`+condition, args...)
+	if err != nil {
+		util.Logger.Error("查询订单变更记录失败", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*model.OrderChange{}
+	for rows.Next() {
+		var change model.OrderChange
+		var oldTierID, newTierID sql.NullInt64
+		var currency, originalCurrency string
+		err := rows.Scan(&change.ID, &change.OrderID, &change.ChangeType, &change.Status, &change.Actor, &oldTierID, &newTierID,
+			&change.OldAmount, &change.NewAmount, &change.AmountDelta, &change.OriginalDelta,
+			&currency, &originalCurrency, &change.PaymentIntentID, &change.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		change.OldAmount.Currency = currency
+		change.NewAmount.Currency = currency
+		change.AmountDelta.Currency = currency
+		change.OriginalDelta.Currency = originalCurrency
+		if oldTierID.Valid {
+			id := int(oldTierID.Int64)
+			change.OldRewardTierID = &id
+		}
+		if newTierID.Valid {
+			id := int(newTierID.Int64)
+			change.NewRewardTierID = &id
+		}
+		changes = append(changes, &change)
+	}
+	return changes, rows.Err()
+}
+
+// GetOrderPaymentIntents 获取订单的各笔扣款及已发起的退款金额，补扣款按时间倒序在前，下单扣款在最后。
+// 下单扣款金额 = 当前原币种金额 - 历次修改的原币种差额
+func (r *PaymentRepository) GetOrderPaymentIntents(orderID int) ([]model.OrderPaymentIntent, error) {
+	rows, err := r.db.Query(`
+		SELECT provider, intent_id, captured, currency
+		FROM (
+			SELECT c.id AS seq, COALESCE(o.payment_provider, '') AS provider, c.payment_intent_id AS intent_id,
+				   c.original_delta AS captured, COALESCE(o.original_currency, o.currency) AS currency
+			FROM order_changes c
+			JOIN orders o ON o.id = c.order_id
+			WHERE c.order_id = ? AND c.payment_intent_id <> '' AND c.status = 'applied'
+			UNION ALL
+			SELECT 0, COALESCE(o.payment_provider, ''), COALESCE(o.payment_intent_id, ''),
+				   COALESCE(o.original_amount, o.amount) - COALESCE((
+					   SELECT SUM(c.original_delta) FROM order_changes c
+					   WHERE c.order_id = o.id AND c.change_type = 'modify' AND c.status = 'applied'
+				   ), 0),
+				   COALESCE(o.original_currency, o.currency)
+			FROM orders o
+			WHERE o.id = ?
+		) intents
+		ORDER BY seq DESC`, orderID, orderID)
+	if err != nil {
+		util.Logger.Error("查询订单扣款失败", zap.Error(err), zap.Int("order_id", orderID))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var intents []model.OrderPaymentIntent
+	for rows.Next() {
+		var intent model.OrderPaymentIntent
+		var currency string
+		if err := rows.Scan(&intent.Provider, &intent.IntentID, &intent.Captured, &currency); err != nil {
+			return nil, err
+		}
+		intent.Captured.Currency = currency
+		intent.Refunded = model.NewMoney(0, currency)
+		intents = append(intents, intent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 执行失败的退款需人工处理，不计入已退款金额
+	refunded, err := r.db.Query(`
+		SELECT payment_intent_id, SUM(amount)
+		FROM order_refunds
+		WHERE order_id = ? AND status <> 'failed'
+		GROUP BY payment_intent_id`, orderID)
+	if err != nil {
+		util.Logger.Error("汇总订单退款失败", zap.Error(err), zap.Int("order_id", orderID))
+		return nil, err
+	}
+	defer refunded.Close()
+
+	for refunded.Next() {
+		var intentID string
+		var amount model.Money
+		if err := refunded.Scan(&intentID, &amount); err != nil {
+			return nil, err
+		}
+		for i := range intents {
+			if intents[i].IntentID == intentID {
+				amount.Currency = intents[i].Refunded.Currency
+				intents[i].Refunded = amount
+				break
+			}
+		}
+	}
+	return intents, refunded.Err()
+}
+
+// HasPendingOrderRefunds 判断订单是否还有待执行的退款
+func (r *PaymentRepository) HasPendingOrderRefunds(orderID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM order_refunds WHERE order_id = ? AND status = 'pending')`, orderID).Scan(&exists)
+	return exists, err
+}
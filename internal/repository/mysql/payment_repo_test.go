@@ -0,0 +1,117 @@
+package mysql
+
+import (
+	"context"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// recordingConnector 记录执行过的语句，每条更新都影响一行；不支持查询
+type recordingConnector struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (c *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return recordingConn{c}, nil
+}
+func (c *recordingConnector) Driver() driver.Driver { return nil }
+
+func (c *recordingConnector) executed(fragment string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, query := range c.queries {
+		if strings.Contains(query, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+type recordingConn struct{ c *recordingConnector }
+
+func (conn recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{conn.c, query}, nil
+}
+func (recordingConn) Close() error              { return nil }
+func (recordingConn) Begin() (driver.Tx, error) { return recordingConn{}, nil }
+func (recordingConn) Commit() error             { return nil }
+func (recordingConn) Rollback() error           { return nil }
+
+type recordingStmt struct {
+	c     *recordingConnector
+	query string
+}
+
+func (recordingStmt) Close() error  { return nil }
+func (recordingStmt) NumInput() int { return -1 }
+func (s recordingStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.c.mu.Lock()
+	s.c.queries = append(s.c.queries, s.query)
+	s.c.mu.Unlock()
+	return driver.RowsAffected(1), nil
+}
+func (recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("recording: 不支持查询")
+}
+
+func newRecordingPaymentRepository(t *testing.T) (*PaymentRepository, *recordingConnector) {
+	util.Logger = zap.NewNop()
+	connector := &recordingConnector{}
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { db.Close() })
+	return NewPaymentRepository(db), connector
+}
+
+// TestTransitionOrderStatusReleasesRewardStock 测试订单退款或失败时在同一事务中释放档位和加购项库存
+func TestTransitionOrderStatusReleasesRewardStock(t *testing.T) {
+	for _, to := range []string{"refunded", "failed"} {
+		repo, recorded := newRecordingPaymentRepository(t)
+		updated, err := repo.TransitionOrderStatus(1, "paid", to, "system", "")
+		assert.NoError(t, err)
+		assert.True(t, updated)
+		assert.True(t, recorded.executed("UPDATE reward_tiers"), to)
+		assert.True(t, recorded.executed("UPDATE reward_add_ons"), to)
+	}
+
+	// 其他流转不释放库存
+	repo, recorded := newRecordingPaymentRepository(t)
+	_, err := repo.TransitionOrderStatus(1, "paid", "shipped", "system", "")
+	assert.NoError(t, err)
+	assert.False(t, recorded.executed("UPDATE reward_tiers"))
+	assert.False(t, recorded.executed("UPDATE reward_add_ons"))
+}
+
+// TestMarkOrderPaymentFailedReleasesRewardStock 测试支付失败时释放订单占用的库存
+func TestMarkOrderPaymentFailedReleasesRewardStock(t *testing.T) {
+	repo, recorded := newRecordingPaymentRepository(t)
+	updated, err := repo.MarkOrderPaymentFailed(1, "system", "declined")
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	assert.True(t, recorded.executed("UPDATE reward_tiers"))
+	assert.True(t, recorded.executed("UPDATE reward_add_ons"))
+}
+
+// TestCancelOrderTxReleasesRewardStock 测试众筹期间取消订单时释放订单占用的库存
+func TestCancelOrderTxReleasesRewardStock(t *testing.T) {
+	repo, recorded := newRecordingPaymentRepository(t)
+	tx, err := repo.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	cancelled, err := repo.CancelOrderTx(tx, 1, "user:2", "支持者取消支持")
+	assert.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.True(t, recorded.executed("UPDATE reward_tiers"))
+	assert.True(t, recorded.executed("UPDATE reward_add_ons"))
+}
@@ -6,7 +6,9 @@ import (
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/util"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -38,16 +40,16 @@ func (r *ProjectRepository) BeginTx() (*sql.Tx, error) {
 func (r *ProjectRepository) CreateProjectTx(tx *sql.Tx, project *model.Project, goals []model.ProjectGoal, images []model.ProjectImage) error {
 	util.Logger.Info("开始创建新项目",
 		zap.String("title", project.Title),
-		zap.Float64("min_reward_amount", project.MinRewardAmount))
+		zap.Stringer("min_reward_amount", project.MinRewardAmount))
 
 	// 插入项目
 	result, err := tx.Exec(`
 		INSERT INTO projects (
 			title, description, creator_id, status, 
-			created_at, updated_at, end_date, min_reward_amount
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			created_at, updated_at, end_date, min_reward_amount, currency
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, project.Title, project.Description, project.CreatorID, project.Status,
-		project.CreatedAt, project.UpdatedAt, project.EndDate, project.MinRewardAmount)
+		project.CreatedAt, project.UpdatedAt, project.EndDate, project.MinRewardAmount, project.Currency)
 	if err != nil {
 		util.Logger.Error("插入项目失败", zap.Error(err))
 		return err
@@ -100,7 +102,7 @@ func (r *ProjectRepository) CreateProjectTx(tx *sql.Tx, project *model.Project,
 
 	util.Logger.Info("项目创建成功",
 		zap.Int("project_id", int(projectID)),
-		zap.Float64("min_reward_amount", project.MinRewardAmount))
+		zap.Stringer("min_reward_amount", project.MinRewardAmount))
 	return nil
 }
 
@@ -112,7 +114,7 @@ func (r *ProjectRepository) GetProjectByID(id int) (*model.Project, error) {
 	query := `
 		SELECT p.id, p.title, p.description, p.creator_id, p.status, 
 			   p.total_amount, p.total_goal_amount, p.progress,
-			   p.min_reward_amount,
+			   p.min_reward_amount, p.currency,
 			   p.created_at, p.updated_at, p.end_date, p.category_id,
 			   u.username as creator_username
 		FROM projects p
@@ -130,6 +132,7 @@ func (r *ProjectRepository) GetProjectByID(id int) (*model.Project, error) {
 		&project.TotalGoalAmount,
 		&project.Progress,
 		&project.MinRewardAmount,
+		&project.Currency,
 		&project.CreatedAt,
 		&project.UpdatedAt,
 		&project.EndDate,
@@ -146,6 +149,8 @@ func (r *ProjectRepository) GetProjectByID(id int) (*model.Project, error) {
 		return nil, err
 	}
 
+	project.SetCurrency(project.Currency)
+
 	// 设置创建者信息
 	creator.ID = project.CreatorID
 	project.Creator = &creator
@@ -153,7 +158,7 @@ func (r *ProjectRepository) GetProjectByID(id int) (*model.Project, error) {
 	util.Logger.Info("成功获取项目",
 		zap.Int("project_id", project.ID),
 		zap.String("title", project.Title),
-		zap.Float64("min_reward_amount", project.MinRewardAmount))
+		zap.Stringer("min_reward_amount", project.MinRewardAmount))
 
 	return &project, nil
 }
@@ -169,13 +174,12 @@ func (r *ProjectRepository) UpdateProject(project *model.Project, goals []model.
 	}
 	defer tx.Rollback()
 
-	// 更新项目基本信息
+	// 更新项目基本信息，状态只能通过项目状态流转修改，已筹金额只能由支付确认修改
 	_, err = tx.Exec(`
 		UPDATE projects
-		SET title = ?, description = ?, status = ?, total_amount = ?, updated_at = ?, end_date = ?
+		SET title = ?, description = ?, updated_at = ?, end_date = ?
 		WHERE id = ?
-	`, project.Title, project.Description, project.Status, project.TotalAmount,
-		project.UpdatedAt, project.EndDate, project.ID)
+	`, project.Title, project.Description, project.UpdatedAt, project.EndDate, project.ID)
 	if err != nil {
 		util.Logger.Error("更新项目基本信息失败", zap.Error(err), zap.Int("project_id", project.ID))
 		return err
@@ -219,7 +223,7 @@ func (r *ProjectRepository) ListProjects(page, pageSize int) ([]model.Project, e
 		SELECT p.id, p.title, p.description, p.creator_id, p.status, 
 			   p.total_amount, 
 			   (SELECT SUM(amount) FROM project_goals WHERE project_id = p.id) as total_goal_amount,
-			   p.created_at, p.updated_at, p.end_date, 
+			   p.currency, p.created_at, p.updated_at, p.end_date, 
 			   pi.image_url AS primary_image
 		FROM projects p
 		LEFT JOIN (
@@ -227,6 +231,7 @@ func (r *ProjectRepository) ListProjects(page, pageSize int) ([]model.Project, e
 			FROM project_images
 			WHERE is_primary = true
 		) pi ON p.id = pi.project_id
+		WHERE p.status NOT IN ('draft', 'pending_review', 'rejected') -- 未公开的项目只有创建者可见
 		ORDER BY p.created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -240,25 +245,17 @@ func (r *ProjectRepository) ListProjects(page, pageSize int) ([]model.Project, e
 	for rows.Next() {
 		var p model.Project
 		var primaryImage sql.NullString
-		var totalGoalAmount sql.NullFloat64
 		err := rows.Scan(
 			&p.ID, &p.Title, &p.Description, &p.CreatorID, &p.Status,
-			&p.TotalAmount, &totalGoalAmount,
-			&p.CreatedAt, &p.UpdatedAt, &p.EndDate, &primaryImage,
+			&p.TotalAmount, &p.TotalGoalAmount,
+			&p.Currency, &p.CreatedAt, &p.UpdatedAt, &p.EndDate, &primaryImage,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		// 设置总目标金额
-		if totalGoalAmount.Valid {
-			p.TotalGoalAmount = totalGoalAmount.Float64
-		}
-
 		// 计算总体进度
-		if p.TotalGoalAmount > 0 {
-			p.Progress = (p.TotalAmount / p.TotalGoalAmount) * 100
-		}
+		p.Progress = model.ProgressPercent(p.TotalAmount, p.TotalGoalAmount)
 
 		// 获取项目目标
 		goals, err := r.GetProjectGoals(p.ID)
@@ -268,12 +265,13 @@ func (r *ProjectRepository) ListProjects(page, pageSize int) ([]model.Project, e
 
 		// 计算每个目标的进度
 		for i := range goals {
-			if goals[i].Amount > 0 {
-				goals[i].Progress = (p.TotalAmount / goals[i].Amount) * 100
-				goals[i].IsReached = p.TotalAmount >= goals[i].Amount
+			if goals[i].Amount.IsPositive() {
+				goals[i].Progress = model.ProgressPercent(p.TotalAmount, goals[i].Amount)
+				goals[i].IsReached = p.TotalAmount.Cmp(goals[i].Amount) >= 0
 			}
 		}
 		p.Goals = goals
+		p.SetCurrency(p.Currency)
 
 		if primaryImage.Valid {
 			p.PrimaryImage = config.AppConfig.BackendURL + "/uploads/" + primaryImage.String
@@ -289,12 +287,13 @@ func (r *ProjectRepository) CreatePledge(pledge *model.Pledge) error {
 	util.Logger.Info("开始创建支持记录",
 		zap.Int("user_id", pledge.UserID),
 		zap.Int("project_id", pledge.ProjectID),
-		zap.Float64("amount", pledge.Amount))
+		zap.Stringer("amount", pledge.Amount))
 
 	_, err := r.db.Exec(`
-		INSERT INTO pledges (user_id, project_id, amount, status, created_at)
-		VALUES (?, ?, ?, ?, ?)
-	`, pledge.UserID, pledge.ProjectID, pledge.Amount, pledge.Status, pledge.CreatedAt)
+		INSERT INTO pledges (user_id, project_id, amount, currency, original_amount, original_currency, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, pledge.UserID, pledge.ProjectID, pledge.Amount, pledge.Currency,
+		pledge.OriginalAmount, pledge.OriginalCurrency, pledge.Status, pledge.CreatedAt)
 	if err != nil {
 		util.Logger.Error("创建支持记录失败", zap.Error(err))
 		return err
@@ -304,39 +303,165 @@ func (r *ProjectRepository) CreatePledge(pledge *model.Pledge) error {
 	return nil
 }
 
-// UpdateProjectStatus 更新项目状态
-func (r *ProjectRepository) UpdateProjectStatus(project *model.Project) error {
+// TransitionProjectStatus 在项目仍处于 from 状态时将其更新为 to
+// 返回 false 表示项目当前状态已不是 from
+func (r *ProjectRepository) TransitionProjectStatus(projectID int, from, to string, audit *model.AuditEntry) (bool, error) {
 	util.Logger.Info("开始更新项目状态",
-		zap.Int("project_id", project.ID),
-		zap.String("new_status", project.Status))
+		zap.Int("project_id", projectID),
+		zap.String("from", from),
+		zap.String("to", to))
 
-	// 直接更新项目状态
-	query := `
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	updated, err := updateProjectStatusTx(tx, projectID, from, to)
+	if err != nil || !updated {
+		return false, err
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ReviewProjectStatus 在项目仍处于 from 状态时将其更新为 to，并在同一事务中写入审核记录
+// 返回 false 表示项目当前状态已不是 from
+func (r *ProjectRepository) ReviewProjectStatus(projectID int, from, to string, review *model.ProjectReview, audit *model.AuditEntry) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	updated, err := updateProjectStatusTx(tx, projectID, from, to)
+	if err != nil || !updated {
+		return false, err
+	}
+
+	fieldComments, err := json.Marshal(review.FieldComments)
+	if err != nil {
+		return false, err
+	}
+	result, err := tx.Exec(`
+		INSERT INTO project_reviews (project_id, reviewer_id, decision, comment, field_comments, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		projectID, review.ReviewerID, review.Decision, review.Comment, string(fieldComments), review.CreatedAt)
+	if err != nil {
+		util.Logger.Error("写入项目审核记录失败", zap.Error(err), zap.Int("project_id", projectID))
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	review.ID = int(id)
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// updateProjectStatusTx 按当前状态条件更新项目状态，返回 false 表示项目当前状态已不是 from
+func updateProjectStatusTx(tx *sql.Tx, projectID int, from, to string) (bool, error) {
+	result, err := tx.Exec(`
 		UPDATE projects 
 		SET status = ?, updated_at = NOW() 
-		WHERE id = ?`
-
-	result, err := r.db.Exec(query, project.Status, project.ID)
+		WHERE id = ? AND status = ?`, to, projectID, from)
 	if err != nil {
 		util.Logger.Error("更新项目状态失败", zap.Error(err))
-		return err
+		return false, err
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		util.Logger.Error("获取影响行数失败", zap.Error(err))
-		return err
+		return false, err
 	}
+	return rowsAffected > 0, nil
+}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("project not found or status not changed")
+// GetProjectReviews 获取项目的审核记录，按时间倒序
+func (r *ProjectRepository) GetProjectReviews(projectID int) ([]*model.ProjectReview, error) {
+	rows, err := r.db.Query(`
+		SELECT id, project_id, reviewer_id, decision, comment, field_comments, created_at
+		FROM project_reviews
+		WHERE project_id = ?
+		ORDER BY created_at DESC, id DESC`, projectID)
+	if err != nil {
+		util.Logger.Error("查询项目审核记录失败", zap.Error(err), zap.Int("project_id", projectID))
+		return nil, err
 	}
+	defer rows.Close()
 
-	util.Logger.Info("项目状态更新成功",
-		zap.Int("project_id", project.ID),
-		zap.String("status", project.Status))
+	reviews := []*model.ProjectReview{}
+	for rows.Next() {
+		var review model.ProjectReview
+		var fieldComments []byte
+		if err := rows.Scan(&review.ID, &review.ProjectID, &review.ReviewerID, &review.Decision,
+			&review.Comment, &fieldComments, &review.CreatedAt); err != nil {
+			return nil, err
+		}
+		review.FieldComments = []model.ProjectFieldComment{}
+		if len(fieldComments) > 0 {
+			if err := json.Unmarshal(fieldComments, &review.FieldComments); err != nil {
+				return nil, err
+			}
+		}
+		reviews = append(reviews, &review)
+	}
+	return reviews, rows.Err()
+}
 
-	return nil
+// MarkReachedGoals 按项目当前筹款总额更新各目标的达成状态和进度，返回已达成的目标数
+func (r *ProjectRepository) MarkReachedGoals(projectID int) (int, error) {
+	var totalAmount model.Money
+	err := r.db.QueryRow(`SELECT total_amount FROM projects WHERE id = ?`, projectID).Scan(&totalAmount)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := r.db.Query(`SELECT id, amount FROM project_goals WHERE project_id = ?`, projectID)
+	if err != nil {
+		return 0, err
+	}
+	type goalAmount struct {
+		id     int
+		amount model.Money
+	}
+	var goals []goalAmount
+	for rows.Next() {
+		var goal goalAmount
+		if err := rows.Scan(&goal.id, &goal.amount); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		goals = append(goals, goal)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	reached := 0
+	for _, goal := range goals {
+		isReached := totalAmount.Cmp(goal.amount) >= 0
+		progress := math.Min(model.ProgressPercent(totalAmount, goal.amount), 100)
+		_, err := r.db.Exec(`UPDATE project_goals SET is_reached = ?, progress = ? WHERE id = ?`,
+			isReached, progress, goal.id)
+		if err != nil {
+			util.Logger.Error("更新项目目标达成状态失败", zap.Error(err), zap.Int("goal_id", goal.id))
+			return 0, err
+		}
+		if isReached {
+			reached++
+		}
+	}
+	return reached, nil
 }
 
 // GetProjectGoals 获取项目目标
@@ -442,7 +567,7 @@ func (r *ProjectRepository) GetProjectImages(projectID int) ([]model.ProjectImag
 func (r *ProjectRepository) SearchProjects(filters model.ProjectFilters, page, pageSize int) ([]model.Project, int, error) {
 	util.Logger.Info("开始搜索目", zap.Any("filters", filters), zap.Int("page", page), zap.Int("pageSize", pageSize))
 
-	query := `SELECT p.id, p.title, p.description, p.creator_id, p.status, p.total_amount, p.created_at, p.updated_at, p.end_date
+	query := `SELECT p.id, p.title, p.description, p.creator_id, p.status, p.total_amount, p.currency, p.created_at, p.updated_at, p.end_date
 			  FROM projects p
 			  LEFT JOIN project_tag_relations ptr ON p.id = ptr.project_id
 			  WHERE 1=1`
@@ -464,17 +589,19 @@ func (r *ProjectRepository) SearchProjects(filters model.ProjectFilters, page, p
 		args = append(args, filters.Category)
 	}
 
+	// 未公开的项目只有创建者可见，不出现在搜索结果中
+	conditions = append(conditions, "p.status NOT IN ('draft', 'pending_review', 'rejected')")
 	if filters.Status != "" {
 		conditions = append(conditions, "p.status = ?")
 		args = append(args, filters.Status)
 	}
 
-	if filters.MinAmount > 0 {
+	if filters.MinAmount.IsPositive() {
 		conditions = append(conditions, "p.total_amount >= ?")
 		args = append(args, filters.MinAmount)
 	}
 
-	if filters.MaxAmount > 0 {
+	if filters.MaxAmount.IsPositive() {
 		conditions = append(conditions, "p.total_amount <= ?")
 		args = append(args, filters.MaxAmount)
 	}
@@ -527,12 +654,13 @@ func (r *ProjectRepository) SearchProjects(filters model.ProjectFilters, page, p
 		var p model.Project
 		err := rows.Scan(
 			&p.ID, &p.Title, &p.Description, &p.CreatorID, &p.Status,
-			&p.TotalAmount, &p.CreatedAt, &p.UpdatedAt, &p.EndDate,
+			&p.TotalAmount, &p.Currency, &p.CreatedAt, &p.UpdatedAt, &p.EndDate,
 		)
 		if err != nil {
 			util.Logger.Error("扫描项目数据失败", zap.Error(err))
 			return nil, 0, err
 		}
+		p.SetCurrency(p.Currency)
 		projects = append(projects, p)
 	}
 
@@ -541,11 +669,17 @@ func (r *ProjectRepository) SearchProjects(filters model.ProjectFilters, page, p
 }
 
 // CreateCategory 创建项目分类
-func (r *ProjectRepository) CreateCategory(category *model.ProjectCategory) error {
+func (r *ProjectRepository) CreateCategory(category *model.ProjectCategory, audit *model.AuditEntry) error {
 	util.Logger.Info("开始创建项目分类", zap.String("name", category.Name))
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO project_categories (name) VALUES (?)`
-	result, err := r.db.Exec(query, category.Name)
+	result, err := tx.Exec(query, category.Name)
 	if err != nil {
 		util.Logger.Error("创建项目分类失败", zap.Error(err))
 		return err
@@ -556,6 +690,15 @@ func (r *ProjectRepository) CreateCategory(category *model.ProjectCategory) erro
 		util.Logger.Error("获取新分类ID失败", zap.Error(err))
 		return err
 	}
+	if audit != nil {
+		audit.TargetID = int(id)
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 	category.ID = int(id)
 
 	util.Logger.Info("项目分类创建成功", zap.Int("category_id", category.ID))
@@ -605,11 +748,17 @@ func (r *ProjectRepository) GetCategories() ([]model.ProjectCategory, error) {
 }
 
 // CreateTag 创建项标签
-func (r *ProjectRepository) CreateTag(tag *model.ProjectTag) error {
+func (r *ProjectRepository) CreateTag(tag *model.ProjectTag, audit *model.AuditEntry) error {
 	util.Logger.Info("开始创建项目标签", zap.String("name", tag.Name))
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO project_tags (name) VALUES (?)`
-	result, err := r.db.Exec(query, tag.Name)
+	result, err := tx.Exec(query, tag.Name)
 	if err != nil {
 		util.Logger.Error("创建项目标签失败", zap.Error(err))
 		return err
@@ -620,6 +769,15 @@ func (r *ProjectRepository) CreateTag(tag *model.ProjectTag) error {
 		util.Logger.Error("获取新标签ID败", zap.Error(err))
 		return err
 	}
+	if audit != nil {
+		audit.TargetID = int(id)
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 	tag.ID = int(id)
 
 	util.Logger.Info("目标签创建成功", zap.Int("tag_id", tag.ID))
@@ -805,12 +963,12 @@ func (r *ProjectRepository) GetProjectComments(projectID int, page, pageSize int
 	return comments, nil
 }
 
-// CreateShipment 创建发货记录
-func (r *ProjectRepository) CreateShipment(shipment *model.Shipment) error {
-	query := `INSERT INTO shipments (project_id, user_id, address_id, status, created_at) 
-			  VALUES (?, ?, ?, ?, NOW())`
-	result, err := r.db.Exec(query,
-		shipment.ProjectID, shipment.UserID, shipment.AddressID, shipment.Status)
+// CreateShipmentTx 在事务中创建发货记录并记录审计日志
+func (r *ProjectRepository) CreateShipmentTx(tx *sql.Tx, shipment *model.Shipment, audit *model.AuditEntry) error {
+	query := `INSERT INTO shipments (project_id, user_id, order_id, address_id, status, created_at) 
+			  VALUES (?, ?, ?, ?, ?, NOW())`
+	result, err := tx.Exec(query,
+		shipment.ProjectID, shipment.UserID, shipment.OrderID, shipment.AddressID, shipment.Status)
 	if err != nil {
 		return err
 	}
@@ -819,12 +977,24 @@ func (r *ProjectRepository) CreateShipment(shipment *model.Shipment) error {
 	if err != nil {
 		return err
 	}
+	if audit != nil {
+		audit.TargetID = int(id)
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
 	shipment.ID = int(id)
 	return nil
 }
 
 // UpdateShipment 更新发货记录
-func (r *ProjectRepository) UpdateShipment(shipment *model.Shipment) error {
+func (r *ProjectRepository) UpdateShipment(shipment *model.Shipment, audit *model.AuditEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE shipments 
 		SET status = ?, 
@@ -835,14 +1005,20 @@ func (r *ProjectRepository) UpdateShipment(shipment *model.Shipment) error {
 			updated_at = NOW()
 		WHERE id = ?`
 
-	_, err := r.db.Exec(query,
+	_, err = tx.Exec(query,
 		shipment.Status,
 		shipment.TrackingNumber,
 		shipment.ShippingCompany,
 		shipment.EstimatedDeliveryAt,
 		shipment.ID)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetShipmentsByProject 获取项目的所有发货记录
@@ -869,7 +1045,7 @@ func (r *ProjectRepository) GetShipmentsByProject(projectID int) ([]*model.Shipm
 			&s.Status, &s.TrackingNumber, &s.ShippingCompany,
 			&s.ShippedAt, &s.DeliveredAt, &s.EstimatedDeliveryAt,
 			&s.CreatedAt, &s.UpdatedAt,
-			&a.ID, &a.UserID, &a.ReceiverName, &a.Phone,
+			&a.ID, &a.UserID, &a.ReceiverName, &a.Phone, &a.Country,
 			&a.Province, &a.City, &a.District, &a.DetailAddress,
 			&a.IsDefault, &a.CreatedAt, &a.UpdatedAt)
 		if err != nil {
@@ -905,7 +1081,7 @@ func (r *ProjectRepository) GetShipmentsByUser(userID int) ([]*model.Shipment, e
 			&s.Status, &s.TrackingNumber, &s.ShippingCompany,
 			&s.ShippedAt, &s.DeliveredAt, &s.EstimatedDeliveryAt,
 			&s.CreatedAt, &s.UpdatedAt,
-			&a.ID, &a.UserID, &a.ReceiverName, &a.Phone,
+			&a.ID, &a.UserID, &a.ReceiverName, &a.Phone, &a.Country,
 			&a.Province, &a.City, &a.District, &a.DetailAddress,
 			&a.IsDefault, &a.CreatedAt, &a.UpdatedAt)
 		if err != nil {
@@ -920,7 +1096,13 @@ func (r *ProjectRepository) GetShipmentsByUser(userID int) ([]*model.Shipment, e
 // GetProjectSuccessfulPledgers 获取项目的成功支持者
 func (r *ProjectRepository) GetProjectSuccessfulPledgers(projectID int) ([]*model.Pledge, error) {
 	query := `
-		SELECT p.*, a.*
+		SELECT p.id, p.user_id, p.project_id, p.amount, p.currency,
+			   COALESCE(p.original_amount, p.amount), COALESCE(p.original_currency, p.currency),
+			   p.status, p.address_id, p.created_at,
+			   COALESCE(a.id, 0), COALESCE(a.user_id, 0), COALESCE(a.receiver_name, ''), COALESCE(a.phone, ''),
+			   COALESCE(a.country, ''), COALESCE(a.province, ''), COALESCE(a.city, ''), COALESCE(a.district, ''),
+			   COALESCE(a.detail_address, ''), COALESCE(a.is_default, FALSE),
+			   COALESCE(a.created_at, p.created_at), COALESCE(a.updated_at, p.created_at)
 		FROM pledges p
 		LEFT JOIN user_addresses a ON p.address_id = a.id
 		WHERE p.project_id = ? AND p.status = 'completed'
@@ -937,21 +1119,25 @@ func (r *ProjectRepository) GetProjectSuccessfulPledgers(projectID int) ([]*mode
 		var p model.Pledge
 		var a model.UserAddress
 		err := rows.Scan(
-			&p.ID, &p.UserID, &p.ProjectID, &p.Amount, &p.Status,
-			&p.AddressID, &p.CreatedAt,
+			&p.ID, &p.UserID, &p.ProjectID, &p.Amount, &p.Currency,
+			&p.OriginalAmount, &p.OriginalCurrency, &p.Status, &p.AddressID, &p.CreatedAt,
 			&a.ID, &a.UserID, &a.ReceiverName, &a.Phone,
-			&a.Province, &a.City, &a.District, &a.DetailAddress,
+			&a.Country, &a.Province, &a.City, &a.District, &a.DetailAddress,
 			&a.IsDefault, &a.CreatedAt, &a.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
-		p.Address = &a
+		p.ApplyCurrency()
+		// 无需发货的支持没有收货地址
+		if p.AddressID != nil {
+			p.Address = &a
+		}
 		pledges = append(pledges, &p)
 	}
 	return pledges, nil
 }
 
-// GetExpiredActiveProjects 获取所有已过期但状态未更新的项目
+// GetExpiredActiveProjects 获取所有已到期但仍处于众筹中的项目，成功与否由结算任务判断
 func (r *ProjectRepository) GetExpiredActiveProjects() ([]*model.Project, error) {
 	util.Logger.Info("开始查询过期项目")
 
@@ -959,7 +1145,7 @@ func (r *ProjectRepository) GetExpiredActiveProjects() ([]*model.Project, error)
 	query := `
 		SELECT p.id, p.title, p.description, p.creator_id, p.status, 
 			   p.total_amount, p.total_goal_amount, p.progress,
-			   p.min_reward_amount, p.created_at, p.updated_at, p.end_date, p.category_id,
+			   p.min_reward_amount, p.currency, p.created_at, p.updated_at, p.end_date, p.category_id,
 			   COALESCE(SUM(o.amount), 0) as total_pledged,
 			   (
 				   SELECT MIN(pg.amount)
@@ -969,15 +1155,11 @@ func (r *ProjectRepository) GetExpiredActiveProjects() ([]*model.Project, error)
 		FROM projects p
 		LEFT JOIN orders o ON p.id = o.project_id 
 			AND o.status = 'paid'
-		WHERE p.status NOT IN ('failed', 'success', 'rejected')
+		WHERE p.status = 'active'
 			AND p.end_date <= NOW()
 		GROUP BY p.id, p.title, p.description, p.creator_id, p.status,
 				 p.total_amount, p.total_goal_amount, p.progress,
-				 p.min_reward_amount, p.created_at, p.updated_at, p.end_date, p.category_id
-		HAVING (
-			first_goal_amount IS NOT NULL 
-				AND total_pledged < first_goal_amount
-		)`
+				 p.min_reward_amount, p.currency, p.created_at, p.updated_at, p.end_date, p.category_id`
 
 	util.Logger.Debug("执行主查询SQL",
 		zap.String("query", query),
@@ -993,7 +1175,7 @@ func (r *ProjectRepository) GetExpiredActiveProjects() ([]*model.Project, error)
 	var projects []*model.Project
 	for rows.Next() {
 		var project model.Project
-		var totalPledged, firstGoalAmount sql.NullFloat64
+		var totalPledged, firstGoalAmount model.Money
 		err := rows.Scan(
 			&project.ID,
 			&project.Title,
@@ -1004,6 +1186,7 @@ func (r *ProjectRepository) GetExpiredActiveProjects() ([]*model.Project, error)
 			&project.TotalGoalAmount,
 			&project.Progress,
 			&project.MinRewardAmount,
+			&project.Currency,
 			&project.CreatedAt,
 			&project.UpdatedAt,
 			&project.EndDate,
@@ -1015,13 +1198,13 @@ func (r *ProjectRepository) GetExpiredActiveProjects() ([]*model.Project, error)
 			util.Logger.Error("扫描项目数据失败", zap.Error(err))
 			return nil, err
 		}
+		project.SetCurrency(project.Currency)
 
-		project.Status = "failed" // 设置状态为失败
 		util.Logger.Info("找到过期项目",
 			zap.Int("project_id", project.ID),
 			zap.String("title", project.Title),
-			zap.Float64("total_pledged", totalPledged.Float64),
-			zap.Float64("first_goal_amount", firstGoalAmount.Float64),
+			zap.Stringer("total_pledged", totalPledged),
+			zap.Stringer("first_goal_amount", firstGoalAmount),
 			zap.Time("end_date", project.EndDate),
 			zap.Time("current_time", time.Now()),
 			zap.String("end_date_str", project.EndDate.Format("2006-01-02 15:04:05")))
@@ -1034,6 +1217,52 @@ func (r *ProjectRepository) GetExpiredActiveProjects() ([]*model.Project, error)
 	return projects, nil
 }
 
+// GetUnsettledCompletedProjects 获取已完成但尚未生成结算记录的项目，供结算任务重试
+func (r *ProjectRepository) GetUnsettledCompletedProjects() ([]*model.Project, error) {
+	query := `
+		SELECT p.id, p.title, p.description, p.creator_id, p.status,
+			   p.total_amount, p.total_goal_amount, p.progress,
+			   p.min_reward_amount, p.currency, p.created_at, p.updated_at, p.end_date, p.category_id
+		FROM projects p
+		WHERE p.status = 'completed'
+			AND NOT EXISTS (SELECT 1 FROM project_payouts pp WHERE pp.project_id = p.id)`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		util.Logger.Error("查询未结算项目失败", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []*model.Project
+	for rows.Next() {
+		var project model.Project
+		err := rows.Scan(
+			&project.ID,
+			&project.Title,
+			&project.Description,
+			&project.CreatorID,
+			&project.Status,
+			&project.TotalAmount,
+			&project.TotalGoalAmount,
+			&project.Progress,
+			&project.MinRewardAmount,
+			&project.Currency,
+			&project.CreatedAt,
+			&project.UpdatedAt,
+			&project.EndDate,
+			&project.CategoryID,
+		)
+		if err != nil {
+			util.Logger.Error("扫描项目数据失败", zap.Error(err))
+			return nil, err
+		}
+		project.SetCurrency(project.Currency)
+		projects = append(projects, &project)
+	}
+	return projects, rows.Err()
+}
+
 // GetProjectsForAdmin 获取项目列表（管理员视图）
 func (r *ProjectRepository) GetProjectsForAdmin(page, pageSize int, status, search string) ([]*model.Project, int, error) {
 	// 构建基础查询
@@ -1045,7 +1274,7 @@ func (r *ProjectRepository) GetProjectsForAdmin(page, pageSize int, status, sear
 			    WHERE project_id = p.id 
 			    ORDER BY amount DESC 
 			    LIMIT 1) as total_goal_amount,
-			   p.progress,
+			   p.progress, p.currency,
 			   p.created_at, p.updated_at,
 			   u.username as creator_username, u.email as creator_email
 		FROM projects p
@@ -1102,12 +1331,13 @@ func (r *ProjectRepository) GetProjectsForAdmin(page, pageSize int, status, sear
 		err := rows.Scan(
 			&p.ID, &p.Title, &p.Description, &p.CreatorID,
 			&p.Status, &p.TotalAmount, &p.TotalGoalAmount,
-			&p.Progress, &p.CreatedAt, &p.UpdatedAt,
+			&p.Progress, &p.Currency, &p.CreatedAt, &p.UpdatedAt,
 			&creator.Username, &creator.Email,
 		)
 		if err != nil {
 			return nil, 0, err
 		}
+		p.SetCurrency(p.Currency)
 
 		// 设置创建者信息
 		creator.ID = p.CreatorID
@@ -1120,7 +1350,7 @@ func (r *ProjectRepository) GetProjectsForAdmin(page, pageSize int, status, sear
 }
 
 // DeleteProject 删除项目及其相关数据
-func (r *ProjectRepository) DeleteProject(projectID int) error {
+func (r *ProjectRepository) DeleteProject(projectID int, audit *model.AuditEntry) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -1149,6 +1379,9 @@ func (r *ProjectRepository) DeleteProject(projectID int) error {
 		return err
 	}
 
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
@@ -0,0 +1,335 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type RewardRepository struct {
+	db *sql.DB
+}
+
+func NewRewardRepository(db *sql.DB) *RewardRepository {
+	return &RewardRepository{db}
+}
+
+// 档位价格以项目币种计，币种从项目表读取
+const rewardTierColumns = `rt.id, rt.project_id, rt.title, COALESCE(rt.description, ''), rt.price, p.currency,
+	rt.image_url, rt.quantity_limit, rt.quantity_claimed, rt.estimated_delivery, rt.shipping_required,
+	rt.created_at, rt.updated_at`
+
+func scanRewardTier(scanner interface{ Scan(...interface{}) error }) (*model.RewardTier, error) {
+	var tier model.RewardTier
+	var quantityLimit sql.NullInt64
+	err := scanner.Scan(&tier.ID, &tier.ProjectID, &tier.Title, &tier.Description, &tier.Price, &tier.Price.Currency,
+		&tier.ImageURL, &quantityLimit, &tier.QuantityClaimed, &tier.EstimatedDelivery, &tier.ShippingRequired,
+		&tier.CreatedAt, &tier.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if quantityLimit.Valid {
+		limit := int(quantityLimit.Int64)
+		tier.QuantityLimit = &limit
+	}
+	tier.ApplyRemaining()
+	return &tier, nil
+}
+
+// ListRewardTiers 获取项目的回报档位，按价格从低到高排列
+func (r *RewardRepository) ListRewardTiers(projectID int) ([]*model.RewardTier, error) {
+	rows, err := r.db.Query(`
+		SELECT `+rewardTierColumns+`
+		FROM reward_tiers rt
+		JOIN projects p ON p.id = rt.project_id
+		WHERE rt.project_id = ?
+		ORDER BY rt.price ASC, rt.id ASC`, projectID)
+	if err != nil {
+		util.Logger.Error("查询回报档位失败", zap.Error(err), zap.Int("project_id", projectID))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tiers := []*model.RewardTier{}
+	for rows.Next() {
+		tier, err := scanRewardTier(rows)
+		if err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, rows.Err()
+}
+
+// GetRewardTier 获取回报档位，不存在时返回 nil
+func (r *RewardRepository) GetRewardTier(id int) (*model.RewardTier, error) {
+	row := r.db.QueryRow(`
+		SELECT `+rewardTierColumns+`
+		FROM reward_tiers rt
+		JOIN projects p ON p.id = rt.project_id
+		WHERE rt.id = ?`, id)
+	tier, err := scanRewardTier(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("查询回报档位失败", zap.Error(err), zap.Int("reward_tier_id", id))
+		return nil, err
+	}
+	return tier, nil
+}
+
+// CreateRewardTier 新增回报档位
+func (r *RewardRepository) CreateRewardTier(tier *model.RewardTier) error {
+	result, err := r.db.Exec(`
+		INSERT INTO reward_tiers (project_id, title, description, price, image_url, quantity_limit,
+			estimated_delivery, shipping_required, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())`,
+		tier.ProjectID, tier.Title, tier.Description, tier.Price, tier.ImageURL, tier.QuantityLimit,
+		tier.EstimatedDelivery, tier.ShippingRequired)
+	if err != nil {
+		util.Logger.Error("创建回报档位失败", zap.Error(err), zap.Int("project_id", tier.ProjectID))
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	tier.ID = int(id)
+	tier.CreatedAt = time.Now()
+	tier.UpdatedAt = tier.CreatedAt
+	tier.ApplyRemaining()
+	return nil
+}
+
+// UpdateRewardTier 更新回报档位，已有订单占用时价格不可修改，限量不能低于已占用数量
+// 返回 false 表示更新期间档位已被占用，条件不再满足
+func (r *RewardRepository) UpdateRewardTier(tier *model.RewardTier) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE reward_tiers
+		SET title = ?, description = ?, price = ?, image_url = ?, quantity_limit = ?,
+			estimated_delivery = ?, shipping_required = ?, updated_at = NOW()
+		WHERE id = ?
+			AND (price = ? OR quantity_claimed = 0)
+			AND (? IS NULL OR quantity_claimed <= ?)`,
+		tier.Title, tier.Description, tier.Price, tier.ImageURL, tier.QuantityLimit,
+		tier.EstimatedDelivery, tier.ShippingRequired,
+		tier.ID, tier.Price, tier.QuantityLimit, tier.QuantityLimit)
+	if err != nil {
+		util.Logger.Error("更新回报档位失败", zap.Error(err), zap.Int("reward_tier_id", tier.ID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		// 内容未变化时 RowsAffected 也为 0，需区分条件不满足的情况
+		var matched bool
+		err = r.db.QueryRow(`
+			SELECT EXISTS(
+				SELECT 1 FROM reward_tiers
+				WHERE id = ? AND (price = ? OR quantity_claimed = 0)
+					AND (? IS NULL OR quantity_claimed <= ?))`,
+			tier.ID, tier.Price, tier.QuantityLimit, tier.QuantityLimit).Scan(&matched)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// DeleteRewardTier 删除回报档位，档位被占用或被订单引用过时不删除并返回 false
+func (r *RewardRepository) DeleteRewardTier(id int) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM reward_tiers
+		WHERE id = ? AND quantity_claimed = 0
+			AND NOT EXISTS (SELECT 1 FROM orders WHERE reward_tier_id = ?)
+			AND NOT EXISTS (SELECT 1 FROM pledges WHERE reward_tier_id = ?)`, id, id, id)
+	if err != nil {
+		util.Logger.Error("删除回报档位失败", zap.Error(err), zap.Int("reward_tier_id", id))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ClaimRewardTierTx 在支付事务中占用一份档位库存，条件更新保证并发下不超卖
+// 返回 false 表示档位已售罄或不属于该项目
+func (r *RewardRepository) ClaimRewardTierTx(tx *sql.Tx, tierID, projectID int) (bool, error) {
+	result, err := tx.Exec(`
+		UPDATE reward_tiers
+		SET quantity_claimed = quantity_claimed + 1
+		WHERE id = ? AND project_id = ?
+			AND (quantity_limit IS NULL OR quantity_claimed < quantity_limit)`, tierID, projectID)
+	if err != nil {
+		util.Logger.Error("占用档位库存失败", zap.Error(err), zap.Int("reward_tier_id", tierID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+const rewardAddOnColumns = `ra.id, ra.project_id, ra.title, COALESCE(ra.description, ''), ra.price, p.currency,
+	ra.image_url, ra.quantity_limit, ra.quantity_claimed, ra.created_at, ra.updated_at`
+
+func scanRewardAddOn(scanner interface{ Scan(...interface{}) error }) (*model.RewardAddOn, error) {
+	var addOn model.RewardAddOn
+	var quantityLimit sql.NullInt64
+	err := scanner.Scan(&addOn.ID, &addOn.ProjectID, &addOn.Title, &addOn.Description, &addOn.Price, &addOn.Price.Currency,
+		&addOn.ImageURL, &quantityLimit, &addOn.QuantityClaimed, &addOn.CreatedAt, &addOn.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if quantityLimit.Valid {
+		limit := int(quantityLimit.Int64)
+		addOn.QuantityLimit = &limit
+	}
+	addOn.ApplyRemaining()
+	return &addOn, nil
+}
+
+// ListRewardAddOns 获取项目的加购项，按价格从低到高排列
+func (r *RewardRepository) ListRewardAddOns(projectID int) ([]*model.RewardAddOn, error) {
+	rows, err := r.db.Query(`
+		SELECT `+rewardAddOnColumns+`
+		FROM reward_add_ons ra
+		JOIN projects p ON p.id = ra.project_id
+		WHERE ra.project_id = ?
+		ORDER BY ra.price ASC, ra.id ASC`, projectID)
+	if err != nil {
+		util.Logger.Error("查询加购项失败", zap.Error(err), zap.Int("project_id", projectID))
+		return nil, err
+	}
+	defer rows.Close()
+
+	addOns := []*model.RewardAddOn{}
+	for rows.Next() {
+		addOn, err := scanRewardAddOn(rows)
+		if err != nil {
+			return nil, err
+		}
+		addOns = append(addOns, addOn)
+	}
+	return addOns, rows.Err()
+}
+
+// GetRewardAddOn 获取加购项，不存在时返回 nil
+func (r *RewardRepository) GetRewardAddOn(id int) (*model.RewardAddOn, error) {
+	row := r.db.QueryRow(`
+		SELECT `+rewardAddOnColumns+`
+		FROM reward_add_ons ra
+		JOIN projects p ON p.id = ra.project_id
+		WHERE ra.id = ?`, id)
+	addOn, err := scanRewardAddOn(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("查询加购项失败", zap.Error(err), zap.Int("add_on_id", id))
+		return nil, err
+	}
+	return addOn, nil
+}
+
+// CreateRewardAddOn 新增加购项
+func (r *RewardRepository) CreateRewardAddOn(addOn *model.RewardAddOn) error {
+	result, err := r.db.Exec(`
+		INSERT INTO reward_add_ons (project_id, title, description, price, image_url, quantity_limit,
+			created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())`,
+		addOn.ProjectID, addOn.Title, addOn.Description, addOn.Price, addOn.ImageURL, addOn.QuantityLimit)
+	if err != nil {
+		util.Logger.Error("创建加购项失败", zap.Error(err), zap.Int("project_id", addOn.ProjectID))
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	addOn.ID = int(id)
+	addOn.CreatedAt = time.Now()
+	addOn.UpdatedAt = addOn.CreatedAt
+	addOn.ApplyRemaining()
+	return nil
+}
+
+// UpdateRewardAddOn 更新加购项，条件与 UpdateRewardTier 相同
+// 返回 false 表示更新期间加购项已被占用，条件不再满足
+func (r *RewardRepository) UpdateRewardAddOn(addOn *model.RewardAddOn) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE reward_add_ons
+		SET title = ?, description = ?, price = ?, image_url = ?, quantity_limit = ?, updated_at = NOW()
+		WHERE id = ?
+			AND (price = ? OR quantity_claimed = 0)
+			AND (? IS NULL OR quantity_claimed <= ?)`,
+		addOn.Title, addOn.Description, addOn.Price, addOn.ImageURL, addOn.QuantityLimit,
+		addOn.ID, addOn.Price, addOn.QuantityLimit, addOn.QuantityLimit)
+	if err != nil {
+		util.Logger.Error("更新加购项失败", zap.Error(err), zap.Int("add_on_id", addOn.ID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		var matched bool
+		err = r.db.QueryRow(`
+			SELECT EXISTS(
+				SELECT 1 FROM reward_add_ons
+				WHERE id = ? AND (price = ? OR quantity_claimed = 0)
+					AND (? IS NULL OR quantity_claimed <= ?))`,
+			addOn.ID, addOn.Price, addOn.QuantityLimit, addOn.QuantityLimit).Scan(&matched)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// DeleteRewardAddOn 删除加购项，被占用或被订单明细引用过时不删除并返回 false
+func (r *RewardRepository) DeleteRewardAddOn(id int) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM reward_add_ons
+		WHERE id = ? AND quantity_claimed = 0
+			AND NOT EXISTS (SELECT 1 FROM order_items WHERE add_on_id = ?)`, id, id)
+	if err != nil {
+		util.Logger.Error("删除加购项失败", zap.Error(err), zap.Int("add_on_id", id))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ClaimRewardAddOnTx 在支付事务中按数量占用加购项库存
+// 返回 false 表示剩余数量不足或不属于该项目
+func (r *RewardRepository) ClaimRewardAddOnTx(tx *sql.Tx, addOnID, projectID, quantity int) (bool, error) {
+	result, err := tx.Exec(`
+		UPDATE reward_add_ons
+		SET quantity_claimed = quantity_claimed + ?
+		WHERE id = ? AND project_id = ?
+			AND (quantity_limit IS NULL OR quantity_claimed + ? <= quantity_limit)`,
+		quantity, addOnID, projectID, quantity)
+	if err != nil {
+		util.Logger.Error("占用加购项库存失败", zap.Error(err), zap.Int("add_on_id", addOnID))
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,367 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"database/sql"
+	"strings"
+)
+
+type RoleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) *RoleRepository {
+	return &RoleRepository{db}
+}
+
+const roleColumns = `r.id, r.name, r.description, r.is_system, r.created_at, r.updated_at`
+
+func scanRole(scanner interface{ Scan(...interface{}) error }) (*model.Role, error) {
+	var role model.Role
+	var description sql.NullString
+	err := scanner.Scan(&role.ID, &role.Name, &description, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	role.Description = description.String
+	role.Permissions = []string{}
+	return &role, nil
+}
+
+// ListRoles 获取所有角色及其权限
+func (r *RoleRepository) ListRoles() ([]*model.Role, error) {
+	return r.queryRoles(`
+		SELECT ` + roleColumns + `
+		FROM roles r
+		ORDER BY r.id`)
+}
+
+// GetRoleByID 按ID获取角色，不存在时返回 nil
+func (r *RoleRepository) GetRoleByID(id int) (*model.Role, error) {
+	return r.queryRole(`
+		SELECT `+roleColumns+`
+		FROM roles r
+		WHERE r.id = ?`, id)
+}
+
+// GetRoleByName 按名称获取角色，不存在时返回 nil
+func (r *RoleRepository) GetRoleByName(name string) (*model.Role, error) {
+	return r.queryRole(`
+		SELECT `+roleColumns+`
+		FROM roles r
+		WHERE r.name = ?`, name)
+}
+
+// CreateRole 创建自定义角色，返回 false 表示名称已存在
+func (r *RoleRepository) CreateRole(role *model.Role, audit *model.AuditEntry) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT IGNORE INTO roles (name, description, is_system) VALUES (?, ?, FALSE)`,
+		role.Name, role.Description)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	if err := replaceRolePermissions(tx, int(id), role.Permissions); err != nil {
+		return false, err
+	}
+	if audit != nil {
+		audit.TargetID = int(id)
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	role.ID = int(id)
+	return true, nil
+}
+
+// UpdateRole 更新角色描述并替换权限
+func (r *RoleRepository) UpdateRole(role *model.Role, audit *model.AuditEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE roles SET description = ? WHERE id = ?`, role.Description, role.ID); err != nil {
+		return err
+	}
+	if err := replaceRolePermissions(tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteRole 删除角色，原先拥有该角色的用户同步更新 users.role
+func (r *RoleRepository) DeleteRole(id int, audit *model.AuditEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT user_id FROM user_roles WHERE role_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM roles WHERE id = ?`, id); err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := syncUserRole(tx, userID); err != nil {
+			return err
+		}
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountRoleMembers 统计拥有该角色且未注销的用户数
+func (r *RoleRepository) CountRoleMembers(roleID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM user_roles ur
+		JOIN users u ON u.id = ur.user_id
+		WHERE ur.role_id = ? AND u.status != 'deleted'`, roleID).Scan(&count)
+	return count, err
+}
+
+// GetUserRoles 获取用户拥有的角色
+func (r *RoleRepository) GetUserRoles(userID int) ([]*model.Role, error) {
+	return r.queryRoles(`
+		SELECT `+roleColumns+`
+		FROM roles r
+		JOIN user_roles ur ON ur.role_id = r.id
+		WHERE ur.user_id = ?
+		ORDER BY r.id`, userID)
+}
+
+// GetUserPermissions 获取用户所有角色的权限并集
+func (r *RoleRepository) GetUserPermissions(userID int) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT rp.permission
+		FROM user_roles ur
+		JOIN role_permissions rp ON rp.role_id = ur.role_id
+		WHERE ur.user_id = ?
+		ORDER BY rp.permission`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+// AssignRole 为用户分配角色，返回 false 表示用户已拥有该角色
+func (r *RoleRepository) AssignRole(userID, roleID, assignedBy int, audit *model.AuditEntry) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT IGNORE INTO user_roles (user_id, role_id, assigned_by) VALUES (?, ?, ?)`,
+		userID, roleID, assignedBy)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	if err := syncUserRole(tx, userID); err != nil {
+		return false, err
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RevokeRole 收回用户的角色，返回 false 表示用户没有该角色
+func (r *RoleRepository) RevokeRole(userID, roleID int, audit *model.AuditEntry) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM user_roles WHERE user_id = ? AND role_id = ?`, userID, roleID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	if err := syncUserRole(tx, userID); err != nil {
+		return false, err
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// SetUserRoles 将用户的角色替换为指定角色，为空时收回全部角色
+func (r *RoleRepository) SetUserRoles(userID int, roleIDs []int, assignedBy int, audit *model.AuditEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_roles WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, roleID := range roleIDs {
+		if _, err := tx.Exec(`
+			INSERT INTO user_roles (user_id, role_id, assigned_by) VALUES (?, ?, ?)`,
+			userID, roleID, assignedBy); err != nil {
+			return err
+		}
+	}
+	if err := syncUserRole(tx, userID); err != nil {
+		return err
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *RoleRepository) queryRole(query string, args ...interface{}) (*model.Role, error) {
+	roles, err := r.queryRoles(query, args...)
+	if err != nil || len(roles) == 0 {
+		return nil, err
+	}
+	return roles[0], nil
+}
+
+// queryRoles 查询角色并一次性加载这些角色的权限
+func (r *RoleRepository) queryRoles(query string, args ...interface{}) ([]*model.Role, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*model.Role
+	byID := make(map[int]*model.Role)
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+		byID[role.ID] = role
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return roles, nil
+	}
+
+	placeholders := make([]string, 0, len(roles))
+	ids := make([]interface{}, 0, len(roles))
+	for _, role := range roles {
+		placeholders = append(placeholders, "?")
+		ids = append(ids, role.ID)
+	}
+	permissionRows, err := r.db.Query(`
+		SELECT role_id, permission
+		FROM role_permissions
+		WHERE role_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY permission`, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer permissionRows.Close()
+
+	for permissionRows.Next() {
+		var roleID int
+		var permission string
+		if err := permissionRows.Scan(&roleID, &permission); err != nil {
+			return nil, err
+		}
+		if role, ok := byID[roleID]; ok {
+			role.Permissions = append(role.Permissions, permission)
+		}
+	}
+	return roles, permissionRows.Err()
+}
+
+func replaceRolePermissions(tx *sql.Tx, roleID int, permissions []string) error {
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_id = ?`, roleID); err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if _, err := tx.Exec(`
+			INSERT INTO role_permissions (role_id, permission) VALUES (?, ?)`, roleID, permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncUserRole 按角色分配重新计算 users.role
+func syncUserRole(tx *sql.Tx, userID int) error {
+	_, err := tx.Exec(`
+		UPDATE users u
+		SET u.role = CASE
+			WHEN EXISTS (
+				SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = u.id AND r.name = ?) THEN ?
+			WHEN EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id) THEN ?
+			ELSE ?
+		END
+		WHERE u.id = ?`,
+		model.RoleAdmin, model.UserRoleAdmin, model.UserRoleStaff, model.UserRoleUser, userID)
+	return err
+}
@@ -0,0 +1,207 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db}
+}
+
+// CreateSession 创建登录会话
+func (r *SessionRepository) CreateSession(session *model.UserSession) error {
+	_, err := r.db.Exec(`
+		INSERT INTO user_sessions (id, user_id, refresh_token_hash, device, ip_address, mfa_verified_at,
+			created_at, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, session.UserID, session.RefreshTokenHash, session.Device, session.IPAddress, session.MFAVerifiedAt,
+		session.CreatedAt, session.LastSeenAt, session.ExpiresAt)
+	if err != nil {
+		util.Logger.Error("创建登录会话失败", zap.Error(err), zap.Int("user_id", session.UserID))
+	}
+	return err
+}
+
+const sessionColumns = `id, user_id, refresh_token_hash, previous_refresh_token_hash, device, ip_address, mfa_verified_at,
+	created_at, last_seen_at, expires_at, revoked_at, revoke_reason`
+
+func scanSession(scanner interface{ Scan(...interface{}) error }) (*model.UserSession, error) {
+	var session model.UserSession
+	var previousHash, device, ip, reason sql.NullString
+	var mfaVerifiedAt, revokedAt sql.NullTime
+	if err := scanner.Scan(
+		&session.ID, &session.UserID, &session.RefreshTokenHash, &previousHash, &device, &ip, &mfaVerifiedAt,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &revokedAt, &reason); err != nil {
+		return nil, err
+	}
+	session.PreviousHash = previousHash.String
+	session.Device = device.String
+	session.IPAddress = ip.String
+	session.RevokeReason = reason.String
+	if mfaVerifiedAt.Valid {
+		session.MFAVerifiedAt = &mfaVerifiedAt.Time
+	}
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return &session, nil
+}
+
+// GetSession 获取登录会话，不存在时返回 nil
+func (r *SessionRepository) GetSession(sessionID string) (*model.UserSession, error) {
+	session, err := scanSession(r.db.QueryRow(`SELECT `+sessionColumns+` FROM user_sessions WHERE id = ?`, sessionID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("查询登录会话失败", zap.Error(err), zap.String("session_id", sessionID))
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetActiveSessionsByUser 获取用户未撤销且未过期的会话，按最近活跃时间倒序
+func (r *SessionRepository) GetActiveSessionsByUser(userID int) ([]*model.UserSession, error) {
+	rows, err := r.db.Query(`
+		SELECT `+sessionColumns+`
+		FROM user_sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		util.Logger.Error("查询用户会话失败", zap.Error(err), zap.Int("user_id", userID))
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*model.UserSession{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RotateRefreshToken 轮换刷新令牌，仅当当前哈希仍为 oldHash 且会话未撤销时更新，返回 false 表示令牌已被使用。
+// 旧哈希保留为 previous_refresh_token_hash，之后再出现即视为重复使用
+func (r *SessionRepository) RotateRefreshToken(sessionID, oldHash, newHash string, expiresAt time.Time, ip string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_sessions
+		SET previous_refresh_token_hash = refresh_token_hash, refresh_token_hash = ?,
+			expires_at = ?, ip_address = ?, last_seen_at = NOW()
+		WHERE id = ? AND refresh_token_hash = ? AND revoked_at IS NULL`,
+		newHash, expiresAt, ip, sessionID, oldHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// TouchSession 更新会话最近活跃时间，一分钟内最多写入一次
+func (r *SessionRepository) TouchSession(sessionID, ip string) error {
+	_, err := r.db.Exec(`
+		UPDATE user_sessions
+		SET last_seen_at = NOW(), ip_address = ?
+		WHERE id = ? AND last_seen_at < NOW() - INTERVAL 1 MINUTE`, ip, sessionID)
+	return err
+}
+
+// MarkSessionMFAVerified 标记会话已通过两步验证
+func (r *SessionRepository) MarkSessionMFAVerified(sessionID string) error {
+	_, err := r.db.Exec(`
+		UPDATE user_sessions SET mfa_verified_at = NOW()
+		WHERE id = ? AND revoked_at IS NULL`, sessionID)
+	return err
+}
+
+// RevokeSession 撤销单个会话，返回 false 表示会话不存在或已撤销
+func (r *SessionRepository) RevokeSession(sessionID, reason string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoke_reason = ?
+		WHERE id = ? AND revoked_at IS NULL`, reason, sessionID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// RevokeUserSessions 撤销用户的全部有效会话
+func (r *SessionRepository) RevokeUserSessions(userID int, reason string) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoke_reason = ?
+		WHERE user_id = ? AND revoked_at IS NULL`, reason, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// AdminRevokeUserSessions 管理员撤销用户的全部有效会话，并在同一事务中记录审计日志
+func (r *SessionRepository) AdminRevokeUserSessions(userID int, reason string, audit *model.AuditEntry) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoke_reason = ?
+		WHERE user_id = ? AND revoked_at IS NULL`, reason, userID)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RevokeOtherSessions 撤销用户除 keepSessionID 以外的全部有效会话
+func (r *SessionRepository) RevokeOtherSessions(userID int, keepSessionID, reason string) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoke_reason = ?
+		WHERE user_id = ? AND id <> ? AND revoked_at IS NULL`, reason, userID, keepSessionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteExpiredSessions 清理刷新令牌已过期的会话
+func (r *SessionRepository) DeleteExpiredSessions() (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM user_sessions WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,233 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type ShippingRepository struct {
+	db *sql.DB
+}
+
+func NewShippingRepository(db *sql.DB) *ShippingRepository {
+	return &ShippingRepository{db}
+}
+
+// ListShippingZones 获取项目的配送区域及其地区和运费
+func (r *ShippingRepository) ListShippingZones(projectID int) ([]*model.ShippingZone, error) {
+	rows, err := r.db.Query(`
+		SELECT id, project_id, name, created_at, updated_at
+		FROM shipping_zones
+		WHERE project_id = ?
+		ORDER BY id ASC`, projectID)
+	if err != nil {
+		util.Logger.Error("查询配送区域失败", zap.Error(err), zap.Int("project_id", projectID))
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []*model.ShippingZone{}
+	byID := make(map[int]*model.ShippingZone)
+	for rows.Next() {
+		zone := &model.ShippingZone{Regions: []model.ShippingRegion{}, Rates: []model.ShippingRate{}}
+		if err := rows.Scan(&zone.ID, &zone.ProjectID, &zone.Name, &zone.CreatedAt, &zone.UpdatedAt); err != nil {
+			return nil, err
+		}
+		zones = append(zones, zone)
+		byID[zone.ID] = zone
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(zones) == 0 {
+		return zones, nil
+	}
+
+	if err := r.loadRegions(byID, "z.project_id = ?", projectID); err != nil {
+		return nil, err
+	}
+	if err := r.loadRates(byID, "z.project_id = ?", projectID); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+// GetShippingZone 获取配送区域，不存在时返回 nil
+func (r *ShippingRepository) GetShippingZone(id int) (*model.ShippingZone, error) {
+	zone := &model.ShippingZone{Regions: []model.ShippingRegion{}, Rates: []model.ShippingRate{}}
+	err := r.db.QueryRow(`
+		SELECT id, project_id, name, created_at, updated_at
+		FROM shipping_zones
+		WHERE id = ?`, id).Scan(&zone.ID, &zone.ProjectID, &zone.Name, &zone.CreatedAt, &zone.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("查询配送区域失败", zap.Error(err), zap.Int("zone_id", id))
+		return nil, err
+	}
+
+	byID := map[int]*model.ShippingZone{zone.ID: zone}
+	if err := r.loadRegions(byID, "z.id = ?", id); err != nil {
+		return nil, err
+	}
+	if err := r.loadRates(byID, "z.id = ?", id); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+func (r *ShippingRepository) loadRegions(byID map[int]*model.ShippingZone, where string, arg int) error {
+	rows, err := r.db.Query(`
+		SELECT sr.zone_id, sr.country, sr.province
+		FROM shipping_zone_regions sr
+		JOIN shipping_zones z ON z.id = sr.zone_id
+		WHERE `+where+`
+		ORDER BY sr.country ASC, sr.province ASC`, arg)
+	if err != nil {
+		util.Logger.Error("查询配送地区失败", zap.Error(err))
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var zoneID int
+		var region model.ShippingRegion
+		if err := rows.Scan(&zoneID, &region.Country, &region.Province); err != nil {
+			return err
+		}
+		if zone, ok := byID[zoneID]; ok {
+			zone.Regions = append(zone.Regions, region)
+		}
+	}
+	return rows.Err()
+}
+
+// loadRates 运费以项目币种计，币种从项目表读取
+func (r *ShippingRepository) loadRates(byID map[int]*model.ShippingZone, where string, arg int) error {
+	rows, err := r.db.Query(`
+		SELECT sz.zone_id, sz.reward_tier_id, sz.fee, p.currency
+		FROM shipping_zone_rates sz
+		JOIN shipping_zones z ON z.id = sz.zone_id
+		JOIN projects p ON p.id = z.project_id
+		WHERE `+where+`
+		ORDER BY sz.reward_tier_id ASC`, arg)
+	if err != nil {
+		util.Logger.Error("查询配送运费失败", zap.Error(err))
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var zoneID int
+		var rate model.ShippingRate
+		if err := rows.Scan(&zoneID, &rate.RewardTierID, &rate.Fee, &rate.Fee.Currency); err != nil {
+			return err
+		}
+		if zone, ok := byID[zoneID]; ok {
+			zone.Rates = append(zone.Rates, rate)
+		}
+	}
+	return rows.Err()
+}
+
+// CreateShippingZone 创建配送区域及其地区和运费。
+// 地区已属于项目的其他区域时返回 false
+func (r *ShippingRepository) CreateShippingZone(zone *model.ShippingZone) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO shipping_zones (project_id, name)
+		VALUES (?, ?)`, zone.ProjectID, zone.Name)
+	if err != nil {
+		util.Logger.Error("创建配送区域失败", zap.Error(err), zap.Int("project_id", zone.ProjectID))
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	zone.ID = int(id)
+
+	if ok, err := insertShippingZoneDetails(tx, zone); !ok || err != nil {
+		return ok, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	created, err := r.GetShippingZone(zone.ID)
+	if err != nil {
+		return false, err
+	}
+	*zone = *created
+	return true, nil
+}
+
+// UpdateShippingZone 修改配送区域名称，并整体替换其地区和运费。
+// 地区已属于项目的其他区域时返回 false
+func (r *ShippingRepository) UpdateShippingZone(zone *model.ShippingZone) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE shipping_zones SET name = ? WHERE id = ?`, zone.Name, zone.ID); err != nil {
+		util.Logger.Error("更新配送区域失败", zap.Error(err), zap.Int("zone_id", zone.ID))
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM shipping_zone_regions WHERE zone_id = ?`, zone.ID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM shipping_zone_rates WHERE zone_id = ?`, zone.ID); err != nil {
+		return false, err
+	}
+
+	if ok, err := insertShippingZoneDetails(tx, zone); !ok || err != nil {
+		return ok, err
+	}
+	return true, tx.Commit()
+}
+
+func insertShippingZoneDetails(tx *sql.Tx, zone *model.ShippingZone) (bool, error) {
+	for _, region := range zone.Regions {
+		_, err := tx.Exec(`
+			INSERT INTO shipping_zone_regions (zone_id, project_id, country, province)
+			VALUES (?, ?, ?, ?)`, zone.ID, zone.ProjectID, region.Country, region.Province)
+		if err != nil {
+			if strings.Contains(err.Error(), "Duplicate entry") {
+				return false, nil
+			}
+			util.Logger.Error("写入配送地区失败", zap.Error(err), zap.Int("zone_id", zone.ID))
+			return false, err
+		}
+	}
+	for _, rate := range zone.Rates {
+		_, err := tx.Exec(`
+			INSERT INTO shipping_zone_rates (zone_id, reward_tier_id, fee)
+			VALUES (?, ?, ?)`, zone.ID, rate.RewardTierID, rate.Fee)
+		if err != nil {
+			util.Logger.Error("写入配送运费失败", zap.Error(err), zap.Int("zone_id", zone.ID))
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// DeleteShippingZone 删除配送区域，地区和运费随之级联删除
+func (r *ShippingRepository) DeleteShippingZone(id int) error {
+	_, err := r.db.Exec(`DELETE FROM shipping_zones WHERE id = ?`, id)
+	if err != nil {
+		util.Logger.Error("删除配送区域失败", zap.Error(err), zap.Int("zone_id", id))
+	}
+	return err
+}
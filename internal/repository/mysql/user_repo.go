@@ -12,6 +12,32 @@ import (
 	"go.uber.org/zap"
 )
 
+const userColumns = `id, username, email, password_hash, avatar_url, bio, role, is_verified, status,
+	created_at, updated_at, suspended_at, deleted_at`
+
+func scanUser(scanner interface{ Scan(...interface{}) error }) (*model.User, error) {
+	var user model.User
+	var avatarURL, bio sql.NullString
+	var suspendedAt, deletedAt sql.NullTime
+	err := scanner.Scan(
+		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &avatarURL, &bio,
+		&user.Role, &user.IsVerified, &user.Status,
+		&user.CreatedAt, &user.UpdatedAt, &suspendedAt, &deletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	user.AvatarURL = avatarURL.String
+	user.Bio = bio.String
+	if suspendedAt.Valid {
+		user.SuspendedAt = &suspendedAt.Time
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+	return &user, nil
+}
+
 // userRepository 实现了 UserRepository 接口
 type userRepository struct {
 	db *sql.DB
@@ -46,37 +72,27 @@ func (r *userRepository) Create(user *model.User) error {
 // FindByID 通过ID查找用户
 func (r *userRepository) FindByID(id int) (*model.User, error) {
 	log.Printf("尝试通过ID查找用户：%d", id)
-	query := `SELECT id, username, email, password_hash, avatar_url, bio, role, is_verified, created_at, updated_at 
-              FROM users WHERE id = ?`
-	var user model.User
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.AvatarURL, &user.Bio,
-		&user.Role, &user.IsVerified, &user.CreatedAt, &user.UpdatedAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+	user, err := scanUser(r.db.QueryRow(query, id))
 	if err != nil {
 		log.Printf("查找用户失败：%v", err)
 		return nil, err
 	}
 	log.Printf("用户查找成功：ID=%d", user.ID)
-	return &user, nil
+	return user, nil
 }
 
 // FindByEmail 通过邮箱查找用户
 func (r *userRepository) FindByEmail(email string) (*model.User, error) {
 	log.Printf("尝试通过邮箱查找用户：%s", email)
-	query := `SELECT id, username, email, password_hash, avatar_url, bio, role, is_verified, created_at, updated_at 
-              FROM users WHERE email = ?`
-	var user model.User
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.AvatarURL, &user.Bio,
-		&user.Role, &user.IsVerified, &user.CreatedAt, &user.UpdatedAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = ?`
+	user, err := scanUser(r.db.QueryRow(query, email))
 	if err != nil {
 		log.Printf("查找用户失败：%v", err)
 		return nil, err
 	}
 	log.Printf("用户查找成功：ID=%d", user.ID)
-	return &user, nil
+	return user, nil
 }
 
 // Update 更新用户信息
@@ -104,37 +120,31 @@ func (r *userRepository) Delete(id int) error {
 
 // FindByUsername 通过用户名查找用户
 func (r *userRepository) FindByUsername(username string) (*model.User, error) {
-	query := `SELECT id, username, email, password_hash, avatar_url, bio, role, is_verified, created_at, updated_at 
-              FROM users WHERE username = ?`
-	var user model.User
-	err := r.db.QueryRow(query, username).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.AvatarURL, &user.Bio,
-		&user.Role, &user.IsVerified, &user.CreatedAt, &user.UpdatedAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = ?`
+	user, err := scanUser(r.db.QueryRow(query, username))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
 }
 
-// Count 返回用户总数
+// Count 返回未注销的用户总数
 func (r *userRepository) Count() (int, error) {
 	var count int
-	err := r.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	err := r.db.QueryRow("SELECT COUNT(*) FROM users WHERE status != 'deleted'").Scan(&count)
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-// FindAll 返回分页的用户列表
+// FindAll 返回分页的未注销用户列表，与 Count 使用相同的过滤条件
 func (r *userRepository) FindAll(page, pageSize int) ([]*model.User, error) {
 	offset := (page - 1) * pageSize
-	query := `SELECT id, username, email, password_hash, avatar_url, bio, role, is_verified, created_at, updated_at 
-              FROM users LIMIT ? OFFSET ?`
+	query := `SELECT ` + userColumns + ` FROM users WHERE status != 'deleted' ORDER BY id LIMIT ? OFFSET ?`
 	rows, err := r.db.Query(query, pageSize, offset)
 	if err != nil {
 		return nil, err
@@ -143,15 +153,11 @@ func (r *userRepository) FindAll(page, pageSize int) ([]*model.User, error) {
 
 	var users []*model.User
 	for rows.Next() {
-		var user model.User
-		err := rows.Scan(
-			&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.AvatarURL, &user.Bio,
-			&user.Role, &user.IsVerified, &user.CreatedAt, &user.UpdatedAt,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
-		users = append(users, &user)
+		users = append(users, user)
 	}
 	return users, nil
 }
@@ -178,20 +184,20 @@ func (r *userRepository) CreateAddress(address *model.UserAddress) error {
 	}
 
 	query := `INSERT INTO user_addresses 
-              (user_id, receiver_name, phone, province, city, district, detail_address, is_default) 
-              VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+              (user_id, receiver_name, phone, country, province, city, district, detail_address, is_default) 
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	// 打印完整的 SQL 和参数
 	util.Logger.Debug("准备执行SQL",
 		zap.String("query", query),
 		zap.Any("params", []interface{}{
-			address.UserID, address.ReceiverName, address.Phone,
+			address.UserID, address.ReceiverName, address.Phone, address.Country,
 			address.Province, address.City, address.District,
 			address.DetailAddress, address.IsDefault,
 		}))
 
 	result, err := r.db.Exec(query,
-		address.UserID, address.ReceiverName, address.Phone,
+		address.UserID, address.ReceiverName, address.Phone, address.Country,
 		address.Province, address.City, address.District,
 		address.DetailAddress, address.IsDefault)
 
@@ -221,11 +227,11 @@ func (r *userRepository) CreateAddress(address *model.UserAddress) error {
 // UpdateAddress 更新地址信息
 func (r *userRepository) UpdateAddress(address *model.UserAddress) error {
 	query := `UPDATE user_addresses 
-              SET receiver_name = ?, phone = ?, province = ?, city = ?, 
+              SET receiver_name = ?, phone = ?, country = ?, province = ?, city = ?, 
                   district = ?, detail_address = ?, is_default = ?
               WHERE id = ? AND user_id = ?`
 	_, err := r.db.Exec(query,
-		address.ReceiverName, address.Phone,
+		address.ReceiverName, address.Phone, address.Country,
 		address.Province, address.City, address.District,
 		address.DetailAddress, address.IsDefault,
 		address.ID, address.UserID)
@@ -242,12 +248,12 @@ func (r *userRepository) DeleteAddress(id int) error {
 // GetAddressByID 通过ID查找地址
 func (r *userRepository) GetAddressByID(id int) (*model.UserAddress, error) {
 	var address model.UserAddress
-	query := `SELECT id, user_id, receiver_name, phone, province, city, district, 
+	query := `SELECT id, user_id, receiver_name, phone, country, province, city, district, 
                      detail_address, is_default, created_at, updated_at 
               FROM user_addresses WHERE id = ?`
 	err := r.db.QueryRow(query, id).Scan(
 		&address.ID, &address.UserID, &address.ReceiverName,
-		&address.Phone, &address.Province, &address.City,
+		&address.Phone, &address.Country, &address.Province, &address.City,
 		&address.District, &address.DetailAddress, &address.IsDefault,
 		&address.CreatedAt, &address.UpdatedAt)
 	if err != nil {
@@ -260,7 +266,7 @@ func (r *userRepository) GetAddressByID(id int) (*model.UserAddress, error) {
 func (r *userRepository) ListUserAddresses(userID int) ([]*model.UserAddress, error) {
 	util.Logger.Info("开始获取用户地址列表", zap.Int("user_id", userID))
 
-	query := `SELECT id, user_id, receiver_name, phone, province, city, district, 
+	query := `SELECT id, user_id, receiver_name, phone, country, province, city, district, 
                      detail_address, is_default, created_at, updated_at 
               FROM user_addresses 
               WHERE user_id = ? 
@@ -280,7 +286,7 @@ func (r *userRepository) ListUserAddresses(userID int) ([]*model.UserAddress, er
 		var address model.UserAddress
 		err := rows.Scan(
 			&address.ID, &address.UserID, &address.ReceiverName,
-			&address.Phone, &address.Province, &address.City,
+			&address.Phone, &address.Country, &address.Province, &address.City,
 			&address.District, &address.DetailAddress, &address.IsDefault,
 			&address.CreatedAt, &address.UpdatedAt)
 		if err != nil {
@@ -360,3 +366,161 @@ func (r *userRepository) SetDefaultAddress(userID, addressID int) error {
 		zap.Int("address_id", addressID))
 	return nil
 }
+
+// GetUserStatus 获取账户状态，认证中间件每次请求都会调用
+func (r *userRepository) GetUserStatus(id int) (string, error) {
+	var status string
+	err := r.db.QueryRow(`SELECT status FROM users WHERE id = ?`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return status, err
+}
+
+// SuspendUser 停用账户，已停用或已注销的账户返回 false
+func (r *userRepository) SuspendUser(id int, reason string, audit *model.AuditEntry) (bool, error) {
+	return r.execAudited(audit, `
+		UPDATE users SET status = 'suspended', suspended_at = NOW(), suspend_reason = ?
+		WHERE id = ? AND status IN ('unverified', 'active')`, reason, id)
+}
+
+// UnsuspendUser 恢复被停用的账户，按邮箱验证情况回到 active 或 unverified
+func (r *userRepository) UnsuspendUser(id int, audit *model.AuditEntry) (bool, error) {
+	return r.execAudited(audit, `
+		UPDATE users SET status = IF(is_verified, 'active', 'unverified'), suspended_at = NULL, suspend_reason = NULL
+		WHERE id = ? AND status = 'suspended'`, id)
+}
+
+// SoftDeleteUser 注销账户，个人数据在恢复期结束后才匿名化
+func (r *userRepository) SoftDeleteUser(id int) (bool, error) {
+	return r.execAffected(`
+		UPDATE users SET status = 'deleted', deleted_at = NOW()
+		WHERE id = ? AND status IN ('unverified', 'active')`, id)
+}
+
+// RestoreUser 恢复期内恢复已注销的账户，deletedAfter 之前注销的账户不能恢复
+func (r *userRepository) RestoreUser(id int, deletedAfter time.Time) (bool, error) {
+	return r.execAffected(`
+		UPDATE users SET status = IF(is_verified, 'active', 'unverified'), deleted_at = NULL
+		WHERE id = ? AND status = 'deleted' AND purged_at IS NULL AND deleted_at > ?`, id, deletedAfter)
+}
+
+// FindUsersPendingPurge 查找恢复期已过、尚未匿名化的账户
+func (r *userRepository) FindUsersPendingPurge(deletedBefore time.Time, limit int) ([]int, error) {
+	rows, err := r.db.Query(`
+		SELECT id FROM users
+		WHERE status = 'deleted' AND purged_at IS NULL AND deleted_at <= ?
+		ORDER BY deleted_at
+		LIMIT ?`, deletedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AnonymizeUser 匿名化已注销账户的个人数据。用户记录保留以维持订单和支付的关联，
+// 用户名和邮箱替换为占位值，地址、会话、令牌、两步验证数据和第三方登录身份直接删除
+func (r *userRepository) AnonymizeUser(id int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var email string
+	err = tx.QueryRow(`
+		SELECT email FROM users
+		WHERE id = ? AND status = 'deleted' AND purged_at IS NULL
+		FOR UPDATE`, id).Scan(&email)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE users
+		SET username = CONCAT('deleted_', id), email = CONCAT('deleted_', id, '@deleted.invalid'),
+			password_hash = '', avatar_url = NULL, bio = NULL, is_verified = FALSE, purged_at = NOW()
+		WHERE id = ?`, id); err != nil {
+		return false, err
+	}
+
+	// 订单仍引用收货地址，只清空地址内容
+	if _, err := tx.Exec(`
+		UPDATE user_addresses
+		SET receiver_name = '', phone = '', province = '', city = '', district = '', detail_address = '', is_default = FALSE
+		WHERE user_id = ?`, id); err != nil {
+		return false, err
+	}
+
+	statements := []string{
+		`DELETE FROM user_sessions WHERE user_id = ?`,
+		`DELETE FROM user_verifications WHERE user_id = ?`,
+		`DELETE FROM user_mfa_recovery_codes WHERE user_id = ?`,
+		`DELETE FROM user_mfa WHERE user_id = ?`,
+		`DELETE FROM user_identities WHERE user_id = ?`,
+		`DELETE FROM oidc_login_states WHERE user_id = ?`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.Exec(`
+		UPDATE login_audit_log SET email = NULL, user_agent = NULL, ip_address = ''
+		WHERE user_id = ? OR email = ?`, id, email); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM auth_throttles WHERE scope IN ('account', 'reset') AND throttle_key = ?`, email); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *userRepository) execAffected(query string, args ...interface{}) (bool, error) {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// execAudited 执行更新并在同一事务中写入审计记录，未更新任何行时不记录
+func (r *userRepository) execAudited(audit *model.AuditEntry, query string, args ...interface{}) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	if err := insertAuditLog(tx, audit); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
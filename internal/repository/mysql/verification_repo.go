@@ -0,0 +1,173 @@
+package mysql
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+type VerificationRepository struct {
+	db *sql.DB
+}
+
+func NewVerificationRepository(db *sql.DB) *VerificationRepository {
+	return &VerificationRepository{db}
+}
+
+// CreateVerification 保存新令牌，同一用户同类型的未使用令牌全部撤销，只有最新一封邮件中的链接有效
+func (r *VerificationRepository) CreateVerification(verification *model.UserVerification) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := revokeVerifications(tx, verification.UserID, verification.Type); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`
+		INSERT INTO user_verifications (user_id, token_hash, type, expires_at)
+		VALUES (?, ?, ?, ?)`,
+		verification.UserID, verification.TokenHash, verification.Type, verification.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	verification.ID = int(id)
+	return tx.Commit()
+}
+
+// GetVerification 按令牌哈希和类型查找令牌，不存在时返回 nil
+func (r *VerificationRepository) GetVerification(tokenHash, verificationType string) (*model.UserVerification, error) {
+	var v model.UserVerification
+	var usedAt, revokedAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, user_id, token_hash, type, expires_at, used_at, revoked_at, created_at
+		FROM user_verifications
+		WHERE token_hash = ? AND type = ?`, tokenHash, verificationType).Scan(
+		&v.ID, &v.UserID, &v.TokenHash, &v.Type, &v.ExpiresAt, &usedAt, &revokedAt, &v.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		util.Logger.Error("查询验证令牌失败", zap.Error(err))
+		return nil, err
+	}
+	if usedAt.Valid {
+		v.UsedAt = &usedAt.Time
+	}
+	if revokedAt.Valid {
+		v.RevokedAt = &revokedAt.Time
+	}
+	return &v, nil
+}
+
+// ConfirmEmail 使用邮箱验证令牌并将用户标记为已验证，返回 false 表示令牌已被使用、撤销或过期
+func (r *VerificationRepository) ConfirmEmail(verificationID, userID int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	used, err := useVerification(tx, verificationID, userID)
+	if err != nil || !used {
+		return false, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE users SET is_verified = TRUE, status = IF(status = 'unverified', 'active', status)
+		WHERE id = ?`, userID); err != nil {
+		return false, err
+	}
+	if err := revokeVerifications(tx, userID, model.VerificationTypeEmail); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ResetPassword 使用密码重置令牌并更新密码，同一事务中撤销用户的全部会话。
+// 返回 false 表示令牌已被使用、撤销或过期
+func (r *VerificationRepository) ResetPassword(verificationID, userID int, passwordHash, revokeReason string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	used, err := useVerification(tx, verificationID, userID)
+	if err != nil || !used {
+		return false, err
+	}
+	if err := updatePassword(tx, userID, passwordHash, "", revokeReason); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// UpdatePassword 修改密码，同时撤销尚未使用的密码重置令牌和除 keepSessionID 外的全部会话
+func (r *VerificationRepository) UpdatePassword(userID int, passwordHash, keepSessionID, revokeReason string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updatePassword(tx, userID, passwordHash, keepSessionID, revokeReason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteExpiredVerifications 删除已过期的令牌
+func (r *VerificationRepository) DeleteExpiredVerifications() (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM user_verifications WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// useVerification 将令牌标记为已使用，条件更新保证并发请求中只有一个能成功
+func useVerification(tx *sql.Tx, verificationID, userID int) (bool, error) {
+	result, err := tx.Exec(`
+		UPDATE user_verifications SET used_at = NOW()
+		WHERE id = ? AND user_id = ? AND used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()`,
+		verificationID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// updatePassword 更新密码并作废未使用的重置令牌，撤销除 keepSessionID 外的会话，旧密码签发的会话不再可用
+func updatePassword(tx *sql.Tx, userID int, passwordHash, keepSessionID, revokeReason string) error {
+	if _, err := tx.Exec(`
+		UPDATE users SET password_hash = ?, updated_at = NOW() WHERE id = ?`, passwordHash, userID); err != nil {
+		return err
+	}
+	if err := revokeVerifications(tx, userID, model.VerificationTypePasswordReset); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoke_reason = ?
+		WHERE user_id = ? AND id <> ? AND revoked_at IS NULL`, revokeReason, userID, keepSessionID)
+	return err
+}
+
+func revokeVerifications(tx *sql.Tx, userID int, verificationType string) error {
+	_, err := tx.Exec(`
+		UPDATE user_verifications SET revoked_at = NOW()
+		WHERE user_id = ? AND type = ? AND used_at IS NULL AND revoked_at IS NULL`,
+		userID, verificationType)
+	return err
+}
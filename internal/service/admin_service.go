@@ -1,27 +1,33 @@
 package service
 
 import (
+	"crowdfunding-backend/internal/gateway"
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/repository/interfaces"
 	"database/sql"
-	"errors"
 )
 
 // AdminService 按功能模块组织业务逻辑
 type AdminService struct {
-	userRepo    interfaces.UserRepository
-	projectRepo interfaces.ProjectRepository
-	paymentRepo interfaces.PaymentRepository
-	db          *sql.DB
+	userRepo       interfaces.UserRepository
+	projectRepo    interfaces.ProjectRepository
+	paymentRepo    interfaces.PaymentRepository
+	projectService *ProjectService
+	orderStates    *OrderStateMachine
+	refunds        *RefundService
+	db             *sql.DB
 }
 
 // NewAdminService 创建一个新的 AdminService 实例
-func NewAdminService(userRepo interfaces.UserRepository, projectRepo interfaces.ProjectRepository, paymentRepo interfaces.PaymentRepository, db *sql.DB) *AdminService {
+func NewAdminService(userRepo interfaces.UserRepository, projectRepo interfaces.ProjectRepository, paymentRepo interfaces.PaymentRepository, projectService *ProjectService, paymentGateway gateway.PaymentGateway, db *sql.DB) *AdminService {
 	return &AdminService{
-		userRepo:    userRepo,
-		projectRepo: projectRepo,
-		paymentRepo: paymentRepo,
-		db:          db,
+		userRepo:       userRepo,
+		projectRepo:    projectRepo,
+		paymentRepo:    paymentRepo,
+		projectService: projectService,
+		orderStates:    NewOrderStateMachine(paymentRepo),
+		refunds:        NewRefundService(paymentRepo, paymentGateway, db),
+		db:             db,
 	}
 }
 
@@ -30,44 +36,32 @@ func (s *AdminService) GetProjects(page, pageSize int, status, search string) ([
 	return s.projectRepo.GetProjectsForAdmin(page, pageSize, status, search)
 }
 
-func (s *AdminService) ReviewProject(projectID int, approved bool, comment string) error {
-	project, err := s.projectRepo.GetProjectByID(projectID)
-	if err != nil {
-		return err
-	}
-	if project == nil {
-		return errors.New("project not found")
-	}
+func (s *AdminService) ReviewProject(projectID int, review *model.ProjectReview, actor model.AuditActor) error {
+	return s.projectService.ReviewProject(projectID, review, actor)
+}
 
-	if approved {
-		project.Status = "active"
-	} else {
-		project.Status = "rejected"
-	}
+// GetProjectReviews 获取项目的审核记录
+func (s *AdminService) GetProjectReviews(projectID int) ([]*model.ProjectReview, error) {
+	return s.projectService.ListProjectReviews(projectID)
+}
 
-	return s.projectRepo.UpdateProjectStatus(project)
+func (s *AdminService) UpdateProjectStatus(projectID int, status string, actor model.AuditActor) (*model.Project, error) {
+	audit := actor.Entry(model.AuditActionProjectStatus, model.AuditTargetProject, projectID)
+	return s.projectService.TransitionStatus(projectID, status, AdminActor(actor.UserID), "管理员更新项目状态", audit)
 }
 
-func (s *AdminService) UpdateProjectStatus(projectID int, status string) (*model.Project, error) {
+// DeleteProject 删除项目，删除前的项目数据保存在审计日志中
+func (s *AdminService) DeleteProject(projectID int, actor model.AuditActor) error {
 	project, err := s.projectRepo.GetProjectByID(projectID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if project == nil {
-		return nil, errors.New("project not found")
+		return ErrProjectNotFound
 	}
-
-	project.Status = status
-	err = s.projectRepo.UpdateProjectStatus(project)
-	if err != nil {
-		return nil, err
-	}
-
-	return project, nil
-}
-
-func (s *AdminService) DeleteProject(projectID int) error {
-	return s.projectRepo.DeleteProject(projectID)
+	audit := actor.Entry(model.AuditActionProjectDelete, model.AuditTargetProject, projectID)
+	audit.SetChange(project, nil)
+	return s.projectRepo.DeleteProject(projectID, audit)
 }
 
 func (s *AdminService) GetProjectPledgers(projectID int) ([]*model.Pledge, error) {
@@ -79,65 +73,60 @@ func (s *AdminService) GetUsers(page, pageSize int) ([]*model.User, error) {
 	return s.userRepo.FindAll(page, pageSize)
 }
 
-func (s *AdminService) UpdateUserRole(userID int, role string) error {
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
-		return err
-	}
-	if user == nil {
-		return errors.New("user not found")
-	}
-
-	user.Role = role
-	return s.userRepo.Update(user)
-}
-
 // 订单和退款管理
 func (s *AdminService) GetAllRefundRequests(page, pageSize int) ([]*model.RefundRequest, int, error) {
 	return s.paymentRepo.GetAllRefundRequests(page, pageSize)
 }
 
-func (s *AdminService) ProcessRefund(requestID int, approved bool, comment string) error {
-	request, err := s.paymentRepo.GetRefundRequestByID(requestID)
+func (s *AdminService) ProcessRefund(requestID int, approved bool, comment string, actor model.AuditActor) error {
+	_, err := s.refunds.resolveRefundRequest(requestID, approved, comment, actor)
+	return err
+}
+
+// 发货管理
+func (s *AdminService) CreateShipmentAndUpdateOrder(shipment *model.Shipment, actor model.AuditActor) error {
+	order, err := s.paymentRepo.GetOrderByID(shipment.OrderID)
 	if err != nil {
 		return err
 	}
-	if request == nil {
-		return errors.New("refund request not found")
+	if order == nil {
+		return ErrOrderNotFound
 	}
-
-	if approved {
-		request.Status = "approved"
-		err = s.paymentRepo.UpdateOrderStatus(request.OrderID, "refunded")
-	} else {
-		request.Status = "rejected"
-		err = s.paymentRepo.UpdateOrderStatus(request.OrderID, "refund_rejected")
+	if !CanTransitionOrder(order.Status, OrderStatusShipped) {
+		return &InvalidTransitionError{Entity: "order", From: order.Status, To: OrderStatusShipped}
 	}
+
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	request.AdminComment = comment
-	return s.paymentRepo.UpdateRefundRequest(request)
-}
-
-// 发货管理
-func (s *AdminService) CreateShipmentAndUpdateOrder(shipment *model.Shipment) error {
-	err := s.projectRepo.CreateShipment(shipment)
-	if err != nil {
+	// 订单流转、发货记录和审计日志一起提交
+	if err := s.orderStates.TransitionTx(tx, order, OrderStatusShipped, AdminActor(actor.UserID), "创建发货记录"); err != nil {
+		return err
+	}
+	audit := actor.Entry(model.AuditActionShipmentCreate, model.AuditTargetShipment, 0)
+	audit.SetChange(nil, shipment)
+	if err := s.projectRepo.CreateShipmentTx(tx, shipment, audit); err != nil {
+		return err
+	}
+	if err := s.paymentRepo.MarkShipmentShippedTx(tx, shipment.ID); err != nil {
 		return err
 	}
 
-	return s.paymentRepo.UpdateOrderAfterShipment(shipment.OrderID, shipment.ID)
+	return tx.Commit()
 }
 
-func (s *AdminService) UpdateShipmentStatus(shipmentID int, status, trackingNumber string) error {
+func (s *AdminService) UpdateShipmentStatus(shipmentID int, status, trackingNumber string, actor model.AuditActor) error {
 	shipment := &model.Shipment{
 		ID:             shipmentID,
 		Status:         status,
 		TrackingNumber: trackingNumber,
 	}
-	return s.projectRepo.UpdateShipment(shipment)
+	audit := actor.Entry(model.AuditActionShipmentUpdate, model.AuditTargetShipment, shipmentID)
+	audit.SetChange(nil, map[string]string{"status": status, "tracking_number": trackingNumber})
+	return s.projectRepo.UpdateShipment(shipment, audit)
 }
 
 // 系统管理
@@ -156,20 +145,3 @@ func (s *AdminService) GetSystemStats() (*model.SystemStats, error) {
 
 	return stats, nil
 }
-
-func (s *AdminService) CheckExpiredProjects() error {
-	projects, err := s.projectRepo.GetExpiredActiveProjects()
-	if err != nil {
-		return err
-	}
-
-	for _, project := range projects {
-		project.Status = "failed"
-		err = s.projectRepo.UpdateProjectStatus(project)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
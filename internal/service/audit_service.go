@@ -0,0 +1,70 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	auditMaxPageSize = 100
+	// auditExportLimit 单次导出的最大记录数，超出时应缩小时间范围分批导出
+	auditExportLimit = 10000
+)
+
+// AuditService 查询和导出管理操作审计日志
+type AuditService struct {
+	auditRepo interfaces.AuditRepository
+}
+
+func NewAuditService(auditRepo interfaces.AuditRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// ListAuditLogs 按条件分页查询审计日志，最新的在前
+func (s *AuditService) ListAuditLogs(filter model.AuditFilter, page, pageSize int) ([]*model.AuditEntry, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > auditMaxPageSize {
+		pageSize = 20
+	}
+	return s.auditRepo.ListAuditLogs(filter, page, pageSize)
+}
+
+// ExportAuditLogs 以 CSV 格式导出符合条件的审计日志，返回导出的记录数和是否因超出上限被截断
+func (s *AuditService) ExportAuditLogs(filter model.AuditFilter, w io.Writer) (int, bool, error) {
+	entries, total, err := s.auditRepo.ListAuditLogs(filter, 1, auditExportLimit)
+	if err != nil {
+		return 0, false, err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"id", "created_at", "actor_id", "action", "target_type", "target_id",
+		"before", "after", "ip", "request_id",
+	}); err != nil {
+		return 0, false, err
+	}
+	for _, entry := range entries {
+		if err := writer.Write([]string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+			strconv.Itoa(entry.ActorID),
+			entry.Action,
+			entry.TargetType,
+			strconv.Itoa(entry.TargetID),
+			string(entry.Before),
+			string(entry.After),
+			entry.IP,
+			entry.RequestID,
+		}); err != nil {
+			return 0, false, err
+		}
+	}
+	writer.Flush()
+	return len(entries), total > len(entries), writer.Error()
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"bytes"
+	"crowdfunding-backend/internal/model"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) ListAuditLogs(filter model.AuditFilter, page, pageSize int) ([]*model.AuditEntry, int, error) {
+	args := m.Called(filter, page, pageSize)
+	return args.Get(0).([]*model.AuditEntry), args.Int(1), args.Error(2)
+}
+
+// TestExportAuditLogs 测试导出的 CSV 内容及超出上限时的截断标记
+func TestExportAuditLogs(t *testing.T) {
+	repo := new(MockAuditRepository)
+	service := NewAuditService(repo)
+	filter := model.AuditFilter{Action: model.AuditActionRefundProcess}
+
+	entry := model.AuditActor{UserID: 1, IP: "10.0.0.1", RequestID: "req-1"}.
+		Entry(model.AuditActionRefundProcess, model.AuditTargetRefundRequest, 42)
+	entry.ID = 7
+	entry.CreatedAt = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	entry.SetChange(map[string]string{"status": "pending"}, map[string]string{"status": "approved"})
+	repo.On("ListAuditLogs", filter, 1, auditExportLimit).Return([]*model.AuditEntry{entry}, 1, nil).Once()
+
+	var buf bytes.Buffer
+	count, truncated, err := service.ExportAuditLogs(filter, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.False(t, truncated)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "id", records[0][0])
+		assert.Equal(t, []string{"7", "2024-05-01T08:00:00Z", "1", "refund.process", "refund_request", "42",
+			`{"status":"pending"}`, `{"status":"approved"}`, "10.0.0.1", "req-1"}, records[1])
+		var after map[string]string
+		assert.NoError(t, json.Unmarshal([]byte(records[1][7]), &after))
+	}
+
+	repo.On("ListAuditLogs", filter, 1, auditExportLimit).Return([]*model.AuditEntry{entry}, auditExportLimit+1, nil).Once()
+	buf.Reset()
+	_, truncated, err = service.ExportAuditLogs(filter, &buf)
+	assert.NoError(t, err)
+	assert.True(t, truncated)
+}
+
+// TestListAuditLogsPageSize 测试分页参数超出范围时使用默认值
+func TestListAuditLogsPageSize(t *testing.T) {
+	repo := new(MockAuditRepository)
+	service := NewAuditService(repo)
+	repo.On("ListAuditLogs", model.AuditFilter{}, 1, 20).Return([]*model.AuditEntry{}, 0, nil)
+
+	_, _, err := service.ListAuditLogs(model.AuditFilter{}, 0, 1000)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
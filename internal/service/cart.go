@@ -0,0 +1,94 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"errors"
+)
+
+// maxCartItemQuantity 单个加购项每笔订单的最大数量
+const maxCartItemQuantity = 99
+
+var (
+	// ErrInvalidCart 加购项数量不合法
+	ErrInvalidCart = errors.New("加购项数量不合法")
+	// ErrAddOnRequiresReward 加购项需搭配回报档位购买
+	ErrAddOnRequiresReward = errors.New("加购项需要搭配回报档位购买")
+)
+
+// mergeCartItems 合并同一加购项的多行并校验数量，保持首次出现的顺序
+func mergeCartItems(items []model.CartItem) ([]model.CartItem, error) {
+	merged := make([]model.CartItem, 0, len(items))
+	index := make(map[int]int, len(items))
+	for _, item := range items {
+		// 逐行校验上限后再累加，避免超大数量溢出
+		if item.AddOnID <= 0 || item.Quantity <= 0 || item.Quantity > maxCartItemQuantity {
+			return nil, ErrInvalidCart
+		}
+		if i, ok := index[item.AddOnID]; ok {
+			merged[i].Quantity += item.Quantity
+			if merged[i].Quantity > maxCartItemQuantity {
+				return nil, ErrInvalidCart
+			}
+		} else {
+			index[item.AddOnID] = len(merged)
+			merged = append(merged, item)
+		}
+	}
+	return merged, nil
+}
+
+// priceCart 按目录价格计算订单明细和总额，金额均为项目币种。
+// addOns 与 cart 一一对应；bonus 为档位和加购之外的额外支持金额，可以为0
+func priceCart(currency string, tier *model.RewardTier, addOns []*model.RewardAddOn, cart []model.CartItem, bonus model.Money) ([]model.OrderItem, model.Money, error) {
+	if len(cart) > 0 && tier == nil {
+		return nil, model.Money{}, ErrAddOnRequiresReward
+	}
+	if bonus.IsNegative() {
+		return nil, model.Money{}, ErrInvalidAmount
+	}
+
+	var items []model.OrderItem
+	total := model.NewMoney(0, currency)
+	if tier != nil {
+		tierID := tier.ID
+		items = append(items, model.OrderItem{
+			ItemType:     model.OrderItemTypeReward,
+			RewardTierID: &tierID,
+			Title:        tier.Title,
+			UnitPrice:    tier.Price,
+			Quantity:     1,
+			Subtotal:     tier.Price,
+		})
+		total = total.Add(tier.Price)
+	}
+	for i, item := range cart {
+		addOn := addOns[i]
+		addOnID := addOn.ID
+		subtotal := model.NewMoney(addOn.Price.Cents*int64(item.Quantity), currency)
+		items = append(items, model.OrderItem{
+			ItemType:  model.OrderItemTypeAddOn,
+			AddOnID:   &addOnID,
+			Title:     addOn.Title,
+			UnitPrice: addOn.Price,
+			Quantity:  item.Quantity,
+			Subtotal:  subtotal,
+		})
+		total = total.Add(subtotal)
+	}
+	if bonus.IsPositive() {
+		bonus.Currency = currency
+		items = append(items, model.OrderItem{
+			ItemType:  model.OrderItemTypeBonus,
+			Title:     "额外支持",
+			UnitPrice: bonus,
+			Quantity:  1,
+			Subtotal:  bonus,
+		})
+		total = total.Add(bonus)
+	}
+
+	if !total.IsPositive() {
+		return nil, model.Money{}, ErrInvalidAmount
+	}
+	return items, total, nil
+}
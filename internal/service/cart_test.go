@@ -0,0 +1,65 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeCartItems(t *testing.T) {
+	merged, err := mergeCartItems([]model.CartItem{{AddOnID: 2, Quantity: 1}, {AddOnID: 5, Quantity: 3}, {AddOnID: 2, Quantity: 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CartItem{{AddOnID: 2, Quantity: 3}, {AddOnID: 5, Quantity: 3}}, merged)
+
+	_, err = mergeCartItems([]model.CartItem{{AddOnID: 2, Quantity: 0}})
+	assert.ErrorIs(t, err, ErrInvalidCart)
+	_, err = mergeCartItems([]model.CartItem{{AddOnID: 2, Quantity: 60}, {AddOnID: 2, Quantity: 40}})
+	assert.ErrorIs(t, err, ErrInvalidCart)
+}
+
+// TestMergeCartItemsRejectsOverflow 测试超大数量在累加前被拒绝，不会溢出为负数而绕过上限
+func TestMergeCartItemsRejectsOverflow(t *testing.T) {
+	_, err := mergeCartItems([]model.CartItem{{AddOnID: 2, Quantity: math.MaxInt}, {AddOnID: 2, Quantity: math.MaxInt}})
+	assert.ErrorIs(t, err, ErrInvalidCart)
+	_, err = mergeCartItems([]model.CartItem{{AddOnID: 2, Quantity: math.MaxInt}, {AddOnID: 2, Quantity: 2}})
+	assert.ErrorIs(t, err, ErrInvalidCart)
+	_, err = mergeCartItems([]model.CartItem{{AddOnID: 2, Quantity: maxCartItemQuantity + 1}})
+	assert.ErrorIs(t, err, ErrInvalidCart)
+
+	merged, err := mergeCartItems([]model.CartItem{{AddOnID: 2, Quantity: maxCartItemQuantity}})
+	assert.NoError(t, err)
+	assert.Equal(t, maxCartItemQuantity, merged[0].Quantity)
+}
+
+func TestPriceCart(t *testing.T) {
+	tier := &model.RewardTier{ID: 1, Title: "标准版", Price: model.NewMoney(19900, "CNY")}
+	poster := &model.RewardAddOn{ID: 7, Title: "海报", Price: model.NewMoney(2550, "CNY")}
+	cart := []model.CartItem{{AddOnID: 7, Quantity: 2}}
+
+	items, total, err := priceCart("CNY", tier, []*model.RewardAddOn{poster}, cart, model.NewMoney(1000, ""))
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(26000, "CNY"), total)
+	if assert.Len(t, items, 3) {
+		assert.Equal(t, model.OrderItemTypeReward, items[0].ItemType)
+		assert.Equal(t, 1, *items[0].RewardTierID)
+		assert.Equal(t, model.OrderItemTypeAddOn, items[1].ItemType)
+		assert.Equal(t, model.NewMoney(5100, "CNY"), items[1].Subtotal)
+		assert.Equal(t, model.OrderItemTypeBonus, items[2].ItemType)
+		assert.Equal(t, model.NewMoney(1000, "CNY"), items[2].Subtotal)
+	}
+
+	// 无偿支持只有额外支持金额一项
+	items, total, err = priceCart("CNY", nil, nil, nil, model.NewMoney(5000, ""))
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(5000, "CNY"), total)
+	assert.Len(t, items, 1)
+
+	_, _, err = priceCart("CNY", nil, []*model.RewardAddOn{poster}, cart, model.Money{})
+	assert.ErrorIs(t, err, ErrAddOnRequiresReward)
+	_, _, err = priceCart("CNY", nil, nil, nil, model.Money{})
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+	_, _, err = priceCart("CNY", tier, nil, nil, model.NewMoney(-100, ""))
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
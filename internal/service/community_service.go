@@ -26,7 +26,12 @@ func (s *CommunityService) UpdatePost(post *model.Post) error {
 }
 
 func (s *CommunityService) DeletePost(id int) error {
-	return s.repo.DeletePost(id)
+	return s.repo.DeletePost(id, nil)
+}
+
+// ModeratePost 管理员删除违规帖子并记录审计日志
+func (s *CommunityService) ModeratePost(id int, actor model.AuditActor) error {
+	return s.repo.DeletePost(id, actor.Entry(model.AuditActionPostDelete, model.AuditTargetPost, id))
 }
 
 func (s *CommunityService) ListPosts(page, pageSize int) ([]*model.Post, int, error) {
@@ -48,7 +53,12 @@ func (s *CommunityService) GetCommentsByPostID(postID, page, pageSize int) ([]*m
 }
 
 func (s *CommunityService) DeleteComment(id int) error {
-	return s.repo.DeleteComment(id)
+	return s.repo.DeleteComment(id, nil)
+}
+
+// ModerateComment 管理员删除违规评论并记录审计日志
+func (s *CommunityService) ModerateComment(id int, actor model.AuditActor) error {
+	return s.repo.DeleteComment(id, actor.Entry(model.AuditActionCommentDelete, model.AuditTargetComment, id))
 }
 
 func (s *CommunityService) CreateLike(like *model.Like) error {
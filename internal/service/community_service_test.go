@@ -0,0 +1,60 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCommunityRepository 模拟 CommunityRepository 中删除相关的方法，其余方法未实现
+type MockCommunityRepository struct {
+	interfaces.CommunityRepository
+	mock.Mock
+}
+
+func (m *MockCommunityRepository) DeletePost(id int, audit *model.AuditEntry) error {
+	args := m.Called(id, audit)
+	return args.Error(0)
+}
+
+func (m *MockCommunityRepository) DeleteComment(id int, audit *model.AuditEntry) error {
+	args := m.Called(id, audit)
+	return args.Error(0)
+}
+
+// TestModerationWritesAuditEntry 测试管理员删除帖子和评论时写入审计记录，作者自行删除时不写入
+func TestModerationWritesAuditEntry(t *testing.T) {
+	repo := new(MockCommunityRepository)
+	service := NewCommunityService(repo)
+	actor := model.AuditActor{UserID: 1, IP: "10.0.0.1", RequestID: "req-1"}
+
+	var postAudit, commentAudit *model.AuditEntry
+	repo.On("DeletePost", 5, mock.AnythingOfType("*model.AuditEntry")).Run(func(args mock.Arguments) {
+		postAudit = args.Get(1).(*model.AuditEntry)
+	}).Return(nil)
+	repo.On("DeleteComment", 9, mock.AnythingOfType("*model.AuditEntry")).Run(func(args mock.Arguments) {
+		commentAudit = args.Get(1).(*model.AuditEntry)
+	}).Return(nil)
+
+	assert.NoError(t, service.ModeratePost(5, actor))
+	if assert.NotNil(t, postAudit) {
+		assert.Equal(t, model.AuditActionPostDelete, postAudit.Action)
+		assert.Equal(t, model.AuditTargetPost, postAudit.TargetType)
+		assert.Equal(t, 5, postAudit.TargetID)
+		assert.Equal(t, 1, postAudit.ActorID)
+	}
+
+	assert.NoError(t, service.ModerateComment(9, actor))
+	if assert.NotNil(t, commentAudit) {
+		assert.Equal(t, model.AuditActionCommentDelete, commentAudit.Action)
+		assert.Equal(t, model.AuditTargetComment, commentAudit.TargetType)
+		assert.Equal(t, 9, commentAudit.TargetID)
+	}
+
+	repo.On("DeletePost", 6, (*model.AuditEntry)(nil)).Return(nil).Once()
+	assert.NoError(t, service.DeletePost(6))
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"crowdfunding-backend/config"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	exportTokenBytes   = 32
+	exportPostPageSize = 100
+	exportPurgeBatch   = 100
+	// exportDailyLimit 每个用户 24 小时内最多申请的导出次数，生成失败的任务不计入
+	exportDailyLimit  = 3
+	exportLimitWindow = 24 * time.Hour
+)
+
+var (
+	ErrExportInProgress   = errors.New("数据导出正在生成中，完成后会发送邮件通知")
+	ErrExportRateLimited  = errors.New("24 小时内导出次数已达上限，请稍后再试")
+	ErrInvalidExportToken = errors.New("下载链接无效或已过期")
+)
+
+// ExportStorage 保存导出压缩包的存储后端
+type ExportStorage interface {
+	SaveFile(path string, src io.Reader) (string, error)
+	FullPath(path string) string
+	DeleteFile(path string) error
+}
+
+// DataExportService 异步生成用户个人数据导出压缩包，并通过邮件发送限时下载链接
+type DataExportService struct {
+	exportRepo    interfaces.DataExportRepository
+	userRepo      interfaces.UserRepository
+	paymentRepo   interfaces.PaymentRepository
+	communityRepo interfaces.CommunityRepository
+	storage       ExportStorage
+	emailService  *EmailService
+	linkTTL       time.Duration
+	// async 执行导出任务，测试中可以替换为同步执行
+	async func(func())
+}
+
+func NewDataExportService(exportRepo interfaces.DataExportRepository, userRepo interfaces.UserRepository, paymentRepo interfaces.PaymentRepository, communityRepo interfaces.CommunityRepository, storage ExportStorage) *DataExportService {
+	return &DataExportService{
+		exportRepo:    exportRepo,
+		userRepo:      userRepo,
+		paymentRepo:   paymentRepo,
+		communityRepo: communityRepo,
+		storage:       storage,
+		emailService:  NewEmailService(userRepo),
+		linkTTL:       time.Duration(config.AppConfig.ExportLinkTTL) * time.Hour,
+		async:         func(f func()) { go f() },
+	}
+}
+
+// RequestExport 创建导出任务并在后台生成压缩包，同一用户同时只能有一个进行中的任务，
+// 24 小时内最多申请 exportDailyLimit 次
+func (s *DataExportService) RequestExport(userID int) (*model.DataExport, error) {
+	pending, err := s.exportRepo.GetPendingExportByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return pending, ErrExportInProgress
+	}
+	recent, err := s.exportRepo.CountExportsSince(userID, time.Now().Add(-exportLimitWindow))
+	if err != nil {
+		return nil, err
+	}
+	if recent >= exportDailyLimit {
+		util.Logger.Warn("个人数据导出次数超过上限", zap.Int("user_id", userID), zap.Int("recent_exports", recent))
+		return nil, ErrExportRateLimited
+	}
+
+	export := &model.DataExport{UserID: userID}
+	if err := s.exportRepo.CreateExport(export); err != nil {
+		return nil, err
+	}
+
+	util.Logger.Info("创建个人数据导出任务", zap.Int("user_id", userID), zap.Int("export_id", export.ID))
+	s.async(func() { s.processExport(export) })
+	return export, nil
+}
+
+// processExport 生成压缩包、保存文件并发送下载链接，失败时记录原因
+func (s *DataExportService) processExport(export *model.DataExport) {
+	user, token, expiresAt, err := s.buildExport(export)
+	if err != nil {
+		util.Logger.Error("生成个人数据导出失败",
+			zap.Error(err),
+			zap.Int("user_id", export.UserID),
+			zap.Int("export_id", export.ID))
+		if err := s.exportRepo.MarkExportFailed(export.ID, err.Error()); err != nil {
+			util.Logger.Error("更新导出任务状态失败", zap.Error(err), zap.Int("export_id", export.ID))
+		}
+		return
+	}
+
+	link := fmt.Sprintf("%s/api/account/export/download?token=%s", config.AppConfig.BackendURL, token)
+	s.emailService.SendDataExportEmail(user.Email, user.Username, link, expiresAt)
+	util.Logger.Info("个人数据导出完成", zap.Int("user_id", export.UserID), zap.Int("export_id", export.ID))
+}
+
+func (s *DataExportService) buildExport(export *model.DataExport) (*model.User, string, time.Time, error) {
+	data, err := s.collectPersonalData(export.UserID)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeExportArchive(&buf, data); err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	token, err := randomHex(exportTokenBytes)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	// 文件名包含随机令牌哈希，无法从导出ID推测
+	path := fmt.Sprintf("%d/%s.zip", export.UserID, hashToken(token)[:32])
+	if _, err := s.storage.SaveFile(path, &buf); err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(s.linkTTL)
+	if err := s.exportRepo.MarkExportReady(export.ID, path, hashToken(token), expiresAt); err != nil {
+		s.storage.DeleteFile(path)
+		return nil, "", time.Time{}, err
+	}
+	return data.Profile, token, expiresAt, nil
+}
+
+// collectPersonalData 汇总用户的个人资料、地址、支持、订单、退款、发货和社区数据
+func (s *DataExportService) collectPersonalData(userID int) (*model.PersonalData, error) {
+	var data model.PersonalData
+	var err error
+
+	if data.Profile, err = s.userRepo.FindByID(userID); err != nil {
+		return nil, fmt.Errorf("查询用户资料失败: %w", err)
+	}
+	if data.Addresses, err = s.userRepo.ListUserAddresses(userID); err != nil {
+		return nil, fmt.Errorf("查询收货地址失败: %w", err)
+	}
+	if data.Pledges, err = s.paymentRepo.GetPledgesByUser(userID); err != nil {
+		return nil, fmt.Errorf("查询支持记录失败: %w", err)
+	}
+	if data.Orders, err = s.paymentRepo.GetOrdersByUser(userID); err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	if data.RefundRequests, err = s.paymentRepo.GetRefundRequestsByUser(userID); err != nil {
+		return nil, fmt.Errorf("查询退款申请失败: %w", err)
+	}
+	if data.Shipments, err = s.paymentRepo.GetShipmentsByUser(userID); err != nil {
+		return nil, fmt.Errorf("查询发货记录失败: %w", err)
+	}
+	for page := 1; ; page++ {
+		posts, total, err := s.communityRepo.GetUserPosts(userID, page, exportPostPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("查询动态失败: %w", err)
+		}
+		data.Posts = append(data.Posts, posts...)
+		if len(posts) == 0 || len(data.Posts) >= total {
+			break
+		}
+	}
+	if data.Comments, err = s.communityRepo.GetCommentsByUser(userID); err != nil {
+		return nil, fmt.Errorf("查询评论失败: %w", err)
+	}
+	if data.Likes, err = s.communityRepo.GetLikesByUser(userID); err != nil {
+		return nil, fmt.Errorf("查询点赞记录失败: %w", err)
+	}
+	if data.Follows, err = s.communityRepo.GetFollowsByUser(userID); err != nil {
+		return nil, fmt.Errorf("查询关注记录失败: %w", err)
+	}
+	return &data, nil
+}
+
+// writeExportArchive 将个人数据写入 ZIP 压缩包，每类数据一个 JSON 文件
+func writeExportArchive(w io.Writer, data *model.PersonalData) error {
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", data.Profile},
+		{"addresses.json", data.Addresses},
+		{"pledges.json", data.Pledges},
+		{"orders.json", data.Orders},
+		{"refund_requests.json", data.RefundRequests},
+		{"shipments.json", data.Shipments},
+		{"posts.json", data.Posts},
+		{"comments.json", data.Comments},
+		{"likes.json", data.Likes},
+		{"follows.json", data.Follows},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.value); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", file.name, err)
+		}
+	}
+	return archive.Close()
+}
+
+// OpenExport 校验下载令牌，返回压缩包的本地路径和下载文件名
+func (s *DataExportService) OpenExport(token string) (string, string, error) {
+	if token == "" {
+		return "", "", ErrInvalidExportToken
+	}
+	export, err := s.exportRepo.GetExportByToken(hashToken(token))
+	if err != nil {
+		return "", "", err
+	}
+	if export == nil || export.Status != model.DataExportReady ||
+		export.ExpiresAt == nil || !time.Now().Before(*export.ExpiresAt) {
+		return "", "", ErrInvalidExportToken
+	}
+
+	filename := fmt.Sprintf("personal-data-%d-%s.zip", export.UserID, export.CreatedAt.Format("20060102"))
+	return s.storage.FullPath(export.FilePath), filename, nil
+}
+
+// PurgeExpiredExports 删除下载链接已过期的导出文件
+func (s *DataExportService) PurgeExpiredExports() (int, error) {
+	exports, err := s.exportRepo.GetExpiredExports(exportPurgeBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, export := range exports {
+		if err := s.storage.DeleteFile(export.FilePath); err != nil {
+			util.Logger.Error("删除过期导出文件失败", zap.Error(err), zap.Int("export_id", export.ID))
+			continue
+		}
+		if err := s.exportRepo.MarkExportExpired(export.ID); err != nil {
+			util.Logger.Error("更新导出任务状态失败", zap.Error(err), zap.Int("export_id", export.ID))
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
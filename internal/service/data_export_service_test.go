@@ -0,0 +1,191 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockDataExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockDataExportRepository) CreateExport(export *model.DataExport) error {
+	args := m.Called(export)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) GetPendingExportByUser(userID int) (*model.DataExport, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) CountExportsSince(userID int, since time.Time) (int, error) {
+	args := m.Called(userID, since)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDataExportRepository) GetExportByToken(tokenHash string) (*model.DataExport, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) MarkExportReady(exportID int, filePath, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(exportID, filePath, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) MarkExportFailed(exportID int, message string) error {
+	args := m.Called(exportID, message)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) GetExpiredExports(limit int) ([]*model.DataExport, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]*model.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) MarkExportExpired(exportID int) error {
+	args := m.Called(exportID)
+	return args.Error(0)
+}
+
+// fakeExportStorage 内存中的导出文件存储
+type fakeExportStorage struct {
+	files map[string][]byte
+}
+
+func (s *fakeExportStorage) SaveFile(path string, src io.Reader) (string, error) {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return "", err
+	}
+	s.files[path] = data
+	return path, nil
+}
+
+func (s *fakeExportStorage) FullPath(path string) string {
+	return filepath.Join("/exports", path)
+}
+
+func (s *fakeExportStorage) DeleteFile(path string) error {
+	delete(s.files, path)
+	return nil
+}
+
+func newExportTestService(exportRepo *MockDataExportRepository) *DataExportService {
+	util.Logger = zap.NewNop()
+	service := NewDataExportService(exportRepo, nil, nil, nil, &fakeExportStorage{files: map[string][]byte{}})
+	service.linkTTL = 48 * time.Hour
+	service.async = func(f func()) {}
+	return service
+}
+
+// TestWriteExportArchive 测试压缩包中每类数据一个 JSON 文件，且不包含密码哈希
+func TestWriteExportArchive(t *testing.T) {
+	data := &model.PersonalData{
+		Profile:   &model.User{ID: 7, Username: "alice", Email: "alice@example.com", PasswordHash: "secret-hash"},
+		Addresses: []*model.UserAddress{{ID: 1, UserID: 7, City: "上海"}},
+		Orders:    []*model.Order{{ID: 3, UserID: 7, Amount: model.NewMoney(1999, "CNY")}},
+		Follows:   []*model.Follow{{ID: 2, FollowerID: 7, FollowedID: 9}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeExportArchive(&buf, data))
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	contents := map[string]string{}
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(body)
+	}
+
+	assert.Len(t, contents, 10)
+	assert.Contains(t, contents["profile.json"], "alice@example.com")
+	assert.NotContains(t, contents["profile.json"], "secret-hash")
+	assert.Contains(t, contents["addresses.json"], "上海")
+	assert.Contains(t, contents["orders.json"], "19.99")
+	assert.Equal(t, "null\n", contents["likes.json"])
+}
+
+// TestRequestExportInProgress 测试同一用户同时只能有一个进行中的导出任务
+func TestRequestExportInProgress(t *testing.T) {
+	exportRepo := new(MockDataExportRepository)
+	service := newExportTestService(exportRepo)
+
+	exportRepo.On("GetPendingExportByUser", 7).Return(&model.DataExport{ID: 1, UserID: 7, Status: model.DataExportPending}, nil)
+	_, err := service.RequestExport(7)
+	assert.ErrorIs(t, err, ErrExportInProgress)
+
+	exportRepo.On("GetPendingExportByUser", 8).Return(nil, nil)
+	exportRepo.On("CountExportsSince", 8, mock.AnythingOfType("time.Time")).Return(0, nil)
+	exportRepo.On("CreateExport", mock.AnythingOfType("*model.DataExport")).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.DataExport).ID = 2
+	}).Return(nil)
+	export, err := service.RequestExport(8)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, export.ID)
+}
+
+// TestRequestExportRateLimited 测试 24 小时内导出次数达到上限后拒绝新的申请
+func TestRequestExportRateLimited(t *testing.T) {
+	exportRepo := new(MockDataExportRepository)
+	service := newExportTestService(exportRepo)
+
+	exportRepo.On("GetPendingExportByUser", 7).Return(nil, nil)
+	exportRepo.On("CountExportsSince", 7, mock.MatchedBy(func(since time.Time) bool {
+		return time.Since(since) > 23*time.Hour && time.Since(since) <= 24*time.Hour+time.Minute
+	})).Return(exportDailyLimit, nil)
+
+	_, err := service.RequestExport(7)
+	assert.ErrorIs(t, err, ErrExportRateLimited)
+	exportRepo.AssertNotCalled(t, "CreateExport", mock.Anything)
+}
+
+// TestOpenExport 测试下载链接只在导出完成且未过期时有效
+func TestOpenExport(t *testing.T) {
+	exportRepo := new(MockDataExportRepository)
+	service := newExportTestService(exportRepo)
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	exportRepo.On("GetExportByToken", hashToken("ready")).Return(&model.DataExport{
+		ID: 1, UserID: 7, Status: model.DataExportReady, FilePath: "7/abc.zip", ExpiresAt: &future,
+	}, nil)
+	exportRepo.On("GetExportByToken", hashToken("expired")).Return(&model.DataExport{
+		ID: 2, UserID: 7, Status: model.DataExportReady, FilePath: "7/def.zip", ExpiresAt: &past,
+	}, nil)
+	exportRepo.On("GetExportByToken", hashToken("unknown")).Return(nil, nil)
+
+	path, filename, err := service.OpenExport("ready")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("/exports", "7/abc.zip"), path)
+	assert.Contains(t, filename, "personal-data-7-")
+
+	_, _, err = service.OpenExport("expired")
+	assert.ErrorIs(t, err, ErrInvalidExportToken)
+	_, _, err = service.OpenExport("unknown")
+	assert.ErrorIs(t, err, ErrInvalidExportToken)
+	_, _, err = service.OpenExport("")
+	assert.ErrorIs(t, err, ErrInvalidExportToken)
+}
@@ -9,7 +9,6 @@ import (
 	"net"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"go.uber.org/zap"
 	"gopkg.in/mail.v2"
 )
@@ -20,7 +19,6 @@ type EmailService struct {
 	username   string
 	password   string
 	userRepo   interfaces.UserRepository
-	jwtSecret  string
 	domainName string
 }
 
@@ -31,18 +29,12 @@ func NewEmailService(userRepo interfaces.UserRepository) *EmailService {
 		username:   config.AppConfig.SMTPUsername,
 		password:   config.AppConfig.SMTPPassword,
 		userRepo:   userRepo,
-		jwtSecret:  config.AppConfig.JWTSecret,
 		domainName: config.AppConfig.DomainName,
 	}
 }
 
-func (s *EmailService) SendVerificationEmail(email, username string) error {
-	token, err := s.generateEmailVerificationToken(email)
-	if err != nil {
-		util.Logger.Error("生成验证令牌失败", zap.Error(err))
-		return fmt.Errorf("生成验证令牌失败: %w", err)
-	}
-
+// SendVerificationEmail 发送邮箱验证链接，令牌由调用方生成并保存
+func (s *EmailService) SendVerificationEmail(email, username, token string) error {
 	verificationLink := fmt.Sprintf("%s/verify-email?token=%s", config.AppConfig.FrontendURL, token)
 
 	subject := "验证您的邮箱"
@@ -116,61 +108,8 @@ func convertIPsToStrings(ips []net.IP) []string {
 	return result
 }
 
-func (s *EmailService) generateEmailVerificationToken(email string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": email,
-		"exp":   time.Now().Add(24 * time.Hour).Unix(),
-	})
-	return token.SignedString([]byte(s.jwtSecret))
-}
-
-func (s *EmailService) VerifyEmailToken(tokenString string) (int, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.jwtSecret), nil
-	})
-
-	if err != nil {
-		util.Logger.Error("解析令牌失败", zap.Error(err))
-		return 0, fmt.Errorf("无效的令牌: %w", err)
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		email, ok := claims["email"].(string)
-		if !ok {
-			util.Logger.Error("令牌中缺少邮箱信息")
-			return 0, fmt.Errorf("无效的令牌: 缺少邮箱信息")
-		}
-
-		userID, err := s.getUserIDByEmail(email)
-		if err != nil {
-			util.Logger.Error("获取用户ID失败", zap.Error(err), zap.String("email", email))
-			return 0, fmt.Errorf("获取用户ID失败: %w", err)
-		}
-		return userID, nil
-	}
-
-	util.Logger.Error("无效的令牌")
-	return 0, fmt.Errorf("无效的令牌")
-}
-
-func (s *EmailService) getUserIDByEmail(email string) (int, error) {
-	user, err := s.userRepo.FindByEmail(email)
-	if err != nil {
-		return 0, fmt.Errorf("查找用户失败: %w", err)
-	}
-	if user == nil {
-		return 0, fmt.Errorf("未找到用户")
-	}
-	return user.ID, nil
-}
-
-func (s *EmailService) SendPasswordResetEmail(email string) error {
-	token, err := s.generatePasswordResetToken(email)
-	if err != nil {
-		util.Logger.Error("生成密码重置令牌失败", zap.Error(err))
-		return fmt.Errorf("生成密码重置令牌失败: %w", err)
-	}
-
+// SendPasswordResetEmail 发送密码重置链接，令牌由调用方生成并保存
+func (s *EmailService) SendPasswordResetEmail(email, token string) error {
 	resetLink := fmt.Sprintf("%s/reset-password?token=%s", config.AppConfig.FrontendURL, token)
 
 	subject := "重置您的密码 - JTL Crowd"
@@ -220,41 +159,25 @@ func (s *EmailService) SendPasswordResetEmail(email string) error {
 	return s.sendEmail(email, subject, body)
 }
 
-func (s *EmailService) generatePasswordResetToken(email string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": email,
-		"exp":   time.Now().Add(1 * time.Hour).Unix(),
-		"type":  "password_reset",
-	})
-	return token.SignedString([]byte(s.jwtSecret))
-}
+// SendAccountLockedEmail 账户因登录失败次数过多被锁定时发送解锁链接
+func (s *EmailService) SendAccountLockedEmail(email, username, unlockToken string, lockedUntil time.Time) {
+	unlockLink := fmt.Sprintf("%s/unlock-account?token=%s", config.AppConfig.FrontendURL, unlockToken)
 
-func (s *EmailService) VerifyPasswordResetToken(tokenString string) (string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.jwtSecret), nil
-	})
+	subject := "您的账户已被临时锁定"
+	body := fmt.Sprintf("亲爱的 %s，<br><br>由于多次登录失败，您的账户已被临时锁定至 %s。<br>"+
+		"如果是您本人操作，可以点击以下链接立即解锁：<br>%s<br><br>"+
+		"如果不是您本人操作，说明有人正在尝试登录您的账户，建议尽快修改密码并开启两步验证。",
+		username, lockedUntil.Format("2006-01-02 15:04:05"), unlockLink)
 
-	if err != nil {
-		util.Logger.Error("解析密码重置令牌失败", zap.Error(err))
-		return "", fmt.Errorf("无效的令牌: %w", err)
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		email, ok := claims["email"].(string)
-		if !ok {
-			util.Logger.Error("令牌中缺少邮箱信息")
-			return "", fmt.Errorf("无效的令牌: 缺少邮箱信息")
-		}
-
-		tokenType, ok := claims["type"].(string)
-		if !ok || tokenType != "password_reset" {
-			util.Logger.Error("无效的令牌类型")
-			return "", fmt.Errorf("无效的令牌类型")
-		}
+	s.sendEmailAsync(email, subject, body)
+}
 
-		return email, nil
-	}
+// SendDataExportEmail 个人数据导出完成后发送限时下载链接
+func (s *EmailService) SendDataExportEmail(email, username, downloadLink string, expiresAt time.Time) {
+	subject := "您的个人数据导出已完成"
+	body := fmt.Sprintf("亲爱的 %s，<br><br>您申请导出的个人数据已经准备好，请点击以下链接下载：<br>%s<br><br>"+
+		"此链接将在 %s 过期。如果不是您本人申请，请尽快修改密码。",
+		username, downloadLink, expiresAt.Format("2006-01-02 15:04:05"))
 
-	util.Logger.Error("无效的令牌")
-	return "", fmt.Errorf("无效的令牌")
+	s.sendEmailAsync(email, subject, body)
 }
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// exchangeRatePrecision 汇率保存的小数位数，与 exchange_rates.rate 列一致
+const exchangeRatePrecision = 8
+
+var (
+	// ErrExchangeRateNotFound 未配置对应币种的汇率
+	ErrExchangeRateNotFound = errors.New("未配置对应币种的汇率")
+	// ErrInvalidCurrency 币种代码无效
+	ErrInvalidCurrency = errors.New("无效的币种代码")
+	// ErrInvalidExchangeRate 汇率无效
+	ErrInvalidExchangeRate = errors.New("汇率必须是大于0的数字")
+)
+
+// ExchangeRateService 维护汇率表，并按汇率将金额折算为项目币种
+type ExchangeRateService struct {
+	repo interfaces.ExchangeRateRepository
+}
+
+func NewExchangeRateService(repo interfaces.ExchangeRateRepository) *ExchangeRateService {
+	return &ExchangeRateService{repo: repo}
+}
+
+// NormalizeCurrency 转换为大写的币种代码并校验格式
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !model.IsValidCurrency(code) {
+		return "", ErrInvalidCurrency
+	}
+	return code, nil
+}
+
+// parseRate 解析十进制汇率，汇率必须大于0
+func parseRate(value string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || rate.Sign() <= 0 {
+		return nil, ErrInvalidExchangeRate
+	}
+	return rate, nil
+}
+
+// CreateRate 录入汇率，effectiveFrom 为零值时立即生效
+func (s *ExchangeRateService) CreateRate(base, quote, rateValue string, effectiveFrom time.Time, actor model.AuditActor) (*model.ExchangeRate, error) {
+	base, err := NormalizeCurrency(base)
+	if err != nil {
+		return nil, err
+	}
+	quote, err = NormalizeCurrency(quote)
+	if err != nil {
+		return nil, err
+	}
+	if base == quote {
+		return nil, ErrInvalidCurrency
+	}
+	rate, err := parseRate(rateValue)
+	if err != nil {
+		return nil, err
+	}
+	if effectiveFrom.IsZero() {
+		effectiveFrom = time.Now()
+	}
+
+	record := &model.ExchangeRate{
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Rate:          rate.FloatString(exchangeRatePrecision),
+		EffectiveFrom: effectiveFrom,
+		CreatedBy:     &actor.UserID,
+	}
+	audit := actor.Entry(model.AuditActionExchangeRateCreate, model.AuditTargetExchangeRate, 0)
+	audit.SetChange(nil, record)
+	if err := s.repo.CreateExchangeRate(record, audit); err != nil {
+		return nil, err
+	}
+
+	util.Logger.Info("录入汇率",
+		zap.String("base_currency", base),
+		zap.String("quote_currency", quote),
+		zap.String("rate", record.Rate),
+		zap.Time("effective_from", effectiveFrom),
+		zap.Int("admin_id", actor.UserID))
+	return record, nil
+}
+
+// ListRates 分页获取汇率记录
+func (s *ExchangeRateService) ListRates(base, quote string, page, pageSize int) ([]*model.ExchangeRate, int, error) {
+	return s.repo.ListExchangeRates(strings.ToUpper(base), strings.ToUpper(quote), page, pageSize)
+}
+
+// GetRate 获取 at 时刻 1 个 from 币种兑换的 to 币种数量。
+// 优先使用 from→to 的汇率，未配置时使用 to→from 汇率的倒数
+func (s *ExchangeRateService) GetRate(from, to string, at time.Time) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	record, err := s.repo.GetEffectiveRate(from, to, at)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		return parseRate(record.Rate)
+	}
+
+	record, err = s.repo.GetEffectiveRate(to, from, at)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		rate, err := parseRate(record.Rate)
+		if err != nil {
+			return nil, err
+		}
+		return rate.Inv(rate), nil
+	}
+	return nil, ErrExchangeRateNotFound
+}
+
+// Convert 将金额折算为 currency 币种，同时返回使用的汇率
+func (s *ExchangeRateService) Convert(amount model.Money, currency string, at time.Time) (model.Money, string, error) {
+	rate, err := s.GetRate(amount.Currency, currency, at)
+	if err != nil {
+		return model.Money{}, "", err
+	}
+	return amount.Convert(rate, currency), rate.FloatString(exchangeRatePrecision), nil
+}
+
+// PriceIn 计算支付 amount 需要的 currency 币种金额，四舍五入到分，
+// 返回的汇率与 Convert 方向一致，表示 1 单位 currency 兑换的 amount 币种数量
+func (s *ExchangeRateService) PriceIn(amount model.Money, currency string, at time.Time) (model.Money, string, error) {
+	rate, err := s.GetRate(currency, amount.Currency, at)
+	if err != nil {
+		return model.Money{}, "", err
+	}
+	return amount.Convert(new(big.Rat).Inv(rate), currency), rate.FloatString(exchangeRatePrecision), nil
+}
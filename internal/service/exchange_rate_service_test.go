@@ -0,0 +1,124 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// MockExchangeRateRepository 是 ExchangeRateRepository 接口的模拟实现
+type MockExchangeRateRepository struct {
+	mock.Mock
+}
+
+func (m *MockExchangeRateRepository) CreateExchangeRate(rate *model.ExchangeRate, audit *model.AuditEntry) error {
+	args := m.Called(rate, audit)
+	return args.Error(0)
+}
+
+func (m *MockExchangeRateRepository) GetEffectiveRate(base, quote string, at time.Time) (*model.ExchangeRate, error) {
+	args := m.Called(base, quote, at)
+	rate, _ := args.Get(0).(*model.ExchangeRate)
+	return rate, args.Error(1)
+}
+
+func (m *MockExchangeRateRepository) ListExchangeRates(base, quote string, page, pageSize int) ([]*model.ExchangeRate, int, error) {
+	args := m.Called(base, quote, page, pageSize)
+	return args.Get(0).([]*model.ExchangeRate), args.Int(1), args.Error(2)
+}
+
+func TestExchangeRateConvert(t *testing.T) {
+	now := time.Now()
+	repo := new(MockExchangeRateRepository)
+	repo.On("GetEffectiveRate", "USD", "CNY", now).Return(&model.ExchangeRate{Rate: "7.20000000"}, nil)
+	repo.On("GetEffectiveRate", "CNY", "USD", now).Return(nil, nil)
+	repo.On("GetEffectiveRate", "CNY", "EUR", now).Return(nil, nil)
+	repo.On("GetEffectiveRate", "EUR", "CNY", now).Return(nil, nil)
+	s := NewExchangeRateService(repo)
+
+	converted, rate, err := s.Convert(model.NewMoney(1050, "USD"), "CNY", now)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(7560, "CNY"), converted)
+	assert.Equal(t, "7.20000000", rate)
+
+	// 未配置 CNY→USD 时使用 USD→CNY 的倒数
+	converted, _, err = s.Convert(model.NewMoney(7200, "CNY"), "USD", now)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(1000, "USD"), converted)
+
+	// 同币种不折算
+	converted, rate, err = s.Convert(model.NewMoney(500, "CNY"), "CNY", now)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(500, "CNY"), converted)
+	assert.Equal(t, "1.00000000", rate)
+
+	_, _, err = s.Convert(model.NewMoney(500, "CNY"), "EUR", now)
+	assert.ErrorIs(t, err, ErrExchangeRateNotFound)
+}
+
+func TestNormalizeCurrency(t *testing.T) {
+	code, err := NormalizeCurrency(" usd ")
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", code)
+
+	_, err = NormalizeCurrency("US1")
+	assert.ErrorIs(t, err, ErrInvalidCurrency)
+}
+
+func TestExchangeRatePriceIn(t *testing.T) {
+	now := time.Now()
+	repo := new(MockExchangeRateRepository)
+	repo.On("GetEffectiveRate", "USD", "CNY", now).Return(&model.ExchangeRate{Rate: "7.20000000"}, nil)
+	s := NewExchangeRateService(repo)
+
+	// 支付 72.00 CNY 需要 10.00 USD，汇率仍按 USD→CNY 记录
+	price, rate, err := s.PriceIn(model.NewMoney(7200, "CNY"), "USD", now)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(1000, "USD"), price)
+	assert.Equal(t, "7.20000000", rate)
+
+	price, _, err = s.PriceIn(model.NewMoney(9900, "CNY"), "USD", now)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(1375, "USD"), price)
+
+	price, rate, err = s.PriceIn(model.NewMoney(500, "CNY"), "CNY", now)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(500, "CNY"), price)
+	assert.Equal(t, "1.00000000", rate)
+}
+
+// TestCreateRateWritesAuditEntry 测试录入汇率时与汇率记录一起写入审计记录
+func TestCreateRateWritesAuditEntry(t *testing.T) {
+	util.Logger = zap.NewNop()
+	repo := new(MockExchangeRateRepository)
+	s := NewExchangeRateService(repo)
+	actor := model.AuditActor{UserID: 1, IP: "10.0.0.1", RequestID: "req-1"}
+
+	var audit *model.AuditEntry
+	repo.On("CreateExchangeRate", mock.AnythingOfType("*model.ExchangeRate"), mock.AnythingOfType("*model.AuditEntry")).Run(func(args mock.Arguments) {
+		audit = args.Get(1).(*model.AuditEntry)
+	}).Return(nil)
+
+	rate, err := s.CreateRate("usd", "cny", "7.2", time.Time{}, actor)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, *rate.CreatedBy)
+	if assert.NotNil(t, audit) {
+		assert.Equal(t, model.AuditActionExchangeRateCreate, audit.Action)
+		assert.Equal(t, model.AuditTargetExchangeRate, audit.TargetType)
+		assert.Equal(t, 1, audit.ActorID)
+		var after map[string]interface{}
+		assert.NoError(t, json.Unmarshal(audit.After, &after))
+		assert.Equal(t, "7.20000000", after["rate"])
+	}
+
+	// 参数无效时不写入
+	_, err = s.CreateRate("USD", "USD", "7.2", time.Time{}, actor)
+	assert.ErrorIs(t, err, ErrInvalidCurrency)
+	repo.AssertNumberOfCalls(t, "CreateExchangeRate", 1)
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// 幂等键状态
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+var (
+	// ErrIdempotencyKeyReused 幂等键已用于内容不同的请求
+	ErrIdempotencyKeyReused = errors.New("Idempotency-Key 已用于不同的请求")
+	// ErrIdempotencyKeyInProgress 使用相同幂等键的请求仍在处理中
+	ErrIdempotencyKeyInProgress = errors.New("使用相同 Idempotency-Key 的请求正在处理中")
+)
+
+// IdempotencyService 管理幂等键，保证客户端重试不会重复创建支持和订单
+type IdempotencyService struct {
+	repo interfaces.IdempotencyRepository
+	ttl  time.Duration
+}
+
+func NewIdempotencyService(repo interfaces.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, ttl: ttl}
+}
+
+// HashRequest 计算请求指纹，同一个幂等键只能用于方法、路径和请求体都相同的请求
+func HashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin 占用幂等键。首次请求返回处理中的记录和 replay=false，调用方处理完成后需调用 Complete 或 Release；
+// 重复请求且首次请求已完成时返回已保存的记录和 replay=true
+func (s *IdempotencyService) Begin(userID int, key, requestHash string) (*model.IdempotencyRecord, bool, error) {
+	record := &model.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		Status:      IdempotencyStatusProcessing,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+
+	// 过期记录被删除后重新占用，最多重试一次
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := s.repo.CreateIdempotencyRecord(record)
+		if err != nil {
+			return nil, false, err
+		}
+		if created {
+			return record, false, nil
+		}
+
+		existing, err := s.repo.GetIdempotencyRecord(userID, key)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing == nil {
+			// 记录刚被删除，重新占用
+			continue
+		}
+		if !existing.ExpiresAt.After(time.Now()) {
+			if err := s.repo.DeleteIdempotencyRecord(existing.ID); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		if existing.RequestHash != requestHash {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		if existing.Status != IdempotencyStatusCompleted {
+			return nil, false, ErrIdempotencyKeyInProgress
+		}
+		return existing, true, nil
+	}
+	return nil, false, ErrIdempotencyKeyInProgress
+}
+
+// Complete 保存首次请求的响应，之后的重复请求将回放该响应
+func (s *IdempotencyService) Complete(record *model.IdempotencyRecord, status int, contentType string, body []byte) error {
+	return s.repo.CompleteIdempotencyRecord(record.ID, status, contentType, body)
+}
+
+// Release 释放幂等键，用于服务端错误等允许客户端使用同一个键重试的情况
+func (s *IdempotencyService) Release(record *model.IdempotencyRecord) error {
+	return s.repo.DeleteIdempotencyRecord(record.ID)
+}
+
+// PurgeExpired 清理过期的幂等键
+func (s *IdempotencyService) PurgeExpired() (int64, error) {
+	return s.repo.DeleteExpiredIdempotencyRecords()
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockIdempotencyRepository 是 IdempotencyRepository 接口的模拟实现
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) CreateIdempotencyRecord(record *model.IdempotencyRecord) (bool, error) {
+	args := m.Called(record)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) GetIdempotencyRecord(userID int, key string) (*model.IdempotencyRecord, error) {
+	args := m.Called(userID, key)
+	record, _ := args.Get(0).(*model.IdempotencyRecord)
+	return record, args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) CompleteIdempotencyRecord(id, status int, contentType string, body []byte) error {
+	args := m.Called(id, status, contentType, body)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) DeleteIdempotencyRecord(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) DeleteExpiredIdempotencyRecords() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestIdempotencyBegin(t *testing.T) {
+	hash := HashRequest("POST", "/api/payments/projects/1", []byte(`{"amount":100}`))
+	future := time.Now().Add(time.Hour)
+
+	t.Run("首次请求占用幂等键", func(t *testing.T) {
+		repo := new(MockIdempotencyRepository)
+		repo.On("CreateIdempotencyRecord", mock.Anything).Return(true, nil)
+		record, replay, err := NewIdempotencyService(repo, time.Hour).Begin(1, "key-1", hash)
+		assert.NoError(t, err)
+		assert.False(t, replay)
+		assert.Equal(t, IdempotencyStatusProcessing, record.Status)
+	})
+
+	t.Run("重复请求回放响应", func(t *testing.T) {
+		repo := new(MockIdempotencyRepository)
+		repo.On("CreateIdempotencyRecord", mock.Anything).Return(false, nil)
+		repo.On("GetIdempotencyRecord", 1, "key-1").Return(&model.IdempotencyRecord{
+			ID: 7, RequestHash: hash, Status: IdempotencyStatusCompleted,
+			ResponseStatus: 201, ResponseBody: []byte(`{"code":201}`), ExpiresAt: future,
+		}, nil)
+		record, replay, err := NewIdempotencyService(repo, time.Hour).Begin(1, "key-1", hash)
+		assert.NoError(t, err)
+		assert.True(t, replay)
+		assert.Equal(t, 201, record.ResponseStatus)
+	})
+
+	t.Run("请求内容不同", func(t *testing.T) {
+		repo := new(MockIdempotencyRepository)
+		repo.On("CreateIdempotencyRecord", mock.Anything).Return(false, nil)
+		repo.On("GetIdempotencyRecord", 1, "key-1").Return(&model.IdempotencyRecord{
+			ID: 7, RequestHash: HashRequest("POST", "/api/payments/projects/1", []byte(`{"amount":200}`)),
+			Status: IdempotencyStatusCompleted, ExpiresAt: future,
+		}, nil)
+		_, _, err := NewIdempotencyService(repo, time.Hour).Begin(1, "key-1", hash)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	})
+
+	t.Run("首次请求仍在处理", func(t *testing.T) {
+		repo := new(MockIdempotencyRepository)
+		repo.On("CreateIdempotencyRecord", mock.Anything).Return(false, nil)
+		repo.On("GetIdempotencyRecord", 1, "key-1").Return(&model.IdempotencyRecord{
+			ID: 7, RequestHash: hash, Status: IdempotencyStatusProcessing, ExpiresAt: future,
+		}, nil)
+		_, _, err := NewIdempotencyService(repo, time.Hour).Begin(1, "key-1", hash)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+	})
+
+	t.Run("过期的幂等键可以重新使用", func(t *testing.T) {
+		repo := new(MockIdempotencyRepository)
+		repo.On("CreateIdempotencyRecord", mock.Anything).Return(false, nil).Once()
+		repo.On("GetIdempotencyRecord", 1, "key-1").Return(&model.IdempotencyRecord{
+			ID: 7, RequestHash: hash, Status: IdempotencyStatusCompleted, ExpiresAt: time.Now().Add(-time.Minute),
+		}, nil)
+		repo.On("DeleteIdempotencyRecord", 7).Return(nil)
+		repo.On("CreateIdempotencyRecord", mock.Anything).Return(true, nil).Once()
+		_, replay, err := NewIdempotencyService(repo, time.Hour).Begin(1, "key-1", hash)
+		assert.NoError(t, err)
+		assert.False(t, replay)
+		repo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 失败计数的统计维度
+const (
+	ThrottleScopeAccount = "account"
+	ThrottleScopeIP      = "ip"
+	ThrottleScopeReset   = "reset"
+	ThrottleScopeResetIP = "reset_ip"
+	ThrottleScopeMFA     = "mfa"
+)
+
+// 受保护的认证操作
+const (
+	LoginActionLogin         = "login"
+	LoginActionAdminLogin    = "admin_login"
+	LoginActionMFALogin      = "mfa_login"
+	LoginActionPasswordReset = "password_reset"
+	LoginActionUnlock        = "unlock"
+	LoginActionRestore       = "account_restore"
+	LoginActionOIDCLogin     = "oidc_login"
+)
+
+// 登录审计结果
+const (
+	LoginResultSuccess = "success"
+	LoginResultFailure = "failure"
+	LoginResultBlocked = "blocked"
+	LoginResultLocked  = "locked"
+)
+
+// backoffFreeFailures 连续失败达到该次数后开始指数退避
+const backoffFreeFailures = 3
+
+var ErrInvalidUnlockToken = errors.New("解锁链接无效或已使用")
+
+// LoginThrottledError 请求处于退避期或账户已被锁定
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("尝试次数过多，已临时锁定，请在 %s 后重试", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("尝试过于频繁，请在 %s 后重试", e.RetryAfter.Round(time.Second))
+}
+
+// LoginProtectionPolicy 暴力破解防护参数
+type LoginProtectionPolicy struct {
+	MaxAccountFailures int           // 同一账户连续失败多少次后锁定
+	MaxIPFailures      int           // 同一 IP 连续失败多少次后锁定
+	MaxResetRequests   int           // 同一邮箱在统计窗口内最多请求几次密码重置
+	Lockout            time.Duration // 锁定时长，也是失败计数的统计窗口
+}
+
+type throttleTarget struct {
+	scope string
+	key   string
+}
+
+// LoginProtectionService 按账户和 IP 统计登录失败次数，实现指数退避、临时锁定、解锁邮件和登录审计
+type LoginProtectionService struct {
+	repo         interfaces.AuthThrottleRepository
+	userRepo     interfaces.UserRepository
+	emailService *EmailService
+	policy       LoginProtectionPolicy
+	now          func() time.Time
+}
+
+func NewLoginProtectionService(repo interfaces.AuthThrottleRepository, userRepo interfaces.UserRepository, policy LoginProtectionPolicy) *LoginProtectionService {
+	return &LoginProtectionService{
+		repo:         repo,
+		userRepo:     userRepo,
+		emailService: NewEmailService(userRepo),
+		policy:       policy,
+		now:          time.Now,
+	}
+}
+
+// targets 返回操作需要统计的维度，email 为空时只按 IP 统计。
+// 密码重置请求单独计数，不占用登录失败的 IP 计数；两步验证按账户单独计数，密码登录成功不会清除
+func (s *LoginProtectionService) targets(action, email, ip string) []throttleTarget {
+	accountScope, ipScope := ThrottleScopeAccount, ThrottleScopeIP
+	switch action {
+	case LoginActionPasswordReset:
+		accountScope, ipScope = ThrottleScopeReset, ThrottleScopeResetIP
+	case LoginActionMFALogin:
+		accountScope = ThrottleScopeMFA
+	}
+	targets := []throttleTarget{{ipScope, ip}}
+	if email != "" {
+		targets = append(targets, throttleTarget{accountScope, email})
+	}
+	return targets
+}
+
+func (s *LoginProtectionService) limit(scope string) int {
+	switch scope {
+	case ThrottleScopeIP, ThrottleScopeResetIP:
+		return s.policy.MaxIPFailures
+	case ThrottleScopeReset:
+		return s.policy.MaxResetRequests
+	}
+	return s.policy.MaxAccountFailures
+}
+
+// penalty 计算退避和锁定：连续失败 backoffFreeFailures 次后每次失败的等待时间翻倍，达到上限后锁定
+func (s *LoginProtectionService) penalty(failures, limit int, now time.Time) (nextAttemptAt, lockedUntil *time.Time) {
+	if failures >= limit {
+		until := now.Add(s.policy.Lockout)
+		return nil, &until
+	}
+	if failures < backoffFreeFailures {
+		return nil, nil
+	}
+	delay := time.Second << uint(failures-backoffFreeFailures)
+	if delay <= 0 || delay > s.policy.Lockout {
+		delay = s.policy.Lockout
+	}
+	next := now.Add(delay)
+	return &next, nil
+}
+
+// Check 请求前检查账户和 IP 是否处于退避期或锁定期，被拒绝的请求记入审计
+func (s *LoginProtectionService) Check(action, email, ip, userAgent string) error {
+	now := s.now()
+	for _, target := range s.targets(action, email, ip) {
+		throttle, err := s.repo.GetThrottle(target.scope, target.key)
+		if err != nil {
+			return err
+		}
+		if throttle == nil {
+			continue
+		}
+
+		var blocked *LoginThrottledError
+		if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+			blocked = &LoginThrottledError{RetryAfter: throttle.LockedUntil.Sub(now), Locked: true}
+		} else if throttle.NextAttemptAt != nil && now.Before(*throttle.NextAttemptAt) {
+			blocked = &LoginThrottledError{RetryAfter: throttle.NextAttemptAt.Sub(now)}
+		}
+		if blocked != nil {
+			s.audit(nil, action, email, ip, userAgent, LoginResultBlocked)
+			return blocked
+		}
+	}
+	return nil
+}
+
+// RecordFailure 记录一次失败，更新退避时间；账户首次达到失败上限时发送解锁邮件
+func (s *LoginProtectionService) RecordFailure(action, email, ip, userAgent string) {
+	s.audit(nil, action, email, ip, userAgent, LoginResultFailure)
+	s.increment(action, email, ip, userAgent)
+}
+
+// RecordPasswordResetRequest 记录一次密码重置请求，无论邮箱是否存在都计数，防止批量发送邮件
+func (s *LoginProtectionService) RecordPasswordResetRequest(email, ip, userAgent string) {
+	s.audit(nil, LoginActionPasswordReset, email, ip, userAgent, LoginResultSuccess)
+	s.increment(LoginActionPasswordReset, email, ip, userAgent)
+}
+
+// RecordSuccess 登录成功后清除该账户在此操作上的失败计数。IP 计数不清除，避免攻击者用自己的账户重置计数
+func (s *LoginProtectionService) RecordSuccess(action string, userID int, email, ip, userAgent string) {
+	var uid *int
+	if userID > 0 {
+		uid = &userID
+	}
+	s.audit(uid, action, email, ip, userAgent, LoginResultSuccess)
+	for _, target := range s.targets(action, email, ip) {
+		if target.scope == ThrottleScopeIP {
+			continue
+		}
+		if err := s.repo.ResetThrottle(target.scope, target.key); err != nil {
+			util.Logger.Error("清除登录失败计数失败", zap.Error(err), zap.String("email", email))
+		}
+	}
+}
+
+// MFAChallengeEmail 从两步验证挑战令牌解析出账户邮箱，用于按账户限制验证码尝试次数。令牌无效时返回空
+func (s *LoginProtectionService) MFAChallengeEmail(token string) string {
+	if token == "" {
+		return ""
+	}
+	userID, err := util.ParseMFAChallengeToken(token)
+	if err != nil {
+		return ""
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(user.Email))
+}
+
+// Unlock 通过解锁邮件中的令牌解除账户锁定
+func (s *LoginProtectionService) Unlock(token, ip, userAgent string) error {
+	if token == "" {
+		return ErrInvalidUnlockToken
+	}
+	email, err := s.repo.ResetThrottleByUnlockToken(hashToken(token))
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return ErrInvalidUnlockToken
+	}
+	s.audit(nil, LoginActionUnlock, email, ip, userAgent, LoginResultSuccess)
+	util.Logger.Info("账户已通过邮件解锁", zap.String("email", email))
+	return nil
+}
+
+func (s *LoginProtectionService) increment(action, email, ip, userAgent string) {
+	now := s.now()
+	windowStart := now.Add(-s.policy.Lockout)
+	for _, target := range s.targets(action, email, ip) {
+		failures, err := s.repo.IncrementFailures(target.scope, target.key, windowStart)
+		if err != nil {
+			util.Logger.Error("记录登录失败次数失败", zap.Error(err), zap.String("scope", target.scope))
+			continue
+		}
+
+		limit := s.limit(target.scope)
+		nextAttemptAt, lockedUntil := s.penalty(failures, limit, now)
+		unlockTokenHash := ""
+		if lockedUntil != nil && failures == limit {
+			s.audit(nil, action, email, ip, userAgent, LoginResultLocked)
+			util.Logger.Warn("尝试次数过多，已临时锁定",
+				zap.String("scope", target.scope),
+				zap.String("key", target.key),
+				zap.Int("failures", failures),
+				zap.Time("locked_until", *lockedUntil))
+			if target.scope == ThrottleScopeAccount {
+				unlockTokenHash = s.sendUnlockEmail(email, *lockedUntil)
+			}
+		}
+
+		if err := s.repo.UpdateThrottle(target.scope, target.key, nextAttemptAt, lockedUntil, unlockTokenHash); err != nil {
+			util.Logger.Error("更新登录退避时间失败", zap.Error(err), zap.String("scope", target.scope))
+		}
+	}
+}
+
+// sendUnlockEmail 向被锁定的账户发送解锁邮件，返回令牌哈希。邮箱未注册时不发送
+func (s *LoginProtectionService) sendUnlockEmail(email string, lockedUntil time.Time) string {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil || user == nil {
+		return ""
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		util.Logger.Error("生成解锁令牌失败", zap.Error(err))
+		return ""
+	}
+	s.emailService.SendAccountLockedEmail(user.Email, user.Username, token, lockedUntil)
+	return hashToken(token)
+}
+
+func (s *LoginProtectionService) audit(userID *int, action, email, ip, userAgent, result string) {
+	if len(userAgent) > maxSessionDeviceLength {
+		userAgent = userAgent[:maxSessionDeviceLength]
+	}
+	entry := &model.LoginAuditEntry{
+		UserID:    userID,
+		Email:     email,
+		IPAddress: ip,
+		UserAgent: userAgent,
+		Action:    action,
+		Result:    result,
+	}
+	if err := s.repo.CreateLoginAudit(entry); err != nil {
+		util.Logger.Error("写入登录审计记录失败", zap.Error(err), zap.String("action", action))
+	}
+}
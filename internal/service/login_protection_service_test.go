@@ -0,0 +1,191 @@
+package service
+
+import (
+	"crowdfunding-backend/config"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// MockAuthThrottleRepository 是 AuthThrottleRepository 接口的模拟实现
+type MockAuthThrottleRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuthThrottleRepository) GetThrottle(scope, key string) (*model.AuthThrottle, error) {
+	args := m.Called(scope, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.AuthThrottle), args.Error(1)
+}
+
+func (m *MockAuthThrottleRepository) IncrementFailures(scope, key string, windowStart time.Time) (int, error) {
+	args := m.Called(scope, key, windowStart)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAuthThrottleRepository) UpdateThrottle(scope, key string, nextAttemptAt, lockedUntil *time.Time, unlockTokenHash string) error {
+	args := m.Called(scope, key, nextAttemptAt, lockedUntil, unlockTokenHash)
+	return args.Error(0)
+}
+
+func (m *MockAuthThrottleRepository) ResetThrottle(scope, key string) error {
+	args := m.Called(scope, key)
+	return args.Error(0)
+}
+
+func (m *MockAuthThrottleRepository) ResetThrottleByUnlockToken(tokenHash string) (string, error) {
+	args := m.Called(tokenHash)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthThrottleRepository) CreateLoginAudit(entry *model.LoginAuditEntry) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func newLoginProtectionTestService(repo *MockAuthThrottleRepository, userRepo *MockUserRepository, now time.Time) *LoginProtectionService {
+	util.Logger = zap.NewNop()
+	service := NewLoginProtectionService(repo, userRepo, LoginProtectionPolicy{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      20,
+		MaxResetRequests:   3,
+		Lockout:            15 * time.Minute,
+	})
+	service.now = func() time.Time { return now }
+	return service
+}
+
+// TestLoginPenalty 测试指数退避：前几次失败不等待，之后等待时间翻倍，达到上限后锁定
+func TestLoginPenalty(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	service := newLoginProtectionTestService(new(MockAuthThrottleRepository), new(MockUserRepository), now)
+
+	next, locked := service.penalty(2, 5, now)
+	assert.Nil(t, next)
+	assert.Nil(t, locked)
+
+	next, _ = service.penalty(3, 5, now)
+	assert.Equal(t, now.Add(time.Second), *next)
+	next, _ = service.penalty(4, 5, now)
+	assert.Equal(t, now.Add(2*time.Second), *next)
+
+	next, locked = service.penalty(5, 5, now)
+	assert.Nil(t, next)
+	assert.Equal(t, now.Add(15*time.Minute), *locked)
+
+	// 退避时间不超过锁定时长
+	next, _ = service.penalty(19, 20, now)
+	assert.Equal(t, now.Add(15*time.Minute), *next)
+}
+
+// TestLoginCheckBlocksLockedAccount 测试锁定期内的请求被拒绝并记入审计
+func TestLoginCheckBlocksLockedAccount(t *testing.T) {
+	repo := new(MockAuthThrottleRepository)
+	now := time.Unix(1700000000, 0)
+	service := newLoginProtectionTestService(repo, new(MockUserRepository), now)
+
+	lockedUntil := now.Add(10 * time.Minute)
+	repo.On("GetThrottle", ThrottleScopeIP, "10.0.0.1").Return(nil, nil)
+	repo.On("GetThrottle", ThrottleScopeAccount, "a@example.com").Return(&model.AuthThrottle{Failures: 5, LockedUntil: &lockedUntil}, nil)
+	repo.On("CreateLoginAudit", mock.MatchedBy(func(entry *model.LoginAuditEntry) bool {
+		return entry.Result == LoginResultBlocked && entry.Email == "a@example.com"
+	})).Return(nil)
+
+	err := service.Check(LoginActionLogin, "a@example.com", "10.0.0.1", "curl")
+	throttled, ok := err.(*LoginThrottledError)
+	assert.True(t, ok)
+	assert.True(t, throttled.Locked)
+	assert.Equal(t, 10*time.Minute, throttled.RetryAfter)
+
+	// 密码重置使用单独的账户和 IP 计数，不受登录锁定影响
+	repo.On("GetThrottle", ThrottleScopeResetIP, "10.0.0.1").Return(nil, nil)
+	repo.On("GetThrottle", ThrottleScopeReset, "a@example.com").Return(nil, nil)
+	assert.NoError(t, service.Check(LoginActionPasswordReset, "a@example.com", "10.0.0.1", "curl"))
+	repo.AssertExpectations(t)
+}
+
+// TestLoginFailureLocksAccount 测试账户达到失败上限时锁定
+func TestLoginFailureLocksAccount(t *testing.T) {
+	repo := new(MockAuthThrottleRepository)
+	userRepo := new(MockUserRepository)
+	now := time.Unix(1700000000, 0)
+	service := newLoginProtectionTestService(repo, userRepo, now)
+
+	windowStart := now.Add(-15 * time.Minute)
+	repo.On("CreateLoginAudit", mock.Anything).Return(nil)
+	repo.On("IncrementFailures", ThrottleScopeIP, "10.0.0.1", windowStart).Return(5, nil)
+	repo.On("IncrementFailures", ThrottleScopeAccount, "a@example.com", windowStart).Return(5, nil)
+	// 未注册的邮箱不发送解锁邮件
+	userRepo.On("FindByEmail", "a@example.com").Return(nil, nil)
+
+	lockedUntil := now.Add(15 * time.Minute)
+	ipNext := now.Add(4 * time.Second)
+	repo.On("UpdateThrottle", ThrottleScopeIP, "10.0.0.1", &ipNext, (*time.Time)(nil), "").Return(nil)
+	repo.On("UpdateThrottle", ThrottleScopeAccount, "a@example.com", (*time.Time)(nil), &lockedUntil, "").Return(nil)
+
+	service.RecordFailure(LoginActionLogin, "a@example.com", "10.0.0.1", "curl")
+	repo.AssertExpectations(t)
+	repo.AssertCalled(t, "CreateLoginAudit", mock.MatchedBy(func(entry *model.LoginAuditEntry) bool {
+		return entry.Result == LoginResultLocked
+	}))
+}
+
+// TestPasswordResetDoesNotCountLoginFailures 测试密码重置请求不计入登录失败的 IP 计数
+func TestPasswordResetDoesNotCountLoginFailures(t *testing.T) {
+	repo := new(MockAuthThrottleRepository)
+	now := time.Unix(1700000000, 0)
+	service := newLoginProtectionTestService(repo, new(MockUserRepository), now)
+
+	windowStart := now.Add(-15 * time.Minute)
+	repo.On("CreateLoginAudit", mock.Anything).Return(nil)
+	repo.On("IncrementFailures", ThrottleScopeResetIP, "10.0.0.1", windowStart).Return(1, nil)
+	repo.On("IncrementFailures", ThrottleScopeReset, "a@example.com", windowStart).Return(1, nil)
+	repo.On("UpdateThrottle", ThrottleScopeResetIP, "10.0.0.1", (*time.Time)(nil), (*time.Time)(nil), "").Return(nil)
+	repo.On("UpdateThrottle", ThrottleScopeReset, "a@example.com", (*time.Time)(nil), (*time.Time)(nil), "").Return(nil)
+
+	service.RecordPasswordResetRequest("a@example.com", "10.0.0.1", "curl")
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "IncrementFailures", ThrottleScopeIP, mock.Anything, mock.Anything)
+}
+
+// TestMFAThrottleKeyedOnChallengedAccount 测试两步验证按挑战令牌对应的账户单独计数，密码登录成功不会清除
+func TestMFAThrottleKeyedOnChallengedAccount(t *testing.T) {
+	config.AppConfig.JWTSecret = "test-secret"
+	repo := new(MockAuthThrottleRepository)
+	userRepo := new(MockUserRepository)
+	now := time.Unix(1700000000, 0)
+	service := newLoginProtectionTestService(repo, userRepo, now)
+
+	userRepo.On("FindByID", 1).Return(&model.User{ID: 1, Email: "A@example.com"}, nil)
+	token, err := util.GenerateMFAChallengeToken(1, 5*time.Minute)
+	assert.NoError(t, err)
+	email := service.MFAChallengeEmail(token)
+	assert.Equal(t, "a@example.com", email)
+	assert.Empty(t, service.MFAChallengeEmail("invalid"))
+	assert.Empty(t, service.MFAChallengeEmail(""))
+
+	lockedUntil := now.Add(10 * time.Minute)
+	repo.On("GetThrottle", ThrottleScopeIP, "10.0.0.1").Return(nil, nil)
+	repo.On("GetThrottle", ThrottleScopeMFA, "a@example.com").Return(&model.AuthThrottle{Failures: 5, LockedUntil: &lockedUntil}, nil)
+	repo.On("CreateLoginAudit", mock.Anything).Return(nil)
+	err = service.Check(LoginActionMFALogin, email, "10.0.0.1", "curl")
+	throttled, ok := err.(*LoginThrottledError)
+	assert.True(t, ok)
+	assert.True(t, throttled.Locked)
+
+	// 密码登录成功只清除密码失败计数
+	repo.On("ResetThrottle", ThrottleScopeAccount, "a@example.com").Return(nil)
+	service.RecordSuccess(LoginActionLogin, 1, email, "10.0.0.1", "curl")
+	repo.AssertNotCalled(t, "ResetThrottle", ThrottleScopeMFA, "a@example.com")
+
+	repo.On("ResetThrottle", ThrottleScopeMFA, "a@example.com").Return(nil)
+	service.RecordSuccess(LoginActionMFALogin, 1, email, "10.0.0.1", "curl")
+	repo.AssertExpectations(t)
+}
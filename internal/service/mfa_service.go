@@ -0,0 +1,269 @@
+package service
+
+import (
+	"crowdfunding-backend/config"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// mfaChallengeTTL 密码验证通过后完成两步验证的期限
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaClockSkew 允许验证器与服务器相差的时间步数
+	mfaClockSkew       = 1
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+var (
+	ErrMFAAlreadyEnabled      = errors.New("已开启两步验证")
+	ErrMFANotEnrolled         = errors.New("尚未开启两步验证")
+	ErrMFAEnrollmentNotFound  = errors.New("请先生成两步验证密钥")
+	ErrInvalidMFACode         = errors.New("验证码错误或已使用")
+	ErrInvalidMFAChallenge    = errors.New("两步验证已过期，请重新登录")
+	ErrAdminMFARequired       = errors.New("管理员必须开启两步验证")
+	ErrMFAVerificationMissing = errors.New("当前会话未通过两步验证，请重新登录")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAService 管理 TOTP 两步验证的开启、校验和恢复码
+type MFAService struct {
+	mfaRepo  interfaces.MFARepository
+	userRepo interfaces.UserRepository
+	issuer   string
+	now      func() time.Time
+}
+
+func NewMFAService(mfaRepo interfaces.MFARepository, userRepo interfaces.UserRepository) *MFAService {
+	return &MFAService{
+		mfaRepo:  mfaRepo,
+		userRepo: userRepo,
+		issuer:   config.AppConfig.MFAIssuer,
+		now:      time.Now,
+	}
+}
+
+// IsEnabled 用户是否已开启两步验证
+func (s *MFAService) IsEnabled(userID int) (bool, error) {
+	mfa, err := s.mfaRepo.GetMFA(userID)
+	if err != nil {
+		return false, err
+	}
+	return mfa != nil && mfa.EnabledAt != nil, nil
+}
+
+// GetStatus 获取两步验证状态和剩余恢复码数量
+func (s *MFAService) GetStatus(userID int) (*model.MFAStatus, error) {
+	mfa, err := s.mfaRepo.GetMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &model.MFAStatus{}
+	if mfa == nil || mfa.EnabledAt == nil {
+		return status, nil
+	}
+	status.Enabled = true
+	status.EnabledAt = mfa.EnabledAt
+	status.RecoveryCodesRemaining, err = s.mfaRepo.CountUnusedRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Enroll 生成新的 TOTP 密钥，需调用 ConfirmEnrollment 验证后才会生效。
+// 重复调用会替换尚未验证的密钥
+func (s *MFAService) Enroll(userID int) (*model.MFAEnrollment, error) {
+	mfa, err := s.mfaRepo.GetMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa != nil && mfa.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err == sql.ErrNoRows || (err == nil && user == nil) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.SavePendingMFA(userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: util.TOTPProvisioningURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment 用验证器生成的验证码确认密钥并开启两步验证，返回只展示一次的恢复码
+func (s *MFAService) ConfirmEnrollment(userID int, code string) ([]string, error) {
+	mfa, err := s.mfaRepo.GetMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, ErrMFAEnrollmentNotFound
+	}
+	if mfa.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := util.ValidateTOTP(mfa.Secret, normalizeMFACode(code), s.now(), mfaClockSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.mfaRepo.EnableMFA(userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		// 并发的确认请求已开启两步验证，保留其恢复码
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	util.Logger.Info("用户开启两步验证", zap.Int("user_id", userID))
+	return codes, nil
+}
+
+// Verify 校验 TOTP 验证码或恢复码，每个验证码和恢复码都只能使用一次
+func (s *MFAService) Verify(userID int, code string) error {
+	mfa, err := s.mfaRepo.GetMFA(userID)
+	if err != nil {
+		return err
+	}
+	if mfa == nil || mfa.EnabledAt == nil {
+		return ErrMFANotEnrolled
+	}
+
+	code = normalizeMFACode(code)
+	if len(code) == util.TOTPDigits {
+		step, ok := util.ValidateTOTP(mfa.Secret, code, s.now(), mfaClockSkew)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		used, err := s.mfaRepo.UseTOTPStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	util.Logger.Info("用户使用恢复码完成两步验证", zap.Int("user_id", userID))
+	return nil
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码，旧恢复码作废
+func (s *MFAService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	if err := s.Verify(userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable 校验验证码后关闭两步验证，管理员和运营人员不能关闭
+func (s *MFAService) Disable(userID int, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err == sql.ErrNoRows || (err == nil && user == nil) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if user.IsStaff() {
+		return ErrAdminMFARequired
+	}
+
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+	if err := s.mfaRepo.DeleteMFA(userID); err != nil {
+		return err
+	}
+	util.Logger.Info("用户关闭两步验证", zap.Int("user_id", userID))
+	return nil
+}
+
+// CreateChallenge 密码验证通过后签发两步验证挑战令牌
+func (s *MFAService) CreateChallenge(userID int) (string, error) {
+	return util.GenerateMFAChallengeToken(userID, mfaChallengeTTL)
+}
+
+// ParseChallenge 校验两步验证挑战令牌，返回用户ID
+func (s *MFAService) ParseChallenge(token string) (int, error) {
+	userID, err := util.ParseMFAChallengeToken(token)
+	if err != nil {
+		return 0, ErrInvalidMFAChallenge
+	}
+	return userID, nil
+}
+
+// normalizeMFACode 去掉用户输入中的空格和连字符，恢复码不区分大小写
+func normalizeMFACode(code string) string {
+	code = strings.ReplaceAll(code, " ", "")
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ToUpper(code)
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeMFACode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes 生成恢复码，返回展示给用户的明文（xxxxx-xxxxx）和入库的哈希
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := recoveryCodeEncoding.EncodeToString(buf)[:recoveryCodeLength]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashRecoveryCode(raw))
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"crowdfunding-backend/config"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// MockMFARepository 是 MFARepository 接口的模拟实现
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) GetMFA(userID int) (*model.UserMFA, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserMFA), args.Error(1)
+}
+
+func (m *MockMFARepository) SavePendingMFA(userID int, secret string) error {
+	args := m.Called(userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) EnableMFA(userID int, step int64, recoveryCodeHashes []string) (bool, error) {
+	args := m.Called(userID, step, recoveryCodeHashes)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	args := m.Called(userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) ReplaceRecoveryCodes(userID int, recoveryCodeHashes []string) error {
+	args := m.Called(userID, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	args := m.Called(userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) CountUnusedRecoveryCodes(userID int) (int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockMFARepository) DeleteMFA(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func newMFATestService(mfaRepo *MockMFARepository, userRepo *MockUserRepository, now time.Time) *MFAService {
+	config.AppConfig.JWTSecret = "test-secret"
+	util.Logger = zap.NewNop()
+	service := NewMFAService(mfaRepo, userRepo)
+	service.issuer = "Crowdfunding"
+	service.now = func() time.Time { return now }
+	return service
+}
+
+// TestMFAEnrollment 测试开启两步验证：确认时校验验证码并生成恢复码哈希
+func TestMFAEnrollment(t *testing.T) {
+	mfaRepo := new(MockMFARepository)
+	userRepo := new(MockUserRepository)
+	now := time.Unix(1700000000, 0)
+	service := newMFATestService(mfaRepo, userRepo, now)
+
+	mfaRepo.On("GetMFA", 7).Return(nil, nil).Once()
+	userRepo.On("FindByID", 7).Return(&model.User{ID: 7, Email: "admin@example.com"}, nil)
+	mfaRepo.On("SavePendingMFA", 7, mock.AnythingOfType("string")).Return(nil)
+
+	enrollment, err := service.Enroll(7)
+	assert.NoError(t, err)
+	assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+
+	pending := &model.UserMFA{UserID: 7, Secret: enrollment.Secret}
+	mfaRepo.On("GetMFA", 7).Return(pending, nil)
+	_, err = service.ConfirmEnrollment(7, "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	code, _ := util.TOTPCode(enrollment.Secret, util.TOTPStep(now))
+	var hashes []string
+	mfaRepo.On("EnableMFA", 7, util.TOTPStep(now), mock.Anything).Run(func(args mock.Arguments) {
+		hashes = args.Get(2).([]string)
+	}).Return(true, nil).Once()
+
+	codes, err := service.ConfirmEnrollment(7, code)
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Equal(t, hashRecoveryCode(codes[0]), hashes[0])
+	assert.NotContains(t, hashes, codes[0])
+
+	// 并发的确认请求已开启两步验证时不返回新的恢复码
+	mfaRepo.On("EnableMFA", 7, util.TOTPStep(now), mock.Anything).Return(false, nil).Once()
+	codes, err = service.ConfirmEnrollment(7, code)
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+	assert.Nil(t, codes)
+	mfaRepo.AssertExpectations(t)
+}
+
+// TestMFAVerify 测试验证码不能重放，恢复码只能使用一次
+func TestMFAVerify(t *testing.T) {
+	mfaRepo := new(MockMFARepository)
+	now := time.Unix(1700000000, 0)
+	service := newMFATestService(mfaRepo, new(MockUserRepository), now)
+
+	secret, _ := util.GenerateTOTPSecret()
+	enabledAt := now.Add(-time.Hour)
+	mfaRepo.On("GetMFA", 7).Return(&model.UserMFA{UserID: 7, Secret: secret, EnabledAt: &enabledAt}, nil)
+
+	code, _ := util.TOTPCode(secret, util.TOTPStep(now))
+	mfaRepo.On("UseTOTPStep", 7, util.TOTPStep(now)).Return(true, nil).Once()
+	assert.NoError(t, service.Verify(7, code))
+
+	mfaRepo.On("UseTOTPStep", 7, util.TOTPStep(now)).Return(false, nil).Once()
+	assert.ErrorIs(t, service.Verify(7, code), ErrInvalidMFACode)
+
+	mfaRepo.On("UseRecoveryCode", 7, hashRecoveryCode("ABCDEFGHJK")).Return(true, nil).Once()
+	assert.NoError(t, service.Verify(7, "abcde-fghjk"))
+	mfaRepo.On("UseRecoveryCode", 7, hashRecoveryCode("ABCDEFGHJK")).Return(false, nil).Once()
+	assert.ErrorIs(t, service.Verify(7, "ABCDE-FGHJK"), ErrInvalidMFACode)
+	mfaRepo.AssertExpectations(t)
+}
+
+// TestMFAChallengeToken 测试挑战令牌不能当作访问令牌使用
+func TestMFAChallengeToken(t *testing.T) {
+	service := newMFATestService(new(MockMFARepository), new(MockUserRepository), time.Now())
+
+	challenge, err := service.CreateChallenge(7)
+	assert.NoError(t, err)
+	userID, err := service.ParseChallenge(challenge)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, userID)
+
+	_, _, err = util.ParseAccessToken(challenge)
+	assert.Error(t, err)
+	_, err = service.ParseChallenge("invalid")
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+}
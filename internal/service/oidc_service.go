@@ -0,0 +1,390 @@
+package service
+
+import (
+	"crowdfunding-backend/config"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// oidcStateTTL 从跳转到提供方到回调完成的期限
+	oidcStateTTL          = 10 * time.Minute
+	oidcStateBytes        = 32
+	oidcBindingBytes      = 32
+	oidcNonceBytes        = 16
+	oidcCodeVerifierBytes = 32
+	maxUsernameLength     = 50
+)
+
+var (
+	ErrOIDCProviderNotFound  = errors.New("不支持的第三方登录方式")
+	ErrInvalidOIDCState      = errors.New("登录请求已过期，请重新发起第三方登录")
+	ErrOIDCEmailRequired     = errors.New("第三方账户未提供已验证的邮箱，无法自动注册")
+	ErrOIDCEmailInUse        = errors.New("该邮箱已注册，请使用密码登录后在个人资料中绑定第三方账户")
+	ErrIdentityAlreadyLinked = errors.New("该第三方账户已绑定其他用户，或当前用户已绑定该登录方式")
+	ErrIdentityNotLinked     = errors.New("未绑定该第三方账户")
+	ErrLastLoginMethod       = errors.New("这是账户唯一的登录方式，请先设置密码或绑定其他第三方账户")
+)
+
+var usernameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_\-.]+`)
+
+// OIDCCallbackResult 回调处理结果，Linked 为 true 表示已登录用户完成绑定，否则为第三方登录
+type OIDCCallbackResult struct {
+	User     *model.User
+	Identity *model.UserIdentity
+	Linked   bool
+	Created  bool // 首次登录自动创建了账户
+}
+
+// OIDCService 通过 OpenID Connect 提供方登录，首次登录自动创建账户，已登录用户可以绑定和解绑第三方身份
+type OIDCService struct {
+	identityRepo interfaces.IdentityRepository
+	userRepo     interfaces.UserRepository
+	providers    map[string]*OIDCProvider
+}
+
+func NewOIDCService(identityRepo interfaces.IdentityRepository, userRepo interfaces.UserRepository, providers map[string]config.OIDCProviderConfig) *OIDCService {
+	s := &OIDCService{
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		providers:    make(map[string]*OIDCProvider),
+	}
+	for name, cfg := range providers {
+		s.providers[name] = NewOIDCProvider(name, cfg)
+	}
+	return s
+}
+
+// Providers 已配置的提供方名称
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OIDCAuthorization 发起授权的结果。Binding 需保存在发起授权的浏览器中（HttpOnly Cookie），
+// 回调时一并提交，确保回调来自发起授权的同一个浏览器
+type OIDCAuthorization struct {
+	URL     string
+	Binding string
+}
+
+// StartLogin 生成第三方登录的授权地址
+func (s *OIDCService) StartLogin(providerName string) (*OIDCAuthorization, error) {
+	return s.start(providerName, 0)
+}
+
+// StartLink 已登录用户绑定第三方身份，生成授权地址
+func (s *OIDCService) StartLink(providerName string, userID int) (*OIDCAuthorization, error) {
+	if err := s.checkAccountStatus(userID); err != nil {
+		return nil, err
+	}
+	return s.start(providerName, userID)
+}
+
+func (s *OIDCService) start(providerName string, userID int) (*OIDCAuthorization, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	state, err := randomHex(oidcStateBytes)
+	if err != nil {
+		return nil, err
+	}
+	binding, err := randomHex(oidcBindingBytes)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomHex(oidcNonceBytes)
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := randomHex(oidcCodeVerifierBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.identityRepo.CreateLoginState(&model.OIDCLoginState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		BindingHash:  hashToken(binding),
+		UserID:       userID,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}); err != nil {
+		return nil, err
+	}
+	return &OIDCAuthorization{URL: authURL, Binding: binding}, nil
+}
+
+// Callback 第三方登录回调：校验 state 和浏览器绑定并用授权码换取 ID Token，按身份登录，
+// 身份不存在时用已验证的邮箱自动创建账户。绑定身份发起的 state 不能在此使用
+func (s *OIDCService) Callback(providerName, code, state, binding string) (*OIDCCallbackResult, error) {
+	provider, loginState, err := s.consumeState(providerName, code, state, binding)
+	if err != nil {
+		return nil, err
+	}
+	if loginState.UserID != 0 {
+		util.Logger.Warn("绑定身份的 state 被用于第三方登录", zap.String("provider", providerName))
+		return nil, ErrInvalidOIDCState
+	}
+
+	claims, err := provider.Exchange(code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	return s.login(providerName, claims)
+}
+
+// LinkCallback 绑定身份回调，需在登录状态下调用，且当前用户必须是发起绑定的用户
+func (s *OIDCService) LinkCallback(providerName, code, state, binding string, userID int) (*OIDCCallbackResult, error) {
+	provider, loginState, err := s.consumeState(providerName, code, state, binding)
+	if err != nil {
+		return nil, err
+	}
+	if loginState.UserID == 0 || loginState.UserID != userID {
+		util.Logger.Warn("绑定身份回调的用户与发起绑定的用户不一致",
+			zap.String("provider", providerName),
+			zap.Int("user_id", userID))
+		return nil, ErrInvalidOIDCState
+	}
+
+	claims, err := provider.Exchange(code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	return s.link(providerName, userID, claims)
+}
+
+// consumeState 取出一次性 state 并校验提供方和浏览器绑定
+func (s *OIDCService) consumeState(providerName, code, state, binding string) (*OIDCProvider, *model.OIDCLoginState, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, nil, ErrOIDCProviderNotFound
+	}
+	if code == "" || state == "" || binding == "" {
+		return nil, nil, ErrInvalidOIDCState
+	}
+	loginState, err := s.identityRepo.ConsumeLoginState(hashToken(state))
+	if err != nil {
+		return nil, nil, err
+	}
+	if loginState == nil || loginState.Provider != providerName ||
+		subtle.ConstantTimeCompare([]byte(hashToken(binding)), []byte(loginState.BindingHash)) != 1 {
+		return nil, nil, ErrInvalidOIDCState
+	}
+	return provider, loginState, nil
+}
+
+func (s *OIDCService) login(providerName string, claims *OIDCClaims) (*OIDCCallbackResult, error) {
+	identity, err := s.identityRepo.GetIdentity(providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity == nil {
+		return s.provision(providerName, claims)
+	}
+
+	user, err := s.userRepo.FindByID(identity.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := accountStatusError(user.Status); err != nil {
+		util.Logger.Info("第三方登录失败，账户不可用", zap.Int("user_id", user.ID), zap.String("status", user.Status))
+		return nil, err
+	}
+	if err := s.identityRepo.TouchIdentity(identity.ID); err != nil {
+		util.Logger.Error("更新第三方登录时间失败", zap.Error(err), zap.Int("identity_id", identity.ID))
+	}
+
+	util.Logger.Info("第三方登录成功", zap.Int("user_id", user.ID), zap.String("provider", providerName))
+	return &OIDCCallbackResult{User: user, Identity: identity}, nil
+}
+
+// provision 首次第三方登录时创建账户。邮箱已被注册时不自动合并，避免通过第三方账户接管已有账户
+func (s *OIDCService) provision(providerName string, claims *OIDCClaims) (*OIDCCallbackResult, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailRequired
+	}
+	existing, err := s.userRepo.FindByEmail(claims.Email)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrOIDCEmailInUse
+	}
+
+	username, err := s.availableUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+	user := &model.User{
+		Username:   username,
+		Email:      claims.Email,
+		IsVerified: true,
+		Status:     model.UserStatusActive,
+	}
+	identity := &model.UserIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := s.identityRepo.CreateUserWithIdentity(user, identity); err != nil {
+		return nil, err
+	}
+
+	util.Logger.Info("第三方登录自动创建账户",
+		zap.Int("user_id", user.ID),
+		zap.String("provider", providerName),
+		zap.String("username", username))
+	return &OIDCCallbackResult{User: user, Identity: identity, Created: true}, nil
+}
+
+// availableUsername 依次尝试 preferred_username、name 和邮箱前缀，已被占用时追加随机后缀
+func (s *OIDCService) availableUsername(claims *OIDCClaims) (string, error) {
+	base := ""
+	for _, candidate := range []string{claims.PreferredUsername, claims.Name, strings.Split(claims.Email, "@")[0]} {
+		candidate = strings.Trim(usernameInvalidChars.ReplaceAllString(candidate, "_"), "_")
+		if candidate != "" {
+			base = candidate
+			break
+		}
+	}
+	if base == "" {
+		base = "user"
+	}
+	// 预留随机后缀的长度
+	if len(base) > maxUsernameLength-7 {
+		base = base[:maxUsernameLength-7]
+	}
+
+	username := base
+	for attempt := 0; attempt < 5; attempt++ {
+		existing, err := s.userRepo.FindByUsername(username)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return username, nil
+		}
+		suffix, err := randomHex(3)
+		if err != nil {
+			return "", err
+		}
+		username = base + "_" + suffix
+	}
+	return "", errors.New("无法生成可用的用户名")
+}
+
+func (s *OIDCService) link(providerName string, userID int, claims *OIDCClaims) (*OIDCCallbackResult, error) {
+	if err := s.checkAccountStatus(userID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.identityRepo.GetIdentity(providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.UserID != userID {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return &OIDCCallbackResult{Identity: existing, Linked: true}, nil
+	}
+
+	identity := &model.UserIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	created, err := s.identityRepo.CreateIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrIdentityAlreadyLinked
+	}
+
+	util.Logger.Info("绑定第三方账户", zap.Int("user_id", userID), zap.String("provider", providerName))
+	return &OIDCCallbackResult{Identity: identity, Linked: true}, nil
+}
+
+// ListIdentities 获取用户绑定的第三方身份
+func (s *OIDCService) ListIdentities(userID int) ([]*model.UserIdentity, error) {
+	return s.identityRepo.ListIdentities(userID)
+}
+
+// Unlink 解绑第三方身份，账户没有密码且没有其他绑定时不允许解绑，避免用户无法再登录
+func (s *OIDCService) Unlink(userID int, providerName string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	identities, err := s.identityRepo.ListIdentities(userID)
+	if err != nil {
+		return err
+	}
+
+	linked := false
+	for _, identity := range identities {
+		if identity.Provider == providerName {
+			linked = true
+		}
+	}
+	if !linked {
+		return ErrIdentityNotLinked
+	}
+	if user.PasswordHash == "" && len(identities) == 1 {
+		return ErrLastLoginMethod
+	}
+
+	deleted, err := s.identityRepo.DeleteIdentity(userID, providerName)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrIdentityNotLinked
+	}
+
+	util.Logger.Info("解绑第三方账户", zap.Int("user_id", userID), zap.String("provider", providerName))
+	return nil
+}
+
+// PurgeExpiredStates 删除过期的授权 state
+func (s *OIDCService) PurgeExpiredStates() (int64, error) {
+	return s.identityRepo.DeleteExpiredLoginStates()
+}
+
+func (s *OIDCService) checkAccountStatus(userID int) error {
+	status, err := s.userRepo.GetUserStatus(userID)
+	if err != nil {
+		return err
+	}
+	if status == "" {
+		return ErrUserNotFound
+	}
+	return accountStatusError(status)
+}
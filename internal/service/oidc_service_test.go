@@ -0,0 +1,374 @@
+package service
+
+import (
+	"crowdfunding-backend/config"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+const (
+	mockOIDCClientID     = "crowdfunding-test"
+	mockOIDCClientSecret = "test-secret"
+	mockOIDCRedirectURL  = "http://localhost:5173/auth/oidc/mock/callback"
+)
+
+// mockOIDCIssuer 本地 OIDC 提供方，提供元数据、JWKS 和令牌接口，用授权码换取预先设置的声明
+type mockOIDCIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	codeChallenge string
+	claims        jwt.MapClaims
+}
+
+func newMockOIDCIssuer(t *testing.T) *mockOIDCIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	issuer := &mockOIDCIssuer{key: key, codes: make(map[string]mockAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", issuer.handleToken)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *mockOIDCIssuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != mockOIDCClientID || secret != mockOIDCClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	i.mu.Lock()
+	auth, found := i.codes[r.FormValue("code")]
+	delete(i.codes, r.FormValue("code"))
+	i.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !found || r.FormValue("redirect_uri") != mockOIDCRedirectURL ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != auth.codeChallenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, auth.claims)
+	token.Header["kid"] = "test-key"
+	idToken, _ := token.SignedString(i.key)
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "id_token": idToken, "token_type": "Bearer"})
+}
+
+// authorize 模拟用户在提供方页面同意授权，返回回调中的 code 和 state
+func (i *mockOIDCIssuer) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (string, string) {
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, mockOIDCClientID, query.Get("client_id"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	base := jwt.MapClaims{
+		"iss":   i.server.URL,
+		"aud":   mockOIDCClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for k, v := range claims {
+		base[k] = v
+	}
+
+	code, err := randomHex(8)
+	assert.NoError(t, err)
+	i.mu.Lock()
+	i.codes[code] = mockAuthorization{codeChallenge: query.Get("code_challenge"), claims: base}
+	i.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// memoryIdentityRepository 内存中的身份存储
+type memoryIdentityRepository struct {
+	states     map[string]*model.OIDCLoginState
+	identities []*model.UserIdentity
+	users      []*model.User
+}
+
+func newMemoryIdentityRepository() *memoryIdentityRepository {
+	return &memoryIdentityRepository{states: make(map[string]*model.OIDCLoginState)}
+}
+
+func (r *memoryIdentityRepository) CreateLoginState(state *model.OIDCLoginState) error {
+	r.states[state.StateHash] = state
+	return nil
+}
+
+func (r *memoryIdentityRepository) ConsumeLoginState(stateHash string) (*model.OIDCLoginState, error) {
+	state := r.states[stateHash]
+	delete(r.states, stateHash)
+	if state == nil || !time.Now().Before(state.ExpiresAt) {
+		return nil, nil
+	}
+	return state, nil
+}
+
+func (r *memoryIdentityRepository) DeleteExpiredLoginStates() (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryIdentityRepository) GetIdentity(provider, subject string) (*model.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryIdentityRepository) ListIdentities(userID int) ([]*model.UserIdentity, error) {
+	var identities []*model.UserIdentity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *memoryIdentityRepository) CreateIdentity(identity *model.UserIdentity) (bool, error) {
+	for _, existing := range r.identities {
+		if (existing.Provider == identity.Provider && existing.Subject == identity.Subject) ||
+			(existing.UserID == identity.UserID && existing.Provider == identity.Provider) {
+			return false, nil
+		}
+	}
+	identity.ID = len(r.identities) + 1
+	r.identities = append(r.identities, identity)
+	return true, nil
+}
+
+func (r *memoryIdentityRepository) CreateUserWithIdentity(user *model.User, identity *model.UserIdentity) error {
+	user.ID = 100 + len(r.users)
+	r.users = append(r.users, user)
+	identity.UserID = user.ID
+	_, err := r.CreateIdentity(identity)
+	return err
+}
+
+func (r *memoryIdentityRepository) TouchIdentity(identityID int) error {
+	return nil
+}
+
+func (r *memoryIdentityRepository) DeleteIdentity(userID int, provider string) (bool, error) {
+	for i, identity := range r.identities {
+		if identity.UserID == userID && identity.Provider == provider {
+			r.identities = append(r.identities[:i], r.identities[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newOIDCTestService(t *testing.T, userRepo *MockUserRepository) (*OIDCService, *mockOIDCIssuer, *memoryIdentityRepository) {
+	util.Logger = zap.NewNop()
+	issuer := newMockOIDCIssuer(t)
+	identityRepo := newMemoryIdentityRepository()
+	service := NewOIDCService(identityRepo, userRepo, map[string]config.OIDCProviderConfig{
+		"mock": {
+			Issuer:       issuer.server.URL,
+			ClientID:     mockOIDCClientID,
+			ClientSecret: mockOIDCClientSecret,
+			RedirectURL:  mockOIDCRedirectURL,
+		},
+	})
+	return service, issuer, identityRepo
+}
+
+// TestOIDCLoginProvisionsUser 测试首次第三方登录自动创建账户，再次登录直接返回该账户
+func TestOIDCLoginProvisionsUser(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	service, issuer, identityRepo := newOIDCTestService(t, userRepo)
+	claims := jwt.MapClaims{"sub": "subject-1", "email": "alice@example.com", "email_verified": true, "preferred_username": "alice"}
+
+	userRepo.On("FindByEmail", "alice@example.com").Return(nil, sql.ErrNoRows)
+	userRepo.On("FindByUsername", "alice").Return(&model.User{ID: 1, Username: "alice"}, nil)
+	userRepo.On("FindByUsername", mock.AnythingOfType("string")).Return(nil, nil)
+
+	auth, err := service.StartLogin("mock")
+	assert.NoError(t, err)
+	code, state := issuer.authorize(t, auth.URL, claims)
+	result, err := service.Callback("mock", code, state, auth.Binding)
+	assert.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.False(t, result.Linked)
+	assert.Equal(t, "alice@example.com", result.User.Email)
+	assert.Regexp(t, `^alice_[0-9a-f]{6}$`, result.User.Username, "用户名已被占用时追加随机后缀")
+	assert.Empty(t, result.User.PasswordHash)
+	assert.Equal(t, model.UserStatusActive, result.User.Status)
+	assert.Len(t, identityRepo.identities, 1)
+
+	userRepo.On("FindByID", result.User.ID).Return(result.User, nil)
+	auth, err = service.StartLogin("mock")
+	assert.NoError(t, err)
+	code, state = issuer.authorize(t, auth.URL, claims)
+	again, err := service.Callback("mock", code, state, auth.Binding)
+	assert.NoError(t, err)
+	assert.False(t, again.Created)
+	assert.Equal(t, result.User.ID, again.User.ID)
+	assert.Len(t, identityRepo.users, 1)
+}
+
+// TestOIDCCallbackRejectsInvalidRequests 测试 state 只能使用一次，nonce、受众不匹配或邮箱已注册时拒绝登录
+func TestOIDCCallbackRejectsInvalidRequests(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	service, issuer, _ := newOIDCTestService(t, userRepo)
+	userRepo.On("FindByEmail", "bob@example.com").Return(&model.User{ID: 2, Email: "bob@example.com"}, nil)
+
+	_, err := service.StartLogin("unknown")
+	assert.ErrorIs(t, err, ErrOIDCProviderNotFound)
+
+	auth, err := service.StartLogin("mock")
+	assert.NoError(t, err)
+	code, state := issuer.authorize(t, auth.URL, jwt.MapClaims{"sub": "subject-2", "email": "bob@example.com", "email_verified": true})
+	_, err = service.Callback("mock", code, state, auth.Binding)
+	assert.ErrorIs(t, err, ErrOIDCEmailInUse, "已注册的邮箱不自动合并")
+	_, err = service.Callback("mock", code, state, auth.Binding)
+	assert.ErrorIs(t, err, ErrInvalidOIDCState, "state 只能使用一次")
+
+	auth, err = service.StartLogin("mock")
+	assert.NoError(t, err)
+	code, state = issuer.authorize(t, auth.URL, jwt.MapClaims{"sub": "subject-2", "nonce": "forged"})
+	_, err = service.Callback("mock", code, state, auth.Binding)
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	auth, err = service.StartLogin("mock")
+	assert.NoError(t, err)
+	code, state = issuer.authorize(t, auth.URL, jwt.MapClaims{"sub": "subject-2", "aud": "another-client"})
+	_, err = service.Callback("mock", code, state, auth.Binding)
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	auth, err = service.StartLogin("mock")
+	assert.NoError(t, err)
+	code, state = issuer.authorize(t, auth.URL, jwt.MapClaims{"sub": "subject-3", "email": "carol@example.com", "email_verified": false})
+	_, err = service.Callback("mock", code, state, auth.Binding)
+	assert.ErrorIs(t, err, ErrOIDCEmailRequired)
+}
+
+// TestOIDCLinkAndUnlink 测试已登录用户绑定和解绑第三方账户，唯一的登录方式不能解绑
+func TestOIDCLinkAndUnlink(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	service, issuer, identityRepo := newOIDCTestService(t, userRepo)
+	userRepo.On("GetUserStatus", 7).Return(model.UserStatusActive, nil)
+	userRepo.On("GetUserStatus", 8).Return(model.UserStatusActive, nil)
+
+	auth, err := service.StartLink("mock", 7)
+	assert.NoError(t, err)
+	code, state := issuer.authorize(t, auth.URL, jwt.MapClaims{"sub": "subject-7", "email": "dave@example.com"})
+	result, err := service.LinkCallback("mock", code, state, auth.Binding, 7)
+	assert.NoError(t, err)
+	assert.True(t, result.Linked)
+	assert.Equal(t, 7, result.Identity.UserID)
+
+	// 同一第三方账户不能再绑定到其他用户
+	auth, err = service.StartLink("mock", 8)
+	assert.NoError(t, err)
+	code, state = issuer.authorize(t, auth.URL, jwt.MapClaims{"sub": "subject-7"})
+	_, err = service.LinkCallback("mock", code, state, auth.Binding, 8)
+	assert.ErrorIs(t, err, ErrIdentityAlreadyLinked)
+
+	userRepo.On("FindByID", 7).Return(&model.User{ID: 7, PasswordHash: ""}, nil).Once()
+	assert.ErrorIs(t, service.Unlink(7, "mock"), ErrLastLoginMethod)
+
+	userRepo.On("FindByID", 7).Return(&model.User{ID: 7, PasswordHash: "hash"}, nil)
+	assert.NoError(t, service.Unlink(7, "mock"))
+	assert.Empty(t, identityRepo.identities)
+	assert.ErrorIs(t, service.Unlink(7, "mock"), ErrIdentityNotLinked)
+}
+
+// TestOIDCCallbackRequiresBrowserBinding 测试回调必须来自发起授权的浏览器，
+// 绑定流程的 state 只能由发起绑定的用户在登录状态下使用
+func TestOIDCCallbackRequiresBrowserBinding(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	service, issuer, identityRepo := newOIDCTestService(t, userRepo)
+	userRepo.On("GetUserStatus", 7).Return(model.UserStatusActive, nil)
+	claims := jwt.MapClaims{"sub": "subject-7", "email": "erin@example.com", "email_verified": true}
+
+	// 授权链接被转发给其他浏览器，缺少或不匹配的 Cookie 都被拒绝
+	auth, err := service.StartLogin("mock")
+	assert.NoError(t, err)
+	code, state := issuer.authorize(t, auth.URL, claims)
+	_, err = service.Callback("mock", code, state, "")
+	assert.ErrorIs(t, err, ErrInvalidOIDCState)
+
+	auth, err = service.StartLogin("mock")
+	assert.NoError(t, err)
+	other, err := service.StartLogin("mock")
+	assert.NoError(t, err)
+	code, state = issuer.authorize(t, auth.URL, claims)
+	_, err = service.Callback("mock", code, state, other.Binding)
+	assert.ErrorIs(t, err, ErrInvalidOIDCState)
+
+	// 攻击者发起的绑定不能由其他用户完成，也不能通过未登录的回调完成
+	auth, err = service.StartLink("mock", 7)
+	assert.NoError(t, err)
+	code, state = issuer.authorize(t, auth.URL, claims)
+	_, err = service.LinkCallback("mock", code, state, auth.Binding, 9)
+	assert.ErrorIs(t, err, ErrInvalidOIDCState)
+
+	auth, err = service.StartLink("mock", 7)
+	assert.NoError(t, err)
+	code, state = issuer.authorize(t, auth.URL, claims)
+	_, err = service.Callback("mock", code, state, auth.Binding)
+	assert.ErrorIs(t, err, ErrInvalidOIDCState)
+
+	// 登录流程的 state 不能用于绑定
+	auth, err = service.StartLogin("mock")
+	assert.NoError(t, err)
+	code, state = issuer.authorize(t, auth.URL, claims)
+	_, err = service.LinkCallback("mock", code, state, auth.Binding, 7)
+	assert.ErrorIs(t, err, ErrInvalidOIDCState)
+
+	assert.Empty(t, identityRepo.identities)
+}
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/gateway"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrOrderNotModifiable 只有已支付的订单可以修改或取消
+	ErrOrderNotModifiable = errors.New("订单当前状态不允许修改或取消")
+	// ErrProjectNotLive 项目不在众筹中或已截止
+	ErrProjectNotLive = errors.New("项目不在众筹中或已截止")
+	// ErrExtraChargeIncomplete 补差价扣款未能立即完成
+	ErrExtraChargeIncomplete = errors.New("补差价扣款未能完成，请稍后重试")
+	// ErrOrderChanged 报价后订单已被其他请求修改
+	ErrOrderChanged = errors.New("订单已被修改，请刷新后重试")
+)
+
+// staleExtraChargeAge 待确认的补扣款超过该时长仍未应用到订单时由对账任务退回
+const staleExtraChargeAge = 10 * time.Minute
+
+// getLiveProject 获取项目并校验项目处于众筹中且未截止
+func (s *PaymentService) getLiveProject(projectID int) (*model.Project, error) {
+	project, err := s.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.Status != ProjectStatusActive {
+		return nil, ErrProjectNotLive
+	}
+	ended, err := s.paymentRepo.CheckProjectEndDate(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if ended {
+		return nil, ErrProjectNotLive
+	}
+	return project, nil
+}
+
+// lockChangeableOrder 在事务中锁定订单，并校验订单属于当前用户、已支付且项目仍在众筹中
+func (s *PaymentService) lockChangeableOrder(tx *sql.Tx, orderID, userID int) (*model.Order, *model.Project, error) {
+	order, err := s.paymentRepo.LockOrderTx(tx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order == nil {
+		return nil, nil, ErrOrderNotFound
+	}
+	if order.UserID != userID {
+		return nil, nil, ErrOrderNotOwned
+	}
+	if order.Status != OrderStatusPaid {
+		return nil, nil, ErrOrderNotModifiable
+	}
+	project, err := s.getLiveProject(order.ProjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, project, nil
+}
+
+// toOriginalAmount 按订单下单时的汇率将项目币种金额折算为扣款币种，保证多次修改后金额一致
+func toOriginalAmount(amount model.Money, order *model.Order) (model.Money, error) {
+	if order.OriginalCurrency == order.Currency {
+		return model.NewMoney(amount.Cents, order.OriginalCurrency), nil
+	}
+	rate, err := parseRate(order.ExchangeRate)
+	if err != nil {
+		return model.Money{}, err
+	}
+	return amount.Convert(new(big.Rat).Inv(rate), order.OriginalCurrency), nil
+}
+
+// negate 取相反数，用于把负的差额转为退款金额
+func negate(m model.Money) model.Money {
+	return model.NewMoney(-m.Cents, m.Currency)
+}
+
+// ModifyOrder 众筹期间修改已支付订单的档位、加购项、额外支持金额或收货地址。
+// 订单金额按当前目录价格重新计算，差额按下单汇率折算为扣款币种：
+// 增加的部分立即补扣款，减少的部分原路退回；项目已筹金额和进度同步调整。
+// 补扣款在锁定订单和项目之前完成，并先记录为待确认的变更，中断后由定时任务退回
+func (s *PaymentService) ModifyOrder(orderID, userID int, payment *model.Payment, addressID int) (*model.Order, *model.OrderChange, error) {
+	order, err := s.paymentRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order == nil {
+		return nil, nil, ErrOrderNotFound
+	}
+	if order.UserID != userID {
+		return nil, nil, ErrOrderNotOwned
+	}
+	if order.Status != OrderStatusPaid {
+		return nil, nil, ErrOrderNotModifiable
+	}
+	project, err := s.getLiveProject(order.ProjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payment.UserID = userID
+	payment.ProjectID = project.ID
+	quote, err := s.quoteOrder(project, payment, addressID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	amount := quote.total()
+	delta := amount.Sub(order.Amount)
+	originalDelta, err := toOriginalAmount(delta, order)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !order.OriginalAmount.Add(originalDelta).IsPositive() {
+		return nil, nil, ErrInvalidAmount
+	}
+
+	change := &model.OrderChange{
+		OrderID:         order.ID,
+		ChangeType:      model.OrderChangeModify,
+		Status:          model.OrderChangeStatusApplied,
+		Actor:           UserActor(userID),
+		OldRewardTierID: order.RewardTierID,
+		NewRewardTierID: payment.RewardTierID,
+		OldAmount:       order.Amount,
+		NewAmount:       amount,
+		AmountDelta:     delta,
+		OriginalDelta:   originalDelta,
+	}
+	if originalDelta.IsPositive() {
+		change.Status = model.OrderChangeStatusPending
+		if err := s.paymentRepo.CreateOrderChange(change); err != nil {
+			return nil, nil, err
+		}
+		if err := s.captureExtraCharge(order, change); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	refunds, err := s.applyOrderModification(order, project, payment, quote, change)
+	if err != nil {
+		s.releaseExtraCharge(change)
+		return nil, nil, err
+	}
+
+	s.executeChangeRefunds(refunds)
+
+	util.Logger.Info("订单已修改",
+		zap.Int("order_id", order.ID),
+		zap.Int("order_change_id", change.ID),
+		zap.Stringer("old_amount", change.OldAmount),
+		zap.Stringer("new_amount", change.NewAmount),
+		zap.Stringer("original_delta", change.OriginalDelta))
+	return order, change, nil
+}
+
+// applyOrderModification 在短事务中锁定订单和项目，应用已报价的修改。
+// 订单在报价后被其他请求修改过时拒绝，避免按过期的金额计算差额
+func (s *PaymentService) applyOrderModification(snapshot *model.Order, project *model.Project, payment *model.Payment,
+	quote *orderQuote, change *model.OrderChange) ([]*model.OrderRefund, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		util.Logger.Error("开始事务失败", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	order, _, err := s.lockChangeableOrder(tx, snapshot.ID, snapshot.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Amount.Cmp(snapshot.Amount) != 0 || order.OriginalAmount.Cmp(snapshot.OriginalAmount) != 0 {
+		return nil, ErrOrderChanged
+	}
+
+	// 先释放原有库存再占用新选择的库存，保留同一档位时不会因售罄被拒绝
+	if err := s.paymentRepo.ReleaseOrderStockTx(tx, order.ID); err != nil {
+		return nil, err
+	}
+	if err := s.claimStock(tx, project.ID, quote.tier, quote.cart); err != nil {
+		return nil, err
+	}
+	if err := s.paymentRepo.ReplaceOrderItemsTx(tx, order.ID, quote.items); err != nil {
+		return nil, fmt.Errorf("failed to replace order items: %w", err)
+	}
+
+	// 退款分摊需要按修改前的扣款计算
+	var intents []model.OrderPaymentIntent
+	if change.OriginalDelta.IsNegative() {
+		if intents, err = s.paymentRepo.GetOrderPaymentIntents(order.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	before := *order
+	order.Subtotal = quote.subtotal
+	order.ShippingFee = quote.shippingFee
+	order.Amount = change.NewAmount
+	order.OriginalAmount = order.OriginalAmount.Add(change.OriginalDelta)
+	order.IsReward = payment.RewardTierID != nil
+	order.RewardTierID = payment.RewardTierID
+	order.AddressID = quote.addressID()
+	order.Items = quote.items
+	if err := s.paymentRepo.UpdateOrderSelectionTx(tx, order); err != nil {
+		return nil, err
+	}
+	if !change.AmountDelta.IsZero() {
+		if err := s.paymentRepo.AddProjectAmountTx(tx, project.ID, change.AmountDelta); err != nil {
+			return nil, err
+		}
+	}
+
+	if change.Status == model.OrderChangeStatusPending {
+		applied, err := s.paymentRepo.UpdateOrderChangeStatusTx(tx, change.ID, model.OrderChangeStatusApplied)
+		if err != nil {
+			return nil, err
+		}
+		if !applied {
+			// 补扣款已被对账任务退回
+			return nil, ErrExtraChargeIncomplete
+		}
+	} else if err := s.paymentRepo.CreateOrderChangeTx(tx, change); err != nil {
+		return nil, err
+	}
+
+	var refunds []*model.OrderRefund
+	if change.OriginalDelta.IsNegative() {
+		refunds, err = s.createChangeRefundsTx(tx, &before, intents, change, negate(change.OriginalDelta), negate(change.AmountDelta))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		util.Logger.Error("提交事务失败", zap.Error(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	change.Status = model.OrderChangeStatusApplied
+	*snapshot = *order
+	return refunds, nil
+}
+
+// CancelOrder 众筹期间取消已支付订单：释放库存、扣减项目已筹金额，并将已支付金额原路退回
+func (s *PaymentService) CancelOrder(orderID, userID int) (*model.Order, *model.OrderChange, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		util.Logger.Error("开始事务失败", zap.Error(err))
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	order, project, err := s.lockChangeableOrder(tx, orderID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !CanTransitionOrder(order.Status, OrderStatusCancelled) {
+		return nil, nil, &InvalidTransitionError{Entity: "order", From: order.Status, To: OrderStatusCancelled}
+	}
+
+	intents, err := s.paymentRepo.GetOrderPaymentIntents(order.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	actor := UserActor(userID)
+	cancelled, err := s.paymentRepo.CancelOrderTx(tx, order.ID, actor, "支持者取消支持")
+	if err != nil {
+		return nil, nil, err
+	}
+	if !cancelled {
+		return nil, nil, ErrOrderNotModifiable
+	}
+	if err := s.paymentRepo.AddProjectAmountTx(tx, project.ID, negate(order.Amount)); err != nil {
+		return nil, nil, err
+	}
+
+	change := &model.OrderChange{
+		OrderID:         order.ID,
+		ChangeType:      model.OrderChangeCancel,
+		Status:          model.OrderChangeStatusApplied,
+		Actor:           actor,
+		OldRewardTierID: order.RewardTierID,
+		OldAmount:       order.Amount,
+		NewAmount:       model.NewMoney(0, order.Currency),
+		AmountDelta:     negate(order.Amount),
+		OriginalDelta:   negate(order.OriginalAmount),
+	}
+	if err := s.paymentRepo.CreateOrderChangeTx(tx, change); err != nil {
+		return nil, nil, err
+	}
+	refunds, err := s.createChangeRefundsTx(tx, order, intents, change, order.OriginalAmount, order.Amount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		util.Logger.Error("提交事务失败", zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	order.Status = OrderStatusCancelled
+
+	s.executeChangeRefunds(refunds)
+
+	util.Logger.Info("订单已取消",
+		zap.Int("order_id", order.ID),
+		zap.Int("order_change_id", change.ID),
+		zap.Stringer("refund_amount", order.OriginalAmount))
+	return order, change, nil
+}
+
+// GetOrderChanges 获取订单的修改和取消记录，只有下单用户可以查看
+func (s *PaymentService) GetOrderChanges(orderID, userID int) ([]*model.OrderChange, error) {
+	order, err := s.paymentRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	if order.UserID != userID {
+		return nil, ErrOrderNotOwned
+	}
+	return s.paymentRepo.GetOrderChanges(orderID)
+}
+
+// captureExtraCharge 为待确认的订单变更创建单独的支付意图并立即扣款。
+// 支付意图在扣款前保存到变更记录，扣款结果不确定时变更保持待确认，由对账任务处理
+func (s *PaymentService) captureExtraCharge(order *model.Order, change *model.OrderChange) error {
+	intent, err := s.gateway.CreateIntent(&gateway.IntentRequest{
+		Amount:      change.OriginalDelta,
+		OrderNumber: order.OrderNumber,
+		Metadata: map[string]string{
+			"order_id":        fmt.Sprintf("%d", order.ID),
+			"project_id":      fmt.Sprintf("%d", order.ProjectID),
+			"order_change":    "extra_charge",
+			"order_change_id": fmt.Sprintf("%d", change.ID),
+		},
+	})
+	if err != nil {
+		util.Logger.Error("创建补差价支付意图失败", zap.Error(err), zap.Int("order_id", order.ID))
+		s.markOrderChangeFailed(change)
+		return fmt.Errorf("failed to create payment intent: %w", err)
+	}
+	if err := s.paymentRepo.UpdateOrderChangeIntent(change.ID, intent.ID); err != nil {
+		s.markOrderChangeFailed(change)
+		return err
+	}
+	change.PaymentIntentID = intent.ID
+
+	intent, err = s.gateway.Capture(intent.ID)
+	if err != nil {
+		util.Logger.Warn("补差价扣款失败", zap.Error(err), zap.Int("order_id", order.ID))
+		if errors.Is(err, gateway.ErrCaptureDeclined) {
+			s.markOrderChangeFailed(change)
+			return ErrPaymentDeclined
+		}
+		return fmt.Errorf("failed to capture payment: %w", err)
+	}
+	// 补扣款的回调无法关联到订单，只接受同步完成的扣款
+	if intent.Status != gateway.IntentSucceeded {
+		util.Logger.Warn("补差价扣款未同步完成",
+			zap.Int("order_id", order.ID),
+			zap.String("intent_id", intent.ID),
+			zap.String("intent_status", string(intent.Status)))
+		return ErrExtraChargeIncomplete
+	}
+	return nil
+}
+
+// releaseExtraCharge 修改未能应用时退回已完成的补扣款，退回失败的变更保持待确认，由对账任务重试
+func (s *PaymentService) releaseExtraCharge(change *model.OrderChange) {
+	if change.Status != model.OrderChangeStatusPending || change.PaymentIntentID == "" {
+		return
+	}
+	if _, err := s.gateway.Refund(change.PaymentIntentID, change.OriginalDelta); err != nil {
+		util.Logger.Error("退回补差价扣款失败，等待对账任务重试",
+			zap.Error(err),
+			zap.Int("order_id", change.OrderID),
+			zap.String("intent_id", change.PaymentIntentID),
+			zap.Stringer("amount", change.OriginalDelta))
+		return
+	}
+	s.markOrderChangeFailed(change)
+}
+
+// markOrderChangeFailed 将待确认的订单变更标记为失败
+func (s *PaymentService) markOrderChangeFailed(change *model.OrderChange) {
+	if _, err := s.paymentRepo.UpdateOrderChangeStatus(change.ID, model.OrderChangeStatusFailed); err != nil {
+		util.Logger.Error("标记订单变更失败状态失败", zap.Error(err), zap.Int("order_change_id", change.ID))
+		return
+	}
+	change.Status = model.OrderChangeStatusFailed
+}
+
+// ReleaseStaleExtraCharges 退回长时间仍待确认的补扣款，由定时任务调用。
+// 这些变更的修改未能应用到订单（进程中断或扣款结果不确定），按支付渠道的实际状态退款或直接标记失败
+func (s *PaymentService) ReleaseStaleExtraCharges() error {
+	changes, err := s.paymentRepo.GetStalePendingOrderChanges(time.Now().Add(-staleExtraChargeAge), refundRetryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		if change.PaymentIntentID == "" {
+			s.markOrderChangeFailed(change)
+			continue
+		}
+		intent, err := s.gateway.QueryStatus(change.PaymentIntentID)
+		if err != nil {
+			util.Logger.Error("查询补差价扣款状态失败", zap.Error(err), zap.Int("order_change_id", change.ID))
+			continue
+		}
+		switch intent.Status {
+		case gateway.IntentSucceeded:
+			s.releaseExtraCharge(change)
+		case gateway.IntentRequiresCapture, gateway.IntentFailed, gateway.IntentRefunded:
+			s.markOrderChangeFailed(change)
+		}
+		// 仍在处理中的扣款等待下一轮
+	}
+	return nil
+}
+
+// createChangeRefundsTx 在事务中为订单变更创建退款执行记录，退款分摊到修改前的各笔扣款
+func (s *PaymentService) createChangeRefundsTx(tx *sql.Tx, order *model.Order, intents []model.OrderPaymentIntent,
+	change *model.OrderChange, amount, projectAmount model.Money) ([]*model.OrderRefund, error) {
+	refunds, err := buildOrderRefunds(order, intents, amount, projectAmount)
+	if err != nil {
+		return nil, err
+	}
+	for _, refund := range refunds {
+		refund.OrderChangeID = &change.ID
+		if err := s.paymentRepo.CreateOrderRefundTx(tx, refund); err != nil {
+			return nil, err
+		}
+	}
+	return refunds, nil
+}
+
+// executeChangeRefunds 立即执行订单变更产生的退款，失败的退款由重试任务继续处理
+func (s *PaymentService) executeChangeRefunds(refunds []*model.OrderRefund) {
+	for _, refund := range refunds {
+		if err := s.refunds.executeRefund(refund); err != nil {
+			util.Logger.Error("执行订单变更退款失败",
+				zap.Error(err),
+				zap.Int("refund_id", refund.ID),
+				zap.Int("order_id", refund.OrderID))
+		}
+	}
+}
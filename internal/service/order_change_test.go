@@ -0,0 +1,273 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/gateway"
+	"crowdfunding-backend/internal/model"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (m *MockPaymentRepository) CheckProjectEndDate(projectID int) (bool, error) {
+	args := m.Called(projectID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) LockOrderTx(tx *sql.Tx, orderID int) (*model.Order, error) {
+	args := m.Called(orderID)
+	order, _ := args.Get(0).(*model.Order)
+	return order, args.Error(1)
+}
+
+func (m *MockPaymentRepository) ReleaseOrderStockTx(tx *sql.Tx, orderID int) error {
+	args := m.Called(orderID)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) ReplaceOrderItemsTx(tx *sql.Tx, orderID int, items []model.OrderItem) error {
+	args := m.Called(orderID, items)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) UpdateOrderSelectionTx(tx *sql.Tx, order *model.Order) error {
+	args := m.Called(order)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) CancelOrderTx(tx *sql.Tx, orderID int, actor, reason string) (bool, error) {
+	args := m.Called(orderID, actor)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) AddProjectAmountTx(tx *sql.Tx, projectID int, amount model.Money) error {
+	args := m.Called(projectID, amount)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) CreateOrderChange(change *model.OrderChange) error {
+	args := m.Called(change)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) CreateOrderChangeTx(tx *sql.Tx, change *model.OrderChange) error {
+	args := m.Called(change)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) UpdateOrderChangeIntent(changeID int, intentID string) error {
+	args := m.Called(changeID, intentID)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) UpdateOrderChangeStatus(changeID int, status string) (bool, error) {
+	args := m.Called(changeID, status)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) UpdateOrderChangeStatusTx(tx *sql.Tx, changeID int, status string) (bool, error) {
+	args := m.Called(changeID, status)
+	return args.Bool(0), args.Error(1)
+}
+
+// newChangeableOrder 返回用户2在项目1中选择档位5、已支付 99 元的订单
+func newChangeableOrder(intentID string) *model.Order {
+	tierID := 5
+	return &model.Order{
+		ID: 9, OrderNumber: "ORD-2025-0009", UserID: 2, ProjectID: 1, Status: OrderStatusPaid,
+		Subtotal: model.NewMoney(9900, "CNY"), Amount: model.NewMoney(9900, "CNY"), Currency: "CNY",
+		OriginalAmount: model.NewMoney(9900, "CNY"), OriginalCurrency: "CNY", ExchangeRate: "1",
+		IsReward: true, RewardTierID: &tierID, PaymentProvider: "sandbox", PaymentIntentID: intentID,
+	}
+}
+
+func TestToOriginalAmount(t *testing.T) {
+	// 差额按下单时锁定的汇率折算为扣款币种
+	order := &model.Order{Currency: "CNY", OriginalCurrency: "USD", ExchangeRate: "7.2"}
+	amount, err := toOriginalAmount(model.NewMoney(72000, "CNY"), order)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(10000, "USD"), amount)
+
+	amount, err = toOriginalAmount(model.NewMoney(-3600, "CNY"), order)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(-500, "USD"), amount)
+
+	sameCurrency := &model.Order{Currency: "CNY", OriginalCurrency: "CNY", ExchangeRate: "1"}
+	amount, err = toOriginalAmount(model.NewMoney(1234, "CNY"), sameCurrency)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(1234, "CNY"), amount)
+}
+
+// TestOrderChangeRejected 测试非本人订单、项目不在众筹中或已截止时不能修改或取消
+func TestOrderChangeRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  int
+		status  string
+		ended   bool
+		wantErr error
+	}{
+		{"非本人订单", 3, ProjectStatusActive, false, ErrOrderNotOwned},
+		{"项目不在众筹中", 2, ProjectStatusCompleted, false, ErrProjectNotLive},
+		{"项目已截止", 2, ProjectStatusActive, true, ErrProjectNotLive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paymentRepo := new(MockPaymentRepository)
+			projectRepo := new(MockProjectRepository)
+			service := newPaymentTestService(t, paymentRepo, projectRepo, new(MockRewardRepository), gateway.NewSandboxGateway())
+
+			paymentRepo.On("GetOrderByID", 9).Return(newChangeableOrder("pi_1"), nil)
+			paymentRepo.On("LockOrderTx", 9).Return(newChangeableOrder("pi_1"), nil)
+			paymentRepo.On("CheckProjectEndDate", 1).Return(tt.ended, nil)
+			projectRepo.On("GetProjectByID", 1).Return(&model.Project{ID: 1, Currency: "CNY", Status: tt.status}, nil)
+
+			_, _, err := service.ModifyOrder(9, tt.userID, &model.Payment{}, 0)
+			assert.ErrorIs(t, err, tt.wantErr)
+			_, _, err = service.CancelOrder(9, tt.userID)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			paymentRepo.AssertNotCalled(t, "ReleaseOrderStockTx", mock.Anything)
+			paymentRepo.AssertNotCalled(t, "CancelOrderTx", mock.Anything, mock.Anything)
+			paymentRepo.AssertNotCalled(t, "AddProjectAmountTx", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// TestModifyOrderDowngrade 测试改选较便宜的档位：释放原库存后占用新档位，项目已筹金额扣减差额并退回差价
+func TestModifyOrderDowngrade(t *testing.T) {
+	paymentRepo := new(MockPaymentRepository)
+	projectRepo := new(MockProjectRepository)
+	rewardRepo := new(MockRewardRepository)
+	sandbox := gateway.NewSandboxGateway()
+	service := newPaymentTestService(t, paymentRepo, projectRepo, rewardRepo, sandbox)
+	intentID := capturedSandboxIntent(t, sandbox, model.NewMoney(9900, "CNY"))
+
+	newTierID := 6
+	projectRepo.On("GetProjectByID", 1).Return(&model.Project{ID: 1, Currency: "CNY", Status: ProjectStatusActive}, nil)
+	rewardRepo.On("GetRewardTier", newTierID).Return(&model.RewardTier{ID: newTierID, ProjectID: 1, Title: "基础版", Price: model.NewMoney(4900, "CNY")}, nil)
+	rewardRepo.On("ClaimRewardTierTx", newTierID, 1).Return(true, nil)
+	paymentRepo.On("GetOrderByID", 9).Return(newChangeableOrder(intentID), nil)
+	paymentRepo.On("LockOrderTx", 9).Return(newChangeableOrder(intentID), nil)
+	paymentRepo.On("CheckProjectEndDate", 1).Return(false, nil)
+	paymentRepo.On("ReleaseOrderStockTx", 9).Return(nil)
+	paymentRepo.On("ReplaceOrderItemsTx", 9, mock.Anything).Return(nil)
+	paymentRepo.On("GetOrderPaymentIntents", 9).Return([]model.OrderPaymentIntent{
+		{Provider: "sandbox", IntentID: intentID, Captured: model.NewMoney(9900, "CNY")},
+	}, nil)
+	paymentRepo.On("UpdateOrderSelectionTx", mock.Anything).Return(nil)
+	paymentRepo.On("AddProjectAmountTx", 1, model.NewMoney(-5000, "CNY")).Return(nil)
+	paymentRepo.On("CreateOrderChangeTx", mock.Anything).Return(nil)
+	paymentRepo.On("CreateOrderRefundTx", mock.MatchedBy(func(refund *model.OrderRefund) bool {
+		return refund.PaymentIntentID == intentID && refund.Amount == model.NewMoney(5000, "CNY")
+	})).Return(nil)
+	paymentRepo.On("ClaimOrderRefund", 0, 0).Return(false, nil)
+
+	order, change, err := service.ModifyOrder(9, 2, &model.Payment{RewardTierID: &newTierID}, 0)
+	assert.NoError(t, err)
+	paymentRepo.AssertExpectations(t)
+	rewardRepo.AssertExpectations(t)
+	assert.Equal(t, model.NewMoney(4900, "CNY"), order.Amount)
+	assert.Equal(t, model.NewMoney(4900, "CNY"), order.OriginalAmount)
+	assert.Equal(t, newTierID, *order.RewardTierID)
+	assert.Equal(t, model.NewMoney(-5000, "CNY"), change.AmountDelta)
+	assert.Equal(t, model.OrderChangeStatusApplied, change.Status)
+}
+
+// TestModifyOrderUpgrade 测试改选较贵的档位：先补扣差价再应用修改，项目已筹金额增加差额
+func TestModifyOrderUpgrade(t *testing.T) {
+	paymentRepo := new(MockPaymentRepository)
+	projectRepo := new(MockProjectRepository)
+	rewardRepo := new(MockRewardRepository)
+	sandbox := gateway.NewSandboxGateway()
+	service := newPaymentTestService(t, paymentRepo, projectRepo, rewardRepo, sandbox)
+
+	newTierID := 7
+	projectRepo.On("GetProjectByID", 1).Return(&model.Project{ID: 1, Currency: "CNY", Status: ProjectStatusActive}, nil)
+	rewardRepo.On("GetRewardTier", newTierID).Return(&model.RewardTier{ID: newTierID, ProjectID: 1, Title: "典藏版", Price: model.NewMoney(14900, "CNY")}, nil)
+	rewardRepo.On("ClaimRewardTierTx", newTierID, 1).Return(true, nil)
+	paymentRepo.On("GetOrderByID", 9).Return(newChangeableOrder("pi_1"), nil)
+	paymentRepo.On("LockOrderTx", 9).Return(newChangeableOrder("pi_1"), nil)
+	paymentRepo.On("CheckProjectEndDate", 1).Return(false, nil)
+	paymentRepo.On("CreateOrderChange", mock.MatchedBy(func(change *model.OrderChange) bool {
+		return change.Status == model.OrderChangeStatusPending && change.OriginalDelta == model.NewMoney(5000, "CNY")
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.OrderChange).ID = 3
+	}).Return(nil)
+	paymentRepo.On("UpdateOrderChangeIntent", 3, mock.Anything).Return(nil)
+	paymentRepo.On("ReleaseOrderStockTx", 9).Return(nil)
+	paymentRepo.On("ReplaceOrderItemsTx", 9, mock.Anything).Return(nil)
+	paymentRepo.On("UpdateOrderSelectionTx", mock.Anything).Return(nil)
+	paymentRepo.On("AddProjectAmountTx", 1, model.NewMoney(5000, "CNY")).Return(nil)
+	paymentRepo.On("UpdateOrderChangeStatusTx", 3, model.OrderChangeStatusApplied).Return(true, nil)
+
+	order, change, err := service.ModifyOrder(9, 2, &model.Payment{RewardTierID: &newTierID}, 0)
+	assert.NoError(t, err)
+	paymentRepo.AssertExpectations(t)
+	assert.Equal(t, model.NewMoney(14900, "CNY"), order.OriginalAmount)
+	assert.Equal(t, model.OrderChangeStatusApplied, change.Status)
+	assert.NotEmpty(t, change.PaymentIntentID)
+	paymentRepo.AssertNotCalled(t, "CreateOrderChangeTx", mock.Anything)
+}
+
+// TestModifyOrderSoldOutRefundsExtraCharge 测试新档位售罄时拒绝修改，已补扣的差价退回
+func TestModifyOrderSoldOutRefundsExtraCharge(t *testing.T) {
+	paymentRepo := new(MockPaymentRepository)
+	projectRepo := new(MockProjectRepository)
+	rewardRepo := new(MockRewardRepository)
+	sandbox := gateway.NewSandboxGateway()
+	service := newPaymentTestService(t, paymentRepo, projectRepo, rewardRepo, sandbox)
+
+	newTierID := 7
+	projectRepo.On("GetProjectByID", 1).Return(&model.Project{ID: 1, Currency: "CNY", Status: ProjectStatusActive}, nil)
+	rewardRepo.On("GetRewardTier", newTierID).Return(&model.RewardTier{ID: newTierID, ProjectID: 1, Title: "典藏版", Price: model.NewMoney(14900, "CNY")}, nil)
+	rewardRepo.On("ClaimRewardTierTx", newTierID, 1).Return(false, nil)
+	paymentRepo.On("GetOrderByID", 9).Return(newChangeableOrder("pi_1"), nil)
+	paymentRepo.On("LockOrderTx", 9).Return(newChangeableOrder("pi_1"), nil)
+	paymentRepo.On("CheckProjectEndDate", 1).Return(false, nil)
+	paymentRepo.On("CreateOrderChange", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.OrderChange).ID = 3
+	}).Return(nil)
+	paymentRepo.On("UpdateOrderChangeIntent", 3, mock.Anything).Return(nil)
+	paymentRepo.On("ReleaseOrderStockTx", 9).Return(nil)
+	paymentRepo.On("UpdateOrderChangeStatus", 3, model.OrderChangeStatusFailed).Return(true, nil)
+
+	_, _, err := service.ModifyOrder(9, 2, &model.Payment{RewardTierID: &newTierID}, 0)
+	assert.ErrorIs(t, err, ErrRewardTierSoldOut)
+	paymentRepo.AssertExpectations(t)
+	paymentRepo.AssertNotCalled(t, "AddProjectAmountTx", mock.Anything, mock.Anything)
+}
+
+// TestCancelOrder 测试取消订单：释放库存、扣减项目已筹金额并原路退回全部扣款
+func TestCancelOrder(t *testing.T) {
+	paymentRepo := new(MockPaymentRepository)
+	projectRepo := new(MockProjectRepository)
+	sandbox := gateway.NewSandboxGateway()
+	service := newPaymentTestService(t, paymentRepo, projectRepo, new(MockRewardRepository), sandbox)
+	intentID := capturedSandboxIntent(t, sandbox, model.NewMoney(9900, "CNY"))
+
+	projectRepo.On("GetProjectByID", 1).Return(&model.Project{ID: 1, Currency: "CNY", Status: ProjectStatusActive}, nil)
+	paymentRepo.On("LockOrderTx", 9).Return(newChangeableOrder(intentID), nil)
+	paymentRepo.On("CheckProjectEndDate", 1).Return(false, nil)
+	paymentRepo.On("GetOrderPaymentIntents", 9).Return([]model.OrderPaymentIntent{
+		{Provider: "sandbox", IntentID: intentID, Captured: model.NewMoney(9900, "CNY")},
+	}, nil)
+	// 库存在 CancelOrderTx 中随订单状态一起释放
+	paymentRepo.On("CancelOrderTx", 9, UserActor(2)).Return(true, nil)
+	paymentRepo.On("AddProjectAmountTx", 1, model.NewMoney(-9900, "CNY")).Return(nil)
+	paymentRepo.On("CreateOrderChangeTx", mock.MatchedBy(func(change *model.OrderChange) bool {
+		return change.ChangeType == model.OrderChangeCancel && change.AmountDelta == model.NewMoney(-9900, "CNY")
+	})).Return(nil)
+	paymentRepo.On("CreateOrderRefundTx", mock.MatchedBy(func(refund *model.OrderRefund) bool {
+		return refund.PaymentIntentID == intentID && refund.Amount == model.NewMoney(9900, "CNY")
+	})).Return(nil)
+	paymentRepo.On("ClaimOrderRefund", 0, 0).Return(false, nil)
+
+	order, change, err := service.CancelOrder(9, 2)
+	assert.NoError(t, err)
+	paymentRepo.AssertExpectations(t)
+	assert.Equal(t, OrderStatusCancelled, order.Status)
+	assert.Equal(t, model.OrderChangeStatusApplied, change.Status)
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// 订单状态
+const (
+	OrderStatusPending            = "pending"
+	OrderStatusPaid               = "paid"
+	OrderStatusFailed             = "failed"
+	OrderStatusReadyToShip        = "ready_to_ship"
+	OrderStatusShipped            = "shipped"
+	OrderStatusDelivered          = "delivered"
+	OrderStatusRefundPending      = "refund_pending"
+	OrderStatusRefunded           = "refunded"
+	OrderStatusRefundRejected     = "refund_rejected"
+	OrderStatusCrowdfundingFailed = "crowdfunding_failed"
+	OrderStatusCancelled          = "cancelled"
+)
+
+// ActorSystem 定时任务等系统操作的操作者标识
+const ActorSystem = "system"
+
+// orderTransitions 订单状态允许的流转，未列出的流转一律拒绝
+var orderTransitions = map[string][]string{
+	OrderStatusPending:            {OrderStatusPaid, OrderStatusFailed, OrderStatusCrowdfundingFailed},
+	OrderStatusPaid:               {OrderStatusReadyToShip, OrderStatusRefundPending, OrderStatusCrowdfundingFailed, OrderStatusCancelled},
+	OrderStatusReadyToShip:        {OrderStatusShipped, OrderStatusRefundPending},
+	OrderStatusShipped:            {OrderStatusDelivered, OrderStatusRefundPending},
+	OrderStatusDelivered:          {OrderStatusRefundPending},
+	OrderStatusRefundPending:      {OrderStatusRefunded, OrderStatusRefundRejected, OrderStatusCrowdfundingFailed},
+	OrderStatusRefundRejected:     {OrderStatusRefundPending, OrderStatusShipped, OrderStatusDelivered, OrderStatusCrowdfundingFailed},
+	OrderStatusCrowdfundingFailed: {OrderStatusRefundPending, OrderStatusRefunded},
+}
+
+// InvalidTransitionError 非法的状态流转
+type InvalidTransitionError struct {
+	Entity string
+	From   string
+	To     string
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("invalid %s status transition from %q to %q", e.Entity, e.From, e.To)
+}
+
+// IsInvalidTransition 判断错误是否为非法状态流转
+func IsInvalidTransition(err error) bool {
+	var target *InvalidTransitionError
+	return errors.As(err, &target)
+}
+
+// UserActor 普通用户操作者标识
+func UserActor(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// AdminActor 管理员操作者标识
+func AdminActor(userID int) string {
+	return fmt.Sprintf("admin:%d", userID)
+}
+
+// WebhookActor 支付渠道回调操作者标识
+func WebhookActor(provider string) string {
+	return "webhook:" + provider
+}
+
+// CanTransitionOrder 判断订单是否允许从 from 流转到 to
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStateMachine 所有订单状态变更的唯一入口，校验流转并记录历史
+type OrderStateMachine struct {
+	paymentRepo interfaces.PaymentRepository
+}
+
+func NewOrderStateMachine(paymentRepo interfaces.PaymentRepository) *OrderStateMachine {
+	return &OrderStateMachine{paymentRepo: paymentRepo}
+}
+
+// Transition 将订单流转到新状态，成功后更新传入订单的状态
+func (m *OrderStateMachine) Transition(order *model.Order, to, actor, reason string) error {
+	return m.transition(order, to, actor, func() (bool, error) {
+		return m.paymentRepo.TransitionOrderStatus(order.ID, order.Status, to, actor, reason)
+	})
+}
+
+// TransitionTx 在调用方的事务中流转订单状态，用于需要与其他写入一起提交的流转
+func (m *OrderStateMachine) TransitionTx(tx *sql.Tx, order *model.Order, to, actor, reason string) error {
+	return m.transition(order, to, actor, func() (bool, error) {
+		return m.paymentRepo.TransitionOrderStatusTx(tx, order.ID, order.Status, to, actor, reason)
+	})
+}
+
+func (m *OrderStateMachine) transition(order *model.Order, to, actor string, update func() (bool, error)) error {
+	if !CanTransitionOrder(order.Status, to) {
+		util.Logger.Warn("拒绝非法的订单状态流转",
+			zap.Int("order_id", order.ID),
+			zap.String("from", order.Status),
+			zap.String("to", to),
+			zap.String("actor", actor))
+		return &InvalidTransitionError{Entity: "order", From: order.Status, To: to}
+	}
+
+	updated, err := update()
+	if err != nil {
+		return err
+	}
+	if !updated {
+		// 订单状态已被其他操作修改
+		current, err := m.paymentRepo.GetOrderByID(order.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return ErrOrderNotFound
+		}
+		return &InvalidTransitionError{Entity: "order", From: current.Status, To: to}
+	}
+
+	util.Logger.Info("订单状态已流转",
+		zap.Int("order_id", order.ID),
+		zap.String("from", order.Status),
+		zap.String("to", to),
+		zap.String("actor", actor))
+
+	order.Status = to
+	return nil
+}
+
+// TransitionByID 按订单ID流转订单状态
+func (m *OrderStateMachine) TransitionByID(orderID int, to, actor, reason string) (*model.Order, error) {
+	order, err := m.paymentRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	if err := m.Transition(order, to, actor, reason); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
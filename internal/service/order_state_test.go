@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionOrder(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{OrderStatusPending, OrderStatusPaid, true},
+		{OrderStatusPending, OrderStatusFailed, true},
+		{OrderStatusPending, OrderStatusRefunded, false},
+		{OrderStatusPaid, OrderStatusRefundPending, true},
+		{OrderStatusPaid, OrderStatusRefunded, false},
+		{OrderStatusPaid, OrderStatusReadyToShip, true},
+		{OrderStatusPaid, OrderStatusShipped, false},
+		{OrderStatusPaid, OrderStatusCancelled, true},
+		{OrderStatusPending, OrderStatusCancelled, false},
+		{OrderStatusCancelled, OrderStatusRefunded, false},
+		{OrderStatusReadyToShip, OrderStatusShipped, true},
+		{OrderStatusReadyToShip, OrderStatusCrowdfundingFailed, false},
+		{OrderStatusRefundPending, OrderStatusRefunded, true},
+		{OrderStatusRefundPending, OrderStatusRefundRejected, true},
+		{OrderStatusCrowdfundingFailed, OrderStatusRefunded, true},
+		{OrderStatusRefunded, OrderStatusPaid, false},
+		{OrderStatusFailed, OrderStatusPaid, false},
+		{"unknown", OrderStatusPaid, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, CanTransitionOrder(tt.from, tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
+
+func TestInvalidTransitionError(t *testing.T) {
+	var err error = &InvalidTransitionError{Entity: "order", From: OrderStatusPending, To: OrderStatusRefunded}
+	wrapped := errors.Join(errors.New("process refund"), err)
+
+	assert.True(t, IsInvalidTransition(wrapped))
+	assert.False(t, IsInvalidTransition(errors.New("other")))
+	assert.Contains(t, err.Error(), `"pending"`)
+}
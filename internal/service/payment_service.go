@@ -1,6 +1,8 @@
 package service
 
 import (
+	"crowdfunding-backend/config"
+	"crowdfunding-backend/internal/gateway"
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/repository/interfaces"
 	"crowdfunding-backend/internal/util"
@@ -12,11 +14,36 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrPaymentDeclined 支付渠道拒绝扣款
+	ErrPaymentDeclined = errors.New("payment declined by gateway")
+	// ErrUnknownWebhookProvider 未配置回调密钥的支付渠道
+	ErrUnknownWebhookProvider = errors.New("unknown webhook provider")
+	// ErrInvalidWebhookSignature 回调签名校验失败
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	// ErrWebhookOrderNotFound 回调对应的订单不存在
+	ErrWebhookOrderNotFound = errors.New("order for webhook event not found")
+	// ErrOrderNotFound 订单不存在
+	ErrOrderNotFound = errors.New("订单不存在")
+	// ErrOrderNotOwned 订单不属于当前用户
+	ErrOrderNotOwned = errors.New("订单不属于当前用户")
+	// ErrInvalidAmount 支付金额必须大于0
+	ErrInvalidAmount = errors.New("支付金额必须大于0")
+	// ErrAddressNotFound 收货地址不存在或不属于当前用户
+	ErrAddressNotFound = errors.New("收货地址不存在")
+)
+
 type PaymentService struct {
-	paymentRepo interfaces.PaymentRepository
-	userRepo    interfaces.UserRepository
-	projectRepo interfaces.ProjectRepository
-	db          *sql.DB
+	paymentRepo   interfaces.PaymentRepository
+	userRepo      interfaces.UserRepository
+	projectRepo   interfaces.ProjectRepository
+	rewardRepo    interfaces.RewardRepository
+	gateway       gateway.PaymentGateway
+	exchangeRates *ExchangeRateService
+	shipping      *ShippingService
+	orderStates   *OrderStateMachine
+	refunds       *RefundService
+	db            *sql.DB
 }
 
 // NewPaymentService 创建一个新的 PaymentService 实例
@@ -24,17 +51,30 @@ func NewPaymentService(
 	paymentRepo interfaces.PaymentRepository,
 	userRepo interfaces.UserRepository,
 	projectRepo interfaces.ProjectRepository,
+	rewardRepo interfaces.RewardRepository,
+	paymentGateway gateway.PaymentGateway,
+	exchangeRates *ExchangeRateService,
+	shipping *ShippingService,
 	db *sql.DB,
 ) *PaymentService {
 	return &PaymentService{
-		paymentRepo: paymentRepo,
-		userRepo:    userRepo,
-		projectRepo: projectRepo,
-		db:          db,
+		paymentRepo:   paymentRepo,
+		userRepo:      userRepo,
+		projectRepo:   projectRepo,
+		rewardRepo:    rewardRepo,
+		gateway:       paymentGateway,
+		exchangeRates: exchangeRates,
+		shipping:      shipping,
+		orderStates:   NewOrderStateMachine(paymentRepo),
+		refunds:       NewRefundService(paymentRepo, paymentGateway, db),
+		db:            db,
 	}
 }
 
-// ProcessPayment 处理支付
+// ProcessPayment 处理支付。订单小计由服务端按所选档位、加购项目录价格和额外支持金额计算（项目币种），
+// 加上按收货地址所在配送区域计算的档位运费得到订单总额，
+// 再按当前汇率换算为用户支付币种（payment.Currency，为空时使用项目币种）扣款；
+// 限量档位和加购项在同一事务中占用库存。addressID 为0表示不提供收货地址，仅无需发货的支持可以不提供
 func (s *PaymentService) ProcessPayment(payment *model.Payment, addressID int) (*model.Order, error) {
 	// 开始事务
 	tx, err := s.db.Begin()
@@ -44,36 +84,71 @@ func (s *PaymentService) ProcessPayment(payment *model.Payment, addressID int) (
 	}
 	defer tx.Rollback()
 
-	// 获取项目信息
-	project, err := s.projectRepo.GetProjectByID(payment.ProjectID)
+	// 只接受众筹中且未截止的项目的支持
+	project, err := s.getLiveProject(payment.ProjectID)
 	if err != nil {
-		util.Logger.Error("获取项目信息失败", zap.Error(err))
+		util.Logger.Warn("项目不可支持", zap.Error(err), zap.Int("project_id", payment.ProjectID))
 		return nil, err
 	}
-	if project == nil {
-		util.Logger.Error("项目不存在", zap.Int("project_id", payment.ProjectID))
-		return nil, errors.New("project not found")
+
+	// 按目录价格和配送区域计算订单明细和金额
+	quote, err := s.quoteOrder(project, payment, addressID)
+	if err != nil {
+		return nil, err
+	}
+	amount := quote.total()
+
+	// 换算为支付币种
+	if payment.Currency == "" {
+		payment.Currency = project.Currency
+	}
+	originalAmount, exchangeRate, err := s.exchangeRates.PriceIn(amount, payment.Currency, time.Now())
+	if err != nil {
+		util.Logger.Warn("支付金额换算失败",
+			zap.Error(err),
+			zap.String("currency", payment.Currency),
+			zap.String("project_currency", project.Currency))
+		return nil, err
+	}
+	if !originalAmount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	payment.Amount = originalAmount
+
+	// 条件更新占用库存，事务回滚时库存随之恢复
+	if err := s.claimStock(tx, project.ID, quote.tier, quote.cart); err != nil {
+		return nil, err
 	}
 
 	// 创建 pledge 记录
 	pledge := &model.Pledge{
-		UserID:    payment.UserID,
-		ProjectID: payment.ProjectID,
-		Amount:    payment.Amount,
-		Status:    "pending",
-		AddressID: &addressID,
-		CreatedAt: time.Now(),
+		UserID:           payment.UserID,
+		ProjectID:        payment.ProjectID,
+		Amount:           amount,
+		Currency:         project.Currency,
+		OriginalAmount:   payment.Amount,
+		OriginalCurrency: payment.Amount.Currency,
+		Status:           "pending",
+		RewardTierID:     payment.RewardTierID,
+		AddressID:        quote.addressID(),
+		CreatedAt:        time.Now(),
 	}
 
 	// 插入 pledge 记录
 	query := `
-		INSERT INTO pledges (user_id, project_id, amount, status, address_id, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)`
+		INSERT INTO pledges (
+			user_id, project_id, amount, currency, original_amount, original_currency,
+			status, reward_tier_id, address_id, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	result, err := tx.Exec(query,
 		pledge.UserID,
 		pledge.ProjectID,
 		pledge.Amount,
+		pledge.Currency,
+		pledge.OriginalAmount,
+		pledge.OriginalCurrency,
 		pledge.Status,
+		pledge.RewardTierID,
 		pledge.AddressID,
 		pledge.CreatedAt)
 	if err != nil {
@@ -88,40 +163,55 @@ func (s *PaymentService) ProcessPayment(payment *model.Payment, addressID int) (
 	}
 	pledge.ID = int(pledgeID)
 
-	// 检查支付金额是否达到最低有奖支持金额
-	isReward := payment.Amount >= project.MinRewardAmount
+	// 选择了回报档位的支持为有奖支持
+	isReward := payment.RewardTierID != nil
 
 	// 创建订单
 	order := &model.Order{
-		OrderNumber: fmt.Sprintf("ORD-%d-%04d", time.Now().Year(), pledgeID),
-		UserID:      payment.UserID,
-		ProjectID:   payment.ProjectID,
-		PledgeID:    pledge.ID,
-		Amount:      payment.Amount,
-		Status:      "pending",
-		IsReward:    isReward,
-		AddressID:   &addressID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		OrderNumber:      fmt.Sprintf("ORD-%d-%04d", time.Now().Year(), pledgeID),
+		UserID:           payment.UserID,
+		ProjectID:        payment.ProjectID,
+		PledgeID:         pledge.ID,
+		Subtotal:         quote.subtotal,
+		ShippingFee:      quote.shippingFee,
+		Amount:           amount,
+		Currency:         project.Currency,
+		OriginalAmount:   payment.Amount,
+		OriginalCurrency: payment.Amount.Currency,
+		ExchangeRate:     exchangeRate,
+		Status:           "pending",
+		IsReward:         isReward,
+		RewardTierID:     payment.RewardTierID,
+		AddressID:        quote.addressID(),
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
 	}
 
 	// 插入订单记录
 	query = `
 		INSERT INTO orders (
-			order_number, user_id, project_id, pledge_id,
-			amount, status, address_id, is_reward,
+			order_number, user_id, project_id, pledge_id, subtotal, shipping_fee,
+			amount, currency, original_amount, original_currency, exchange_rate,
+			status, address_id, is_reward, reward_tier_id,
 			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	result, err = tx.Exec(query,
 		order.OrderNumber,
 		order.UserID,
 		order.ProjectID,
 		order.PledgeID,
+		order.Subtotal,
+		order.ShippingFee,
 		order.Amount,
+		order.Currency,
+		order.OriginalAmount,
+		order.OriginalCurrency,
+		order.ExchangeRate,
 		order.Status,
 		order.AddressID,
 		order.IsReward,
+		order.RewardTierID,
 		order.CreatedAt,
 		order.UpdatedAt)
 
@@ -137,113 +227,360 @@ func (s *PaymentService) ProcessPayment(payment *model.Payment, addressID int) (
 	}
 	order.ID = int(orderID)
 
-	// 更新项目总金额和进度
-	query = `
-		UPDATE projects 
-		SET total_amount = total_amount + ?,
-			progress = CASE 
-				WHEN total_goal_amount > 0 THEN ((total_amount + ?) / total_goal_amount) * 100
-				ELSE 0 
-			END,
-			updated_at = NOW()
-		WHERE id = ?`
-
-	_, err = tx.Exec(query, payment.Amount, payment.Amount, payment.ProjectID)
-	if err != nil {
-		util.Logger.Error("更新项目金额失败", zap.Error(err))
-		return nil, fmt.Errorf("failed to update project amount: %w", err)
+	// 写入订单明细
+	if err := s.paymentRepo.CreateOrderItemsTx(tx, order.ID, quote.items); err != nil {
+		return nil, fmt.Errorf("failed to create order items: %w", err)
 	}
+	order.Items = quote.items
 
-	// 提交事务
+	// 提交事务，订单此时为待支付状态，项目金额在扣款成功后才会增加
 	if err := tx.Commit(); err != nil {
 		util.Logger.Error("提交事务失败", zap.Error(err))
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// 通过支付渠道扣款
+	if err := s.chargeOrder(order); err != nil {
+		return order, err
+	}
+
 	util.Logger.Info("支付处理成功",
 		zap.Int("order_id", order.ID),
 		zap.String("order_number", order.OrderNumber),
+		zap.String("status", order.Status),
 		zap.Bool("is_reward", order.IsReward),
-		zap.Float64("amount", payment.Amount))
+		zap.Stringer("amount", order.Amount),
+		zap.Stringer("shipping_fee", order.ShippingFee),
+		zap.Stringer("original_amount", order.OriginalAmount),
+		zap.String("original_currency", order.OriginalCurrency))
 
 	return order, nil
 }
 
-func (s *PaymentService) CreateOrder(pledge *model.Pledge, addressID int) (*model.Order, error) {
-	order := &model.Order{
-		UserID:    pledge.UserID,
-		ProjectID: pledge.ProjectID,
-		PledgeID:  pledge.ID,
-		Amount:    pledge.Amount,
-		Status:    "pending",
-		AddressID: &addressID,
-	}
-	return order, s.paymentRepo.CreateOrder(order)
+// orderQuote 按所选内容计算的订单明细和金额，金额均为项目币种
+type orderQuote struct {
+	tier        *model.RewardTier
+	cart        []model.CartItem
+	items       []model.OrderItem
+	subtotal    model.Money
+	shippingFee model.Money
+	address     *model.UserAddress
 }
 
-func (s *PaymentService) RequestRefund(orderID int, userID int, reason string) error {
-	request := &model.RefundRequest{
-		OrderID: orderID,
-		UserID:  userID,
-		Reason:  reason,
-		Status:  "pending",
-	}
-	return s.paymentRepo.CreateRefundRequest(request)
+// total 订单总额 = 小计 + 运费
+func (q *orderQuote) total() model.Money {
+	return q.subtotal.Add(q.shippingFee)
 }
 
-func (s *PaymentService) ProcessRefundRequest(requestID int, approved bool, comment string) error {
-	util.Logger.Info("开始处理退款申请",
-		zap.Int("request_id", requestID),
-		zap.Bool("approved", approved))
+func (q *orderQuote) addressID() *int {
+	if q.address == nil {
+		return nil
+	}
+	return &q.address.ID
+}
 
-	// 获取退款申请信息
-	request, err := s.paymentRepo.GetRefundRequestByID(requestID)
+// quoteOrder 按目录价格计算订单明细和小计，并按收货地址所在配送区域计算运费
+func (s *PaymentService) quoteOrder(project *model.Project, payment *model.Payment, addressID int) (*orderQuote, error) {
+	tier, cart, addOns, err := s.resolveCart(project, payment)
 	if err != nil {
-		util.Logger.Error("获取退款申请失败", zap.Error(err))
-		return err
+		return nil, err
+	}
+	items, subtotal, err := priceCart(project.Currency, tier, addOns, cart, payment.BonusAmount)
+	if err != nil {
+		return nil, err
 	}
 
-	if request == nil {
-		return errors.New("refund request not found")
+	address, err := s.getPaymentAddress(payment.UserID, addressID)
+	if err != nil {
+		return nil, err
+	}
+	shippingFee, err := s.shipping.QuoteShipping(project, tier, address)
+	if err != nil {
+		return nil, err
 	}
 
-	// 开始事务
-	tx, err := s.db.Begin()
+	return &orderQuote{
+		tier:        tier,
+		cart:        cart,
+		items:       items,
+		subtotal:    subtotal,
+		shippingFee: shippingFee,
+		address:     address,
+	}, nil
+}
+
+// resolveCart 获取所选档位和加购项并校验其属于该项目，返回合并后的加购项
+func (s *PaymentService) resolveCart(project *model.Project, payment *model.Payment) (*model.RewardTier, []model.CartItem, []*model.RewardAddOn, error) {
+	cart, err := mergeCartItems(payment.Items)
 	if err != nil {
-		util.Logger.Error("开始事务失败", zap.Error(err))
-		return err
+		return nil, nil, nil, err
 	}
-	defer tx.Rollback()
 
-	// 更新退款申请状态
-	if approved {
-		request.Status = "approved"
-		// 更新订单状态为已退款
-		err = s.paymentRepo.UpdateOrderStatus(request.OrderID, "refunded")
+	var tier *model.RewardTier
+	if payment.RewardTierID != nil {
+		tier, err = s.rewardRepo.GetRewardTier(*payment.RewardTierID)
+		if err != nil {
+			util.Logger.Error("获取回报档位失败", zap.Error(err))
+			return nil, nil, nil, err
+		}
+		if tier == nil || tier.ProjectID != project.ID {
+			return nil, nil, nil, ErrRewardTierNotFound
+		}
+	}
+
+	addOns := make([]*model.RewardAddOn, len(cart))
+	for i, item := range cart {
+		addOn, err := s.rewardRepo.GetRewardAddOn(item.AddOnID)
+		if err != nil {
+			util.Logger.Error("获取加购项失败", zap.Error(err))
+			return nil, nil, nil, err
+		}
+		if addOn == nil || addOn.ProjectID != project.ID {
+			return nil, nil, nil, ErrRewardAddOnNotFound
+		}
+		addOns[i] = addOn
+	}
+	return tier, cart, addOns, nil
+}
+
+// claimStock 在事务中占用所选档位和加购项的库存
+func (s *PaymentService) claimStock(tx *sql.Tx, projectID int, tier *model.RewardTier, cart []model.CartItem) error {
+	if tier != nil {
+		claimed, err := s.rewardRepo.ClaimRewardTierTx(tx, tier.ID, projectID)
 		if err != nil {
-			util.Logger.Error("更新订单状态失败", zap.Error(err))
 			return err
 		}
-	} else {
-		request.Status = "rejected"
-		// 更新订单状态为退款被拒绝
-		err = s.paymentRepo.UpdateOrderStatus(request.OrderID, "refund_rejected")
+		if !claimed {
+			util.Logger.Info("回报档位已售罄", zap.Int("reward_tier_id", tier.ID))
+			return ErrRewardTierSoldOut
+		}
+	}
+	for _, item := range cart {
+		claimed, err := s.rewardRepo.ClaimRewardAddOnTx(tx, item.AddOnID, projectID, item.Quantity)
 		if err != nil {
-			util.Logger.Error("更新订单状态失败", zap.Error(err))
 			return err
 		}
+		if !claimed {
+			util.Logger.Info("加购项库存不足", zap.Int("add_on_id", item.AddOnID), zap.Int("quantity", item.Quantity))
+			return ErrRewardAddOnSoldOut
+		}
 	}
+	return nil
+}
 
-	request.AdminComment = comment
-	err = s.paymentRepo.UpdateRefundRequest(request)
+// getPaymentAddress 获取支付使用的收货地址并校验其属于当前用户，addressID 为0时返回 nil
+func (s *PaymentService) getPaymentAddress(userID, addressID int) (*model.UserAddress, error) {
+	if addressID == 0 {
+		return nil, nil
+	}
+	address, err := s.userRepo.GetAddressByID(addressID)
+	if err == sql.ErrNoRows {
+		return nil, ErrAddressNotFound
+	}
+	if err != nil {
+		util.Logger.Error("获取收货地址失败", zap.Error(err), zap.Int("address_id", addressID))
+		return nil, err
+	}
+	if address == nil || address.UserID != userID {
+		return nil, ErrAddressNotFound
+	}
+	return address, nil
+}
+
+// chargeOrder 按订单原币种金额创建支付意图并扣款
+// 扣款成功后订单变为已支付并计入项目金额；渠道异步处理时订单保持待支付，等待回调确认
+func (s *PaymentService) chargeOrder(order *model.Order) error {
+	intent, err := s.gateway.CreateIntent(&gateway.IntentRequest{
+		Amount:      order.OriginalAmount,
+		OrderNumber: order.OrderNumber,
+		Metadata: map[string]string{
+			"order_id":   fmt.Sprintf("%d", order.ID),
+			"project_id": fmt.Sprintf("%d", order.ProjectID),
+		},
+	})
+	if err != nil {
+		util.Logger.Error("创建支付意图失败", zap.Error(err), zap.Int("order_id", order.ID))
+		return fmt.Errorf("failed to create payment intent: %w", err)
+	}
+
+	if err := s.paymentRepo.UpdateOrderPaymentIntent(order.ID, s.gateway.Name(), intent.ID); err != nil {
+		return fmt.Errorf("failed to save payment intent: %w", err)
+	}
+	order.PaymentProvider = s.gateway.Name()
+	order.PaymentIntentID = intent.ID
+
+	intent, err = s.gateway.Capture(intent.ID)
+	if err != nil {
+		util.Logger.Warn("支付渠道扣款失败",
+			zap.Error(err),
+			zap.Int("order_id", order.ID),
+			zap.String("intent_id", order.PaymentIntentID))
+		if _, markErr := s.paymentRepo.MarkOrderPaymentFailed(order.ID, ActorSystem, err.Error()); markErr != nil {
+			util.Logger.Error("更新订单为支付失败状态失败", zap.Error(markErr), zap.Int("order_id", order.ID))
+		}
+		order.Status = "failed"
+		if errors.Is(err, gateway.ErrCaptureDeclined) {
+			return ErrPaymentDeclined
+		}
+		return fmt.Errorf("failed to capture payment: %w", err)
+	}
+
+	if intent.Status != gateway.IntentSucceeded {
+		util.Logger.Info("扣款处理中，等待支付渠道确认",
+			zap.Int("order_id", order.ID),
+			zap.String("intent_status", string(intent.Status)))
+		return nil
+	}
+
+	confirmed, err := s.paymentRepo.ConfirmOrderPayment(order.ID, ActorSystem)
+	if err != nil {
+		return fmt.Errorf("failed to confirm order payment: %w", err)
+	}
+	if confirmed {
+		now := time.Now()
+		order.Status = "paid"
+		order.PaidAt = &now
+	}
+	return nil
+}
+
+// HandleWebhook 处理支付渠道回调：校验签名、按事件ID去重，并根据扣款结果更新订单状态
+// 返回 true 表示该事件此前已处理过
+func (s *PaymentService) HandleWebhook(provider string, payload []byte, signature string) (bool, error) {
+	secret, ok := config.AppConfig.WebhookSecrets[provider]
+	if !ok {
+		util.Logger.Warn("收到未配置渠道的回调", zap.String("provider", provider))
+		return false, ErrUnknownWebhookProvider
+	}
+	if !gateway.VerifySignature(secret, payload, signature) {
+		util.Logger.Warn("回调签名校验失败", zap.String("provider", provider))
+		return false, ErrInvalidWebhookSignature
+	}
+
+	event, err := gateway.ParseWebhookEvent(payload)
+	if err != nil {
+		return false, err
+	}
+
+	exists, err := s.paymentRepo.WebhookEventExists(provider, event.ID)
+	if err != nil {
+		util.Logger.Error("检查回调事件失败", zap.Error(err))
+		return false, err
+	}
+	if exists {
+		util.Logger.Info("回调事件已处理，忽略",
+			zap.String("provider", provider),
+			zap.String("event_id", event.ID))
+		return true, nil
+	}
+
+	order, err := s.paymentRepo.GetOrderByPaymentIntent(provider, event.IntentID)
+	if err != nil {
+		return false, err
+	}
+	if order == nil {
+		util.Logger.Warn("回调对应的订单不存在",
+			zap.String("provider", provider),
+			zap.String("intent_id", event.IntentID))
+		return false, ErrWebhookOrderNotFound
+	}
+
+	var reconcile bool
+	switch event.Type {
+	case gateway.EventPaymentSucceeded:
+		confirmed, err := s.paymentRepo.ConfirmOrderPayment(order.ID, WebhookActor(provider))
+		if err != nil {
+			return false, err
+		}
+		if !confirmed {
+			// 订单已不是待支付状态。订单从未入账时（如同步扣款已判定失败）渠道侧的扣款无法对应到订单，标记待对账
+			current, err := s.paymentRepo.GetOrderByID(order.ID)
+			if err != nil {
+				return false, err
+			}
+			reconcile = current != nil && current.PaidAt == nil
+		}
+		if reconcile {
+			util.Logger.Error("支付渠道扣款成功但订单未入账，需人工对账",
+				zap.String("provider", provider),
+				zap.String("event_id", event.ID),
+				zap.String("intent_id", event.IntentID),
+				zap.Int("order_id", order.ID))
+		}
+	case gateway.EventPaymentFailed:
+		if _, err := s.paymentRepo.MarkOrderPaymentFailed(order.ID, WebhookActor(provider), "支付渠道通知扣款失败"); err != nil {
+			return false, err
+		}
+	default:
+		util.Logger.Info("忽略不支持的回调事件类型",
+			zap.String("provider", provider),
+			zap.String("event_type", event.Type))
+	}
+
+	// 订单状态更新只对待支付订单生效，即使并发重复投递也不会重复入账
+	created, err := s.paymentRepo.CreateWebhookEvent(&model.PaymentWebhookEvent{
+		Provider:            provider,
+		EventID:             event.ID,
+		EventType:           event.Type,
+		OrderID:             &order.ID,
+		Payload:             string(payload),
+		NeedsReconciliation: reconcile,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	util.Logger.Info("回调事件处理完成",
+		zap.String("provider", provider),
+		zap.String("event_id", event.ID),
+		zap.String("event_type", event.Type),
+		zap.Int("order_id", order.ID))
+
+	return !created, nil
+}
+
+func (s *PaymentService) CreateOrder(pledge *model.Pledge, addressID int) (*model.Order, error) {
+	order := &model.Order{
+		UserID:    pledge.UserID,
+		ProjectID: pledge.ProjectID,
+		PledgeID:  pledge.ID,
+		Amount:    pledge.Amount,
+		Status:    "pending",
+		AddressID: &addressID,
+	}
+	return order, s.paymentRepo.CreateOrder(order)
+}
+
+func (s *PaymentService) RequestRefund(orderID int, userID int, reason string) error {
+	order, err := s.paymentRepo.GetOrderByID(orderID)
 	if err != nil {
-		util.Logger.Error("更新退款申请失败", zap.Error(err))
 		return err
 	}
+	if order == nil {
+		return ErrOrderNotFound
+	}
+	if order.UserID != userID {
+		return ErrOrderNotOwned
+	}
+
+	request := &model.RefundRequest{
+		OrderID: orderID,
+		UserID:  userID,
+		Reason:  reason,
+		Status:  "pending",
+	}
+	return s.refunds.submitRefundRequest(order, request, reason)
+}
 
-	err = tx.Commit()
+// ProcessRefundRequest 管理员审批退款申请
+func (s *PaymentService) ProcessRefundRequest(requestID int, approved bool, comment string, actor model.AuditActor) error {
+	util.Logger.Info("开始处理退款申请",
+		zap.Int("request_id", requestID),
+		zap.Bool("approved", approved))
+
+	request, err := s.refunds.resolveRefundRequest(requestID, approved, comment, actor)
 	if err != nil {
-		util.Logger.Error("提交事务失败", zap.Error(err))
+		util.Logger.Error("处理退款申请失败", zap.Error(err), zap.Int("request_id", requestID))
 		return err
 	}
 
@@ -262,6 +599,21 @@ func (s *PaymentService) GetOrderByID(orderID int) (*model.Order, error) {
 	return order, nil
 }
 
+// GetOrderHistory 获取订单状态流转历史，仅订单所有者可查看
+func (s *PaymentService) GetOrderHistory(orderID, userID int) ([]*model.OrderStatusHistory, error) {
+	order, err := s.paymentRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	if order.UserID != userID {
+		return nil, ErrOrderNotOwned
+	}
+	return s.paymentRepo.GetOrderStatusHistory(orderID)
+}
+
 func (s *PaymentService) GetOrdersByUser(userID int) ([]*model.Order, error) {
 	orders, err := s.paymentRepo.GetOrdersByUser(userID)
 	if err != nil {
@@ -312,7 +664,7 @@ func (s *PaymentService) RequestRefundForFailedProject(orderID, userID int) erro
 		return err
 	}
 	if order == nil {
-		return errors.New("订单不存在")
+		return ErrOrderNotFound
 	}
 
 	// 验证订单属于当前用户
@@ -320,7 +672,7 @@ func (s *PaymentService) RequestRefundForFailedProject(orderID, userID int) erro
 		util.Logger.Warn("订单不属于该用户",
 			zap.Int("order_id", orderID),
 			zap.Int("user_id", userID))
-		return errors.New("订单不属于当前用户")
+		return ErrOrderNotOwned
 	}
 
 	// 检查是否已经存在退款申请
@@ -344,7 +696,7 @@ func (s *PaymentService) RequestRefundForFailedProject(orderID, userID int) erro
 		Status:  "pending",
 	}
 
-	err = s.paymentRepo.CreateRefundRequest(refundRequest)
+	err = s.refunds.submitRefundRequest(order, refundRequest, "众筹失败，用户申请退款")
 	if err != nil {
 		util.Logger.Error("创建退款申请失败", zap.Error(err))
 		return err
@@ -362,47 +714,14 @@ func (s *PaymentService) ProcessProjectFailure(projectID int) error {
 	util.Logger.Info("开始处理项目失败的订单状态更新",
 		zap.Int("project_id", projectID))
 
-	// 获取项目的所有待处理订单
+	// 获取项目的所有订单
 	orders, err := s.paymentRepo.GetOrdersByProject(projectID)
 	if err != nil {
 		util.Logger.Error("获取项目订单失败", zap.Error(err))
 		return err
 	}
 
-	// 开始事务
-	tx, err := s.db.Begin()
-	if err != nil {
-		util.Logger.Error("开始事务失败", zap.Error(err))
-		return err
-	}
-	defer tx.Rollback()
-
-	// 更新所有相关订单的状态
-	for _, order := range orders {
-		if order.Status == "pending" || order.Status == "paid" {
-			err = s.paymentRepo.UpdateOrderStatus(order.ID, "crowdfunding_failed")
-			if err != nil {
-				util.Logger.Error("更新订单状态失败",
-					zap.Error(err),
-					zap.Int("order_id", order.ID))
-				return err
-			}
-			util.Logger.Info("订单状态已更新为众筹失败",
-				zap.Int("order_id", order.ID))
-		}
-	}
-
-	// 提交事务
-	if err = tx.Commit(); err != nil {
-		util.Logger.Error("提交事务失败", zap.Error(err))
-		return err
-	}
-
-	util.Logger.Info("项目失败订单状态更新完成",
-		zap.Int("project_id", projectID),
-		zap.Int("updated_orders", len(orders)))
-
-	return nil
+	return s.markOrdersCrowdfundingFailed(projectID, orders)
 }
 
 // SyncOrdersWithProjectStatus 同步订单状态与项目状态
@@ -421,47 +740,38 @@ func (s *PaymentService) SyncOrdersWithProjectStatus(projectID int) error {
 	}
 
 	// 如果项目状态是失败，更新所有相关订单
-	if project.Status == "failed" {
-		// 获取项目的所有订单
+	if project.Status == ProjectStatusFailed {
 		orders, err := s.paymentRepo.GetOrdersByProject(projectID)
 		if err != nil {
 			util.Logger.Error("获取项目订单失败", zap.Error(err))
 			return err
 		}
+		return s.markOrdersCrowdfundingFailed(projectID, orders)
+	}
 
-		// 开始事务
-		tx, err := s.db.Begin()
-		if err != nil {
-			util.Logger.Error("开始事务失败", zap.Error(err))
-			return err
-		}
-		defer tx.Rollback()
-
-		// 更新所有相关订单的状态
-		for _, order := range orders {
-			if order.Status == "pending" || order.Status == "paid" {
-				err = s.paymentRepo.UpdateOrderStatus(order.ID, "crowdfunding_failed")
-				if err != nil {
-					util.Logger.Error("更新订单状态失败",
-						zap.Error(err),
-						zap.Int("order_id", order.ID))
-					return err
-				}
-				util.Logger.Info("订单状态已更新为众筹失败",
-					zap.Int("order_id", order.ID))
-			}
-		}
+	return nil
+}
 
-		// 提交事务
-		if err = tx.Commit(); err != nil {
-			util.Logger.Error("提交事务失败", zap.Error(err))
+// markOrdersCrowdfundingFailed 将项目中待支付和已支付的订单流转为众筹失败
+func (s *PaymentService) markOrdersCrowdfundingFailed(projectID int, orders []*model.Order) error {
+	updated := 0
+	for _, order := range orders {
+		if order.Status != OrderStatusPending && order.Status != OrderStatusPaid {
+			continue
+		}
+		err := s.orderStates.Transition(order, OrderStatusCrowdfundingFailed, ActorSystem, "项目众筹失败")
+		if err != nil {
+			util.Logger.Error("更新订单状态失败",
+				zap.Error(err),
+				zap.Int("order_id", order.ID))
 			return err
 		}
-
-		util.Logger.Info("订单状态同步完成",
-			zap.Int("project_id", projectID),
-			zap.Int("updated_orders", len(orders)))
+		updated++
 	}
 
+	util.Logger.Info("项目失败订单状态更新完成",
+		zap.Int("project_id", projectID),
+		zap.Int("updated_orders", updated))
+
 	return nil
 }
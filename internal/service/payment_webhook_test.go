@@ -0,0 +1,129 @@
+package service
+
+import (
+	"crowdfunding-backend/config"
+	"crowdfunding-backend/internal/gateway"
+	"crowdfunding-backend/internal/model"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (m *MockPaymentRepository) WebhookEventExists(provider, eventID string) (bool, error) {
+	args := m.Called(provider, eventID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetOrderByPaymentIntent(provider, intentID string) (*model.Order, error) {
+	args := m.Called(provider, intentID)
+	order, _ := args.Get(0).(*model.Order)
+	return order, args.Error(1)
+}
+
+func (m *MockPaymentRepository) ConfirmOrderPayment(orderID int, actor string) (bool, error) {
+	args := m.Called(orderID, actor)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) CreateWebhookEvent(event *model.PaymentWebhookEvent) (bool, error) {
+	args := m.Called(event)
+	return args.Bool(0), args.Error(1)
+}
+
+const testWebhookSecret = "whsec_test"
+
+// signedWebhook 构造沙箱渠道的回调内容和签名
+func signedWebhook(eventID, eventType, intentID string) ([]byte, string) {
+	payload := []byte(fmt.Sprintf(`{"id":%q,"type":%q,"intent_id":%q}`, eventID, eventType, intentID))
+	return payload, gateway.SignPayload(testWebhookSecret, payload)
+}
+
+func newWebhookTestService(t *testing.T, paymentRepo *MockPaymentRepository) *PaymentService {
+	config.AppConfig.WebhookSecrets = map[string]string{"sandbox": testWebhookSecret}
+	return newPaymentTestService(t, paymentRepo, new(MockProjectRepository), new(MockRewardRepository), gateway.NewSandboxGateway())
+}
+
+// TestHandleWebhookDeduplicatesEvents 测试已处理的回调事件直接确认，不再更新订单
+func TestHandleWebhookDeduplicatesEvents(t *testing.T) {
+	paymentRepo := new(MockPaymentRepository)
+	service := newWebhookTestService(t, paymentRepo)
+
+	paymentRepo.On("WebhookEventExists", "sandbox", "evt_1").Return(true, nil)
+
+	payload, signature := signedWebhook("evt_1", gateway.EventPaymentSucceeded, "sb_pi_1")
+	duplicate, err := service.HandleWebhook("sandbox", payload, signature)
+	assert.NoError(t, err)
+	assert.True(t, duplicate)
+	paymentRepo.AssertNotCalled(t, "GetOrderByPaymentIntent", mock.Anything, mock.Anything)
+	paymentRepo.AssertNotCalled(t, "ConfirmOrderPayment", mock.Anything, mock.Anything)
+}
+
+// TestHandleWebhookUpdatesPendingOrder 测试扣款成功回调将待支付订单置为已支付，扣款失败回调置为支付失败
+func TestHandleWebhookUpdatesPendingOrder(t *testing.T) {
+	paymentRepo := new(MockPaymentRepository)
+	service := newWebhookTestService(t, paymentRepo)
+
+	paymentRepo.On("WebhookEventExists", "sandbox", mock.Anything).Return(false, nil)
+	paymentRepo.On("GetOrderByPaymentIntent", "sandbox", "sb_pi_1").Return(&model.Order{ID: 1, Status: OrderStatusPending}, nil)
+	paymentRepo.On("GetOrderByPaymentIntent", "sandbox", "sb_pi_2").Return(&model.Order{ID: 2, Status: OrderStatusPending}, nil)
+	paymentRepo.On("ConfirmOrderPayment", 1, WebhookActor("sandbox")).Return(true, nil)
+	paymentRepo.On("MarkOrderPaymentFailed", 2, WebhookActor("sandbox")).Return(true, nil)
+	var events []*model.PaymentWebhookEvent
+	paymentRepo.On("CreateWebhookEvent", mock.Anything).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(0).(*model.PaymentWebhookEvent))
+	}).Return(true, nil)
+
+	payload, signature := signedWebhook("evt_1", gateway.EventPaymentSucceeded, "sb_pi_1")
+	duplicate, err := service.HandleWebhook("sandbox", payload, signature)
+	assert.NoError(t, err)
+	assert.False(t, duplicate)
+
+	payload, signature = signedWebhook("evt_2", gateway.EventPaymentFailed, "sb_pi_2")
+	_, err = service.HandleWebhook("sandbox", payload, signature)
+	assert.NoError(t, err)
+
+	paymentRepo.AssertCalled(t, "ConfirmOrderPayment", 1, WebhookActor("sandbox"))
+	paymentRepo.AssertCalled(t, "MarkOrderPaymentFailed", 2, WebhookActor("sandbox"))
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "evt_1", events[0].EventID)
+		assert.False(t, events[0].NeedsReconciliation)
+		assert.Equal(t, "evt_2", events[1].EventID)
+	}
+}
+
+// TestHandleWebhookFlagsCaptureOnFailedOrder 测试同步扣款已判定失败后才收到扣款成功回调时，
+// 订单不会被重新入账，回调事件标记为待对账；已支付订单重复收到成功回调不需要对账
+func TestHandleWebhookFlagsCaptureOnFailedOrder(t *testing.T) {
+	paymentRepo := new(MockPaymentRepository)
+	service := newWebhookTestService(t, paymentRepo)
+
+	paidAt := time.Now()
+	paymentRepo.On("WebhookEventExists", "sandbox", mock.Anything).Return(false, nil)
+	paymentRepo.On("GetOrderByPaymentIntent", "sandbox", "sb_pi_1").Return(&model.Order{ID: 1, Status: OrderStatusFailed}, nil)
+	paymentRepo.On("GetOrderByPaymentIntent", "sandbox", "sb_pi_2").Return(&model.Order{ID: 2, Status: OrderStatusPaid, PaidAt: &paidAt}, nil)
+	paymentRepo.On("ConfirmOrderPayment", mock.Anything, WebhookActor("sandbox")).Return(false, nil)
+	paymentRepo.On("GetOrderByID", 1).Return(&model.Order{ID: 1, Status: OrderStatusFailed}, nil)
+	paymentRepo.On("GetOrderByID", 2).Return(&model.Order{ID: 2, Status: OrderStatusPaid, PaidAt: &paidAt}, nil)
+	var events []*model.PaymentWebhookEvent
+	paymentRepo.On("CreateWebhookEvent", mock.Anything).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(0).(*model.PaymentWebhookEvent))
+	}).Return(true, nil)
+
+	payload, signature := signedWebhook("evt_1", gateway.EventPaymentSucceeded, "sb_pi_1")
+	_, err := service.HandleWebhook("sandbox", payload, signature)
+	assert.NoError(t, err)
+
+	payload, signature = signedWebhook("evt_2", gateway.EventPaymentSucceeded, "sb_pi_2")
+	_, err = service.HandleWebhook("sandbox", payload, signature)
+	assert.NoError(t, err)
+
+	if assert.Len(t, events, 2) {
+		assert.True(t, events[0].NeedsReconciliation)
+		assert.Equal(t, 1, *events[0].OrderID)
+		assert.False(t, events[1].NeedsReconciliation)
+	}
+	paymentRepo.AssertNotCalled(t, "MarkOrderPaymentFailed", mock.Anything, mock.Anything)
+}
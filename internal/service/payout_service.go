@@ -0,0 +1,207 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"math"
+
+	"go.uber.org/zap"
+)
+
+// 结算记录状态
+const (
+	PayoutStatusPending  = "pending"
+	PayoutStatusApproved = "approved"
+	PayoutStatusPaid     = "paid"
+)
+
+const payoutMaxPageSize = 100
+
+var (
+	ErrPayoutNotFound    = errors.New("结算记录不存在")
+	ErrNotProjectCreator = errors.New("只有项目创建者可以查看结算记录")
+)
+
+// PayoutService 管理创作者结算记录的核算、审批和打款
+type PayoutService struct {
+	paymentRepo interfaces.PaymentRepository
+	projectRepo interfaces.ProjectRepository
+	feePercent  float64
+}
+
+func NewPayoutService(paymentRepo interfaces.PaymentRepository, projectRepo interfaces.ProjectRepository, feePercent float64) *PayoutService {
+	return &PayoutService{
+		paymentRepo: paymentRepo,
+		projectRepo: projectRepo,
+		feePercent:  feePercent,
+	}
+}
+
+// calculatePayout 计算平台服务费和应付创作者金额，服务费按扣除退款后的金额收取并四舍五入到分，
+// 服务费与应付金额之和始终等于扣除退款后的金额
+func calculatePayout(gross, refunded model.Money, feePercent float64) (fee, net model.Money) {
+	base := gross.Sub(refunded)
+	if base.IsNegative() {
+		base = model.NewMoney(0, base.Currency)
+	}
+	fee = base.MulBasisPoints(int64(math.Round(feePercent * 100)))
+	net = base.Sub(fee)
+	return fee, net
+}
+
+// applyAmounts 按订单和退款数据重新核算结算金额
+func (s *PayoutService) applyAmounts(payout *model.ProjectPayout) error {
+	gross, refunded, err := s.paymentRepo.GetProjectPayoutAmounts(payout.ProjectID)
+	if err != nil {
+		return err
+	}
+	payout.GrossAmount = gross
+	payout.RefundAmount = refunded
+	payout.FeePercent = s.feePercent
+	payout.FeeAmount, payout.NetAmount = calculatePayout(gross, refunded, s.feePercent)
+	return nil
+}
+
+// CreateProjectPayout 为众筹成功的项目生成结算记录，已存在时直接返回已有记录
+func (s *PayoutService) CreateProjectPayout(project *model.Project) (*model.ProjectPayout, error) {
+	payout, err := s.paymentRepo.GetProjectPayout(project.ID)
+	if err != nil {
+		return nil, err
+	}
+	if payout != nil {
+		return payout, nil
+	}
+
+	payout = &model.ProjectPayout{
+		ProjectID: project.ID,
+		CreatorID: project.CreatorID,
+		Currency:  project.Currency,
+		Status:    PayoutStatusPending,
+	}
+	if err := s.applyAmounts(payout); err != nil {
+		return nil, err
+	}
+	created, err := s.paymentRepo.CreateProjectPayout(payout)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		// 并发结算时已由其他任务创建
+		return s.paymentRepo.GetProjectPayout(project.ID)
+	}
+
+	util.Logger.Info("创建创作者结算记录",
+		zap.Int("project_id", project.ID),
+		zap.Int("payout_id", payout.ID),
+		zap.Stringer("gross_amount", payout.GrossAmount),
+		zap.Stringer("fee_amount", payout.FeeAmount),
+		zap.Stringer("net_amount", payout.NetAmount))
+	return payout, nil
+}
+
+// GetProjectPayouts 获取项目的结算记录，仅项目创建者可查看。待审批记录展示按当前退款情况核算的金额
+func (s *PayoutService) GetProjectPayouts(projectID, userID int) ([]*model.ProjectPayout, error) {
+	project, err := s.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.CreatorID != userID {
+		return nil, ErrNotProjectCreator
+	}
+
+	payouts, err := s.paymentRepo.GetPayoutsByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, payout := range payouts {
+		if payout.Status == PayoutStatusPending {
+			if err := s.applyAmounts(payout); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return payouts, nil
+}
+
+// ListPayouts 分页获取结算记录（管理员）
+func (s *PayoutService) ListPayouts(page, pageSize int, status string) ([]*model.ProjectPayout, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > payoutMaxPageSize {
+		pageSize = 10
+	}
+	return s.paymentRepo.GetPayouts(page, pageSize, status)
+}
+
+// ApprovePayout 审批结算记录，审批时按最新的退款情况重新核算并锁定金额
+func (s *PayoutService) ApprovePayout(payoutID int, actor model.AuditActor) (*model.ProjectPayout, error) {
+	payout, err := s.paymentRepo.GetPayoutByID(payoutID)
+	if err != nil {
+		return nil, err
+	}
+	if payout == nil {
+		return nil, ErrPayoutNotFound
+	}
+	if payout.Status != PayoutStatusPending {
+		return nil, &InvalidTransitionError{Entity: "payout", From: payout.Status, To: PayoutStatusApproved}
+	}
+
+	before := *payout
+	if err := s.applyAmounts(payout); err != nil {
+		return nil, err
+	}
+	after := *payout
+	after.Status = PayoutStatusApproved
+	audit := actor.Entry(model.AuditActionPayoutApprove, model.AuditTargetPayout, payoutID)
+	audit.SetChange(before, after)
+	updated, err := s.paymentRepo.ApprovePayout(payout, actor.UserID, audit)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, &InvalidTransitionError{Entity: "payout", From: payout.Status, To: PayoutStatusApproved}
+	}
+
+	util.Logger.Info("结算记录已批准",
+		zap.Int("payout_id", payoutID),
+		zap.Int("admin_id", actor.UserID),
+		zap.Stringer("net_amount", payout.NetAmount))
+	return s.paymentRepo.GetPayoutByID(payoutID)
+}
+
+// MarkPayoutPaid 将已批准的结算记录标记为已打款
+func (s *PayoutService) MarkPayoutPaid(payoutID int, actor model.AuditActor, reference string) (*model.ProjectPayout, error) {
+	payout, err := s.paymentRepo.GetPayoutByID(payoutID)
+	if err != nil {
+		return nil, err
+	}
+	if payout == nil {
+		return nil, ErrPayoutNotFound
+	}
+	if payout.Status != PayoutStatusApproved {
+		return nil, &InvalidTransitionError{Entity: "payout", From: payout.Status, To: PayoutStatusPaid}
+	}
+
+	audit := actor.Entry(model.AuditActionPayoutPaid, model.AuditTargetPayout, payoutID)
+	audit.SetChange(map[string]string{"status": payout.Status},
+		map[string]string{"status": PayoutStatusPaid, "payment_reference": reference})
+	updated, err := s.paymentRepo.MarkPayoutPaid(payoutID, actor.UserID, reference, audit)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, &InvalidTransitionError{Entity: "payout", From: payout.Status, To: PayoutStatusPaid}
+	}
+
+	util.Logger.Info("结算记录已打款",
+		zap.Int("payout_id", payoutID),
+		zap.Int("admin_id", actor.UserID),
+		zap.String("reference", reference))
+	return s.paymentRepo.GetPayoutByID(payoutID)
+}
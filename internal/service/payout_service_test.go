@@ -0,0 +1,58 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (m *MockPaymentRepository) GetPayouts(page, pageSize int, status string) ([]*model.ProjectPayout, int, error) {
+	args := m.Called(page, pageSize, status)
+	return args.Get(0).([]*model.ProjectPayout), args.Int(1), args.Error(2)
+}
+
+func TestCalculatePayout(t *testing.T) {
+	tests := []struct {
+		name            string
+		gross, refunded int64
+		feePercent      float64
+		fee, net        int64
+	}{
+		{"无退款", 100000, 0, 5, 5000, 95000},
+		{"扣除退款后收取服务费", 100000, 20000, 5, 4000, 76000},
+		{"服务费四舍五入到分", 9999, 0, 3.5, 350, 9649},
+		{"全部退款", 50000, 50000, 5, 0, 0},
+		{"不收服务费", 30000, 10000, 0, 0, 20000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gross := model.NewMoney(tt.gross, model.DefaultCurrency)
+			refunded := model.NewMoney(tt.refunded, model.DefaultCurrency)
+			fee, net := calculatePayout(gross, refunded, tt.feePercent)
+			assert.Equal(t, tt.fee, fee.Cents)
+			assert.Equal(t, tt.net, net.Cents)
+			assert.Equal(t, gross.Sub(refunded), fee.Add(net))
+		})
+	}
+}
+
+// TestListPayoutsClampsPaging 测试页码和每页数量超出范围时使用默认值
+func TestListPayoutsClampsPaging(t *testing.T) {
+	paymentRepo := new(MockPaymentRepository)
+	service := NewPayoutService(paymentRepo, new(MockProjectRepository), 5)
+
+	paymentRepo.On("GetPayouts", 1, 10, PayoutStatusPending).Return([]*model.ProjectPayout{}, 0, nil)
+	paymentRepo.On("GetPayouts", 2, 50, "").Return([]*model.ProjectPayout{}, 0, nil)
+
+	_, _, err := service.ListPayouts(0, 100000, PayoutStatusPending)
+	assert.NoError(t, err)
+	_, _, err = service.ListPayouts(-3, -1, PayoutStatusPending)
+	assert.NoError(t, err)
+	_, _, err = service.ListPayouts(2, 50, "")
+	assert.NoError(t, err)
+	paymentRepo.AssertNumberOfCalls(t, "GetPayouts", 3)
+	paymentRepo.AssertNotCalled(t, "GetPayouts", mock.Anything, 100000, mock.Anything)
+}
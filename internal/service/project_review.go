@@ -0,0 +1,120 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+const projectReviewCommentMaxLength = 2000
+
+var (
+	// ErrNotProjectOwner 只有项目创建者可以操作
+	ErrNotProjectOwner = errors.New("只有项目创建者可以操作该项目")
+	// ErrProjectUnderReview 项目审核期间不能修改
+	ErrProjectUnderReview = errors.New("项目正在审核中，暂不能修改")
+	// ErrProjectNotEditable 项目已通过审核，不能再修改
+	ErrProjectNotEditable = errors.New("项目已通过审核，不能再修改")
+	// ErrInvalidProjectReview 审核结论或意见不合法
+	ErrInvalidProjectReview = errors.New("审核意见不合法")
+)
+
+// reviewStatus 审核结论对应的项目状态
+var reviewStatus = map[string]string{
+	model.ProjectReviewApproved:         ProjectStatusActive,
+	model.ProjectReviewChangesRequested: ProjectStatusDraft,
+	model.ProjectReviewRejected:         ProjectStatusRejected,
+}
+
+// reviewableFields 审核员可以逐项提出意见的项目字段
+var reviewableFields = map[string]bool{
+	"title":             true,
+	"description":       true,
+	"end_date":          true,
+	"category":          true,
+	"currency":          true,
+	"min_reward_amount": true,
+	"goals":             true,
+	"images":            true,
+	"rewards":           true,
+	"add_ons":           true,
+	"shipping":          true,
+}
+
+// normalizeProjectReview 校验审核结论和意见。要求修改或驳回时必须给出总体意见或至少一条字段意见
+func normalizeProjectReview(review *model.ProjectReview) error {
+	if _, ok := reviewStatus[review.Decision]; !ok {
+		return ErrInvalidProjectReview
+	}
+	review.Comment = strings.TrimSpace(review.Comment)
+	if utf8.RuneCountInString(review.Comment) > projectReviewCommentMaxLength {
+		return ErrInvalidProjectReview
+	}
+
+	seen := make(map[string]bool)
+	fieldComments := make([]model.ProjectFieldComment, 0, len(review.FieldComments))
+	for _, fc := range review.FieldComments {
+		fc.Field = strings.TrimSpace(fc.Field)
+		fc.Comment = strings.TrimSpace(fc.Comment)
+		if !reviewableFields[fc.Field] || seen[fc.Field] || fc.Comment == "" ||
+			utf8.RuneCountInString(fc.Comment) > projectReviewCommentMaxLength {
+			return ErrInvalidProjectReview
+		}
+		seen[fc.Field] = true
+		fieldComments = append(fieldComments, fc)
+	}
+	review.FieldComments = fieldComments
+
+	if review.Decision != model.ProjectReviewApproved && review.Comment == "" && len(fieldComments) == 0 {
+		return ErrInvalidProjectReview
+	}
+	return nil
+}
+
+// getOwnedProject 获取项目并校验当前用户是创建者
+func (s *ProjectService) getOwnedProject(projectID, userID int) (*model.Project, error) {
+	project, err := s.repo.GetProjectByID(projectID)
+	if err != nil {
+		util.Logger.Error("获取项目失败", zap.Error(err), zap.Int("project_id", projectID))
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.CreatorID != userID {
+		return nil, ErrNotProjectOwner
+	}
+	return project, nil
+}
+
+// SubmitProject 创建者将草稿提交审核，包括根据审核意见修改后重新提交
+func (s *ProjectService) SubmitProject(projectID, userID int) (*model.Project, error) {
+	if _, err := s.getOwnedProject(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.TransitionStatus(projectID, ProjectStatusPendingReview, UserActor(userID), "创建者提交审核", nil)
+}
+
+// GetProjectReviews 创建者查看项目的审核记录
+func (s *ProjectService) GetProjectReviews(projectID, userID int) ([]*model.ProjectReview, error) {
+	if _, err := s.getOwnedProject(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetProjectReviews(projectID)
+}
+
+// ListProjectReviews 管理员查看项目的审核记录
+func (s *ProjectService) ListProjectReviews(projectID int) ([]*model.ProjectReview, error) {
+	project, err := s.repo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	return s.repo.GetProjectReviews(projectID)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeProjectReview(t *testing.T) {
+	review := &model.ProjectReview{
+		Decision: model.ProjectReviewChangesRequested,
+		Comment:  "  请补充细节  ",
+		FieldComments: []model.ProjectFieldComment{
+			{Field: " title ", Comment: " 标题过长 "},
+			{Field: "goals", Comment: "目标金额过高"},
+		},
+	}
+	assert.NoError(t, normalizeProjectReview(review))
+	assert.Equal(t, "请补充细节", review.Comment)
+	assert.Equal(t, []model.ProjectFieldComment{{Field: "title", Comment: "标题过长"}, {Field: "goals", Comment: "目标金额过高"}}, review.FieldComments)
+
+	// 通过时可以不填写意见
+	assert.NoError(t, normalizeProjectReview(&model.ProjectReview{Decision: model.ProjectReviewApproved}))
+
+	// 要求修改或驳回时必须说明原因
+	assert.ErrorIs(t, normalizeProjectReview(&model.ProjectReview{Decision: model.ProjectReviewRejected, Comment: "  "}), ErrInvalidProjectReview)
+	assert.NoError(t, normalizeProjectReview(&model.ProjectReview{Decision: model.ProjectReviewRejected, Comment: "违反平台规则"}))
+
+	assert.ErrorIs(t, normalizeProjectReview(&model.ProjectReview{Decision: "pending"}), ErrInvalidProjectReview)
+	assert.ErrorIs(t, normalizeProjectReview(&model.ProjectReview{
+		Decision:      model.ProjectReviewChangesRequested,
+		FieldComments: []model.ProjectFieldComment{{Field: "creator_id", Comment: "不可审核的字段"}},
+	}), ErrInvalidProjectReview)
+	assert.ErrorIs(t, normalizeProjectReview(&model.ProjectReview{
+		Decision:      model.ProjectReviewChangesRequested,
+		FieldComments: []model.ProjectFieldComment{{Field: "title", Comment: "a"}, {Field: "title", Comment: "b"}},
+	}), ErrInvalidProjectReview)
+	assert.ErrorIs(t, normalizeProjectReview(&model.ProjectReview{
+		Decision:      model.ProjectReviewChangesRequested,
+		FieldComments: []model.ProjectFieldComment{{Field: "title", Comment: " "}},
+	}), ErrInvalidProjectReview)
+}
@@ -4,7 +4,6 @@ import (
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/repository/interfaces"
 	"crowdfunding-backend/internal/util"
-	"errors"
 	"time"
 
 	"go.uber.org/zap"
@@ -12,16 +11,18 @@ import (
 
 // ProjectService 处理与项目相关的业务逻辑
 type ProjectService struct {
-	repo interfaces.ProjectRepository
+	repo          interfaces.ProjectRepository
+	exchangeRates *ExchangeRateService
+	hooks         []ProjectTransitionHook
 }
 
 // NewProjectService 创建一个新的 ProjectService 实例
-func NewProjectService(repo interfaces.ProjectRepository) *ProjectService {
-	return &ProjectService{repo}
+func NewProjectService(repo interfaces.ProjectRepository, exchangeRates *ExchangeRateService) *ProjectService {
+	return &ProjectService{repo: repo, exchangeRates: exchangeRates}
 }
 
-// CreateProject 创建新项目
-func (s *ProjectService) CreateProject(project *model.Project, goals []model.ProjectGoal) error {
+// CreateProject 创建新项目。项目默认保存为草稿，创建者预览确认后再提交审核；submit 为 true 时创建后直接提交审核
+func (s *ProjectService) CreateProject(project *model.Project, goals []model.ProjectGoal, submit bool) error {
 	tx, err := s.repo.BeginTx()
 	if err != nil {
 		util.Logger.Error("开始事务失败", zap.Error(err))
@@ -31,7 +32,13 @@ func (s *ProjectService) CreateProject(project *model.Project, goals []model.Pro
 
 	util.Logger.Info("开始创建新项目", zap.String("title", project.Title))
 
-	project.Status = "pending_review"
+	project.Status = ProjectStatusDraft
+	if submit {
+		project.Status = ProjectStatusPendingReview
+	}
+	if project.Currency == "" {
+		project.Currency = model.DefaultCurrency
+	}
 	project.CreatedAt = time.Now()
 	project.UpdatedAt = time.Now()
 
@@ -46,7 +53,7 @@ func (s *ProjectService) CreateProject(project *model.Project, goals []model.Pro
 		return err
 	}
 
-	util.Logger.Info("项目创建成功", zap.Int("project_id", project.ID))
+	util.Logger.Info("项目创建成功", zap.Int("project_id", project.ID), zap.String("status", project.Status))
 	return nil
 }
 
@@ -64,13 +71,43 @@ func (s *ProjectService) GetProjectByID(id int) (*model.Project, error) {
 	return project, nil
 }
 
-// UpdateProject 更新项目信息
-func (s *ProjectService) UpdateProject(project *model.Project, goals []model.ProjectGoal) error {
+// GetPublicProject 获取公开可见的项目，未公开的项目按不存在处理
+func (s *ProjectService) GetPublicProject(id int) (*model.Project, error) {
+	project, err := s.GetProjectByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil || !IsProjectPublic(project.Status) {
+		return nil, ErrProjectNotFound
+	}
+	return project, nil
+}
+
+// PreviewProject 创建者预览自己的项目，包括尚未公开的草稿和审核中的项目
+func (s *ProjectService) PreviewProject(id, userID int) (*model.Project, error) {
+	return s.getOwnedProject(id, userID)
+}
+
+// UpdateProject 创建者更新项目信息。只有草稿和被驳回的项目可以修改，审核期间需等待审核结果，
+// 通过审核后不能再修改目标、截止时间等内容
+func (s *ProjectService) UpdateProject(userID int, project *model.Project, goals []model.ProjectGoal) error {
 	util.Logger.Info("开始更新项目", zap.Int("project_id", project.ID))
 
+	current, err := s.getOwnedProject(project.ID, userID)
+	if err != nil {
+		return err
+	}
+	switch current.Status {
+	case ProjectStatusDraft, ProjectStatusRejected:
+	case ProjectStatusPendingReview:
+		return ErrProjectUnderReview
+	default:
+		return ErrProjectNotEditable
+	}
+
 	project.UpdatedAt = time.Now()
 
-	err := s.repo.UpdateProject(project, goals)
+	err = s.repo.UpdateProject(project, goals)
 	if err != nil {
 		util.Logger.Error("更新项目失败", zap.Error(err), zap.Int("project_id", project.ID))
 		return err
@@ -85,19 +122,37 @@ func (s *ProjectService) ListProjects(page, pageSize int) ([]model.Project, erro
 	return s.repo.ListProjects(page, pageSize)
 }
 
-// PledgeToProject 支持项目
-func (s *ProjectService) PledgeToProject(userID, projectID int, amount float64) error {
-	util.Logger.Info("开始支持项目", zap.Int("user_id", userID), zap.Int("project_id", projectID), zap.Float64("amount", amount))
+// PledgeToProject 支持项目，amount 未指定币种时按项目币种处理，其他币种按当前汇率折算
+func (s *ProjectService) PledgeToProject(userID, projectID int, amount model.Money) error {
+	util.Logger.Info("开始支持项目", zap.Int("user_id", userID), zap.Int("project_id", projectID), zap.Stringer("amount", amount))
+
+	project, err := s.repo.GetProjectByID(projectID)
+	if err != nil {
+		return err
+	}
+	if project == nil {
+		return ErrProjectNotFound
+	}
+	if amount.Currency == "" {
+		amount.Currency = project.Currency
+	}
+	converted, _, err := s.exchangeRates.Convert(amount, project.Currency, time.Now())
+	if err != nil {
+		return err
+	}
 
 	pledge := &model.Pledge{
-		UserID:    userID,
-		ProjectID: projectID,
-		Amount:    amount,
-		Status:    "pending",
-		CreatedAt: time.Now(),
+		UserID:           userID,
+		ProjectID:        projectID,
+		Amount:           converted,
+		Currency:         project.Currency,
+		OriginalAmount:   amount,
+		OriginalCurrency: amount.Currency,
+		Status:           "pending",
+		CreatedAt:        time.Now(),
 	}
 
-	err := s.repo.CreatePledge(pledge)
+	err = s.repo.CreatePledge(pledge)
 	if err != nil {
 		util.Logger.Error("创建支持记录失败", zap.Error(err))
 		return err
@@ -107,32 +162,36 @@ func (s *ProjectService) PledgeToProject(userID, projectID int, amount float64)
 	return nil
 }
 
-// ReviewProject 审核项目
-func (s *ProjectService) ReviewProject(projectID int, approved bool, comment string) error {
-	util.Logger.Info("开始审核项目", zap.Int("project_id", projectID), zap.Bool("approved", approved))
+// ReviewProject 审核项目：通过后项目上线，需要修改时退回草稿等待创建者重新提交，驳回后不再上线。
+// 审核结论和意见与状态流转在同一事务中保存
+func (s *ProjectService) ReviewProject(projectID int, review *model.ProjectReview, reviewer model.AuditActor) error {
+	util.Logger.Info("开始审核项目", zap.Int("project_id", projectID), zap.String("decision", review.Decision))
+
+	if err := normalizeProjectReview(review); err != nil {
+		return err
+	}
 
 	project, err := s.repo.GetProjectByID(projectID)
 	if err != nil {
 		util.Logger.Error("获取项目失败", zap.Error(err), zap.Int("project_id", projectID))
 		return err
 	}
-
-	if project.Status != "pending_review" {
-		util.Logger.Warn("项目状态不是待审核", zap.String("current_status", project.Status))
-		return errors.New("project is not in pending review status")
+	if project == nil {
+		return ErrProjectNotFound
 	}
 
-	if approved {
-		project.Status = "active"
-	} else {
-		project.Status = "rejected"
+	if project.Status != ProjectStatusPendingReview {
+		util.Logger.Warn("项目状态不是待审核", zap.String("current_status", project.Status))
+		return &InvalidTransitionError{Entity: "project", From: project.Status, To: reviewStatus[review.Decision]}
 	}
 
-	project.UpdatedAt = time.Now()
+	review.ProjectID = projectID
+	review.ReviewerID = reviewer.UserID
+	review.CreatedAt = time.Now()
 
-	err = s.repo.UpdateProjectStatus(project)
+	audit := reviewer.Entry(model.AuditActionProjectReview, model.AuditTargetProject, projectID)
+	project, err = s.transitionStatus(projectID, reviewStatus[review.Decision], AdminActor(reviewer.UserID), review.Comment, audit, review)
 	if err != nil {
-		util.Logger.Error("更新项目状态失败", zap.Error(err), zap.Int("project_id", projectID))
 		return err
 	}
 
@@ -158,10 +217,12 @@ func (s *ProjectService) SearchProjects(filters model.ProjectFilters, page, page
 	return s.repo.SearchProjects(filters, page, pageSize)
 }
 
-// CreateCategory 创建项目分类
-func (s *ProjectService) CreateCategory(category *model.ProjectCategory) error {
+// CreateCategory 创建项目分类并记录审计日志
+func (s *ProjectService) CreateCategory(category *model.ProjectCategory, actor model.AuditActor) error {
 	util.Logger.Info("开始创建项目分类", zap.String("name", category.Name))
-	return s.repo.CreateCategory(category)
+	audit := actor.Entry(model.AuditActionCategoryCreate, model.AuditTargetCategory, 0)
+	audit.SetChange(nil, map[string]string{"name": category.Name})
+	return s.repo.CreateCategory(category, audit)
 }
 
 // GetCategories 获取所有项目分类
@@ -170,10 +231,12 @@ func (s *ProjectService) GetCategories() ([]model.ProjectCategory, error) {
 	return s.repo.GetCategories()
 }
 
-// CreateTag 创建项目标签
-func (s *ProjectService) CreateTag(tag *model.ProjectTag) error {
+// CreateTag 创建项目标签并记录审计日志
+func (s *ProjectService) CreateTag(tag *model.ProjectTag, actor model.AuditActor) error {
 	util.Logger.Info("开始创建项目标签", zap.String("name", tag.Name))
-	return s.repo.CreateTag(tag)
+	audit := actor.Entry(model.AuditActionTagCreate, model.AuditTargetTag, 0)
+	audit.SetChange(nil, map[string]string{"name": tag.Name})
+	return s.repo.CreateTag(tag, audit)
 }
 
 // GetTags 获取所有项目标签
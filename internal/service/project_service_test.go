@@ -0,0 +1,137 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// MockProjectRepository 模拟 ProjectRepository 中测试用到的方法，其余方法未实现
+type MockProjectRepository struct {
+	interfaces.ProjectRepository
+	mock.Mock
+}
+
+func (m *MockProjectRepository) GetProjectByID(id int) (*model.Project, error) {
+	args := m.Called(id)
+	project, _ := args.Get(0).(*model.Project)
+	return project, args.Error(1)
+}
+
+func (m *MockProjectRepository) UpdateProject(project *model.Project, goals []model.ProjectGoal) error {
+	args := m.Called(project.ID)
+	return args.Error(0)
+}
+
+func (m *MockProjectRepository) CreateCategory(category *model.ProjectCategory, audit *model.AuditEntry) error {
+	args := m.Called(category, audit)
+	return args.Error(0)
+}
+
+func (m *MockProjectRepository) CreateTag(tag *model.ProjectTag, audit *model.AuditEntry) error {
+	args := m.Called(tag, audit)
+	return args.Error(0)
+}
+
+// TestCreateCategoryAndTagWriteAuditEntry 测试创建分类和标签时写入审计记录
+func TestCreateCategoryAndTagWriteAuditEntry(t *testing.T) {
+	util.Logger = zap.NewNop()
+	repo := new(MockProjectRepository)
+	service := NewProjectService(repo, nil)
+	actor := model.AuditActor{UserID: 1, IP: "10.0.0.1", RequestID: "req-1"}
+
+	var categoryAudit, tagAudit *model.AuditEntry
+	repo.On("CreateCategory", mock.AnythingOfType("*model.ProjectCategory"), mock.AnythingOfType("*model.AuditEntry")).Run(func(args mock.Arguments) {
+		categoryAudit = args.Get(1).(*model.AuditEntry)
+	}).Return(nil)
+	repo.On("CreateTag", mock.AnythingOfType("*model.ProjectTag"), mock.AnythingOfType("*model.AuditEntry")).Run(func(args mock.Arguments) {
+		tagAudit = args.Get(1).(*model.AuditEntry)
+	}).Return(nil)
+
+	assert.NoError(t, service.CreateCategory(&model.ProjectCategory{Name: "科技"}, actor))
+	if assert.NotNil(t, categoryAudit) {
+		assert.Equal(t, model.AuditActionCategoryCreate, categoryAudit.Action)
+		assert.Equal(t, model.AuditTargetCategory, categoryAudit.TargetType)
+		assert.Equal(t, 1, categoryAudit.ActorID)
+		assert.JSONEq(t, `{"name":"科技"}`, string(categoryAudit.After))
+	}
+
+	assert.NoError(t, service.CreateTag(&model.ProjectTag{Name: "环保"}, actor))
+	if assert.NotNil(t, tagAudit) {
+		assert.Equal(t, model.AuditActionTagCreate, tagAudit.Action)
+		assert.Equal(t, model.AuditTargetTag, tagAudit.TargetType)
+		assert.JSONEq(t, `{"name":"环保"}`, string(tagAudit.After))
+	}
+}
+
+// TestGetPublicProjectHidesUnpublished 测试草稿、审核中和被驳回的项目不公开，只有创建者可以预览
+func TestGetPublicProjectHidesUnpublished(t *testing.T) {
+	util.Logger = zap.NewNop()
+	repo := new(MockProjectRepository)
+	service := NewProjectService(repo, nil)
+
+	statuses := map[int]string{
+		1: ProjectStatusDraft,
+		2: ProjectStatusPendingReview,
+		3: ProjectStatusRejected,
+		4: ProjectStatusActive,
+		5: ProjectStatusCompleted,
+	}
+	for id, status := range statuses {
+		repo.On("GetProjectByID", id).Return(&model.Project{ID: id, CreatorID: 7, Status: status}, nil)
+	}
+	repo.On("GetProjectByID", 6).Return(nil, nil)
+
+	for _, id := range []int{1, 2, 3, 6} {
+		_, err := service.GetPublicProject(id)
+		assert.ErrorIs(t, err, ErrProjectNotFound, "project %d", id)
+	}
+	for _, id := range []int{4, 5} {
+		project, err := service.GetPublicProject(id)
+		assert.NoError(t, err)
+		assert.Equal(t, id, project.ID)
+	}
+
+	// 创建者可以预览未公开的项目，其他用户不可以
+	project, err := service.PreviewProject(1, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, ProjectStatusDraft, project.Status)
+	_, err = service.PreviewProject(1, 8)
+	assert.ErrorIs(t, err, ErrNotProjectOwner)
+}
+
+// TestUpdateProjectOnlyBeforeApproval 测试只有草稿和被驳回的项目可以修改，审核中和已通过审核的项目不能修改
+func TestUpdateProjectOnlyBeforeApproval(t *testing.T) {
+	util.Logger = zap.NewNop()
+	repo := new(MockProjectRepository)
+	service := NewProjectService(repo, nil)
+
+	statuses := map[int]string{
+		1: ProjectStatusDraft,
+		2: ProjectStatusRejected,
+		3: ProjectStatusPendingReview,
+		4: ProjectStatusActive,
+		5: ProjectStatusCompleted,
+		6: ProjectStatusFailed,
+		7: ProjectStatusSuspended,
+	}
+	for id, status := range statuses {
+		repo.On("GetProjectByID", id).Return(&model.Project{ID: id, CreatorID: 7, Status: status}, nil)
+	}
+	repo.On("UpdateProject", mock.Anything).Return(nil)
+
+	for _, id := range []int{1, 2} {
+		assert.NoError(t, service.UpdateProject(7, &model.Project{ID: id, Title: "新标题"}, nil), statuses[id])
+	}
+	assert.ErrorIs(t, service.UpdateProject(7, &model.Project{ID: 3}, nil), ErrProjectUnderReview)
+	for _, id := range []int{4, 5, 6, 7} {
+		assert.ErrorIs(t, service.UpdateProject(7, &model.Project{ID: id}, nil), ErrProjectNotEditable, statuses[id])
+		repo.AssertNotCalled(t, "UpdateProject", id)
+	}
+	assert.ErrorIs(t, service.UpdateProject(8, &model.Project{ID: 1}, nil), ErrNotProjectOwner)
+}
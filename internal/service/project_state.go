@@ -0,0 +1,152 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// 项目状态
+const (
+	ProjectStatusDraft         = "draft"
+	ProjectStatusPendingReview = "pending_review"
+	ProjectStatusActive        = "active"
+	ProjectStatusCompleted     = "completed"
+	ProjectStatusFailed        = "failed"
+	ProjectStatusRejected      = "rejected"
+	ProjectStatusSuspended     = "suspended"
+)
+
+// ErrProjectNotFound 项目不存在
+var ErrProjectNotFound = errors.New("project not found")
+
+// projectTransitions 项目生命周期中允许的状态流转
+var projectTransitions = map[string][]string{
+	ProjectStatusDraft:         {ProjectStatusPendingReview},
+	ProjectStatusPendingReview: {ProjectStatusActive, ProjectStatusRejected, ProjectStatusDraft},
+	ProjectStatusActive:        {ProjectStatusCompleted, ProjectStatusFailed, ProjectStatusSuspended},
+	ProjectStatusSuspended:     {ProjectStatusActive, ProjectStatusFailed},
+	ProjectStatusRejected:      {ProjectStatusDraft},
+}
+
+// CanTransitionProject 判断项目是否允许从 from 流转到 to
+func CanTransitionProject(from, to string) bool {
+	for _, next := range projectTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidProjectStatus 判断是否为合法的项目状态
+func IsValidProjectStatus(status string) bool {
+	switch status {
+	case ProjectStatusDraft, ProjectStatusPendingReview, ProjectStatusActive,
+		ProjectStatusCompleted, ProjectStatusFailed, ProjectStatusRejected, ProjectStatusSuspended:
+		return true
+	}
+	return false
+}
+
+// IsProjectPublic 判断项目是否公开可见。草稿、审核中和被驳回的项目只有创建者可以预览
+func IsProjectPublic(status string) bool {
+	switch status {
+	case ProjectStatusDraft, ProjectStatusPendingReview, ProjectStatusRejected:
+		return false
+	}
+	return true
+}
+
+// ProjectTransition 一次项目状态流转
+type ProjectTransition struct {
+	Project *model.Project
+	From    string
+	To      string
+	Actor   string
+	Reason  string
+}
+
+// ProjectTransitionHook 项目状态流转成功后触发的回调，结算、退款、通知等模块通过它订阅状态变化
+type ProjectTransitionHook func(event *ProjectTransition) error
+
+// OnTransition 注册项目状态流转回调，需在服务启动时注册
+func (s *ProjectService) OnTransition(hook ProjectTransitionHook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+// TransitionStatus 按项目生命周期流转项目状态，并依次触发已注册的回调。
+// 管理员发起的流转传入 audit，与状态更新在同一事务中写入审计日志；系统任务传 nil
+func (s *ProjectService) TransitionStatus(projectID int, to, actor, reason string, audit *model.AuditEntry) (*model.Project, error) {
+	return s.transitionStatus(projectID, to, actor, reason, audit, nil)
+}
+
+// transitionStatus 流转项目状态，review 不为 nil 时与状态更新在同一事务中写入审核记录
+func (s *ProjectService) transitionStatus(projectID int, to, actor, reason string, audit *model.AuditEntry, review *model.ProjectReview) (*model.Project, error) {
+	project, err := s.repo.GetProjectByID(projectID)
+	if err != nil {
+		util.Logger.Error("获取项目失败", zap.Error(err), zap.Int("project_id", projectID))
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	from := project.Status
+	if !CanTransitionProject(from, to) {
+		util.Logger.Warn("拒绝非法的项目状态流转",
+			zap.Int("project_id", projectID),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.String("actor", actor))
+		return nil, &InvalidTransitionError{Entity: "project", From: from, To: to}
+	}
+
+	if audit != nil {
+		audit.SetChange(map[string]string{"status": from}, map[string]string{"status": to, "reason": reason})
+	}
+	var updated bool
+	if review != nil {
+		updated, err = s.repo.ReviewProjectStatus(projectID, from, to, review, audit)
+	} else {
+		updated, err = s.repo.TransitionProjectStatus(projectID, from, to, audit)
+	}
+	if err != nil {
+		util.Logger.Error("更新项目状态失败", zap.Error(err), zap.Int("project_id", projectID))
+		return nil, err
+	}
+	if !updated {
+		// 项目状态已被其他操作修改
+		current, err := s.repo.GetProjectByID(projectID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, ErrProjectNotFound
+		}
+		return nil, &InvalidTransitionError{Entity: "project", From: current.Status, To: to}
+	}
+	project.Status = to
+
+	util.Logger.Info("项目状态已流转",
+		zap.Int("project_id", projectID),
+		zap.String("from", from),
+		zap.String("to", to),
+		zap.String("actor", actor),
+		zap.String("reason", reason))
+
+	// 状态已经落库，回调失败只记录日志，不影响本次流转
+	event := &ProjectTransition{Project: project, From: from, To: to, Actor: actor, Reason: reason}
+	for _, hook := range s.hooks {
+		if err := hook(event); err != nil {
+			util.Logger.Error("项目状态流转回调执行失败",
+				zap.Error(err),
+				zap.Int("project_id", projectID),
+				zap.String("to", to))
+		}
+	}
+
+	return project, nil
+}
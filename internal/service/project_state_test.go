@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionProject(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{ProjectStatusDraft, ProjectStatusPendingReview, true},
+		{ProjectStatusDraft, ProjectStatusActive, false},
+		{ProjectStatusPendingReview, ProjectStatusActive, true},
+		{ProjectStatusPendingReview, ProjectStatusRejected, true},
+		{ProjectStatusActive, ProjectStatusCompleted, true},
+		{ProjectStatusActive, ProjectStatusFailed, true},
+		{ProjectStatusActive, ProjectStatusSuspended, true},
+		{ProjectStatusSuspended, ProjectStatusActive, true},
+		{ProjectStatusSuspended, ProjectStatusCompleted, false},
+		{ProjectStatusCompleted, ProjectStatusFailed, false},
+		{ProjectStatusFailed, ProjectStatusActive, false},
+		{ProjectStatusRejected, ProjectStatusActive, false},
+		{"success", ProjectStatusCompleted, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, CanTransitionProject(tt.from, tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
+
+func TestIsValidProjectStatus(t *testing.T) {
+	assert.True(t, IsValidProjectStatus(ProjectStatusSuspended))
+	assert.False(t, IsValidProjectStatus("success"))
+	assert.False(t, IsValidProjectStatus("successful"))
+}
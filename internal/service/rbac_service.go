@@ -0,0 +1,315 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"errors"
+	"regexp"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrRoleNotFound         = errors.New("角色不存在")
+	ErrRoleExists           = errors.New("角色名称已存在")
+	ErrSystemRole           = errors.New("内置角色不能修改或删除")
+	ErrInvalidPermission    = errors.New("无效的权限标识")
+	ErrPermissionEscalation = errors.New("不能授予超出自身权限范围的角色或权限")
+	ErrLastAdmin            = errors.New("至少需要保留一名管理员")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// RBACService 基于角色的权限控制：管理角色、角色权限和用户的角色分配
+type RBACService struct {
+	roleRepo interfaces.RoleRepository
+	userRepo interfaces.UserRepository
+}
+
+func NewRBACService(roleRepo interfaces.RoleRepository, userRepo interfaces.UserRepository) *RBACService {
+	return &RBACService{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+	}
+}
+
+// HasPermission 权限列表中是否包含指定权限，* 表示全部权限
+func HasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == model.PermissionAll || p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUserPermissions 获取用户所有角色的权限并集
+func (s *RBACService) GetUserPermissions(userID int) ([]string, error) {
+	return s.roleRepo.GetUserPermissions(userID)
+}
+
+// ListPermissions 可以授予角色的全部权限
+func (s *RBACService) ListPermissions() []string {
+	return model.AllPermissions
+}
+
+// ListRoles 获取所有角色及其权限
+func (s *RBACService) ListRoles() ([]*model.Role, error) {
+	return s.roleRepo.ListRoles()
+}
+
+// CreateRole 创建自定义角色，只能包含操作者自身拥有的权限
+func (s *RBACService) CreateRole(role *model.Role, actor model.AuditActor) error {
+	if !roleNamePattern.MatchString(role.Name) || role.Name == model.UserRoleUser {
+		return ErrInvalidRole
+	}
+	if err := s.checkGrantable(actor.UserID, role.Permissions); err != nil {
+		return err
+	}
+
+	audit := actor.Entry(model.AuditActionRoleCreate, model.AuditTargetRole, 0)
+	audit.SetChange(nil, role)
+	created, err := s.roleRepo.CreateRole(role, audit)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return ErrRoleExists
+	}
+	util.Logger.Info("创建角色",
+		zap.Int("role_id", role.ID),
+		zap.String("name", role.Name),
+		zap.Strings("permissions", role.Permissions),
+		zap.Int("admin_id", actor.UserID))
+	return nil
+}
+
+// UpdateRole 修改自定义角色的描述和权限
+func (s *RBACService) UpdateRole(roleID int, description string, permissions []string, actor model.AuditActor) (*model.Role, error) {
+	role, err := s.getCustomRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+	// 操作者既不能授予自己没有的权限，也不能修改超出自身权限范围的角色
+	if err := s.checkGrantable(actor.UserID, append(permissions, role.Permissions...)); err != nil {
+		return nil, err
+	}
+
+	before := *role
+	role.Description = description
+	role.Permissions = permissions
+	audit := actor.Entry(model.AuditActionRoleUpdate, model.AuditTargetRole, roleID)
+	audit.SetChange(before, role)
+	if err := s.roleRepo.UpdateRole(role, audit); err != nil {
+		return nil, err
+	}
+	util.Logger.Info("修改角色权限",
+		zap.Int("role_id", roleID),
+		zap.Strings("permissions", permissions),
+		zap.Int("admin_id", actor.UserID))
+	return s.roleRepo.GetRoleByID(roleID)
+}
+
+// DeleteRole 删除自定义角色，拥有该角色的用户随即失去相应权限
+func (s *RBACService) DeleteRole(roleID int, actor model.AuditActor) error {
+	role, err := s.getCustomRole(roleID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkGrantable(actor.UserID, role.Permissions); err != nil {
+		return err
+	}
+	audit := actor.Entry(model.AuditActionRoleDelete, model.AuditTargetRole, roleID)
+	audit.SetChange(role, nil)
+	if err := s.roleRepo.DeleteRole(roleID, audit); err != nil {
+		return err
+	}
+	util.Logger.Info("删除角色", zap.Int("role_id", roleID), zap.String("name", role.Name), zap.Int("admin_id", actor.UserID))
+	return nil
+}
+
+// GetUserRoles 获取用户拥有的角色
+func (s *RBACService) GetUserRoles(userID int) ([]*model.Role, error) {
+	if err := s.ensureUserExists(userID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.GetUserRoles(userID)
+}
+
+// AssignRole 为用户分配角色，已拥有时不做处理
+func (s *RBACService) AssignRole(userID int, roleName string, actor model.AuditActor) error {
+	role, err := s.grantableRole(roleName, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureUserExists(userID); err != nil {
+		return err
+	}
+	audit := actor.Entry(model.AuditActionUserRoleAssign, model.AuditTargetUser, userID)
+	audit.SetChange(nil, map[string]string{"role": roleName})
+	assigned, err := s.roleRepo.AssignRole(userID, role.ID, actor.UserID, audit)
+	if err != nil {
+		return err
+	}
+	if assigned {
+		util.Logger.Info("分配角色", zap.Int("user_id", userID), zap.String("role", roleName), zap.Int("admin_id", actor.UserID))
+	}
+	return nil
+}
+
+// RevokeRole 收回用户的角色，不能收回最后一名管理员的 admin 角色
+func (s *RBACService) RevokeRole(userID int, roleName string, actor model.AuditActor) error {
+	role, err := s.grantableRole(roleName, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureUserExists(userID); err != nil {
+		return err
+	}
+	if err := s.checkLastAdmin(userID, []*model.Role{role}); err != nil {
+		return err
+	}
+	audit := actor.Entry(model.AuditActionUserRoleRevoke, model.AuditTargetUser, userID)
+	audit.SetChange(map[string]string{"role": roleName}, nil)
+	revoked, err := s.roleRepo.RevokeRole(userID, role.ID, audit)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		util.Logger.Info("收回角色", zap.Int("user_id", userID), zap.String("role", roleName), zap.Int("admin_id", actor.UserID))
+	}
+	return nil
+}
+
+// SetUserRole 将用户的角色替换为指定角色，role 为 user 时收回全部角色
+func (s *RBACService) SetUserRole(userID int, roleName string, actor model.AuditActor) error {
+	if err := s.ensureUserExists(userID); err != nil {
+		return err
+	}
+	current, err := s.roleRepo.GetUserRoles(userID)
+	if err != nil {
+		return err
+	}
+	// 被替换掉的角色同样需要在操作者的权限范围内
+	currentNames := []string{}
+	for _, role := range current {
+		if err := s.checkGrantable(actor.UserID, role.Permissions); err != nil {
+			return err
+		}
+		currentNames = append(currentNames, role.Name)
+	}
+
+	var roleIDs []int
+	if roleName != model.UserRoleUser {
+		role, err := s.grantableRole(roleName, actor.UserID)
+		if err != nil {
+			return err
+		}
+		roleIDs = append(roleIDs, role.ID)
+		if role.Name == model.RoleAdmin {
+			current = nil
+		}
+	}
+	if err := s.checkLastAdmin(userID, current); err != nil {
+		return err
+	}
+	audit := actor.Entry(model.AuditActionUserRoleSet, model.AuditTargetUser, userID)
+	audit.SetChange(map[string][]string{"roles": currentNames}, map[string]string{"role": roleName})
+	if err := s.roleRepo.SetUserRoles(userID, roleIDs, actor.UserID, audit); err != nil {
+		return err
+	}
+	util.Logger.Info("设置用户角色", zap.Int("user_id", userID), zap.String("role", roleName), zap.Int("admin_id", actor.UserID))
+	return nil
+}
+
+// grantableRole 查找角色并确认操作者可以授予
+func (s *RBACService) grantableRole(roleName string, actorID int) (*model.Role, error) {
+	role, err := s.roleRepo.GetRoleByName(roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	if err := s.checkGrantable(actorID, role.Permissions); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// checkGrantable 权限必须有效，且在操作者自身的权限范围内，防止通过分配角色提升权限
+func (s *RBACService) checkGrantable(actorID int, permissions []string) error {
+	actorPermissions, err := s.roleRepo.GetUserPermissions(actorID)
+	if err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if !model.IsValidPermission(permission) {
+			return ErrInvalidPermission
+		}
+		if permission == model.PermissionAll {
+			if !HasPermission(actorPermissions, model.PermissionAll) {
+				return ErrPermissionEscalation
+			}
+			continue
+		}
+		if !HasPermission(actorPermissions, permission) {
+			return ErrPermissionEscalation
+		}
+	}
+	return nil
+}
+
+// checkLastAdmin 即将移除的角色中包含 admin 且用户是唯一的管理员时拒绝
+func (s *RBACService) checkLastAdmin(userID int, removing []*model.Role) error {
+	for _, role := range removing {
+		if role.Name != model.RoleAdmin {
+			continue
+		}
+		roles, err := s.roleRepo.GetUserRoles(userID)
+		if err != nil {
+			return err
+		}
+		holdsAdmin := false
+		for _, r := range roles {
+			if r.ID == role.ID {
+				holdsAdmin = true
+			}
+		}
+		if !holdsAdmin {
+			return nil
+		}
+		count, err := s.roleRepo.CountRoleMembers(role.ID)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			return ErrLastAdmin
+		}
+	}
+	return nil
+}
+
+func (s *RBACService) getCustomRole(roleID int) (*model.Role, error) {
+	role, err := s.roleRepo.GetRoleByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	if role.IsSystem {
+		return nil, ErrSystemRole
+	}
+	return role, nil
+}
+
+func (s *RBACService) ensureUserExists(userID int) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err == sql.ErrNoRows || (err == nil && user == nil) {
+		return ErrUserNotFound
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) ListRoles() ([]*model.Role, error) {
+	args := m.Called()
+	return args.Get(0).([]*model.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) GetRoleByID(id int) (*model.Role, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) GetRoleByName(name string) (*model.Role, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) CreateRole(role *model.Role, audit *model.AuditEntry) (bool, error) {
+	args := m.Called(role, audit)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoleRepository) UpdateRole(role *model.Role, audit *model.AuditEntry) error {
+	args := m.Called(role, audit)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) DeleteRole(id int, audit *model.AuditEntry) error {
+	args := m.Called(id, audit)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) CountRoleMembers(roleID int) (int, error) {
+	args := m.Called(roleID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRoleRepository) GetUserRoles(userID int) ([]*model.Role, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]*model.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) GetUserPermissions(userID int) ([]string, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRoleRepository) AssignRole(userID, roleID, assignedBy int, audit *model.AuditEntry) (bool, error) {
+	args := m.Called(userID, roleID, assignedBy, audit)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoleRepository) RevokeRole(userID, roleID int, audit *model.AuditEntry) (bool, error) {
+	args := m.Called(userID, roleID, audit)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoleRepository) SetUserRoles(userID int, roleIDs []int, assignedBy int, audit *model.AuditEntry) error {
+	args := m.Called(userID, roleIDs, assignedBy, audit)
+	return args.Error(0)
+}
+
+func newRBACTestService() (*RBACService, *MockRoleRepository, *MockUserRepository) {
+	util.Logger = zap.NewNop()
+	roleRepo := new(MockRoleRepository)
+	userRepo := new(MockUserRepository)
+	return NewRBACService(roleRepo, userRepo), roleRepo, userRepo
+}
+
+var testAdminRole = &model.Role{ID: 1, Name: model.RoleAdmin, IsSystem: true, Permissions: []string{model.PermissionAll}}
+
+var (
+	testAdminActor = model.AuditActor{UserID: 1, IP: "127.0.0.1", RequestID: "req-1"}
+	testStaffActor = model.AuditActor{UserID: 2, IP: "127.0.0.1", RequestID: "req-2"}
+)
+
+// TestHasPermission 测试 * 表示拥有全部权限
+func TestHasPermission(t *testing.T) {
+	assert.True(t, HasPermission([]string{model.PermissionAll}, model.PermissionRefundApprove))
+	assert.True(t, HasPermission([]string{model.PermissionOrderRead, model.PermissionRefundApprove}, model.PermissionRefundApprove))
+	assert.False(t, HasPermission([]string{model.PermissionOrderRead}, model.PermissionRefundApprove))
+	assert.False(t, HasPermission(nil, model.PermissionOrderRead))
+}
+
+// TestAssignRolePreventsEscalation 测试只能分配在自身权限范围内的角色
+func TestAssignRolePreventsEscalation(t *testing.T) {
+	service, roleRepo, userRepo := newRBACTestService()
+	roleRepo.On("GetUserPermissions", 2).Return([]string{model.PermissionRoleManage, model.PermissionOrderRead}, nil)
+	roleRepo.On("GetRoleByName", model.RoleFinance).Return(&model.Role{ID: 4, Name: model.RoleFinance,
+		Permissions: []string{model.PermissionOrderRead, model.PermissionRefundApprove}}, nil)
+	roleRepo.On("GetRoleByName", model.RoleAdmin).Return(testAdminRole, nil)
+	roleRepo.On("GetRoleByName", "viewer").Return(&model.Role{ID: 6, Name: "viewer", Permissions: []string{model.PermissionOrderRead}}, nil)
+	roleRepo.On("GetRoleByName", "unknown").Return(nil, nil)
+	userRepo.On("FindByID", 9).Return(&model.User{ID: 9}, nil)
+	roleRepo.On("AssignRole", 9, 6, 2, mock.Anything).Return(true, nil)
+
+	assert.ErrorIs(t, service.AssignRole(9, model.RoleFinance, testStaffActor), ErrPermissionEscalation)
+	assert.ErrorIs(t, service.AssignRole(9, model.RoleAdmin, testStaffActor), ErrPermissionEscalation)
+	assert.ErrorIs(t, service.AssignRole(9, "unknown", testStaffActor), ErrRoleNotFound)
+	assert.NoError(t, service.AssignRole(9, "viewer", testStaffActor))
+
+	// 角色分配与审计记录一起交给仓储在同一事务中写入
+	roleRepo.AssertCalled(t, "AssignRole", 9, 6, 2, mock.MatchedBy(func(audit *model.AuditEntry) bool {
+		return audit.ActorID == 2 && audit.Action == model.AuditActionUserRoleAssign &&
+			audit.TargetType == model.AuditTargetUser && audit.TargetID == 9 &&
+			audit.IP == "127.0.0.1" && audit.RequestID == "req-2" &&
+			string(audit.After) == `{"role":"viewer"}`
+	}))
+
+	err := service.CreateRole(&model.Role{Name: "refunder", Permissions: []string{model.PermissionRefundApprove}}, testStaffActor)
+	assert.ErrorIs(t, err, ErrPermissionEscalation)
+	err = service.CreateRole(&model.Role{Name: "reader", Permissions: []string{"order.delete"}}, testStaffActor)
+	assert.ErrorIs(t, err, ErrInvalidPermission)
+	err = service.CreateRole(&model.Role{Name: "Bad Name", Permissions: []string{model.PermissionOrderRead}}, testStaffActor)
+	assert.ErrorIs(t, err, ErrInvalidRole)
+}
+
+// TestRevokeLastAdmin 测试不能收回最后一名管理员的 admin 角色，内置角色不能修改
+func TestRevokeLastAdmin(t *testing.T) {
+	service, roleRepo, userRepo := newRBACTestService()
+	roleRepo.On("GetUserPermissions", 1).Return([]string{model.PermissionAll}, nil)
+	roleRepo.On("GetRoleByName", model.RoleAdmin).Return(testAdminRole, nil)
+	roleRepo.On("GetUserRoles", 1).Return([]*model.Role{testAdminRole}, nil)
+	userRepo.On("FindByID", 1).Return(&model.User{ID: 1, Role: model.UserRoleAdmin}, nil)
+
+	roleRepo.On("CountRoleMembers", 1).Return(1, nil).Twice()
+	assert.ErrorIs(t, service.RevokeRole(1, model.RoleAdmin, testAdminActor), ErrLastAdmin)
+	assert.ErrorIs(t, service.SetUserRole(1, model.UserRoleUser, testAdminActor), ErrLastAdmin)
+
+	roleRepo.On("CountRoleMembers", 1).Return(2, nil)
+	roleRepo.On("RevokeRole", 1, 1, mock.Anything).Return(true, nil)
+	assert.NoError(t, service.RevokeRole(1, model.RoleAdmin, testAdminActor))
+
+	roleRepo.On("GetRoleByID", 1).Return(testAdminRole, nil)
+	_, err := service.UpdateRole(1, "", []string{model.PermissionOrderRead}, testAdminActor)
+	assert.ErrorIs(t, err, ErrSystemRole)
+}
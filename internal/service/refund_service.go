@@ -1,25 +1,145 @@
 package service
 
 import (
+	"crowdfunding-backend/internal/gateway"
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
 	"database/sql"
 	"errors"
 	"fmt"
+	"math/big"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrRefundRequestNotFound 退款申请不存在
+	ErrRefundRequestNotFound = errors.New("退款申请不存在")
+	// ErrRefundRequestProcessed 退款申请已处理
+	ErrRefundRequestProcessed = errors.New("退款申请已处理")
+	// ErrRefundExceedsPaid 退款金额超过订单剩余可退金额
+	ErrRefundExceedsPaid = errors.New("退款金额超过订单已支付金额")
+)
+
+// 订单退款执行状态
+const (
+	OrderRefundStatusPending   = "pending"
+	OrderRefundStatusSucceeded = "succeeded"
+	OrderRefundStatusFailed    = "failed"
+)
+
+const (
+	maxRefundAttempts    = 5               // 单笔退款最多尝试次数
+	maxRefundRetryDelay  = time.Hour       // 重试间隔上限
+	refundExecutionLease = 5 * time.Minute // 执行中的退款在此时间内不会被重试任务再次领取
+	refundRetryBatchSize = 50              // 每轮重试处理的退款数
 )
 
 type RefundService struct {
 	paymentRepo interfaces.PaymentRepository
+	orderStates *OrderStateMachine
+	gateway     gateway.PaymentGateway
 	db          *sql.DB
 }
 
-func NewRefundService(paymentRepo interfaces.PaymentRepository, db *sql.DB) *RefundService {
+func NewRefundService(paymentRepo interfaces.PaymentRepository, paymentGateway gateway.PaymentGateway, db *sql.DB) *RefundService {
 	return &RefundService{
 		paymentRepo: paymentRepo,
+		orderStates: NewOrderStateMachine(paymentRepo),
+		gateway:     paymentGateway,
 		db:          db,
 	}
 }
 
+// resolveRefundRequest 审批退款申请，订单状态流转、申请更新和审计日志在同一事务中提交。
+// 批准时按订单的各笔扣款创建退款执行记录并扣减项目已筹金额，提交后通过支付渠道原路退回，
+// 渠道确认全部退款成功后订单才变为已退款，失败的退款由重试任务继续处理
+func (s *RefundService) resolveRefundRequest(requestID int, approved bool, comment string, admin model.AuditActor) (*model.RefundRequest, error) {
+	request, err := s.paymentRepo.GetRefundRequestByID(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, ErrRefundRequestNotFound
+	}
+	if request.Status != "pending" {
+		return nil, ErrRefundRequestProcessed
+	}
+
+	order := request.Order
+	var refunds []*model.OrderRefund
+	if approved {
+		intents, err := s.paymentRepo.GetOrderPaymentIntents(order.ID)
+		if err != nil {
+			return nil, err
+		}
+		refunds, err = buildOrderRefunds(order, intents, order.OriginalAmount, order.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		util.Logger.Error("开始事务失败", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	actor := AdminActor(admin.UserID)
+	orderStatus := order.Status
+	// 状态机上线前创建的退款申请，订单可能仍停留在原状态
+	if order.Status != OrderStatusRefundPending {
+		if err := s.orderStates.TransitionTx(tx, order, OrderStatusRefundPending, actor, request.Reason); err != nil {
+			return nil, err
+		}
+	}
+
+	if approved {
+		request.Status = "approved"
+		for _, refund := range refunds {
+			refund.RefundRequestID = &request.ID
+			if err := s.paymentRepo.CreateOrderRefundTx(tx, refund); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.paymentRepo.AddProjectAmountTx(tx, order.ProjectID, negate(order.Amount)); err != nil {
+			return nil, err
+		}
+	} else {
+		request.Status = "rejected"
+		if err := s.orderStates.TransitionTx(tx, order, OrderStatusRefundRejected, actor, comment); err != nil {
+			return nil, err
+		}
+	}
+
+	request.AdminComment = comment
+	audit := admin.Entry(model.AuditActionRefundProcess, model.AuditTargetRefundRequest, request.ID)
+	audit.SetChange(map[string]string{"status": "pending", "order_status": orderStatus},
+		map[string]string{"status": request.Status, "order_status": order.Status, "admin_comment": comment})
+	updated, err := s.paymentRepo.UpdateRefundRequestTx(tx, request, audit)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrRefundRequestProcessed
+	}
+
+	if err := tx.Commit(); err != nil {
+		util.Logger.Error("提交事务失败", zap.Error(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, refund := range refunds {
+		if err := s.executeRefund(refund); err != nil {
+			util.Logger.Error("执行退款失败，等待重试任务处理", zap.Error(err), zap.Int("refund_id", refund.ID))
+		}
+	}
+	return request, nil
+}
+
 // RequestRefund 申请退款
 func (s *RefundService) RequestRefund(orderID, userID int, reason string) error {
 	// 获取订单信息
@@ -28,12 +148,12 @@ func (s *RefundService) RequestRefund(orderID, userID int, reason string) error
 		return err
 	}
 	if order == nil {
-		return errors.New("订单不存在")
+		return ErrOrderNotFound
 	}
 
 	// 验证订单属于当前用户
 	if order.UserID != userID {
-		return errors.New("订单不属于当前用户")
+		return ErrOrderNotOwned
 	}
 
 	// 检查是否已经存在退款申请
@@ -41,7 +161,7 @@ func (s *RefundService) RequestRefund(orderID, userID int, reason string) error
 	if err != nil {
 		return err
 	}
-	if refundRequest != nil {
+	if refundRequest != nil && refundRequest.Status == "pending" {
 		return fmt.Errorf("该订单已存在退款申请，状态为：%s", refundRequest.Status)
 	}
 
@@ -53,45 +173,37 @@ func (s *RefundService) RequestRefund(orderID, userID int, reason string) error
 		Status:  "pending",
 	}
 
-	return s.paymentRepo.CreateRefundRequest(refundRequest)
+	return s.submitRefundRequest(order, refundRequest, reason)
 }
 
-// ProcessRefund 处理退款申请
-func (s *RefundService) ProcessRefund(requestID int, approved bool, comment string) error {
+// submitRefundRequest 将订单流转为退款中并创建待审批的退款申请，两者在同一事务中提交，
+// 不会出现订单已在退款中却没有可审批申请的情况
+func (s *RefundService) submitRefundRequest(order *model.Order, request *model.RefundRequest, reason string) error {
 	tx, err := s.db.Begin()
 	if err != nil {
+		util.Logger.Error("开始事务失败", zap.Error(err))
 		return err
 	}
 	defer tx.Rollback()
 
-	// 获取退款申请信息
-	request, err := s.paymentRepo.GetRefundRequestByID(requestID)
-	if err != nil {
+	if err := s.orderStates.TransitionTx(tx, order, OrderStatusRefundPending, UserActor(request.UserID), reason); err != nil {
 		return err
 	}
-	if request == nil {
-		return errors.New("退款申请不存在")
-	}
-
-	// 更新退款申请状态
-	if approved {
-		request.Status = "approved"
-		err = s.paymentRepo.UpdateOrderStatus(request.OrderID, "refunded")
-	} else {
-		request.Status = "rejected"
-		err = s.paymentRepo.UpdateOrderStatus(request.OrderID, "refund_rejected")
-	}
-	if err != nil {
+	if err := s.paymentRepo.CreateRefundRequestTx(tx, request); err != nil {
 		return err
 	}
 
-	request.AdminComment = comment
-	err = s.paymentRepo.UpdateRefundRequest(request)
-	if err != nil {
-		return err
+	if err := tx.Commit(); err != nil {
+		util.Logger.Error("提交事务失败", zap.Error(err))
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	return nil
+}
 
-	return tx.Commit()
+// ProcessRefund 处理退款申请
+func (s *RefundService) ProcessRefund(requestID int, approved bool, comment string, actor model.AuditActor) error {
+	_, err := s.resolveRefundRequest(requestID, approved, comment, actor)
+	return err
 }
 
 // GetRefundStatus 获取退款状态
@@ -99,40 +211,344 @@ func (s *RefundService) GetRefundStatus(orderID int) (*model.RefundRequest, erro
 	return s.paymentRepo.GetRefundStatus(orderID)
 }
 
-// AutoRefundForFailedProject 为众筹失败的项目自动创建退款申请
-func (s *RefundService) AutoRefundForFailedProject(projectID int) error {
+// OnProjectTransition 项目状态流转回调，项目失败时自动为支持者发起退款
+func (s *RefundService) OnProjectTransition(event *ProjectTransition) error {
+	if event.To != ProjectStatusFailed {
+		return nil
+	}
+	_, err := s.AutoRefundForFailedProject(event.Project.ID)
+	return err
+}
+
+// AutoRefundForFailedProject 为众筹失败的项目自动退款：订单流转为众筹失败，已扣款的订单通过支付渠道原路退回。
+// 单个订单退款失败不影响其他订单，处理失败的订单记录在报告中，失败的退款由重试任务继续处理。返回项目的退款汇总报告
+func (s *RefundService) AutoRefundForFailedProject(projectID int) (*model.ProjectRefundReport, error) {
 	orders, err := s.paymentRepo.GetOrdersByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []model.ProjectRefundError
+	for _, order := range orders {
+		if err := s.refundFailedProjectOrder(order); err != nil {
+			util.Logger.Error("众筹失败订单退款失败",
+				zap.Error(err),
+				zap.Int("project_id", projectID),
+				zap.Int("order_id", order.ID))
+			failures = append(failures, model.ProjectRefundError{OrderID: order.ID, Error: err.Error()})
+		}
+	}
+
+	report, err := s.GetProjectRefundReport(projectID)
+	if err != nil {
+		return nil, err
+	}
+	report.Errors = failures
+	util.Logger.Info("项目众筹失败自动退款完成",
+		zap.Int("project_id", projectID),
+		zap.Int("total_refunds", report.TotalRefunds),
+		zap.Int("succeeded", report.SucceededCount),
+		zap.Int("pending", report.PendingCount),
+		zap.Int("failed", report.FailedCount),
+		zap.Int("order_errors", len(failures)),
+		zap.Stringer("refunded_amount", report.RefundedAmount))
+	return report, nil
+}
+
+// refundFailedProjectOrder 将单个订单流转为众筹失败，已扣款且未退款的订单创建并执行退款。
+// 支持者申请退款中或退款被拒绝的订单同样退款；订单已有批准的退款申请时由重试任务继续执行退款
+func (s *RefundService) refundFailedProjectOrder(order *model.Order) error {
+	if order.Status == OrderStatusRefunded || order.Status == OrderStatusCancelled {
+		return nil
+	}
+	// 未扣款的订单无需退款
+	if order.PaidAt == nil {
+		if order.Status == OrderStatusPending || order.Status == OrderStatusPaid {
+			return s.orderStates.Transition(order, OrderStatusCrowdfundingFailed, ActorSystem, "项目众筹失败")
+		}
+		return nil
+	}
+
+	latest, err := s.paymentRepo.GetRefundStatus(order.ID)
 	if err != nil {
 		return err
 	}
+	if latest != nil && latest.Status == "approved" {
+		return nil
+	}
+	if order.Status != OrderStatusCrowdfundingFailed {
+		if err := s.orderStates.Transition(order, OrderStatusCrowdfundingFailed, ActorSystem, "项目众筹失败"); err != nil {
+			return err
+		}
+	}
+
+	// 支持者待审批的退款申请直接批准，不再另建申请
+	var pending *model.RefundRequest
+	if latest != nil && latest.Status == "pending" {
+		pending = latest
+	}
+	refunds, err := s.createFailedProjectRefunds(order, pending)
+	if err != nil {
+		return fmt.Errorf("创建退款记录失败: %w", err)
+	}
+	var errs []error
+	for _, refund := range refunds {
+		if err := s.executeRefund(refund); err != nil {
+			errs = append(errs, fmt.Errorf("执行退款 %d 失败: %w", refund.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// createFailedProjectRefunds 为众筹失败的已扣款订单创建已批准的退款申请和退款执行记录，
+// 申请、订单流转和执行记录在同一事务中提交。pending 为支持者待审批的退款申请，不为空时批准该申请。
+// 订单有补扣款时每笔扣款分别原路退回
+func (s *RefundService) createFailedProjectRefunds(order *model.Order, pending *model.RefundRequest) ([]*model.OrderRefund, error) {
+	intents, err := s.paymentRepo.GetOrderPaymentIntents(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := buildOrderRefunds(order, intents, order.OriginalAmount, order.Amount)
+	if err != nil {
+		return nil, err
+	}
 
 	tx, err := s.db.Begin()
 	if err != nil {
-		return err
+		util.Logger.Error("开始事务失败", zap.Error(err))
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	for _, order := range orders {
-		if order.Status == "pending" || order.Status == "paid" {
-			// 创建退款申请
-			refundRequest := &model.RefundRequest{
-				OrderID: order.ID,
-				UserID:  order.UserID,
-				Reason:  "项目众筹失败自动退款",
-				Status:  "approved",
-			}
+	refundRequest := pending
+	if refundRequest != nil {
+		refundRequest.Status = "approved"
+		refundRequest.AdminComment = "项目众筹失败自动退款"
+		updated, err := s.paymentRepo.UpdateRefundRequestTx(tx, refundRequest, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			return nil, ErrRefundRequestProcessed
+		}
+	} else {
+		refundRequest = &model.RefundRequest{
+			OrderID: order.ID,
+			UserID:  order.UserID,
+			Reason:  "项目众筹失败自动退款",
+			Status:  "approved",
+		}
+		if err := s.paymentRepo.CreateRefundRequestTx(tx, refundRequest); err != nil {
+			return nil, err
+		}
+	}
 
-			err = s.paymentRepo.CreateRefundRequest(refundRequest)
-			if err != nil {
-				return err
-			}
+	if err := s.orderStates.TransitionTx(tx, order, OrderStatusRefundPending, ActorSystem, "项目众筹失败自动退款"); err != nil {
+		return nil, err
+	}
 
-			err = s.paymentRepo.UpdateOrderStatus(order.ID, "refunded")
-			if err != nil {
-				return err
-			}
+	for _, refund := range refunds {
+		refund.RefundRequestID = &refundRequest.ID
+		if err := s.paymentRepo.CreateOrderRefundTx(tx, refund); err != nil {
+			return nil, err
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		util.Logger.Error("提交事务失败", zap.Error(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return refunds, nil
+}
+
+// buildOrderRefunds 将退款金额分摊到订单的各笔扣款，优先退回最近的补扣款，最后退回下单扣款。
+// amount 为扣款币种金额，projectAmount 为对应的项目币种金额，按比例分摊到各笔退款
+func buildOrderRefunds(order *model.Order, intents []model.OrderPaymentIntent, amount, projectAmount model.Money) ([]*model.OrderRefund, error) {
+	var refunds []*model.OrderRefund
+	remaining := amount
+	allocated := model.NewMoney(0, order.Currency)
+	for _, intent := range intents {
+		if !remaining.IsPositive() {
+			break
+		}
+		part := intent.Refundable()
+		if !part.IsPositive() {
+			continue
+		}
+		if part.Cmp(remaining) > 0 {
+			part = remaining
+		}
+		remaining = remaining.Sub(part)
+
+		// 最后一笔取剩余金额，避免分摊的舍入误差
+		partProject := projectAmount.Sub(allocated)
+		if remaining.IsPositive() {
+			partProject = projectAmount.Convert(big.NewRat(part.Cents, amount.Cents), order.Currency)
+		}
+		allocated = allocated.Add(partProject)
+
+		refunds = append(refunds, &model.OrderRefund{
+			OrderID:         order.ID,
+			ProjectID:       order.ProjectID,
+			Provider:        intent.Provider,
+			PaymentIntentID: intent.IntentID,
+			Amount:          model.NewMoney(part.Cents, order.OriginalCurrency), // 按扣款币种原路退回
+			Currency:        order.OriginalCurrency,
+			ProjectAmount:   model.NewMoney(partProject.Cents, order.Currency),
+			ProjectCurrency: order.Currency,
+			Status:          OrderRefundStatusPending,
+		})
+	}
+	if remaining.IsPositive() {
+		return nil, ErrRefundExceedsPaid
+	}
+	return refunds, nil
+}
+
+// RetryPendingRefunds 重试到期的待执行退款，由定时任务调用
+func (s *RefundService) RetryPendingRefunds() error {
+	refunds, err := s.paymentRepo.GetDueOrderRefunds(refundRetryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, refund := range refunds {
+		if err := s.executeRefund(refund); err != nil {
+			util.Logger.Error("重试退款失败", zap.Error(err), zap.Int("refund_id", refund.ID))
+		}
+	}
+	return nil
+}
+
+// RetryFailedProjectRefunds 为仍有订单未退款的众筹失败项目重新发起自动退款，由定时任务调用。
+// 项目失败的回调在退款申请创建前出错时，依靠此任务补齐退款
+func (s *RefundService) RetryFailedProjectRefunds() error {
+	projectIDs, err := s.paymentRepo.GetFailedProjectsAwaitingRefund(refundRetryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, projectID := range projectIDs {
+		if _, err := s.AutoRefundForFailedProject(projectID); err != nil {
+			util.Logger.Error("重试众筹失败项目退款失败", zap.Error(err), zap.Int("project_id", projectID))
+		}
+	}
+	return nil
+}
+
+// executeRefund 通过支付渠道执行退款。渠道调用失败时记录错误并安排重试，达到最大次数后标记为失败等待人工处理
+func (s *RefundService) executeRefund(refund *model.OrderRefund) error {
+	claimed, err := s.paymentRepo.ClaimOrderRefund(refund.ID, refund.Attempts, time.Now().Add(refundExecutionLease))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		// 已被其他任务处理
+		return nil
+	}
+	refund.Attempts++
+
+	if refund.PaymentIntentID == "" {
+		return s.recordRefundFailure(refund, errors.New("订单缺少支付意图，需人工退款"), true)
+	}
+	if refund.Provider != s.gateway.Name() {
+		return s.recordRefundFailure(refund, fmt.Errorf("支付渠道 %s 不可用", refund.Provider), true)
+	}
+
+	result, err := s.gateway.Refund(refund.PaymentIntentID, refund.Amount)
+	if errors.Is(err, gateway.ErrRefundExceeded) {
+		// 上次退款可能已在渠道侧成功但未能落库
+		intent, queryErr := s.gateway.QueryStatus(refund.PaymentIntentID)
+		if queryErr == nil && intent.Status == gateway.IntentRefunded {
+			result, err = &gateway.Refund{}, nil
+		}
+	}
+	if err != nil {
+		return s.recordRefundFailure(refund, err, false)
+	}
+
+	now := time.Now()
+	refund.Status = OrderRefundStatusSucceeded
+	refund.GatewayRefundID = result.ID
+	refund.LastError = ""
+	refund.NextAttemptAt = nil
+	refund.CompletedAt = &now
+	if err := s.paymentRepo.UpdateOrderRefund(refund); err != nil {
+		return err
+	}
+
+	util.Logger.Info("订单退款成功",
+		zap.Int("refund_id", refund.ID),
+		zap.Int("order_id", refund.OrderID),
+		zap.Stringer("amount", refund.Amount))
+
+	// 订单变更产生的退款不改变订单状态；订单的各笔退款全部成功后订单才变为已退款
+	if refund.OrderChangeID != nil {
+		return nil
+	}
+	pending, err := s.paymentRepo.HasPendingOrderRefunds(refund.OrderID)
+	if err != nil || pending {
+		return err
+	}
+	if _, err := s.orderStates.TransitionByID(refund.OrderID, OrderStatusRefunded, ActorSystem, "支付渠道退款成功"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// recordRefundFailure 记录退款失败，permanent 为 true 或达到最大重试次数时不再重试
+func (s *RefundService) recordRefundFailure(refund *model.OrderRefund, cause error, permanent bool) error {
+	refund.LastError = cause.Error()
+	if permanent || refund.Attempts >= maxRefundAttempts {
+		refund.Status = OrderRefundStatusFailed
+		refund.NextAttemptAt = nil
+	} else {
+		next := time.Now().Add(refundRetryDelay(refund.Attempts))
+		refund.NextAttemptAt = &next
+	}
+
+	util.Logger.Warn("订单退款失败",
+		zap.Error(cause),
+		zap.Int("refund_id", refund.ID),
+		zap.Int("order_id", refund.OrderID),
+		zap.Int("attempts", refund.Attempts),
+		zap.String("status", refund.Status))
+	return s.paymentRepo.UpdateOrderRefund(refund)
+}
+
+// refundRetryDelay 按指数退避计算下次重试的间隔
+func refundRetryDelay(attempts int) time.Duration {
+	delay := time.Minute << attempts
+	if delay <= 0 || delay > maxRefundRetryDelay {
+		return maxRefundRetryDelay
+	}
+	return delay
+}
+
+// GetProjectRefundReport 汇总项目的退款执行情况
+func (s *RefundService) GetProjectRefundReport(projectID int) (*model.ProjectRefundReport, error) {
+	refunds, err := s.paymentRepo.GetOrderRefundsByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 各笔退款可能使用不同的扣款币种，汇总金额统一按项目币种计算
+	report := &model.ProjectRefundReport{ProjectID: projectID, Refunds: refunds}
+	for _, refund := range refunds {
+		report.Currency = refund.ProjectCurrency
+		report.TotalRefunds++
+		report.TotalAmount = report.TotalAmount.Add(refund.ProjectAmount)
+		switch refund.Status {
+		case OrderRefundStatusSucceeded:
+			report.SucceededCount++
+			report.RefundedAmount = report.RefundedAmount.Add(refund.ProjectAmount)
+		case OrderRefundStatusPending:
+			report.PendingCount++
+			report.PendingAmount = report.PendingAmount.Add(refund.ProjectAmount)
+		case OrderRefundStatusFailed:
+			report.FailedCount++
+			report.FailedAmount = report.FailedAmount.Add(refund.ProjectAmount)
+		}
+	}
+	return report, nil
 }
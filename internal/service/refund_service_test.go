@@ -0,0 +1,436 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/gateway"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// nopTxDriver 只支持开启、提交和回滚事务的数据库驱动，供仓储层被模拟、只需要 *sql.Tx 的服务测试使用
+type nopTxDriver struct{}
+
+func (nopTxDriver) Open(string) (driver.Conn, error) { return nopTxConn{}, nil }
+
+type nopTxConn struct{}
+
+func (nopTxConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("nop_tx: 不支持执行语句")
+}
+func (nopTxConn) Close() error              { return nil }
+func (nopTxConn) Begin() (driver.Tx, error) { return nopTxConn{}, nil }
+func (nopTxConn) Commit() error             { return nil }
+func (nopTxConn) Rollback() error           { return nil }
+
+func init() {
+	sql.Register("nop_tx", nopTxDriver{})
+}
+
+func newNopTxDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("nop_tx", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// MockPaymentRepository 模拟 PaymentRepository 中测试用到的方法，其余方法未实现
+type MockPaymentRepository struct {
+	interfaces.PaymentRepository
+	mock.Mock
+}
+
+func (m *MockPaymentRepository) GetOrderByID(id int) (*model.Order, error) {
+	args := m.Called(id)
+	order, _ := args.Get(0).(*model.Order)
+	return order, args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetOrdersByProject(projectID int) ([]*model.Order, error) {
+	args := m.Called(projectID)
+	return args.Get(0).([]*model.Order), args.Error(1)
+}
+
+func (m *MockPaymentRepository) TransitionOrderStatus(orderID int, from, to, actor, reason string) (bool, error) {
+	args := m.Called(orderID, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) TransitionOrderStatusTx(tx *sql.Tx, orderID int, from, to, actor, reason string) (bool, error) {
+	args := m.Called(orderID, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetOrderPaymentIntents(orderID int) ([]model.OrderPaymentIntent, error) {
+	args := m.Called(orderID)
+	intents, _ := args.Get(0).([]model.OrderPaymentIntent)
+	return intents, args.Error(1)
+}
+
+func (m *MockPaymentRepository) CreateRefundRequestTx(tx *sql.Tx, request *model.RefundRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) GetRefundRequestByID(requestID int) (*model.RefundRequest, error) {
+	args := m.Called(requestID)
+	request, _ := args.Get(0).(*model.RefundRequest)
+	return request, args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetRefundStatus(orderID int) (*model.RefundRequest, error) {
+	args := m.Called(orderID)
+	request, _ := args.Get(0).(*model.RefundRequest)
+	return request, args.Error(1)
+}
+
+func (m *MockPaymentRepository) UpdateRefundRequestTx(tx *sql.Tx, request *model.RefundRequest, audit *model.AuditEntry) (bool, error) {
+	args := m.Called(request)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) CreateOrderRefundTx(tx *sql.Tx, refund *model.OrderRefund) error {
+	args := m.Called(refund)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) ClaimOrderRefund(refundID, attempts int, leaseUntil time.Time) (bool, error) {
+	args := m.Called(refundID, attempts)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) UpdateOrderRefund(refund *model.OrderRefund) error {
+	args := m.Called(refund)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) HasPendingOrderRefunds(orderID int) (bool, error) {
+	args := m.Called(orderID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetOrderRefundsByProject(projectID int) ([]*model.OrderRefund, error) {
+	args := m.Called(projectID)
+	return args.Get(0).([]*model.OrderRefund), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetFailedProjectsAwaitingRefund(limit int) ([]int, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockPaymentRepository) UpdateOrderPaymentIntent(orderID int, provider, intentID string) error {
+	args := m.Called(orderID, provider, intentID)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) MarkOrderPaymentFailed(orderID int, actor, reason string) (bool, error) {
+	args := m.Called(orderID, actor)
+	return args.Bool(0), args.Error(1)
+}
+
+// capturedSandboxIntent 在沙箱渠道创建并完成一笔扣款
+func capturedSandboxIntent(t *testing.T, g *gateway.SandboxGateway, amount model.Money) string {
+	intent, err := g.CreateIntent(&gateway.IntentRequest{Amount: amount, OrderNumber: "TEST"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Capture(intent.ID); err != nil {
+		t.Fatal(err)
+	}
+	return intent.ID
+}
+
+func TestRefundRetryDelay(t *testing.T) {
+	assert.Equal(t, 2*time.Minute, refundRetryDelay(1))
+	assert.Equal(t, 16*time.Minute, refundRetryDelay(4))
+	assert.Equal(t, maxRefundRetryDelay, refundRetryDelay(7))
+	assert.Equal(t, maxRefundRetryDelay, refundRetryDelay(100))
+}
+
+func TestBuildOrderRefunds(t *testing.T) {
+	order := &model.Order{ID: 9, ProjectID: 3, Currency: "CNY", OriginalCurrency: "USD"}
+	intents := []model.OrderPaymentIntent{
+		{Provider: "stripe", IntentID: "pi_extra", Captured: model.NewMoney(2000, "USD")},
+		{Provider: "stripe", IntentID: "pi_original", Captured: model.NewMoney(10000, "USD"), Refunded: model.NewMoney(1000, "USD")},
+	}
+
+	// 先退最近的补扣款，项目币种金额按比例分摊
+	refunds, err := buildOrderRefunds(order, intents, model.NewMoney(5000, "USD"), model.NewMoney(36000, "CNY"))
+	assert.NoError(t, err)
+	if assert.Len(t, refunds, 2) {
+		assert.Equal(t, "pi_extra", refunds[0].PaymentIntentID)
+		assert.Equal(t, model.NewMoney(2000, "USD"), refunds[0].Amount)
+		assert.Equal(t, model.NewMoney(14400, "CNY"), refunds[0].ProjectAmount)
+		assert.Equal(t, "pi_original", refunds[1].PaymentIntentID)
+		assert.Equal(t, model.NewMoney(3000, "USD"), refunds[1].Amount)
+		assert.Equal(t, model.NewMoney(21600, "CNY"), refunds[1].ProjectAmount)
+		assert.Equal(t, OrderRefundStatusPending, refunds[1].Status)
+	}
+
+	// 退款金额超过剩余可退金额
+	_, err = buildOrderRefunds(order, intents, model.NewMoney(11001, "USD"), model.NewMoney(79207, "CNY"))
+	assert.ErrorIs(t, err, ErrRefundExceedsPaid)
+}
+
+// TestAutoRefundContinuesAfterOrderFailure 测试单个订单处理失败时继续为其余订单退款，失败订单记录在报告中
+func TestAutoRefundContinuesAfterOrderFailure(t *testing.T) {
+	util.Logger = zap.NewNop()
+	repo := new(MockPaymentRepository)
+	sandbox := gateway.NewSandboxGateway()
+	service := NewRefundService(repo, sandbox, newNopTxDB(t))
+
+	paidAt := time.Now().Add(-time.Hour)
+	newOrder := func(id int, status string) *model.Order {
+		return &model.Order{
+			ID: id, UserID: 7, ProjectID: 3, Status: status, PaidAt: &paidAt,
+			Amount: model.NewMoney(1000, "CNY"), Currency: "CNY",
+			OriginalAmount: model.NewMoney(1000, "CNY"), OriginalCurrency: "CNY",
+		}
+	}
+	intentFor := func(id string) []model.OrderPaymentIntent {
+		return []model.OrderPaymentIntent{{Provider: "sandbox", IntentID: id, Captured: model.NewMoney(1000, "CNY")}}
+	}
+	unpaid := newOrder(4, OrderStatusPaid)
+	unpaid.PaidAt = nil
+	repo.On("GetOrdersByProject", 3).Return([]*model.Order{
+		newOrder(1, OrderStatusPaid),
+		newOrder(2, OrderStatusPaid),
+		newOrder(3, OrderStatusCrowdfundingFailed),
+		unpaid,
+	}, nil)
+	repo.On("GetRefundStatus", mock.Anything).Return(nil, nil)
+
+	// 订单1流转失败
+	repo.On("TransitionOrderStatus", 1, OrderStatusPaid, OrderStatusCrowdfundingFailed).Return(false, errors.New("数据库不可用"))
+
+	// 订单2正常退款
+	repo.On("TransitionOrderStatus", 2, OrderStatusPaid, OrderStatusCrowdfundingFailed).Return(true, nil)
+	repo.On("GetOrderPaymentIntents", 2).Return(intentFor(capturedSandboxIntent(t, sandbox, model.NewMoney(1000, "CNY"))), nil)
+	repo.On("CreateRefundRequestTx", mock.MatchedBy(func(r *model.RefundRequest) bool { return r.OrderID == 2 })).Return(nil)
+	repo.On("TransitionOrderStatusTx", 2, OrderStatusCrowdfundingFailed, OrderStatusRefundPending).Return(true, nil)
+	repo.On("CreateOrderRefundTx", mock.MatchedBy(func(r *model.OrderRefund) bool { return r.OrderID == 2 })).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.OrderRefund).ID = 20
+	}).Return(nil)
+	repo.On("ClaimOrderRefund", 20, 0).Return(true, nil)
+	var executed *model.OrderRefund
+	repo.On("UpdateOrderRefund", mock.MatchedBy(func(r *model.OrderRefund) bool { return r.ID == 20 })).Run(func(args mock.Arguments) {
+		executed = args.Get(0).(*model.OrderRefund)
+	}).Return(nil)
+	repo.On("HasPendingOrderRefunds", 2).Return(false, nil)
+	repo.On("GetOrderByID", 2).Return(newOrder(2, OrderStatusRefundPending), nil)
+	repo.On("TransitionOrderStatus", 2, OrderStatusRefundPending, OrderStatusRefunded).Return(true, nil)
+
+	// 订单3创建退款申请失败，事务回滚，不会留下孤立的退款执行记录
+	repo.On("GetOrderPaymentIntents", 3).Return(intentFor("sb_pi_3"), nil)
+	repo.On("CreateRefundRequestTx", mock.MatchedBy(func(r *model.RefundRequest) bool { return r.OrderID == 3 })).Return(errors.New("写入失败"))
+
+	// 订单4未扣款，只流转状态
+	repo.On("TransitionOrderStatus", 4, OrderStatusPaid, OrderStatusCrowdfundingFailed).Return(true, nil)
+
+	repo.On("GetOrderRefundsByProject", 3).Return([]*model.OrderRefund{}, nil)
+
+	report, err := service.AutoRefundForFailedProject(3)
+	assert.NoError(t, err)
+	if assert.Len(t, report.Errors, 2) {
+		assert.Equal(t, 1, report.Errors[0].OrderID)
+		assert.Equal(t, 3, report.Errors[1].OrderID)
+	}
+	if assert.NotNil(t, executed) {
+		assert.Equal(t, OrderRefundStatusSucceeded, executed.Status)
+	}
+	repo.AssertCalled(t, "TransitionOrderStatus", 4, OrderStatusPaid, OrderStatusCrowdfundingFailed)
+	repo.AssertNotCalled(t, "CreateOrderRefundTx", mock.MatchedBy(func(r *model.OrderRefund) bool { return r.OrderID == 3 }))
+	repo.AssertNotCalled(t, "GetOrderPaymentIntents", 4)
+}
+
+// TestAutoRefundIncludesRefundRequestedOrders 测试退款被拒绝或申请退款中的已扣款订单在项目失败时同样退款，
+// 已有批准的退款申请的订单不会重复退款
+func TestAutoRefundIncludesRefundRequestedOrders(t *testing.T) {
+	util.Logger = zap.NewNop()
+	repo := new(MockPaymentRepository)
+	sandbox := gateway.NewSandboxGateway()
+	service := NewRefundService(repo, sandbox, newNopTxDB(t))
+
+	paidAt := time.Now().Add(-time.Hour)
+	newOrder := func(id int, status string) *model.Order {
+		return &model.Order{
+			ID: id, UserID: 7, ProjectID: 3, Status: status, PaidAt: &paidAt,
+			Amount: model.NewMoney(1000, "CNY"), Currency: "CNY",
+			OriginalAmount: model.NewMoney(1000, "CNY"), OriginalCurrency: "CNY",
+		}
+	}
+	repo.On("GetOrdersByProject", 3).Return([]*model.Order{
+		newOrder(1, OrderStatusRefundRejected),
+		newOrder(2, OrderStatusRefundPending),
+		newOrder(3, OrderStatusRefundPending),
+		newOrder(4, OrderStatusRefunded),
+	}, nil)
+
+	expectRefund := func(orderID, refundID int) {
+		intentID := capturedSandboxIntent(t, sandbox, model.NewMoney(1000, "CNY"))
+		repo.On("GetOrderPaymentIntents", orderID).Return([]model.OrderPaymentIntent{
+			{Provider: "sandbox", IntentID: intentID, Captured: model.NewMoney(1000, "CNY")},
+		}, nil)
+		repo.On("TransitionOrderStatusTx", orderID, OrderStatusCrowdfundingFailed, OrderStatusRefundPending).Return(true, nil)
+		repo.On("CreateOrderRefundTx", mock.MatchedBy(func(r *model.OrderRefund) bool { return r.OrderID == orderID })).Run(func(args mock.Arguments) {
+			args.Get(0).(*model.OrderRefund).ID = refundID
+		}).Return(nil)
+		repo.On("ClaimOrderRefund", refundID, 0).Return(true, nil)
+		repo.On("UpdateOrderRefund", mock.MatchedBy(func(r *model.OrderRefund) bool { return r.ID == refundID })).Return(nil)
+		repo.On("HasPendingOrderRefunds", orderID).Return(false, nil)
+		repo.On("GetOrderByID", orderID).Return(newOrder(orderID, OrderStatusRefundPending), nil)
+		repo.On("TransitionOrderStatus", orderID, OrderStatusRefundPending, OrderStatusRefunded).Return(true, nil)
+	}
+
+	// 订单1退款被拒绝，项目失败后创建新的退款申请
+	repo.On("GetRefundStatus", 1).Return(&model.RefundRequest{ID: 11, OrderID: 1, Status: "rejected"}, nil)
+	repo.On("TransitionOrderStatus", 1, OrderStatusRefundRejected, OrderStatusCrowdfundingFailed).Return(true, nil)
+	repo.On("CreateRefundRequestTx", mock.MatchedBy(func(r *model.RefundRequest) bool { return r.OrderID == 1 })).Return(nil)
+	expectRefund(1, 10)
+
+	// 订单2的退款申请待审批，直接批准该申请
+	repo.On("GetRefundStatus", 2).Return(&model.RefundRequest{ID: 12, OrderID: 2, Status: "pending"}, nil)
+	repo.On("TransitionOrderStatus", 2, OrderStatusRefundPending, OrderStatusCrowdfundingFailed).Return(true, nil)
+	var approved *model.RefundRequest
+	repo.On("UpdateRefundRequestTx", mock.MatchedBy(func(r *model.RefundRequest) bool { return r.ID == 12 })).Run(func(args mock.Arguments) {
+		approved = args.Get(0).(*model.RefundRequest)
+	}).Return(true, nil)
+	expectRefund(2, 20)
+
+	// 订单3已有批准的退款申请，由重试任务继续执行
+	repo.On("GetRefundStatus", 3).Return(&model.RefundRequest{ID: 13, OrderID: 3, Status: "approved"}, nil)
+
+	repo.On("GetOrderRefundsByProject", 3).Return([]*model.OrderRefund{}, nil)
+
+	report, err := service.AutoRefundForFailedProject(3)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Errors)
+	repo.AssertCalled(t, "TransitionOrderStatus", 1, OrderStatusRefundPending, OrderStatusRefunded)
+	repo.AssertCalled(t, "TransitionOrderStatus", 2, OrderStatusRefundPending, OrderStatusRefunded)
+	if assert.NotNil(t, approved) {
+		assert.Equal(t, "approved", approved.Status)
+	}
+	repo.AssertNotCalled(t, "CreateRefundRequestTx", mock.MatchedBy(func(r *model.RefundRequest) bool { return r.OrderID == 2 }))
+	repo.AssertNotCalled(t, "GetOrderPaymentIntents", 3)
+	repo.AssertNotCalled(t, "GetRefundStatus", 4)
+}
+
+// TestRetryFailedProjectRefunds 测试项目失败回调未能创建退款时，定时任务为仍未退款的订单补发起退款
+func TestRetryFailedProjectRefunds(t *testing.T) {
+	util.Logger = zap.NewNop()
+	repo := new(MockPaymentRepository)
+	sandbox := gateway.NewSandboxGateway()
+	service := NewRefundService(repo, sandbox, newNopTxDB(t))
+
+	paidAt := time.Now().Add(-time.Hour)
+	order := &model.Order{
+		ID: 1, UserID: 7, ProjectID: 3, Status: OrderStatusPaid, PaidAt: &paidAt,
+		Amount: model.NewMoney(1000, "CNY"), Currency: "CNY",
+		OriginalAmount: model.NewMoney(1000, "CNY"), OriginalCurrency: "CNY",
+	}
+	repo.On("GetFailedProjectsAwaitingRefund", refundRetryBatchSize).Return([]int{3}, nil)
+	repo.On("GetOrdersByProject", 3).Return([]*model.Order{order}, nil)
+	repo.On("GetRefundStatus", 1).Return(nil, nil)
+	repo.On("TransitionOrderStatus", 1, OrderStatusPaid, OrderStatusCrowdfundingFailed).Return(true, nil)
+	repo.On("GetOrderPaymentIntents", 1).Return([]model.OrderPaymentIntent{
+		{Provider: "sandbox", IntentID: capturedSandboxIntent(t, sandbox, model.NewMoney(1000, "CNY")), Captured: model.NewMoney(1000, "CNY")},
+	}, nil)
+	repo.On("CreateRefundRequestTx", mock.Anything).Return(nil)
+	repo.On("TransitionOrderStatusTx", 1, OrderStatusCrowdfundingFailed, OrderStatusRefundPending).Return(true, nil)
+	repo.On("CreateOrderRefundTx", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.OrderRefund).ID = 10
+	}).Return(nil)
+	repo.On("ClaimOrderRefund", 10, 0).Return(true, nil)
+	repo.On("UpdateOrderRefund", mock.Anything).Return(nil)
+	repo.On("HasPendingOrderRefunds", 1).Return(false, nil)
+	repo.On("GetOrderByID", 1).Return(&model.Order{ID: 1, Status: OrderStatusRefundPending}, nil)
+	repo.On("TransitionOrderStatus", 1, OrderStatusRefundPending, OrderStatusRefunded).Return(true, nil)
+	repo.On("GetOrderRefundsByProject", 3).Return([]*model.OrderRefund{}, nil)
+
+	assert.NoError(t, service.RetryFailedProjectRefunds())
+	repo.AssertCalled(t, "TransitionOrderStatus", 1, OrderStatusRefundPending, OrderStatusRefunded)
+}
+
+// TestProcessRefundApprovedRefundsThroughGateway 测试批准退款申请时扣减项目已筹金额并通过支付渠道原路退回，
+// 渠道确认退款后订单才变为已退款；渠道退款失败时订单保持退款中，等待重试
+func TestProcessRefundApprovedRefundsThroughGateway(t *testing.T) {
+	for _, captured := range []bool{true, false} {
+		util.Logger = zap.NewNop()
+		repo := new(MockPaymentRepository)
+		sandbox := gateway.NewSandboxGateway()
+		service := NewRefundService(repo, sandbox, newNopTxDB(t))
+
+		// 未在渠道扣款的支付意图无法退款
+		intentID := "sb_pi_missing"
+		if captured {
+			intentID = capturedSandboxIntent(t, sandbox, model.NewMoney(1000, "CNY"))
+		}
+		order := &model.Order{
+			ID: 1, UserID: 7, ProjectID: 3, Status: OrderStatusRefundPending,
+			Amount: model.NewMoney(1000, "CNY"), Currency: "CNY",
+			OriginalAmount: model.NewMoney(1000, "CNY"), OriginalCurrency: "CNY",
+		}
+		repo.On("GetRefundRequestByID", 5).Return(&model.RefundRequest{ID: 5, OrderID: 1, Status: "pending", Order: order}, nil)
+		repo.On("GetOrderPaymentIntents", 1).Return([]model.OrderPaymentIntent{
+			{Provider: "sandbox", IntentID: intentID, Captured: model.NewMoney(1000, "CNY")},
+		}, nil)
+		repo.On("CreateOrderRefundTx", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(0).(*model.OrderRefund).ID = 10
+		}).Return(nil)
+		repo.On("AddProjectAmountTx", 3, model.NewMoney(-1000, "CNY")).Return(nil)
+		repo.On("UpdateRefundRequestTx", mock.Anything).Return(true, nil)
+		repo.On("ClaimOrderRefund", 10, 0).Return(true, nil)
+		var executed *model.OrderRefund
+		repo.On("UpdateOrderRefund", mock.Anything).Run(func(args mock.Arguments) {
+			executed = args.Get(0).(*model.OrderRefund)
+		}).Return(nil)
+		repo.On("HasPendingOrderRefunds", 1).Return(false, nil)
+		repo.On("GetOrderByID", 1).Return(&model.Order{ID: 1, Status: OrderStatusRefundPending}, nil)
+		repo.On("TransitionOrderStatus", 1, OrderStatusRefundPending, OrderStatusRefunded).Return(true, nil)
+
+		err := service.ProcessRefund(5, true, "同意退款", model.AuditActor{UserID: 99})
+		assert.NoError(t, err)
+		repo.AssertCalled(t, "AddProjectAmountTx", 3, model.NewMoney(-1000, "CNY"))
+		repo.AssertNotCalled(t, "TransitionOrderStatusTx", 1, OrderStatusRefundPending, OrderStatusRefunded)
+		if captured {
+			assert.Equal(t, OrderRefundStatusSucceeded, executed.Status)
+			repo.AssertCalled(t, "TransitionOrderStatus", 1, OrderStatusRefundPending, OrderStatusRefunded)
+		} else {
+			assert.Equal(t, OrderRefundStatusPending, executed.Status)
+			assert.NotNil(t, executed.NextAttemptAt)
+			repo.AssertNotCalled(t, "TransitionOrderStatus", 1, OrderStatusRefundPending, OrderStatusRefunded)
+		}
+	}
+}
+
+// TestRequestRefundInOneTransaction 测试申请退款时订单流转和退款申请在同一事务中写入，申请写入失败时整体回滚
+func TestRequestRefundInOneTransaction(t *testing.T) {
+	util.Logger = zap.NewNop()
+	repo := new(MockPaymentRepository)
+	service := NewRefundService(repo, gateway.NewSandboxGateway(), newNopTxDB(t))
+
+	repo.On("GetOrderByID", 1).Return(&model.Order{ID: 1, UserID: 7, Status: OrderStatusPaid}, nil)
+	repo.On("GetOrderByID", 2).Return(&model.Order{ID: 2, UserID: 7, Status: OrderStatusPaid}, nil)
+	repo.On("GetRefundStatus", mock.Anything).Return(nil, nil)
+	repo.On("TransitionOrderStatusTx", mock.Anything, OrderStatusPaid, OrderStatusRefundPending).Return(true, nil)
+	repo.On("CreateRefundRequestTx", mock.MatchedBy(func(r *model.RefundRequest) bool { return r.OrderID == 1 })).Return(nil)
+	repo.On("CreateRefundRequestTx", mock.MatchedBy(func(r *model.RefundRequest) bool { return r.OrderID == 2 })).Return(errors.New("写入失败"))
+
+	assert.NoError(t, service.RequestRefund(1, 7, "不想要了"))
+	assert.Error(t, service.RequestRefund(2, 7, "不想要了"))
+	repo.AssertNotCalled(t, "TransitionOrderStatus", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertCalled(t, "CreateRefundRequestTx", mock.MatchedBy(func(r *model.RefundRequest) bool {
+		return r.OrderID == 1 && r.Status == "pending" && r.Reason == "不想要了"
+	}))
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+const rewardTierTitleMaxLength = 100
+
+var (
+	// ErrRewardTierNotFound 回报档位不存在或不属于该项目
+	ErrRewardTierNotFound = errors.New("回报档位不存在")
+	// ErrRewardTierSoldOut 限量档位已售罄
+	ErrRewardTierSoldOut = errors.New("回报档位已售罄")
+	// ErrRewardAddOnNotFound 加购项不存在或不属于该项目
+	ErrRewardAddOnNotFound = errors.New("加购项不存在")
+	// ErrRewardAddOnSoldOut 限量加购项剩余数量不足
+	ErrRewardAddOnSoldOut = errors.New("加购项库存不足")
+	// ErrInvalidRewardTier 档位信息不合法
+	ErrInvalidRewardTier = errors.New("回报档位信息不合法")
+	// ErrRewardTierInUse 档位已被订单占用，不能删除或修改价格
+	ErrRewardTierInUse = errors.New("回报档位已有支持者，不能删除或修改价格")
+	// ErrInvalidRewardAddOn 加购项信息不合法
+	ErrInvalidRewardAddOn = errors.New("加购项信息不合法")
+	// ErrRewardAddOnInUse 加购项已被订单占用，不能删除或修改价格
+	ErrRewardAddOnInUse = errors.New("加购项已有支持者购买，不能删除或修改价格")
+	// ErrRewardLimitBelowClaimed 限量不能低于已占用数量
+	ErrRewardLimitBelowClaimed = errors.New("限量不能低于已被支持的数量")
+	// ErrNotRewardOwner 只有项目创建者可以管理回报档位和加购项
+	ErrNotRewardOwner = errors.New("只有项目创建者可以管理回报档位和加购项")
+	// ErrRewardProjectClosed 项目已结束，不能再修改回报档位
+	ErrRewardProjectClosed = errors.New("项目已结束，无法修改回报档位")
+)
+
+// RewardService 管理项目回报档位和加购项
+type RewardService struct {
+	rewardRepo  interfaces.RewardRepository
+	projectRepo interfaces.ProjectRepository
+}
+
+func NewRewardService(rewardRepo interfaces.RewardRepository, projectRepo interfaces.ProjectRepository) *RewardService {
+	return &RewardService{
+		rewardRepo:  rewardRepo,
+		projectRepo: projectRepo,
+	}
+}
+
+// validateRewardTier 校验档位字段，价格按项目币种计
+func validateRewardTier(tier *model.RewardTier) error {
+	tier.Title = strings.TrimSpace(tier.Title)
+	if tier.Title == "" || utf8.RuneCountInString(tier.Title) > rewardTierTitleMaxLength {
+		return ErrInvalidRewardTier
+	}
+	if !tier.Price.IsPositive() {
+		return ErrInvalidRewardTier
+	}
+	if tier.QuantityLimit != nil && *tier.QuantityLimit <= 0 {
+		return ErrInvalidRewardTier
+	}
+	if _, err := time.Parse(model.RewardTierDeliveryLayout, tier.EstimatedDelivery); err != nil {
+		return ErrInvalidRewardTier
+	}
+	return nil
+}
+
+// validateRewardAddOn 校验加购项字段，价格按项目币种计
+func validateRewardAddOn(addOn *model.RewardAddOn) error {
+	addOn.Title = strings.TrimSpace(addOn.Title)
+	if addOn.Title == "" || utf8.RuneCountInString(addOn.Title) > rewardTierTitleMaxLength {
+		return ErrInvalidRewardAddOn
+	}
+	if !addOn.Price.IsPositive() {
+		return ErrInvalidRewardAddOn
+	}
+	if addOn.QuantityLimit != nil && *addOn.QuantityLimit <= 0 {
+		return ErrInvalidRewardAddOn
+	}
+	return nil
+}
+
+// getOwnedProject 获取项目并校验当前用户为项目创建者且项目未结束
+func (s *RewardService) getOwnedProject(projectID, userID int) (*model.Project, error) {
+	project, err := s.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.CreatorID != userID {
+		return nil, ErrNotRewardOwner
+	}
+	if project.Status == ProjectStatusCompleted || project.Status == ProjectStatusFailed {
+		return nil, ErrRewardProjectClosed
+	}
+	return project, nil
+}
+
+// ListRewardTiers 获取项目的回报档位
+func (s *RewardService) ListRewardTiers(projectID int) ([]*model.RewardTier, error) {
+	project, err := s.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	return s.rewardRepo.ListRewardTiers(projectID)
+}
+
+// CreateRewardTier 项目创建者新增回报档位
+func (s *RewardService) CreateRewardTier(userID int, tier *model.RewardTier) error {
+	project, err := s.getOwnedProject(tier.ProjectID, userID)
+	if err != nil {
+		return err
+	}
+	tier.Price.Currency = project.Currency
+	if err := validateRewardTier(tier); err != nil {
+		return err
+	}
+	tier.QuantityClaimed = 0
+	if err := s.rewardRepo.CreateRewardTier(tier); err != nil {
+		return err
+	}
+
+	util.Logger.Info("回报档位创建成功",
+		zap.Int("project_id", tier.ProjectID),
+		zap.Int("reward_tier_id", tier.ID))
+	return nil
+}
+
+// UpdateRewardTier 项目创建者修改回报档位，已有支持者时不能修改价格，限量不能低于已占用数量
+func (s *RewardService) UpdateRewardTier(userID int, tier *model.RewardTier) (*model.RewardTier, error) {
+	existing, err := s.rewardRepo.GetRewardTier(tier.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil || existing.ProjectID != tier.ProjectID {
+		return nil, ErrRewardTierNotFound
+	}
+	project, err := s.getOwnedProject(tier.ProjectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	tier.Price.Currency = project.Currency
+	if err := validateRewardTier(tier); err != nil {
+		return nil, err
+	}
+	if existing.QuantityClaimed > 0 && tier.Price.Cmp(existing.Price) != 0 {
+		return nil, ErrRewardTierInUse
+	}
+	if tier.QuantityLimit != nil && *tier.QuantityLimit < existing.QuantityClaimed {
+		return nil, ErrRewardLimitBelowClaimed
+	}
+
+	updated, err := s.rewardRepo.UpdateRewardTier(tier)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		// 校验之后又有订单占用了档位
+		return nil, ErrRewardTierInUse
+	}
+	return s.rewardRepo.GetRewardTier(tier.ID)
+}
+
+// DeleteRewardTier 项目创建者删除回报档位，已被订单引用过的档位不能删除
+func (s *RewardService) DeleteRewardTier(userID, projectID, tierID int) error {
+	tier, err := s.rewardRepo.GetRewardTier(tierID)
+	if err != nil {
+		return err
+	}
+	if tier == nil || tier.ProjectID != projectID {
+		return ErrRewardTierNotFound
+	}
+	if _, err := s.getOwnedProject(projectID, userID); err != nil {
+		return err
+	}
+
+	deleted, err := s.rewardRepo.DeleteRewardTier(tierID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrRewardTierInUse
+	}
+
+	util.Logger.Info("回报档位已删除",
+		zap.Int("project_id", projectID),
+		zap.Int("reward_tier_id", tierID))
+	return nil
+}
+
+// ListRewardAddOns 获取项目的加购项
+func (s *RewardService) ListRewardAddOns(projectID int) ([]*model.RewardAddOn, error) {
+	project, err := s.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	return s.rewardRepo.ListRewardAddOns(projectID)
+}
+
+// CreateRewardAddOn 项目创建者新增加购项
+func (s *RewardService) CreateRewardAddOn(userID int, addOn *model.RewardAddOn) error {
+	project, err := s.getOwnedProject(addOn.ProjectID, userID)
+	if err != nil {
+		return err
+	}
+	addOn.Price.Currency = project.Currency
+	if err := validateRewardAddOn(addOn); err != nil {
+		return err
+	}
+	addOn.QuantityClaimed = 0
+	if err := s.rewardRepo.CreateRewardAddOn(addOn); err != nil {
+		return err
+	}
+
+	util.Logger.Info("加购项创建成功",
+		zap.Int("project_id", addOn.ProjectID),
+		zap.Int("add_on_id", addOn.ID))
+	return nil
+}
+
+// UpdateRewardAddOn 项目创建者修改加购项，规则与回报档位相同
+func (s *RewardService) UpdateRewardAddOn(userID int, addOn *model.RewardAddOn) (*model.RewardAddOn, error) {
+	existing, err := s.rewardRepo.GetRewardAddOn(addOn.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil || existing.ProjectID != addOn.ProjectID {
+		return nil, ErrRewardAddOnNotFound
+	}
+	project, err := s.getOwnedProject(addOn.ProjectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	addOn.Price.Currency = project.Currency
+	if err := validateRewardAddOn(addOn); err != nil {
+		return nil, err
+	}
+	if existing.QuantityClaimed > 0 && addOn.Price.Cmp(existing.Price) != 0 {
+		return nil, ErrRewardAddOnInUse
+	}
+	if addOn.QuantityLimit != nil && *addOn.QuantityLimit < existing.QuantityClaimed {
+		return nil, ErrRewardLimitBelowClaimed
+	}
+
+	updated, err := s.rewardRepo.UpdateRewardAddOn(addOn)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrRewardAddOnInUse
+	}
+	return s.rewardRepo.GetRewardAddOn(addOn.ID)
+}
+
+// DeleteRewardAddOn 项目创建者删除加购项，已被订单购买过的加购项不能删除
+func (s *RewardService) DeleteRewardAddOn(userID, projectID, addOnID int) error {
+	addOn, err := s.rewardRepo.GetRewardAddOn(addOnID)
+	if err != nil {
+		return err
+	}
+	if addOn == nil || addOn.ProjectID != projectID {
+		return ErrRewardAddOnNotFound
+	}
+	if _, err := s.getOwnedProject(projectID, userID); err != nil {
+		return err
+	}
+
+	deleted, err := s.rewardRepo.DeleteRewardAddOn(addOnID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrRewardAddOnInUse
+	}
+
+	util.Logger.Info("加购项已删除",
+		zap.Int("project_id", projectID),
+		zap.Int("add_on_id", addOnID))
+	return nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/gateway"
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// MockRewardRepository 模拟 RewardRepository 中测试用到的方法，其余方法未实现
+type MockRewardRepository struct {
+	interfaces.RewardRepository
+	mock.Mock
+}
+
+func (m *MockRewardRepository) GetRewardTier(id int) (*model.RewardTier, error) {
+	args := m.Called(id)
+	tier, _ := args.Get(0).(*model.RewardTier)
+	return tier, args.Error(1)
+}
+
+func (m *MockRewardRepository) ClaimRewardTierTx(tx *sql.Tx, tierID, projectID int) (bool, error) {
+	args := m.Called(tierID, projectID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRewardRepository) ClaimRewardAddOnTx(tx *sql.Tx, addOnID, projectID, quantity int) (bool, error) {
+	args := m.Called(addOnID, projectID, quantity)
+	return args.Bool(0), args.Error(1)
+}
+
+func newPaymentTestService(t *testing.T, paymentRepo *MockPaymentRepository, projectRepo *MockProjectRepository, rewardRepo *MockRewardRepository, g gateway.PaymentGateway) *PaymentService {
+	util.Logger = zap.NewNop()
+	return NewPaymentService(paymentRepo, new(MockUserRepository), projectRepo, rewardRepo, g,
+		NewExchangeRateService(nil), NewShippingService(nil, rewardRepo, projectRepo), newNopTxDB(t))
+}
+
+func TestValidateRewardTier(t *testing.T) {
+	limit := func(n int) *int { return &n }
+	tests := []struct {
+		name  string
+		tier  model.RewardTier
+		valid bool
+	}{
+		{"不限量档位", model.RewardTier{Title: "早鸟", Price: model.NewMoney(9900, "CNY"), EstimatedDelivery: "2025-03"}, true},
+		{"限量档位", model.RewardTier{Title: "限定版", Price: model.NewMoney(19900, "CNY"), QuantityLimit: limit(100), EstimatedDelivery: "2025-12"}, true},
+		{"标题为空", model.RewardTier{Title: "  ", Price: model.NewMoney(9900, "CNY"), EstimatedDelivery: "2025-03"}, false},
+		{"标题过长", model.RewardTier{Title: strings.Repeat("档", 101), Price: model.NewMoney(9900, "CNY"), EstimatedDelivery: "2025-03"}, false},
+		{"价格为0", model.RewardTier{Title: "早鸟", Price: model.NewMoney(0, "CNY"), EstimatedDelivery: "2025-03"}, false},
+		{"限量为0", model.RewardTier{Title: "早鸟", Price: model.NewMoney(9900, "CNY"), QuantityLimit: limit(0), EstimatedDelivery: "2025-03"}, false},
+		{"发货月份格式错误", model.RewardTier{Title: "早鸟", Price: model.NewMoney(9900, "CNY"), EstimatedDelivery: "2025-3"}, false},
+		{"发货月份不合法", model.RewardTier{Title: "早鸟", Price: model.NewMoney(9900, "CNY"), EstimatedDelivery: "2025-13"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRewardTier(&tt.tier)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidRewardTier)
+			}
+		})
+	}
+}
+
+func TestRewardTierRemaining(t *testing.T) {
+	limit := 2
+	tier := &model.RewardTier{QuantityLimit: &limit, QuantityClaimed: 1}
+	tier.ApplyRemaining()
+	assert.Equal(t, 1, *tier.Remaining)
+	assert.False(t, tier.SoldOut())
+
+	tier.QuantityClaimed = 2
+	tier.ApplyRemaining()
+	assert.Equal(t, 0, *tier.Remaining)
+	assert.True(t, tier.SoldOut())
+
+	unlimited := &model.RewardTier{QuantityClaimed: 500}
+	unlimited.ApplyRemaining()
+	assert.Nil(t, unlimited.Remaining)
+	assert.False(t, unlimited.SoldOut())
+}
+
+// TestProcessPaymentRejectsSoldOutTier 测试限量档位占用失败时拒绝支付，不创建订单也不扣款
+func TestProcessPaymentRejectsSoldOutTier(t *testing.T) {
+	paymentRepo := new(MockPaymentRepository)
+	projectRepo := new(MockProjectRepository)
+	rewardRepo := new(MockRewardRepository)
+	sandbox := gateway.NewSandboxGateway()
+	service := newPaymentTestService(t, paymentRepo, projectRepo, rewardRepo, sandbox)
+
+	limit := 1
+	tierID := 5
+	projectRepo.On("GetProjectByID", 1).Return(&model.Project{ID: 1, Currency: "CNY", Status: ProjectStatusActive}, nil)
+	paymentRepo.On("CheckProjectEndDate", 1).Return(false, nil)
+	rewardRepo.On("GetRewardTier", tierID).Return(&model.RewardTier{
+		ID: tierID, ProjectID: 1, Title: "限定版", Price: model.NewMoney(9900, "CNY"),
+		QuantityLimit: &limit, QuantityClaimed: 1,
+	}, nil)
+	rewardRepo.On("ClaimRewardTierTx", tierID, 1).Return(false, nil)
+
+	order, err := service.ProcessPayment(&model.Payment{UserID: 2, ProjectID: 1, RewardTierID: &tierID}, 0)
+	assert.ErrorIs(t, err, ErrRewardTierSoldOut)
+	assert.Nil(t, order)
+	rewardRepo.AssertExpectations(t)
+	paymentRepo.AssertNotCalled(t, "UpdateOrderPaymentIntent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestProcessPaymentRejectsProjectNotLive 测试项目不在众筹中或已截止时拒绝支持，不占用库存也不扣款
+func TestProcessPaymentRejectsProjectNotLive(t *testing.T) {
+	for _, status := range []string{ProjectStatusDraft, ProjectStatusPendingReview, ProjectStatusCompleted, ProjectStatusFailed, ProjectStatusSuspended} {
+		paymentRepo := new(MockPaymentRepository)
+		projectRepo := new(MockProjectRepository)
+		rewardRepo := new(MockRewardRepository)
+		service := newPaymentTestService(t, paymentRepo, projectRepo, rewardRepo, gateway.NewSandboxGateway())
+
+		projectRepo.On("GetProjectByID", 1).Return(&model.Project{ID: 1, Currency: "CNY", Status: status}, nil)
+
+		order, err := service.ProcessPayment(&model.Payment{UserID: 2, ProjectID: 1, BonusAmount: model.NewMoney(1000, "CNY")}, 0)
+		assert.ErrorIs(t, err, ErrProjectNotLive, status)
+		assert.Nil(t, order)
+		rewardRepo.AssertNotCalled(t, "ClaimRewardTierTx", mock.Anything, mock.Anything)
+	}
+
+	// 众筹中但已过截止时间
+	paymentRepo := new(MockPaymentRepository)
+	projectRepo := new(MockProjectRepository)
+	service := newPaymentTestService(t, paymentRepo, projectRepo, new(MockRewardRepository), gateway.NewSandboxGateway())
+	projectRepo.On("GetProjectByID", 1).Return(&model.Project{ID: 1, Currency: "CNY", Status: ProjectStatusActive}, nil)
+	paymentRepo.On("CheckProjectEndDate", 1).Return(true, nil)
+
+	_, err := service.ProcessPayment(&model.Payment{UserID: 2, ProjectID: 1, BonusAmount: model.NewMoney(1000, "CNY")}, 0)
+	assert.ErrorIs(t, err, ErrProjectNotLive)
+}
+
+// TestChargeOrderDeclineReleasesStock 测试扣款被拒绝时订单置为支付失败，由仓储层在同一事务中释放库存
+func TestChargeOrderDeclineReleasesStock(t *testing.T) {
+	paymentRepo := new(MockPaymentRepository)
+	sandbox := gateway.NewSandboxGateway()
+	sandbox.SetDeclineCapture(true)
+	service := newPaymentTestService(t, paymentRepo, new(MockProjectRepository), new(MockRewardRepository), sandbox)
+
+	paymentRepo.On("UpdateOrderPaymentIntent", 7, "sandbox", mock.Anything).Return(nil)
+	paymentRepo.On("MarkOrderPaymentFailed", 7, ActorSystem).Return(true, nil)
+
+	order := &model.Order{ID: 7, ProjectID: 1, OrderNumber: "ORD-2025-0007", Status: "pending", OriginalAmount: model.NewMoney(9900, "CNY")}
+	err := service.chargeOrder(order)
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+	assert.Equal(t, "failed", order.Status)
+	paymentRepo.AssertExpectations(t)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// SettlementService 负责众筹到期后的项目结算
+type SettlementService struct {
+	projectRepo    interfaces.ProjectRepository
+	paymentRepo    interfaces.PaymentRepository
+	projectService *ProjectService
+	payoutService  *PayoutService
+	orderStates    *OrderStateMachine
+}
+
+func NewSettlementService(projectRepo interfaces.ProjectRepository, paymentRepo interfaces.PaymentRepository, projectService *ProjectService, payoutService *PayoutService) *SettlementService {
+	return &SettlementService{
+		projectRepo:    projectRepo,
+		paymentRepo:    paymentRepo,
+		projectService: projectService,
+		payoutService:  payoutService,
+		orderStates:    NewOrderStateMachine(paymentRepo),
+	}
+}
+
+// ProcessExpiredProjects 处理所有到期的众筹项目：达成第一个目标的项目标记为完成，否则标记为失败。
+// 结算在项目完成的回调中执行，回调失败时项目已保存为完成，由此重试尚未生成结算记录的项目
+func (s *SettlementService) ProcessExpiredProjects() error {
+	projects, err := s.projectRepo.GetExpiredActiveProjects()
+	if err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		reached, err := s.paymentRepo.CheckProjectGoalStatus(project.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// 项目未设置目标，视为达成
+			reached, err = true, nil
+		}
+		if err != nil {
+			util.Logger.Error("检查项目目标达成状态失败", zap.Error(err), zap.Int("project_id", project.ID))
+			continue
+		}
+
+		if reached {
+			_, err = s.projectService.TransitionStatus(project.ID, ProjectStatusCompleted, ActorSystem, "项目到期已达成目标", nil)
+		} else {
+			_, err = s.projectService.TransitionStatus(project.ID, ProjectStatusFailed, ActorSystem, "项目到期未达成目标", nil)
+		}
+		if err != nil {
+			util.Logger.Error("更新到期项目状态失败", zap.Error(err), zap.Int("project_id", project.ID))
+		}
+	}
+
+	unsettled, err := s.projectRepo.GetUnsettledCompletedProjects()
+	if err != nil {
+		return err
+	}
+	for _, project := range unsettled {
+		if _, err := s.SettleProject(project); err != nil {
+			util.Logger.Error("重试项目结算失败", zap.Error(err), zap.Int("project_id", project.ID))
+		}
+	}
+
+	return nil
+}
+
+// OnProjectTransition 项目状态流转回调，项目完成时执行结算
+func (s *SettlementService) OnProjectTransition(event *ProjectTransition) error {
+	if event.To != ProjectStatusCompleted {
+		return nil
+	}
+	_, err := s.SettleProject(event.Project)
+	return err
+}
+
+// SettleProject 结算众筹成功的项目：已支付订单转为待发货，未支付订单作废，更新目标达成状态并生成创作者结算记录。
+// 重复执行是安全的，已处理过的订单和已存在的结算记录会被跳过。单个订单处理失败不影响其他订单，
+// 但不会生成结算记录，由结算任务重试
+func (s *SettlementService) SettleProject(project *model.Project) (*model.ProjectPayout, error) {
+	orders, err := s.paymentRepo.GetOrdersByProject(project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, order := range orders {
+		switch order.Status {
+		case OrderStatusPaid:
+			err = s.orderStates.Transition(order, OrderStatusReadyToShip, ActorSystem, "项目众筹成功，等待发货")
+		case OrderStatusPending:
+			err = s.orderStates.Transition(order, OrderStatusFailed, ActorSystem, "众筹结束时订单仍未支付")
+		default:
+			continue
+		}
+		if err != nil {
+			util.Logger.Error("结算订单失败",
+				zap.Error(err),
+				zap.Int("project_id", project.ID),
+				zap.Int("order_id", order.ID))
+			errs = append(errs, fmt.Errorf("结算订单 %d 失败: %w", order.ID, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	reachedGoals, err := s.projectRepo.MarkReachedGoals(project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	payout, err := s.payoutService.CreateProjectPayout(project)
+	if err != nil {
+		return nil, err
+	}
+
+	util.Logger.Info("项目结算完成",
+		zap.Int("project_id", project.ID),
+		zap.Int("reached_goals", reachedGoals),
+		zap.Int("payout_id", payout.ID))
+	return payout, nil
+}
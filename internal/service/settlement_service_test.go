@@ -0,0 +1,132 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func (m *MockProjectRepository) GetExpiredActiveProjects() ([]*model.Project, error) {
+	args := m.Called()
+	return args.Get(0).([]*model.Project), args.Error(1)
+}
+
+func (m *MockProjectRepository) GetUnsettledCompletedProjects() ([]*model.Project, error) {
+	args := m.Called()
+	return args.Get(0).([]*model.Project), args.Error(1)
+}
+
+func (m *MockProjectRepository) MarkReachedGoals(projectID int) (int, error) {
+	args := m.Called(projectID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetProjectPayout(projectID int) (*model.ProjectPayout, error) {
+	args := m.Called(projectID)
+	payout, _ := args.Get(0).(*model.ProjectPayout)
+	return payout, args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetProjectPayoutAmounts(projectID int) (gross, refunded model.Money, err error) {
+	args := m.Called(projectID)
+	return args.Get(0).(model.Money), args.Get(1).(model.Money), args.Error(2)
+}
+
+func (m *MockPaymentRepository) CreateProjectPayout(payout *model.ProjectPayout) (bool, error) {
+	args := m.Called(payout)
+	return args.Bool(0), args.Error(1)
+}
+
+func newSettlementTestService(projectRepo *MockProjectRepository, paymentRepo *MockPaymentRepository) *SettlementService {
+	util.Logger = zap.NewNop()
+	payoutService := NewPayoutService(paymentRepo, projectRepo, 5)
+	return NewSettlementService(projectRepo, paymentRepo, NewProjectService(projectRepo, nil), payoutService)
+}
+
+// expectPayoutCreated 模拟项目尚无结算记录，创建时分配结算ID
+func expectPayoutCreated(paymentRepo *MockPaymentRepository, projectID, payoutID int) {
+	paymentRepo.On("GetProjectPayout", projectID).Return(nil, nil)
+	paymentRepo.On("GetProjectPayoutAmounts", projectID).Return(model.NewMoney(10000, "CNY"), model.NewMoney(0, "CNY"), nil)
+	paymentRepo.On("CreateProjectPayout", mock.MatchedBy(func(p *model.ProjectPayout) bool { return p.ProjectID == projectID })).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.ProjectPayout).ID = payoutID
+	}).Return(true, nil)
+}
+
+// TestSettleProject 测试结算时已支付订单转为待发货，未支付订单作废，并生成创作者结算记录
+func TestSettleProject(t *testing.T) {
+	projectRepo := new(MockProjectRepository)
+	paymentRepo := new(MockPaymentRepository)
+	service := newSettlementTestService(projectRepo, paymentRepo)
+
+	project := &model.Project{ID: 5, CreatorID: 9, Currency: "CNY", Status: ProjectStatusCompleted}
+	paymentRepo.On("GetOrdersByProject", 5).Return([]*model.Order{
+		{ID: 1, ProjectID: 5, Status: OrderStatusPaid},
+		{ID: 2, ProjectID: 5, Status: OrderStatusPending},
+		{ID: 3, ProjectID: 5, Status: OrderStatusRefunded},
+	}, nil)
+	paymentRepo.On("TransitionOrderStatus", 1, OrderStatusPaid, OrderStatusReadyToShip).Return(true, nil)
+	paymentRepo.On("TransitionOrderStatus", 2, OrderStatusPending, OrderStatusFailed).Return(true, nil)
+	projectRepo.On("MarkReachedGoals", 5).Return(1, nil)
+	expectPayoutCreated(paymentRepo, 5, 50)
+
+	payout, err := service.SettleProject(project)
+	assert.NoError(t, err)
+	if assert.NotNil(t, payout) {
+		assert.Equal(t, 50, payout.ID)
+		assert.Equal(t, 9, payout.CreatorID)
+		assert.Equal(t, model.NewMoney(9500, "CNY"), payout.NetAmount)
+	}
+	paymentRepo.AssertNumberOfCalls(t, "TransitionOrderStatus", 2)
+}
+
+// TestSettleProjectPartialFailure 测试单个订单结算失败时继续处理其余订单，但不生成结算记录
+func TestSettleProjectPartialFailure(t *testing.T) {
+	projectRepo := new(MockProjectRepository)
+	paymentRepo := new(MockPaymentRepository)
+	service := newSettlementTestService(projectRepo, paymentRepo)
+
+	project := &model.Project{ID: 5, CreatorID: 9, Currency: "CNY", Status: ProjectStatusCompleted}
+	paymentRepo.On("GetOrdersByProject", 5).Return([]*model.Order{
+		{ID: 1, ProjectID: 5, Status: OrderStatusPaid},
+		{ID: 2, ProjectID: 5, Status: OrderStatusPaid},
+	}, nil)
+	paymentRepo.On("TransitionOrderStatus", 1, OrderStatusPaid, OrderStatusReadyToShip).Return(false, errors.New("数据库不可用"))
+	paymentRepo.On("TransitionOrderStatus", 2, OrderStatusPaid, OrderStatusReadyToShip).Return(true, nil)
+
+	payout, err := service.SettleProject(project)
+	assert.Error(t, err)
+	assert.Nil(t, payout)
+	paymentRepo.AssertCalled(t, "TransitionOrderStatus", 2, OrderStatusPaid, OrderStatusReadyToShip)
+	projectRepo.AssertNotCalled(t, "MarkReachedGoals", 5)
+	paymentRepo.AssertNotCalled(t, "CreateProjectPayout", mock.Anything)
+}
+
+// TestProcessExpiredProjectsRetriesSettlement 测试结算任务重试已完成但尚未生成结算记录的项目，
+// 已处理过的订单不会重复流转
+func TestProcessExpiredProjectsRetriesSettlement(t *testing.T) {
+	projectRepo := new(MockProjectRepository)
+	paymentRepo := new(MockPaymentRepository)
+	service := newSettlementTestService(projectRepo, paymentRepo)
+
+	project := &model.Project{ID: 5, CreatorID: 9, Currency: "CNY", Status: ProjectStatusCompleted}
+	projectRepo.On("GetExpiredActiveProjects").Return([]*model.Project{}, nil)
+	projectRepo.On("GetUnsettledCompletedProjects").Return([]*model.Project{project}, nil)
+
+	// 上次结算时订单1已流转，订单2失败后仍为已支付
+	paymentRepo.On("GetOrdersByProject", 5).Return([]*model.Order{
+		{ID: 1, ProjectID: 5, Status: OrderStatusReadyToShip},
+		{ID: 2, ProjectID: 5, Status: OrderStatusPaid},
+	}, nil)
+	paymentRepo.On("TransitionOrderStatus", 2, OrderStatusPaid, OrderStatusReadyToShip).Return(true, nil)
+	projectRepo.On("MarkReachedGoals", 5).Return(1, nil)
+	expectPayoutCreated(paymentRepo, 5, 50)
+
+	assert.NoError(t, service.ProcessExpiredProjects())
+	paymentRepo.AssertNumberOfCalls(t, "TransitionOrderStatus", 1)
+	paymentRepo.AssertCalled(t, "CreateProjectPayout", mock.Anything)
+}
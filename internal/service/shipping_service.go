@@ -0,0 +1,274 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/repository/interfaces"
+	"crowdfunding-backend/internal/util"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+const shippingZoneNameMaxLength = 50
+
+var (
+	// ErrShippingZoneNotFound 配送区域不存在或不属于该项目
+	ErrShippingZoneNotFound = errors.New("配送区域不存在")
+	// ErrInvalidShippingZone 配送区域信息不合法
+	ErrInvalidShippingZone = errors.New("配送区域信息不合法")
+	// ErrShippingRegionConflict 同一地区只能属于项目的一个配送区域
+	ErrShippingRegionConflict = errors.New("地区已属于该项目的其他配送区域")
+	// ErrNotShippingOwner 只有项目创建者可以管理配送区域
+	ErrNotShippingOwner = errors.New("只有项目创建者可以管理配送区域")
+	// ErrShippingAddressRequired 所选回报需要发货，必须提供收货地址
+	ErrShippingAddressRequired = errors.New("所选回报需要发货，请选择收货地址")
+	// ErrAddressNotShippable 收货地址不在所选回报的配送范围内
+	ErrAddressNotShippable = errors.New("收货地址不在该回报的配送范围内")
+)
+
+// ShippingService 管理项目配送区域并计算订单运费
+type ShippingService struct {
+	shippingRepo interfaces.ShippingRepository
+	rewardRepo   interfaces.RewardRepository
+	projectRepo  interfaces.ProjectRepository
+}
+
+func NewShippingService(shippingRepo interfaces.ShippingRepository, rewardRepo interfaces.RewardRepository, projectRepo interfaces.ProjectRepository) *ShippingService {
+	return &ShippingService{
+		shippingRepo: shippingRepo,
+		rewardRepo:   rewardRepo,
+		projectRepo:  projectRepo,
+	}
+}
+
+// normalizeShippingZone 校验配送区域字段并统一地区格式，运费按项目币种计
+func normalizeShippingZone(zone *model.ShippingZone, currency string) error {
+	zone.Name = strings.TrimSpace(zone.Name)
+	if zone.Name == "" || utf8.RuneCountInString(zone.Name) > shippingZoneNameMaxLength {
+		return ErrInvalidShippingZone
+	}
+	if len(zone.Regions) == 0 {
+		return ErrInvalidShippingZone
+	}
+
+	seenRegions := make(map[model.ShippingRegion]bool, len(zone.Regions))
+	for i := range zone.Regions {
+		region := &zone.Regions[i]
+		region.Country = strings.ToUpper(strings.TrimSpace(region.Country))
+		region.Province = strings.TrimSpace(region.Province)
+		if !model.IsValidCountryCode(region.Country) {
+			return ErrInvalidShippingZone
+		}
+		if seenRegions[*region] {
+			return ErrInvalidShippingZone
+		}
+		seenRegions[*region] = true
+	}
+
+	seenTiers := make(map[int]bool, len(zone.Rates))
+	for i := range zone.Rates {
+		rate := &zone.Rates[i]
+		rate.Fee.Currency = currency
+		if rate.RewardTierID <= 0 || rate.Fee.IsNegative() || seenTiers[rate.RewardTierID] {
+			return ErrInvalidShippingZone
+		}
+		seenTiers[rate.RewardTierID] = true
+	}
+	return nil
+}
+
+// matchShippingZone 查找地址所在且设置了档位运费的配送区域，省份匹配优先于整个国家；
+// 省份所在区域未设置该档位运费时按整个国家的区域计算。省份名称去除首尾空白后比较，不区分大小写
+func matchShippingZone(zones []*model.ShippingZone, address *model.UserAddress, tierID int) *model.ShippingZone {
+	country := strings.ToUpper(strings.TrimSpace(address.Country))
+	if country == "" {
+		country = model.DefaultCountry
+	}
+	province := strings.TrimSpace(address.Province)
+
+	var countryMatch *model.ShippingZone
+	for _, zone := range zones {
+		if _, ok := zone.FeeFor(tierID); !ok {
+			continue
+		}
+		for _, region := range zone.Regions {
+			if region.Country != country {
+				continue
+			}
+			if region.Province == "" {
+				if countryMatch == nil {
+					countryMatch = zone
+				}
+			} else if strings.EqualFold(region.Province, province) {
+				return zone
+			}
+		}
+	}
+	return countryMatch
+}
+
+// getOwnedProject 获取项目并校验当前用户为项目创建者且项目未结束
+func (s *ShippingService) getOwnedProject(projectID, userID int) (*model.Project, error) {
+	project, err := s.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.CreatorID != userID {
+		return nil, ErrNotShippingOwner
+	}
+	if project.Status == ProjectStatusCompleted || project.Status == ProjectStatusFailed {
+		return nil, ErrRewardProjectClosed
+	}
+	return project, nil
+}
+
+// validateZone 校验区域内容，并确认运费档位属于该项目、地区未被项目的其他区域使用
+func (s *ShippingService) validateZone(project *model.Project, zone *model.ShippingZone) error {
+	if err := normalizeShippingZone(zone, project.Currency); err != nil {
+		return err
+	}
+	for _, rate := range zone.Rates {
+		tier, err := s.rewardRepo.GetRewardTier(rate.RewardTierID)
+		if err != nil {
+			return err
+		}
+		if tier == nil || tier.ProjectID != project.ID {
+			return ErrRewardTierNotFound
+		}
+	}
+
+	zones, err := s.shippingRepo.ListShippingZones(project.ID)
+	if err != nil {
+		return err
+	}
+	for _, other := range zones {
+		if other.ID == zone.ID {
+			continue
+		}
+		for _, used := range other.Regions {
+			for _, region := range zone.Regions {
+				if used == region {
+					return ErrShippingRegionConflict
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ListShippingZones 获取项目的配送区域
+func (s *ShippingService) ListShippingZones(projectID int) ([]*model.ShippingZone, error) {
+	project, err := s.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	return s.shippingRepo.ListShippingZones(projectID)
+}
+
+// CreateShippingZone 项目创建者新增配送区域
+func (s *ShippingService) CreateShippingZone(userID int, zone *model.ShippingZone) error {
+	project, err := s.getOwnedProject(zone.ProjectID, userID)
+	if err != nil {
+		return err
+	}
+	zone.ID = 0
+	if err := s.validateZone(project, zone); err != nil {
+		return err
+	}
+
+	created, err := s.shippingRepo.CreateShippingZone(zone)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return ErrShippingRegionConflict
+	}
+
+	util.Logger.Info("配送区域创建成功",
+		zap.Int("project_id", zone.ProjectID),
+		zap.Int("zone_id", zone.ID))
+	return nil
+}
+
+// UpdateShippingZone 项目创建者修改配送区域，地区和运费整体替换
+func (s *ShippingService) UpdateShippingZone(userID int, zone *model.ShippingZone) (*model.ShippingZone, error) {
+	existing, err := s.shippingRepo.GetShippingZone(zone.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil || existing.ProjectID != zone.ProjectID {
+		return nil, ErrShippingZoneNotFound
+	}
+	project, err := s.getOwnedProject(zone.ProjectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateZone(project, zone); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.shippingRepo.UpdateShippingZone(zone)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrShippingRegionConflict
+	}
+	return s.shippingRepo.GetShippingZone(zone.ID)
+}
+
+// DeleteShippingZone 项目创建者删除配送区域，已下单的订单运费不受影响
+func (s *ShippingService) DeleteShippingZone(userID, projectID, zoneID int) error {
+	zone, err := s.shippingRepo.GetShippingZone(zoneID)
+	if err != nil {
+		return err
+	}
+	if zone == nil || zone.ProjectID != projectID {
+		return ErrShippingZoneNotFound
+	}
+	if _, err := s.getOwnedProject(projectID, userID); err != nil {
+		return err
+	}
+
+	if err := s.shippingRepo.DeleteShippingZone(zoneID); err != nil {
+		return err
+	}
+
+	util.Logger.Info("配送区域已删除",
+		zap.Int("project_id", projectID),
+		zap.Int("zone_id", zoneID))
+	return nil
+}
+
+// QuoteShipping 计算档位寄送到地址的运费（项目币种）。
+// 无需发货的档位运费为0；需要发货时地址必须落在设置了该档位运费的配送区域内
+func (s *ShippingService) QuoteShipping(project *model.Project, tier *model.RewardTier, address *model.UserAddress) (model.Money, error) {
+	free := model.NewMoney(0, project.Currency)
+	if tier == nil || !tier.ShippingRequired {
+		return free, nil
+	}
+	if address == nil {
+		return model.Money{}, ErrShippingAddressRequired
+	}
+
+	zones, err := s.shippingRepo.ListShippingZones(project.ID)
+	if err != nil {
+		util.Logger.Error("获取配送区域失败", zap.Error(err), zap.Int("project_id", project.ID))
+		return model.Money{}, err
+	}
+	zone := matchShippingZone(zones, address, tier.ID)
+	if zone == nil {
+		return model.Money{}, ErrAddressNotShippable
+	}
+	fee, _ := zone.FeeFor(tier.ID)
+	fee.Currency = project.Currency
+	return fee, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchShippingZone(t *testing.T) {
+	rates := []model.ShippingRate{{RewardTierID: 1, Fee: model.NewMoney(1000, "CNY")}}
+	mainland := &model.ShippingZone{ID: 1, Regions: []model.ShippingRegion{{Country: "CN"}}, Rates: rates}
+	remote := &model.ShippingZone{ID: 2, Regions: []model.ShippingRegion{{Country: "CN", Province: "新疆"}, {Country: "CN", Province: "西藏"}}, Rates: rates}
+	overseas := &model.ShippingZone{ID: 3, Regions: []model.ShippingRegion{{Country: "US"}, {Country: "JP"}}, Rates: rates}
+	zones := []*model.ShippingZone{mainland, remote, overseas}
+
+	// 省份匹配优先于整个国家
+	assert.Equal(t, remote, matchShippingZone(zones, &model.UserAddress{Country: "CN", Province: "西藏"}, 1))
+	assert.Equal(t, mainland, matchShippingZone(zones, &model.UserAddress{Country: "CN", Province: "广东"}, 1))
+	// 未填写国家的历史地址按中国处理
+	assert.Equal(t, mainland, matchShippingZone(zones, &model.UserAddress{Province: "浙江"}, 1))
+	assert.Equal(t, overseas, matchShippingZone(zones, &model.UserAddress{Country: "jp", Province: "东京"}, 1))
+	assert.Nil(t, matchShippingZone(zones, &model.UserAddress{Country: "DE", Province: "柏林"}, 1))
+}
+
+// TestMatchShippingZoneFallsBackToCountry 测试省份所在区域未设置档位运费时按整个国家的区域计算
+func TestMatchShippingZoneFallsBackToCountry(t *testing.T) {
+	mainland := &model.ShippingZone{ID: 1, Regions: []model.ShippingRegion{{Country: "CN"}}, Rates: []model.ShippingRate{
+		{RewardTierID: 1, Fee: model.NewMoney(1000, "CNY")},
+		{RewardTierID: 2, Fee: model.NewMoney(1500, "CNY")},
+	}}
+	remote := &model.ShippingZone{ID: 2, Regions: []model.ShippingRegion{{Country: "CN", Province: "西藏"}}, Rates: []model.ShippingRate{
+		{RewardTierID: 1, Fee: model.NewMoney(3000, "CNY")},
+	}}
+	zones := []*model.ShippingZone{remote, mainland}
+
+	address := &model.UserAddress{Country: "CN", Province: "西藏"}
+	assert.Equal(t, remote, matchShippingZone(zones, address, 1))
+	assert.Equal(t, mainland, matchShippingZone(zones, address, 2))
+	// 没有任何区域设置该档位运费
+	assert.Nil(t, matchShippingZone(zones, address, 3))
+}
+
+// TestMatchShippingZoneComparesProvince 测试省份名称去除首尾空白、不区分大小写比较，其余须完全一致
+func TestMatchShippingZoneComparesProvince(t *testing.T) {
+	rates := []model.ShippingRate{{RewardTierID: 1, Fee: model.NewMoney(1000, "CNY")}}
+	country := &model.ShippingZone{ID: 1, Regions: []model.ShippingRegion{{Country: "US"}}, Rates: rates}
+	hawaii := &model.ShippingZone{ID: 2, Regions: []model.ShippingRegion{{Country: "US", Province: "Hawaii"}}, Rates: rates}
+	tibet := &model.ShippingZone{ID: 3, Regions: []model.ShippingRegion{{Country: "CN", Province: "西藏"}}, Rates: rates}
+	zones := []*model.ShippingZone{country, hawaii, tibet}
+
+	assert.Equal(t, hawaii, matchShippingZone(zones, &model.UserAddress{Country: "US", Province: "Hawaii"}, 1))
+	assert.Equal(t, hawaii, matchShippingZone(zones, &model.UserAddress{Country: " us ", Province: " hawaii "}, 1))
+	assert.Equal(t, tibet, matchShippingZone(zones, &model.UserAddress{Country: "CN", Province: " 西藏 "}, 1))
+	// 简称、全称等不同写法不视为同一省份
+	assert.Equal(t, country, matchShippingZone(zones, &model.UserAddress{Country: "US", Province: "HI"}, 1))
+	assert.Nil(t, matchShippingZone(zones, &model.UserAddress{Country: "CN", Province: "西藏自治区"}, 1))
+}
+
+func TestNormalizeShippingZone(t *testing.T) {
+	zone := &model.ShippingZone{
+		Name:    " 海外 ",
+		Regions: []model.ShippingRegion{{Country: " us "}, {Country: "CN", Province: " 香港 "}},
+		Rates:   []model.ShippingRate{{RewardTierID: 1, Fee: model.NewMoney(8000, "")}},
+	}
+	assert.NoError(t, normalizeShippingZone(zone, "CNY"))
+	assert.Equal(t, "海外", zone.Name)
+	assert.Equal(t, []model.ShippingRegion{{Country: "US"}, {Country: "CN", Province: "香港"}}, zone.Regions)
+	assert.Equal(t, model.NewMoney(8000, "CNY"), zone.Rates[0].Fee)
+
+	invalid := []*model.ShippingZone{
+		{Name: "无地区"},
+		{Name: "国家代码错误", Regions: []model.ShippingRegion{{Country: "CHN"}}},
+		{Name: "地区重复", Regions: []model.ShippingRegion{{Country: "CN"}, {Country: "cn"}}},
+		{Name: "运费为负", Regions: []model.ShippingRegion{{Country: "CN"}},
+			Rates: []model.ShippingRate{{RewardTierID: 1, Fee: model.NewMoney(-1, "")}}},
+		{Name: "档位重复", Regions: []model.ShippingRegion{{Country: "CN"}},
+			Rates: []model.ShippingRate{{RewardTierID: 1}, {RewardTierID: 1}}},
+	}
+	for _, z := range invalid {
+		assert.ErrorIs(t, normalizeShippingZone(z, "CNY"), ErrInvalidShippingZone, z.Name)
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/repository/interfaces"
 )
 
@@ -35,16 +36,24 @@ func (s *StatsService) GetSystemStats() (map[string]interface{}, error) {
 	// 计算成功的项目数量
 	var successfulProjects int
 	for _, project := range projects {
-		if project.Status == "successful" {
+		if project.Status == ProjectStatusCompleted {
 			successfulProjects++
 		}
 	}
 	stats["successful_projects"] = successfulProjects
 
-	// 计算总支持金额
-	var totalPledged float64
+	// 计算总支持金额，各项目币种不同，按币种分别汇总
+	totalPledged := make(map[string]model.Money)
 	for _, project := range projects {
-		totalPledged += project.TotalAmount // 假设 Project 结构体有 TotalAmount 字段
+		currency := project.Currency
+		if currency == "" {
+			currency = model.DefaultCurrency
+		}
+		total, ok := totalPledged[currency]
+		if !ok {
+			total = model.NewMoney(0, currency)
+		}
+		totalPledged[currency] = total.Add(project.TotalAmount)
 	}
 	stats["total_pledged_amount"] = totalPledged
 
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"crowdfunding-backend/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (m *MockProjectRepository) ListProjects(page, pageSize int) ([]model.Project, error) {
+	args := m.Called(page, pageSize)
+	return args.Get(0).([]model.Project), args.Error(1)
+}
+
+// TestGetSystemStatsTotalsPerCurrency 测试不同币种项目的支持金额按币种分别汇总，不直接相加
+func TestGetSystemStatsTotalsPerCurrency(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	projectRepo := new(MockProjectRepository)
+	service := NewStatsService(userRepo, projectRepo)
+
+	userRepo.On("Count").Return(3, nil)
+	projectRepo.On("ListProjects", 1, 1000000).Return([]model.Project{
+		{ID: 1, Status: ProjectStatusCompleted, Currency: "CNY", TotalAmount: model.NewMoney(100000, "CNY")},
+		{ID: 2, Status: ProjectStatusActive, Currency: "USD", TotalAmount: model.NewMoney(5000, "USD")},
+		{ID: 3, Status: ProjectStatusCompleted, Currency: "CNY", TotalAmount: model.NewMoney(20000, "CNY")},
+	}, nil)
+
+	stats, err := service.GetSystemStats()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats["total_projects"])
+	assert.Equal(t, 2, stats["successful_projects"])
+	assert.Equal(t, map[string]model.Money{
+		"CNY": model.NewMoney(120000, "CNY"),
+		"USD": model.NewMoney(5000, "USD"),
+	}, stats["total_pledged_amount"])
+}
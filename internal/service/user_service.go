@@ -1,15 +1,19 @@
 package service
 
 import (
+	"crowdfunding-backend/config"
 	"crowdfunding-backend/internal/errors"
 	"crowdfunding-backend/internal/model"
 	"crowdfunding-backend/internal/repository/interfaces"
 	"crowdfunding-backend/internal/util"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/hex"
 	stderrors "errors"
 	"fmt"
-	"log"
-	"sync"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -17,21 +21,72 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// 会话撤销原因
+const (
+	SessionRevokeLogout      = "logout"
+	SessionRevokeTokenReused = "refresh_token_reused"
+	SessionRevokeByUser      = "revoked_by_user"
+	SessionRevokeByAdmin     = "admin_force_logout"
+	SessionRevokePassword    = "password_changed"
+	SessionRevokeSuspended   = "account_suspended"
+	SessionRevokeDeleted     = "account_deleted"
+)
+
+const (
+	maxSessionDeviceLength  = 255
+	refreshTokenSecretBytes = 32
+	sessionIDBytes          = 16
+	verificationTokenBytes  = 32
+
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+
+	// purgeBatchSize 每次清理任务最多匿名化的账户数
+	purgeBatchSize = 100
+)
+
+var (
+	ErrInvalidRefreshToken = stderrors.New("无效或已过期的刷新令牌")
+	ErrRefreshTokenReused  = stderrors.New("刷新令牌已被使用，会话已撤销")
+	ErrSessionRevoked      = stderrors.New("会话已失效，请重新登录")
+	ErrSessionNotFound     = stderrors.New("会话不存在或已失效")
+	ErrUserNotFound        = stderrors.New("用户不存在")
+
+	ErrInvalidVerificationToken = stderrors.New("链接无效、已使用或已过期")
+	ErrEmailAlreadyVerified     = stderrors.New("邮箱已验证")
+	ErrIncorrectPassword        = stderrors.New("当前密码不正确")
+
+	ErrAccountSuspended     = stderrors.New("账户已被停用")
+	ErrAccountDeleted       = stderrors.New("账户已注销")
+	ErrAccountNotRestorable = stderrors.New("账户不存在或已超过恢复期")
+	ErrInvalidRole          = stderrors.New("无效的用户角色")
+	ErrInvalidAccountState  = stderrors.New("当前账户状态不允许该操作")
+)
+
 // UserService 处理与用户相关的业务逻辑
 type UserService struct {
-	userRepo interfaces.UserRepository
+	userRepo         interfaces.UserRepository
+	sessionRepo      interfaces.SessionRepository
+	verificationRepo interfaces.VerificationRepository
+	mfaService       *MFAService
 	// 添加邮件服务
-	emailService   *EmailService
-	tokenBlacklist map[string]time.Time
-	blacklistMutex sync.RWMutex
+	emailService    *EmailService
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	deleteGrace     time.Duration // 注销后可恢复的时长
 }
 
 // NewUserService 创建一个新的 UserService 实例
-func NewUserService(userRepo interfaces.UserRepository) *UserService {
+func NewUserService(userRepo interfaces.UserRepository, sessionRepo interfaces.SessionRepository, verificationRepo interfaces.VerificationRepository, mfaService *MFAService) *UserService {
 	return &UserService{
-		userRepo:       userRepo,
-		emailService:   NewEmailService(userRepo),
-		tokenBlacklist: make(map[string]time.Time),
+		userRepo:         userRepo,
+		sessionRepo:      sessionRepo,
+		verificationRepo: verificationRepo,
+		mfaService:       mfaService,
+		emailService:     NewEmailService(userRepo),
+		accessTokenTTL:   time.Duration(config.AppConfig.AccessTokenTTL) * time.Minute,
+		refreshTokenTTL:  time.Duration(config.AppConfig.RefreshTokenTTL) * time.Hour,
+		deleteGrace:      time.Duration(config.AppConfig.AccountDeleteGrace) * 24 * time.Hour,
 	}
 }
 
@@ -72,7 +127,7 @@ func (s *UserService) Register(user *model.User) error {
 	}
 
 	// 发送验证邮件
-	err = s.emailService.SendVerificationEmail(user.Email, user.Username)
+	err = s.SendVerificationEmail(user)
 	if err != nil {
 		util.Logger.Error("发送验证邮件失败", zap.Error(err))
 		// 考虑是否需要回滚用户创建
@@ -81,25 +136,36 @@ func (s *UserService) Register(user *model.User) error {
 	return nil
 }
 
-// Login 用户登录
-func (s *UserService) Login(email, password string) (*model.User, error) {
-	log.Printf("尝试用户登录：%s", email)
+// dummyPasswordHash 用户不存在时仍执行一次 bcrypt 比较，避免通过响应时间判断邮箱是否已注册
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
 
+// Login 用户登录，失败次数限制由登录防护中间件负责
+func (s *UserService) Login(email, password string) (*model.User, error) {
 	// 查找用户
 	user, err := s.userRepo.FindByEmail(email)
-	if err != nil {
-		log.Printf("用户登录失败，未找到用户：%v", err)
+	if err != nil || user == nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		if err == nil || err == sql.ErrNoRows {
+			err = errors.New(errors.ErrInvalidCredentials, "邮箱或密码错误")
+		}
+		util.Logger.Info("用户登录失败，未找到用户", zap.Error(err))
 		return nil, err
 	}
 
 	// 验证密码
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
-		log.Printf("用户登录失败，密码不正确：%v", err)
+		util.Logger.Info("用户登录失败，密码不正确", zap.Int("user_id", user.ID))
+		return nil, errors.New(errors.ErrInvalidCredentials, "邮箱或密码错误")
+	}
+
+	// 密码正确后才提示账户状态，避免泄露账户是否被停用
+	if err := accountStatusError(user.Status); err != nil {
+		util.Logger.Info("用户登录失败，账户不可用", zap.Int("user_id", user.ID), zap.String("status", user.Status))
 		return nil, err
 	}
 
-	log.Printf("用户登录成功：ID=%d", user.ID)
+	util.Logger.Info("用户登录成功", zap.Int("user_id", user.ID))
 	return user, nil
 }
 
@@ -135,37 +201,48 @@ func (s *UserService) UpdateUser(user *model.User) error {
 	return nil
 }
 
-// 添加验证邮箱的方法
+// SendVerificationEmail 生成邮箱验证令牌并发送验证邮件，之前发出的验证链接随之失效
+func (s *UserService) SendVerificationEmail(user *model.User) error {
+	token, err := s.createVerification(user.ID, model.VerificationTypeEmail, emailVerificationTTL)
+	if err != nil {
+		return fmt.Errorf("生成验证令牌失败: %w", err)
+	}
+	return s.emailService.SendVerificationEmail(user.Email, user.Username, token)
+}
+
+// VerifyEmail 使用邮箱验证令牌完成验证，令牌只能使用一次
 func (s *UserService) VerifyEmail(token string) error {
-	userID, err := s.emailService.VerifyEmailToken(token)
+	verification, err := s.findVerification(token, model.VerificationTypeEmail)
 	if err != nil {
-		util.Logger.Error("验证邮箱令牌失败", zap.Error(err))
 		return err
 	}
 
-	user, err := s.userRepo.FindByID(userID)
+	user, err := s.userRepo.FindByID(verification.UserID)
+	if err == sql.ErrNoRows || (err == nil && user == nil) {
+		return ErrInvalidVerificationToken
+	}
 	if err != nil {
-		util.Logger.Error("查找用户失败", zap.Error(err), zap.Int("user_id", userID))
+		util.Logger.Error("查找用户失败", zap.Error(err), zap.Int("user_id", verification.UserID))
 		return err
 	}
-	if user == nil {
-		return errors.New(errors.ErrUserNotFound, "user not found")
-	}
-
 	if user.IsVerified {
-		return errors.New(errors.ErrResourceExists, "email already verified")
+		return ErrEmailAlreadyVerified
 	}
 
-	user.IsVerified = true
-	if err := s.userRepo.Update(user); err != nil {
+	confirmed, err := s.verificationRepo.ConfirmEmail(verification.ID, user.ID)
+	if err != nil {
 		util.Logger.Error("更新用户验证状态失败", zap.Error(err), zap.Int("user_id", user.ID))
 		return err
 	}
+	if !confirmed {
+		return ErrInvalidVerificationToken
+	}
 
 	util.Logger.Info("邮箱验证成功", zap.Int("user_id", user.ID))
 	return nil
 }
 
+// RequestPasswordReset 生成密码重置令牌并发送重置邮件，之前发出的重置链接随之失效
 func (s *UserService) RequestPasswordReset(email string) error {
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
@@ -174,77 +251,409 @@ func (s *UserService) RequestPasswordReset(email string) error {
 	if user == nil {
 		return errors.New(errors.ErrUserNotFound, "user not found")
 	}
-	return s.emailService.SendPasswordResetEmail(email)
+
+	token, err := s.createVerification(user.ID, model.VerificationTypePasswordReset, passwordResetTTL)
+	if err != nil {
+		return fmt.Errorf("生成密码重置令牌失败: %w", err)
+	}
+	return s.emailService.SendPasswordResetEmail(email, token)
 }
 
+// ResetPassword 使用密码重置令牌设置新密码。令牌只能使用一次，
+// 重置后其余未使用的重置令牌作废，用户的所有会话被撤销
 func (s *UserService) ResetPassword(token, newPassword string) error {
-	email, err := s.emailService.VerifyPasswordResetToken(token)
+	verification, err := s.findVerification(token, model.VerificationTypePasswordReset)
 	if err != nil {
-		util.Logger.Error("验证密码重置令牌失败", zap.Error(err))
 		return err
 	}
 
-	user, err := s.userRepo.FindByEmail(email)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
-		util.Logger.Error("查找用户失败", zap.Error(err), zap.String("email", email))
+		util.Logger.Error("生成密码哈希失败", zap.Error(err))
 		return err
 	}
-	if user == nil {
-		return errors.New(errors.ErrUserNotFound, "user not found")
+
+	reset, err := s.verificationRepo.ResetPassword(verification.ID, verification.UserID, string(hashedPassword), SessionRevokePassword)
+	if err != nil {
+		util.Logger.Error("更新用户密码失败", zap.Error(err), zap.Int("user_id", verification.UserID))
+		return err
+	}
+	if !reset {
+		return ErrInvalidVerificationToken
+	}
+
+	util.Logger.Info("密码重置成功，已撤销全部会话", zap.Int("user_id", verification.UserID))
+	return nil
+}
+
+// ChangePassword 校验当前密码后修改密码，未使用的密码重置令牌作废，除当前会话外的其他会话被撤销
+func (s *UserService) ChangePassword(userID int, sessionID, currentPassword, newPassword string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err == sql.ErrNoRows || (err == nil && user == nil) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return ErrIncorrectPassword
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
-		util.Logger.Error("生成密码哈希失败", zap.Error(err))
+		return err
+	}
+	if err := s.verificationRepo.UpdatePassword(userID, string(hashedPassword), sessionID, SessionRevokePassword); err != nil {
+		util.Logger.Error("更新用户密码失败", zap.Error(err), zap.Int("user_id", userID))
 		return err
 	}
 
-	user.PasswordHash = string(hashedPassword)
-	if err := s.userRepo.Update(user); err != nil {
-		util.Logger.Error("更用户密码失败", zap.Error(err), zap.Int("user_id", user.ID))
+	util.Logger.Info("密码修改成功，已撤销其他会话", zap.Int("user_id", userID))
+	return nil
+}
+
+// PurgeExpiredVerifications 清理已过期的邮箱验证和密码重置令牌
+func (s *UserService) PurgeExpiredVerifications() (int64, error) {
+	return s.verificationRepo.DeleteExpiredVerifications()
+}
+
+// createVerification 生成随机令牌并保存其哈希，返回明文令牌用于邮件链接
+func (s *UserService) createVerification(userID int, verificationType string, ttl time.Duration) (string, error) {
+	token, err := randomHex(verificationTokenBytes)
+	if err != nil {
+		return "", err
+	}
+	verification := &model.UserVerification{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		Type:      verificationType,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.verificationRepo.CreateVerification(verification); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// findVerification 查找可用的令牌，令牌类型必须匹配，已使用、撤销或过期的令牌视为无效
+func (s *UserService) findVerification(token, verificationType string) (*model.UserVerification, error) {
+	if token == "" {
+		return nil, ErrInvalidVerificationToken
+	}
+	verification, err := s.verificationRepo.GetVerification(hashToken(token), verificationType)
+	if err != nil {
+		return nil, err
+	}
+	if verification == nil || !verification.IsUsable(time.Now()) {
+		return nil, ErrInvalidVerificationToken
+	}
+	return verification, nil
+}
+
+// CreateSession 为登录成功的用户创建会话，签发访问令牌和刷新令牌。
+// mfaVerified 表示本次登录已通过两步验证
+func (s *UserService) CreateSession(userID int, device, ip string, mfaVerified bool) (*model.TokenPair, error) {
+	sessionID, err := randomHex(sessionIDBytes)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(refreshTokenSecretBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(device) > maxSessionDeviceLength {
+		device = device[:maxSessionDeviceLength]
+	}
+	now := time.Now()
+	session := &model.UserSession{
+		ID:               sessionID,
+		UserID:           userID,
+		RefreshTokenHash: hashToken(secret),
+		Device:           device,
+		IPAddress:        ip,
+		CreatedAt:        now,
+		LastSeenAt:       now,
+		ExpiresAt:        now.Add(s.refreshTokenTTL),
+	}
+	if mfaVerified {
+		session.MFAVerifiedAt = &now
+	}
+	if err := s.sessionRepo.CreateSession(session); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(userID, sessionID, secret)
+}
+
+// IsMFAEnabled 用户是否已开启两步验证，开启后登录需要先完成两步验证
+func (s *UserService) IsMFAEnabled(userID int) (bool, error) {
+	return s.mfaService.IsEnabled(userID)
+}
+
+// CreateMFAChallenge 密码验证通过后签发两步验证挑战令牌
+func (s *UserService) CreateMFAChallenge(userID int) (string, error) {
+	return s.mfaService.CreateChallenge(userID)
+}
+
+// CompleteMFALogin 校验挑战令牌和验证码（或恢复码），通过后创建已完成两步验证的会话
+func (s *UserService) CompleteMFALogin(challengeToken, code, device, ip string) (*model.User, *model.TokenPair, error) {
+	userID, err := s.mfaService.ParseChallenge(challengeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.mfaService.Verify(userID, code); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err == sql.ErrNoRows || (err == nil && user == nil) {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := accountStatusError(user.Status); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.CreateSession(userID, device, ip, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// CheckAdminMFA 管理员必须开启两步验证，且当前会话已通过两步验证
+func (s *UserService) CheckAdminMFA(userID int, sessionID string) error {
+	enabled, err := s.mfaService.IsEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrAdminMFARequired
+	}
+	session, err := s.sessionRepo.GetSession(sessionID)
+	if err != nil {
 		return err
 	}
+	if session == nil || session.MFAVerifiedAt == nil {
+		return ErrMFAVerificationMissing
+	}
+	return nil
+}
 
-	util.Logger.Info("密码重置成功", zap.Int("user_id", user.ID))
+// MarkSessionMFAVerified 在当前会话中完成两步验证后标记会话
+func (s *UserService) MarkSessionMFAVerified(sessionID string) error {
+	return s.sessionRepo.MarkSessionMFAVerified(sessionID)
+}
+
+// RefreshSession 用刷新令牌换取新的令牌对，旧的刷新令牌随即失效。
+// 已轮换过的刷新令牌再次出现说明令牌可能已泄露，此时撤销整个会话；
+// 与会话不匹配的其他令牌只拒绝，不撤销会话，避免知道会话ID的人借此让用户下线
+func (s *UserService) RefreshSession(refreshToken, ip string) (*model.TokenPair, error) {
+	sessionID, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || sessionID == "" || secret == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionRepo.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || !session.IsActive(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	oldHash := hashToken(secret)
+	if subtle.ConstantTimeCompare([]byte(oldHash), []byte(session.RefreshTokenHash)) != 1 {
+		if session.PreviousHash != "" && subtle.ConstantTimeCompare([]byte(oldHash), []byte(session.PreviousHash)) == 1 {
+			return nil, s.revokeReusedSession(session)
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newSecret, err := randomHex(refreshTokenSecretBytes)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.sessionRepo.RotateRefreshToken(sessionID, oldHash, hashToken(newSecret), time.Now().Add(s.refreshTokenTTL), ip)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// 同一个刷新令牌被并发使用，另一个请求已完成轮换
+		return nil, s.revokeReusedSession(session)
+	}
+	return s.issueTokens(session.UserID, sessionID, newSecret)
+}
+
+// ValidateSession 校验访问令牌对应的会话仍然有效，并记录最近活跃时间
+func (s *UserService) ValidateSession(sessionID string, userID int, ip string) error {
+	session, err := s.sessionRepo.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID || !session.IsActive(time.Now()) {
+		return ErrSessionRevoked
+	}
+	status, err := s.userRepo.GetUserStatus(userID)
+	if err != nil {
+		return err
+	}
+	if status == "" {
+		return ErrSessionRevoked
+	}
+	if err := accountStatusError(status); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.TouchSession(sessionID, ip); err != nil {
+		util.Logger.Warn("更新会话活跃时间失败", zap.Error(err), zap.String("session_id", sessionID))
+	}
+	return nil
+}
+
+// Logout 注销当前会话，会话的访问令牌和刷新令牌立即失效
+func (s *UserService) Logout(sessionID string) error {
+	if _, err := s.sessionRepo.RevokeSession(sessionID, SessionRevokeLogout); err != nil {
+		return err
+	}
+	util.Logger.Info("用户注销，会话已撤销", zap.String("session_id", sessionID))
 	return nil
 }
 
-func (s *UserService) Logout(userID int) error {
-	token, err := util.GenerateToken(userID)
+// ListSessions 获取用户当前登录的设备，标记发起请求的会话
+func (s *UserService) ListSessions(userID int, currentSessionID string) ([]*model.UserSession, error) {
+	sessions, err := s.sessionRepo.GetActiveSessionsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession 用户撤销自己的某个会话，撤销当前会话等同于注销
+func (s *UserService) RevokeSession(userID int, sessionID string) error {
+	session, err := s.sessionRepo.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID || !session.IsActive(time.Now()) {
+		return ErrSessionNotFound
+	}
+	revoked, err := s.sessionRepo.RevokeSession(sessionID, SessionRevokeByUser)
 	if err != nil {
 		return err
 	}
-	s.blacklistMutex.Lock()
-	s.tokenBlacklist[token] = time.Now().Add(24 * time.Hour) // 令牌在黑名单中保留24小时
-	s.blacklistMutex.Unlock()
-	util.Logger.Info("用户注销，令牌已加入黑名单", zap.Int("user_id", userID))
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	util.Logger.Info("用户撤销会话", zap.Int("user_id", userID), zap.String("session_id", sessionID))
 	return nil
 }
 
-func (s *UserService) IsTokenBlacklisted(token string) bool {
-	s.blacklistMutex.RLock()
-	defer s.blacklistMutex.RUnlock()
-	expiry, exists := s.tokenBlacklist[token]
-	if !exists {
-		return false
+// RevokeOtherSessions 撤销用户除当前会话以外的全部会话
+func (s *UserService) RevokeOtherSessions(userID int, currentSessionID string) (int64, error) {
+	count, err := s.sessionRepo.RevokeOtherSessions(userID, currentSessionID, SessionRevokeByUser)
+	if err != nil {
+		return 0, err
+	}
+	util.Logger.Info("用户撤销其他会话", zap.Int("user_id", userID), zap.Int64("count", count))
+	return count, nil
+}
+
+// ListUserSessions 管理员查看用户的登录会话
+func (s *UserService) ListUserSessions(userID int) ([]*model.UserSession, error) {
+	if err := s.ensureUserExists(userID); err != nil {
+		return nil, err
+	}
+	return s.sessionRepo.GetActiveSessionsByUser(userID)
+}
+
+// ForceLogout 管理员强制用户下线，撤销其全部会话
+func (s *UserService) ForceLogout(userID int, actor model.AuditActor) (int64, error) {
+	if err := s.ensureUserExists(userID); err != nil {
+		return 0, err
+	}
+	audit := actor.Entry(model.AuditActionUserForceLogout, model.AuditTargetUser, userID)
+	audit.SetChange(nil, map[string]string{"revoke_reason": SessionRevokeByAdmin})
+	count, err := s.sessionRepo.AdminRevokeUserSessions(userID, SessionRevokeByAdmin, audit)
+	if err != nil {
+		return 0, err
+	}
+	util.Logger.Info("管理员强制用户下线",
+		zap.Int("user_id", userID),
+		zap.Int("admin_id", actor.UserID),
+		zap.Int64("count", count))
+	return count, nil
+}
+
+func (s *UserService) ensureUserExists(userID int) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err == sql.ErrNoRows || (err == nil && user == nil) {
+		return ErrUserNotFound
 	}
-	if time.Now().After(expiry) {
-		delete(s.tokenBlacklist, token)
-		return false
+	return err
+}
+
+// PurgeExpiredSessions 清理刷新令牌已过期的会话
+func (s *UserService) PurgeExpiredSessions() (int64, error) {
+	return s.sessionRepo.DeleteExpiredSessions()
+}
+
+func (s *UserService) issueTokens(userID int, sessionID, secret string) (*model.TokenPair, error) {
+	accessToken, err := util.GenerateAccessToken(userID, sessionID, s.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: sessionID + "." + secret,
+		ExpiresIn:    int(s.accessTokenTTL.Seconds()),
+		SessionID:    sessionID,
+	}, nil
+}
+
+func (s *UserService) revokeReusedSession(session *model.UserSession) error {
+	if _, err := s.sessionRepo.RevokeSession(session.ID, SessionRevokeTokenReused); err != nil {
+		return err
+	}
+	util.Logger.Warn("检测到刷新令牌重复使用，已撤销会话",
+		zap.Int("user_id", session.UserID),
+		zap.String("session_id", session.ID))
+	return ErrRefreshTokenReused
+}
+
+// hashToken 数据库中只保存令牌的 SHA-256
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
 	}
-	return true
+	return hex.EncodeToString(buf), nil
 }
 
 type UserServiceInterface interface {
 	Register(user *model.User) error
 	Login(email, password string) (*model.User, error)
+	RestoreAccount(email, password string) (*model.User, error)
 	GetUserByID(id int) (*model.User, error)
 	UpdateUser(user *model.User) error
 	VerifyEmail(token string) error
 	RequestPasswordReset(email string) error
 	ResetPassword(token, newPassword string) error
-	Logout(userID int) error
-	IsTokenBlacklisted(token string) bool
+	CreateSession(userID int, device, ip string, mfaVerified bool) (*model.TokenPair, error)
+	IsMFAEnabled(userID int) (bool, error)
+	CreateMFAChallenge(userID int) (string, error)
+	CompleteMFALogin(challengeToken, code, device, ip string) (*model.User, *model.TokenPair, error)
+	RefreshSession(refreshToken, ip string) (*model.TokenPair, error)
+	Logout(sessionID string) error
 }
 
 // 确保 UserService 实现了 UserServiceInterface
@@ -263,39 +672,142 @@ func (s *UserService) GetUsers(page, pageSize int) ([]*model.User, error) {
 	return s.userRepo.FindAll(page, pageSize)
 }
 
-func (s *UserService) UpdateUserRole(userID int, newRole string) error {
-	// 实现更新用户角色的逻辑
+// UpdateAvatar 更新用户头像
+func (s *UserService) UpdateAvatar(userID int, avatarURL string) error {
 	user, err := s.GetUserByID(userID)
 	if err != nil {
 		return err
 	}
-	user.Role = newRole
+
+	user.AvatarURL = avatarURL
 	return s.userRepo.Update(user)
 }
 
-// UpdateAvatar 更新用户头像
-func (s *UserService) UpdateAvatar(userID int, avatarURL string) error {
-	user, err := s.GetUserByID(userID)
+// DeleteAccount 注销用户账户并撤销所有会话，返回可恢复账户的截止时间。
+// 恢复期内个人数据保留，到期后由 PurgeDeletedAccounts 匿名化
+func (s *UserService) DeleteAccount(userID int) (time.Time, error) {
+	deleted, err := s.userRepo.SoftDeleteUser(userID)
 	if err != nil {
+		return time.Time{}, err
+	}
+	if !deleted {
+		return time.Time{}, ErrInvalidAccountState
+	}
+	if _, err := s.sessionRepo.RevokeUserSessions(userID, SessionRevokeDeleted); err != nil {
+		return time.Time{}, err
+	}
+
+	restoreBefore := time.Now().Add(s.deleteGrace)
+	util.Logger.Info("用户注销账户", zap.Int("user_id", userID), zap.Time("restore_before", restoreBefore))
+	return restoreBefore, nil
+}
+
+// RestoreAccount 恢复期内凭邮箱和密码恢复已注销的账户，恢复后需要重新登录
+func (s *UserService) RestoreAccount(email, password string) (*model.User, error) {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil || user == nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		if err == nil || err == sql.ErrNoRows {
+			err = errors.New(errors.ErrInvalidCredentials, "邮箱或密码错误")
+		}
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, errors.New(errors.ErrInvalidCredentials, "邮箱或密码错误")
+	}
+	if user.Status != model.UserStatusDeleted {
+		return nil, ErrAccountNotRestorable
+	}
+
+	restored, err := s.userRepo.RestoreUser(user.ID, time.Now().Add(-s.deleteGrace))
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrAccountNotRestorable
+	}
+
+	util.Logger.Info("用户恢复已注销的账户", zap.Int("user_id", user.ID))
+	return s.userRepo.FindByID(user.ID)
+}
+
+// SuspendUser 管理员停用账户，账户的所有会话立即失效
+func (s *UserService) SuspendUser(userID int, actor model.AuditActor, reason string) error {
+	if err := s.ensureUserExists(userID); err != nil {
+		return err
+	}
+	audit := actor.Entry(model.AuditActionUserSuspend, model.AuditTargetUser, userID)
+	audit.SetChange(nil, map[string]string{"status": model.UserStatusSuspended, "reason": reason})
+	suspended, err := s.userRepo.SuspendUser(userID, reason, audit)
+	if err != nil {
+		return err
+	}
+	if !suspended {
+		return ErrInvalidAccountState
+	}
+	if _, err := s.sessionRepo.RevokeUserSessions(userID, SessionRevokeSuspended); err != nil {
 		return err
 	}
 
-	user.AvatarURL = avatarURL
-	return s.userRepo.Update(user)
+	util.Logger.Info("管理员停用账户",
+		zap.Int("user_id", userID),
+		zap.Int("admin_id", actor.UserID),
+		zap.String("reason", reason))
+	return nil
 }
 
-// DeleteAccount 注销用户账户
-func (s *UserService) DeleteAccount(userID int) error {
-	user, err := s.GetUserByID(userID)
+// UnsuspendUser 管理员恢复被停用的账户
+func (s *UserService) UnsuspendUser(userID int, actor model.AuditActor) error {
+	if err := s.ensureUserExists(userID); err != nil {
+		return err
+	}
+	audit := actor.Entry(model.AuditActionUserUnsuspend, model.AuditTargetUser, userID)
+	audit.SetChange(map[string]string{"status": model.UserStatusSuspended}, nil)
+	unsuspended, err := s.userRepo.UnsuspendUser(userID, audit)
 	if err != nil {
 		return err
 	}
+	if !unsuspended {
+		return ErrInvalidAccountState
+	}
 
-	// 软删除：设置 DeletedAt 字段
-	now := time.Now()
-	user.DeletedAt = &now
+	util.Logger.Info("管理员恢复账户", zap.Int("user_id", userID), zap.Int("admin_id", actor.UserID))
+	return nil
+}
 
-	return s.userRepo.Update(user)
+// PurgeDeletedAccounts 匿名化超过恢复期的已注销账户，返回本次处理的账户数
+func (s *UserService) PurgeDeletedAccounts() (int, error) {
+	ids, err := s.userRepo.FindUsersPendingPurge(time.Now().Add(-s.deleteGrace), purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		ok, err := s.userRepo.AnonymizeUser(id)
+		if err != nil {
+			util.Logger.Error("匿名化已注销账户失败", zap.Error(err), zap.Int("user_id", id))
+			continue
+		}
+		if ok {
+			purged++
+		}
+	}
+	if purged > 0 {
+		util.Logger.Info("已匿名化超过恢复期的注销账户", zap.Int("count", purged))
+	}
+	return purged, nil
+}
+
+// accountStatusError 停用或注销的账户不能登录，也不能继续使用已签发的令牌
+func accountStatusError(status string) error {
+	switch status {
+	case model.UserStatusSuspended:
+		return ErrAccountSuspended
+	case model.UserStatusDeleted:
+		return ErrAccountDeleted
+	}
+	return nil
 }
 
 // 在 UserService 结构体中添加地址相关的方法
@@ -362,6 +874,18 @@ func validateAddress(address *model.UserAddress) error {
 	if address.DetailAddress == "" {
 		return errors.New(errors.ErrValidation, "detail address is required")
 	}
+	return normalizeAddressCountry(address)
+}
+
+// normalizeAddressCountry 统一国家代码为大写，未填写时默认为中国
+func normalizeAddressCountry(address *model.UserAddress) error {
+	address.Country = strings.ToUpper(strings.TrimSpace(address.Country))
+	if address.Country == "" {
+		address.Country = model.DefaultCountry
+	}
+	if !model.IsValidCountryCode(address.Country) {
+		return errors.New(errors.ErrValidation, "invalid country code")
+	}
 	return nil
 }
 
@@ -383,6 +907,9 @@ func (s *UserService) handleDefaultAddress(userID int) error {
 }
 
 func (s *UserService) UpdateAddress(address *model.UserAddress) error {
+	if err := normalizeAddressCountry(address); err != nil {
+		return err
+	}
 	return s.userRepo.UpdateAddress(address)
 }
 
@@ -1,12 +1,17 @@
 package service
 
 import (
+	"crowdfunding-backend/config"
 	"crowdfunding-backend/internal/model"
+	"crowdfunding-backend/internal/util"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // MockUserRepository 是 UserRepository 接口的模拟实现
@@ -96,10 +101,46 @@ func (m *MockUserRepository) SetDefaultAddress(userID, addressID int) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) GetUserStatus(id int) (string, error) {
+	args := m.Called(id)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserRepository) SuspendUser(id int, reason string, audit *model.AuditEntry) (bool, error) {
+	args := m.Called(id, reason, audit)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UnsuspendUser(id int, audit *model.AuditEntry) (bool, error) {
+	args := m.Called(id, audit)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) SoftDeleteUser(id int) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) RestoreUser(id int, deletedAfter time.Time) (bool, error) {
+	args := m.Called(id, deletedAfter)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) FindUsersPendingPurge(deletedBefore time.Time, limit int) ([]int, error) {
+	args := m.Called(deletedBefore, limit)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockUserRepository) AnonymizeUser(id int) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
 // TestRegister 测试用户注册功能
 func TestRegister(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	verificationRepo := new(MockVerificationRepository)
+	service := NewUserService(mockRepo, new(MockSessionRepository), verificationRepo, nil)
 
 	user := &model.User{
 		Username:     "testuser",
@@ -110,6 +151,7 @@ func TestRegister(t *testing.T) {
 	// 测试成功注册
 	mockRepo.On("FindByUsername", "testuser").Return(nil, nil)
 	mockRepo.On("Create", mock.AnythingOfType("*model.User")).Return(nil)
+	verificationRepo.On("CreateVerification", mock.AnythingOfType("*model.UserVerification")).Return(nil)
 
 	err := service.Register(user)
 	assert.NoError(t, err)
@@ -128,7 +170,7 @@ func TestRegister(t *testing.T) {
 // TestUpdateProfile 测试更新用户资料功能
 func TestUpdateProfile(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, new(MockSessionRepository), new(MockVerificationRepository), nil)
 
 	user := &model.User{
 		ID:       1,
@@ -154,7 +196,7 @@ func TestUpdateProfile(t *testing.T) {
 // TestCreateAddress 测试创建地址功能
 func TestCreateAddress(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, new(MockSessionRepository), new(MockVerificationRepository), nil)
 
 	address := &model.UserAddress{
 		UserID:        1,
@@ -176,4 +218,420 @@ func TestCreateAddress(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+// MockSessionRepository 是 SessionRepository 接口的模拟实现
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) CreateSession(session *model.UserSession) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) GetSession(sessionID string) (*model.UserSession, error) {
+	args := m.Called(sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserSession), args.Error(1)
+}
+
+func (m *MockSessionRepository) GetActiveSessionsByUser(userID int) ([]*model.UserSession, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]*model.UserSession), args.Error(1)
+}
+
+func (m *MockSessionRepository) RotateRefreshToken(sessionID, oldHash, newHash string, expiresAt time.Time, ip string) (bool, error) {
+	args := m.Called(sessionID, oldHash, newHash, expiresAt, ip)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) TouchSession(sessionID, ip string) error {
+	args := m.Called(sessionID, ip)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) MarkSessionMFAVerified(sessionID string) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) RevokeSession(sessionID, reason string) (bool, error) {
+	args := m.Called(sessionID, reason)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeUserSessions(userID int, reason string) (int64, error) {
+	args := m.Called(userID, reason)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSessionRepository) AdminRevokeUserSessions(userID int, reason string, audit *model.AuditEntry) (int64, error) {
+	args := m.Called(userID, reason, audit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeOtherSessions(userID int, keepSessionID, reason string) (int64, error) {
+	args := m.Called(userID, keepSessionID, reason)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSessionRepository) DeleteExpiredSessions() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func newSessionTestService(sessionRepo *MockSessionRepository) *UserService {
+	config.AppConfig.JWTSecret = "test-secret"
+	util.Logger = zap.NewNop()
+	service := NewUserService(new(MockUserRepository), sessionRepo, new(MockVerificationRepository), nil)
+	service.accessTokenTTL = 15 * time.Minute
+	service.refreshTokenTTL = 24 * time.Hour
+	return service
+}
+
+// TestSessionRefreshRotation 测试刷新令牌轮换：数据库只保存哈希，刷新后签发新的刷新令牌
+func TestSessionRefreshRotation(t *testing.T) {
+	sessionRepo := new(MockSessionRepository)
+	service := newSessionTestService(sessionRepo)
+
+	var stored *model.UserSession
+	sessionRepo.On("CreateSession", mock.AnythingOfType("*model.UserSession")).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*model.UserSession)
+	}).Return(nil)
+
+	tokens, err := service.CreateSession(7, "Mozilla/5.0", "10.0.0.1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 900, tokens.ExpiresIn)
+	sessionID, secret, ok := strings.Cut(tokens.RefreshToken, ".")
+	assert.True(t, ok)
+	assert.Equal(t, stored.ID, sessionID)
+	assert.Equal(t, hashToken(secret), stored.RefreshTokenHash)
+	assert.NotContains(t, stored.RefreshTokenHash, secret)
+
+	userID, jti, err := util.ParseAccessToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, userID)
+	assert.Equal(t, sessionID, jti)
+
+	sessionRepo.On("GetSession", sessionID).Return(stored, nil)
+	sessionRepo.On("RotateRefreshToken", sessionID, stored.RefreshTokenHash, mock.Anything, mock.Anything, "10.0.0.2").Return(true, nil)
+
+	refreshed, err := service.RefreshSession(tokens.RefreshToken, "10.0.0.2")
+	assert.NoError(t, err)
+	assert.Equal(t, sessionID, refreshed.SessionID)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+	sessionRepo.AssertExpectations(t)
+}
+
+// TestSessionRefreshTokenReuse 测试已轮换的刷新令牌再次使用时撤销整个会话，其他不匹配的令牌不会撤销会话
+func TestSessionRefreshTokenReuse(t *testing.T) {
+	sessionRepo := new(MockSessionRepository)
+	service := newSessionTestService(sessionRepo)
+
+	session := &model.UserSession{
+		ID:               "abc",
+		UserID:           7,
+		RefreshTokenHash: hashToken("current"),
+		PreviousHash:     hashToken("previous"),
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+	sessionRepo.On("GetSession", "abc").Return(session, nil)
+	sessionRepo.On("RevokeSession", "abc", SessionRevokeTokenReused).Return(true, nil)
+
+	// 从未签发过的令牌只拒绝，不撤销会话
+	_, err := service.RefreshSession("abc.guessed", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	sessionRepo.AssertNotCalled(t, "RevokeSession", "abc", SessionRevokeTokenReused)
+
+	_, err = service.RefreshSession("abc.previous", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	sessionRepo.AssertExpectations(t)
+
+	// 格式错误和不存在的会话不会撤销任何会话
+	sessionRepo.On("GetSession", "missing").Return(nil, nil)
+	_, err = service.RefreshSession("missing.secret", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = service.RefreshSession("no-separator", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	sessionRepo.AssertNumberOfCalls(t, "RevokeSession", 1)
+}
+
+// TestValidateSession 测试已撤销或属于其他用户的会话不能通过认证
+func TestValidateSession(t *testing.T) {
+	sessionRepo := new(MockSessionRepository)
+	service := newSessionTestService(sessionRepo)
+
+	revokedAt := time.Now()
+	sessionRepo.On("GetSession", "active").Return(&model.UserSession{ID: "active", UserID: 7, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	sessionRepo.On("GetSession", "revoked").Return(&model.UserSession{ID: "revoked", UserID: 7, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}, nil)
+	sessionRepo.On("TouchSession", "active", "10.0.0.1").Return(nil)
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserStatus", 7).Return(model.UserStatusActive, nil)
+	service.userRepo = userRepo
+
+	assert.NoError(t, service.ValidateSession("active", 7, "10.0.0.1"))
+	assert.ErrorIs(t, service.ValidateSession("active", 8, "10.0.0.1"), ErrSessionRevoked)
+	assert.ErrorIs(t, service.ValidateSession("revoked", 7, "10.0.0.1"), ErrSessionRevoked)
+}
+
+// TestRevokeSession 测试用户只能撤销自己的会话，并能识别当前会话
+func TestRevokeSession(t *testing.T) {
+	sessionRepo := new(MockSessionRepository)
+	service := newSessionTestService(sessionRepo)
+
+	expiresAt := time.Now().Add(time.Hour)
+	sessionRepo.On("GetActiveSessionsByUser", 7).Return([]*model.UserSession{
+		{ID: "phone", UserID: 7, ExpiresAt: expiresAt},
+		{ID: "laptop", UserID: 7, ExpiresAt: expiresAt},
+	}, nil)
+	sessions, err := service.ListSessions(7, "laptop")
+	assert.NoError(t, err)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+
+	sessionRepo.On("GetSession", "phone").Return(&model.UserSession{ID: "phone", UserID: 7, ExpiresAt: expiresAt}, nil)
+	sessionRepo.On("RevokeSession", "phone", SessionRevokeByUser).Return(true, nil)
+	assert.ErrorIs(t, service.RevokeSession(8, "phone"), ErrSessionNotFound)
+	assert.NoError(t, service.RevokeSession(7, "phone"))
+	sessionRepo.AssertNumberOfCalls(t, "RevokeSession", 1)
+}
+
+type MockVerificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockVerificationRepository) CreateVerification(verification *model.UserVerification) error {
+	args := m.Called(verification)
+	return args.Error(0)
+}
+
+func (m *MockVerificationRepository) GetVerification(tokenHash, verificationType string) (*model.UserVerification, error) {
+	args := m.Called(tokenHash, verificationType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserVerification), args.Error(1)
+}
+
+func (m *MockVerificationRepository) ConfirmEmail(verificationID, userID int) (bool, error) {
+	args := m.Called(verificationID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockVerificationRepository) ResetPassword(verificationID, userID int, passwordHash, revokeReason string) (bool, error) {
+	args := m.Called(verificationID, userID, passwordHash, revokeReason)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockVerificationRepository) UpdatePassword(userID int, passwordHash, keepSessionID, revokeReason string) error {
+	args := m.Called(userID, passwordHash, keepSessionID, revokeReason)
+	return args.Error(0)
+}
+
+func (m *MockVerificationRepository) DeleteExpiredVerifications() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func newUserTestService(userRepo *MockUserRepository, sessionRepo *MockSessionRepository, verificationRepo *MockVerificationRepository) *UserService {
+	util.Logger = zap.NewNop()
+	return NewUserService(userRepo, sessionRepo, verificationRepo, nil)
+}
+
+// TestCreateVerificationStoresHash 测试数据库只保存令牌哈希，且令牌带有类型和有效期
+func TestCreateVerificationStoresHash(t *testing.T) {
+	verificationRepo := new(MockVerificationRepository)
+	service := newUserTestService(new(MockUserRepository), new(MockSessionRepository), verificationRepo)
+
+	var stored *model.UserVerification
+	verificationRepo.On("CreateVerification", mock.AnythingOfType("*model.UserVerification")).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*model.UserVerification)
+	}).Return(nil)
+
+	token, err := service.createVerification(7, model.VerificationTypePasswordReset, passwordResetTTL)
+	assert.NoError(t, err)
+	assert.Equal(t, hashToken(token), stored.TokenHash)
+	assert.Equal(t, model.VerificationTypePasswordReset, stored.Type)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+}
+
+// TestResetPasswordSingleUse 测试重置令牌只能使用一次，重置后撤销所有会话
+func TestResetPasswordSingleUse(t *testing.T) {
+	sessionRepo := new(MockSessionRepository)
+	verificationRepo := new(MockVerificationRepository)
+	service := newUserTestService(new(MockUserRepository), sessionRepo, verificationRepo)
+
+	verification := &model.UserVerification{
+		ID:        3,
+		UserID:    7,
+		Type:      model.VerificationTypePasswordReset,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	verificationRepo.On("GetVerification", hashToken("reset-token"), model.VerificationTypePasswordReset).Return(verification, nil).Once()
+	// 会话在更新密码的同一事务中撤销
+	verificationRepo.On("ResetPassword", 3, 7, mock.AnythingOfType("string"), SessionRevokePassword).Return(true, nil).Once()
+
+	assert.NoError(t, service.ResetPassword("reset-token", "NewPassword1!"))
+	verificationRepo.AssertCalled(t, "ResetPassword", 3, 7, mock.AnythingOfType("string"), SessionRevokePassword)
+
+	// 再次使用同一个链接
+	usedAt := time.Now()
+	used := *verification
+	used.UsedAt = &usedAt
+	verificationRepo.On("GetVerification", hashToken("reset-token"), model.VerificationTypePasswordReset).Return(&used, nil).Once()
+	assert.ErrorIs(t, service.ResetPassword("reset-token", "NewPassword1!"), ErrInvalidVerificationToken)
+
+	// 并发请求中令牌已被另一个请求使用
+	verificationRepo.On("GetVerification", hashToken("raced-token"), model.VerificationTypePasswordReset).Return(verification, nil)
+	verificationRepo.On("ResetPassword", 3, 7, mock.AnythingOfType("string"), SessionRevokePassword).Return(false, nil)
+	assert.ErrorIs(t, service.ResetPassword("raced-token", "NewPassword1!"), ErrInvalidVerificationToken)
+	sessionRepo.AssertNotCalled(t, "RevokeUserSessions", mock.Anything, mock.Anything)
+}
+
+// TestChangePasswordRevokesOtherSessions 测试修改密码时在同一事务中撤销除当前会话外的其他会话
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+	verificationRepo := new(MockVerificationRepository)
+	service := newUserTestService(userRepo, sessionRepo, verificationRepo)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Password1!"), bcrypt.MinCost)
+	userRepo.On("FindByID", 7).Return(&model.User{ID: 7, PasswordHash: string(hash)}, nil)
+	verificationRepo.On("UpdatePassword", 7, mock.AnythingOfType("string"), "current", SessionRevokePassword).Return(nil)
+
+	assert.ErrorIs(t, service.ChangePassword(7, "current", "wrong", "NewPassword1!"), ErrIncorrectPassword)
+	assert.NoError(t, service.ChangePassword(7, "current", "Password1!", "NewPassword1!"))
+	verificationRepo.AssertNumberOfCalls(t, "UpdatePassword", 1)
+	sessionRepo.AssertNotCalled(t, "RevokeOtherSessions", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestVerificationTokenType 测试邮箱验证令牌不能用于重置密码，撤销或过期的令牌无效
+func TestVerificationTokenType(t *testing.T) {
+	verificationRepo := new(MockVerificationRepository)
+	service := newUserTestService(new(MockUserRepository), new(MockSessionRepository), verificationRepo)
+
+	verificationRepo.On("GetVerification", hashToken("email-token"), model.VerificationTypePasswordReset).Return(nil, nil)
+	assert.ErrorIs(t, service.ResetPassword("email-token", "NewPassword1!"), ErrInvalidVerificationToken)
+
+	revokedAt := time.Now()
+	verificationRepo.On("GetVerification", hashToken("revoked-token"), model.VerificationTypeEmail).Return(&model.UserVerification{
+		ID: 4, UserID: 7, Type: model.VerificationTypeEmail, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt,
+	}, nil)
+	assert.ErrorIs(t, service.VerifyEmail("revoked-token"), ErrInvalidVerificationToken)
+
+	verificationRepo.On("GetVerification", hashToken("expired-token"), model.VerificationTypeEmail).Return(&model.UserVerification{
+		ID: 5, UserID: 7, Type: model.VerificationTypeEmail, ExpiresAt: time.Now().Add(-time.Minute),
+	}, nil)
+	assert.ErrorIs(t, service.VerifyEmail("expired-token"), ErrInvalidVerificationToken)
+	verificationRepo.AssertNotCalled(t, "ConfirmEmail", mock.Anything, mock.Anything)
+}
+
+// TestSuspendedAccountIsRefused 测试停用或注销的账户不能登录，已签发的令牌也立即失效
+func TestSuspendedAccountIsRefused(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+	service := newUserTestService(userRepo, sessionRepo, new(MockVerificationRepository))
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Password1!"), bcrypt.MinCost)
+	userRepo.On("FindByEmail", "suspended@example.com").Return(&model.User{
+		ID: 7, PasswordHash: string(hash), Status: model.UserStatusSuspended,
+	}, nil)
+	_, err := service.Login("suspended@example.com", "Password1!")
+	assert.ErrorIs(t, err, ErrAccountSuspended)
+
+	// 密码错误时不暴露账户状态
+	_, err = service.Login("suspended@example.com", "wrong")
+	assert.NotErrorIs(t, err, ErrAccountSuspended)
+
+	sessionRepo.On("GetSession", "s1").Return(&model.UserSession{ID: "s1", UserID: 8, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	userRepo.On("GetUserStatus", 8).Return(model.UserStatusDeleted, nil)
+	assert.ErrorIs(t, service.ValidateSession("s1", 8, "10.0.0.1"), ErrAccountDeleted)
+	sessionRepo.AssertNotCalled(t, "TouchSession", "s1", "10.0.0.1")
+}
+
+// TestDeleteAndRestoreAccount 测试注销后撤销会话，恢复期内可以恢复
+func TestDeleteAndRestoreAccount(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+	service := newUserTestService(userRepo, sessionRepo, new(MockVerificationRepository))
+	service.deleteGrace = 30 * 24 * time.Hour
+
+	userRepo.On("SoftDeleteUser", 7).Return(true, nil)
+	sessionRepo.On("RevokeUserSessions", 7, SessionRevokeDeleted).Return(int64(2), nil)
+	restoreBefore, err := service.DeleteAccount(7)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(service.deleteGrace), restoreBefore, time.Minute)
+
+	userRepo.On("SoftDeleteUser", 8).Return(false, nil)
+	_, err = service.DeleteAccount(8)
+	assert.ErrorIs(t, err, ErrInvalidAccountState)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Password1!"), bcrypt.MinCost)
+	deleted := &model.User{ID: 7, PasswordHash: string(hash), Status: model.UserStatusDeleted}
+	userRepo.On("FindByEmail", "deleted@example.com").Return(deleted, nil)
+	userRepo.On("RestoreUser", 7, mock.MatchedBy(func(deletedAfter time.Time) bool {
+		return deletedAfter.Before(time.Now().Add(-29 * 24 * time.Hour))
+	})).Return(true, nil).Once()
+	userRepo.On("FindByID", 7).Return(&model.User{ID: 7, Status: model.UserStatusActive}, nil)
+
+	restored, err := service.RestoreAccount("deleted@example.com", "Password1!")
+	assert.NoError(t, err)
+	assert.Equal(t, model.UserStatusActive, restored.Status)
+
+	// 已超过恢复期或已匿名化
+	userRepo.On("RestoreUser", 7, mock.Anything).Return(false, nil)
+	_, err = service.RestoreAccount("deleted@example.com", "Password1!")
+	assert.ErrorIs(t, err, ErrAccountNotRestorable)
+}
+
+// TestPurgeDeletedAccounts 测试清理任务只匿名化超过恢复期的账户，单个失败不影响其他账户
+func TestPurgeDeletedAccounts(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	service := newUserTestService(userRepo, new(MockSessionRepository), new(MockVerificationRepository))
+	service.deleteGrace = 30 * 24 * time.Hour
+
+	userRepo.On("FindUsersPendingPurge", mock.MatchedBy(func(deletedBefore time.Time) bool {
+		return deletedBefore.Before(time.Now().Add(-29 * 24 * time.Hour))
+	}), purgeBatchSize).Return([]int{3, 4, 5}, nil)
+	userRepo.On("AnonymizeUser", 3).Return(true, nil)
+	userRepo.On("AnonymizeUser", 4).Return(false, assert.AnError)
+	userRepo.On("AnonymizeUser", 5).Return(true, nil)
+
+	purged, err := service.PurgeDeletedAccounts()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+}
+
+// TestForceLogoutWritesAuditEntry 测试管理员强制下线时与撤销会话一起写入审计记录
+func TestForceLogoutWritesAuditEntry(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+	service := newUserTestService(userRepo, sessionRepo, new(MockVerificationRepository))
+	actor := model.AuditActor{UserID: 1, IP: "10.0.0.1", RequestID: "req-1"}
+
+	userRepo.On("FindByID", 7).Return(&model.User{ID: 7}, nil)
+	var audit *model.AuditEntry
+	sessionRepo.On("AdminRevokeUserSessions", 7, SessionRevokeByAdmin, mock.AnythingOfType("*model.AuditEntry")).Run(func(args mock.Arguments) {
+		audit = args.Get(2).(*model.AuditEntry)
+	}).Return(int64(3), nil)
+
+	count, err := service.ForceLogout(7, actor)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	if assert.NotNil(t, audit) {
+		assert.Equal(t, model.AuditActionUserForceLogout, audit.Action)
+		assert.Equal(t, model.AuditTargetUser, audit.TargetType)
+		assert.Equal(t, 7, audit.TargetID)
+		assert.Equal(t, 1, audit.ActorID)
+		assert.Equal(t, "req-1", audit.RequestID)
+	}
+
+	// 用户不存在时不撤销会话
+	userRepo.On("FindByID", 8).Return(nil, nil)
+	_, err = service.ForceLogout(8, actor)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	sessionRepo.AssertNumberOfCalls(t, "AdminRevokeUserSessions", 1)
+}
+
 // 可以继续添加更多测试用例...
@@ -47,3 +47,35 @@ func (s *LocalStorage) UploadFile(file *multipart.FileHeader, path string) (stri
 	util.Logger.Info("文件上传成功", zap.String("fullPath", fullPath))
 	return path, nil // 返回相对路径
 }
+
+// SaveFile 将数据流写入存储目录，返回相对路径
+func (s *LocalStorage) SaveFile(path string, src io.Reader) (string, error) {
+	fullPath := filepath.Join(s.basePath, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return "", fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	dst, err := os.Create(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("创建文件失败: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err = io.Copy(dst, src); err != nil {
+		return "", fmt.Errorf("保存文件失败: %w", err)
+	}
+	return path, nil
+}
+
+// FullPath 返回相对路径对应的本地文件路径
+func (s *LocalStorage) FullPath(path string) string {
+	return filepath.Join(s.basePath, path)
+}
+
+// DeleteFile 删除文件，文件不存在时视为成功
+func (s *LocalStorage) DeleteFile(path string) error {
+	if err := os.Remove(filepath.Join(s.basePath, path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -3,61 +3,95 @@ package util
 import (
 	"crowdfunding-backend/config"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
-func GenerateToken(userID int) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+// 令牌用途，防止两步验证挑战令牌被当作访问令牌使用
+const (
+	tokenTypeAccess       = "access"
+	tokenTypeMFAChallenge = "mfa_challenge"
+)
 
-	return token.SignedString([]byte(config.AppConfig.JWTSecret))
+// GenerateAccessToken 签发访问令牌，jti 为登录会话ID，注销或撤销会话后令牌随即失效
+func GenerateAccessToken(userID int, sessionID string, ttl time.Duration) (string, error) {
+	return signToken(jwt.MapClaims{
+		"typ":     tokenTypeAccess,
+		"user_id": userID,
+		"jti":     sessionID,
+	}, ttl)
 }
 
-func ValidateToken(tokenString string) (int, error) {
-	if tokenString == "" {
-		return 0, errors.New("令牌为空")
+// ParseAccessToken 校验访问令牌，返回用户ID和会话ID
+func ParseAccessToken(tokenString string) (int, string, error) {
+	claims, err := parseToken(tokenString, tokenTypeAccess)
+	if err != nil {
+		return 0, "", err
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.AppConfig.JWTSecret), nil
-	})
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, "", errors.New("无效的用户ID")
+	}
+	sessionID, ok := claims["jti"].(string)
+	if !ok || sessionID == "" {
+		return 0, "", errors.New("令牌缺少会话ID")
+	}
+	return int(userID), sessionID, nil
+}
+
+// GenerateMFAChallengeToken 签发两步验证挑战令牌，密码验证通过后用于换取会话
+func GenerateMFAChallengeToken(userID int, ttl time.Duration) (string, error) {
+	return signToken(jwt.MapClaims{
+		"typ":     tokenTypeMFAChallenge,
+		"user_id": userID,
+	}, ttl)
+}
 
+// ParseMFAChallengeToken 校验两步验证挑战令牌，返回用户ID
+func ParseMFAChallengeToken(tokenString string) (int, error) {
+	claims, err := parseToken(tokenString, tokenTypeMFAChallenge)
 	if err != nil {
 		return 0, err
 	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			return 0, errors.New("无效的用户ID")
-		}
-		return int(userID), nil
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, errors.New("无效的用户ID")
 	}
+	return int(userID), nil
+}
 
-	return 0, errors.New("无效的令牌")
+func signToken(claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.AppConfig.JWTSecret))
 }
 
-func RefreshToken(tokenString string) (string, error) {
+func parseToken(tokenString, tokenType string) (jwt.MapClaims, error) {
+	if tokenString == "" {
+		return nil, errors.New("令牌为空")
+	}
+
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("不支持的签名算法: %v", token.Header["alg"])
+		}
 		return []byte(config.AppConfig.JWTSecret), nil
 	})
-
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID := int(claims["user_id"].(float64))
-		newToken, err := GenerateToken(userID)
-		if err != nil {
-			return "", err
-		}
-		return newToken, nil
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("无效的令牌")
 	}
-
-	return "", errors.New("invalid token")
+	if typ, _ := claims["typ"].(string); typ != tokenType {
+		return nil, errors.New("令牌类型不匹配")
+	}
+	return claims, nil
 }
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTOTPCode 使用 RFC 6238 附录 B 的 SHA1 测试向量
+func TestTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(tt.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.code, code, tt.unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	previous, _ := TOTPCode(secret, TOTPStep(now)-1)
+	step, ok := ValidateTOTP(secret, previous, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now)-1, step)
+
+	stale, _ := TOTPCode(secret, TOTPStep(now)-2)
+	_, ok = ValidateTOTP(secret, stale, now, 1)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(secret, "12345", now, 1)
+	assert.False(t, ok)
+
+	uri := TOTPProvisioningURI("Crowdfunding", "a@example.com", secret)
+	assert.Contains(t, uri, "otpauth://totp/Crowdfunding:a@example.com?")
+	assert.Contains(t, uri, "secret="+secret)
+}